@@ -0,0 +1,48 @@
+//go:build !js
+
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WithAdminToken(token string) HandlerOption {
+	return func(h *Handler) {
+		h.adminToken = token
+	}
+}
+
+func (h *Handler) requireAdmin(c *gin.Context) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": map[string]string{
+				"authorization": "valid admin token required",
+			},
+		})
+		return
+	}
+	c.Next()
+}
+
+// setupAdminRoutes registers nothing unless an admin token is configured, so
+// a deployment without ADMIN_TOKEN has no admin surface at all.
+func (h *Handler) setupAdminRoutes(router gin.IRouter) {
+	if h.adminToken == "" {
+		return
+	}
+
+	admin := router.Group("/admin", h.stage(StageAuth, h.requireAdmin))
+	{
+		admin.PUT("/disabled-countries", h.SetDisabledCountries)
+		admin.POST("/maintenance", h.SetMaintenance)
+		admin.GET("/stats/export", h.ExportUsageStats)
+		admin.POST("/metadata/dry-run", h.MetadataDryRun)
+		admin.GET("/metadata/coverage", h.MetadataCoverage)
+		admin.GET("/shadow-report", h.ShadowReport)
+	}
+}
@@ -0,0 +1,223 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyConfigKey is where requireAPIKey stores the caller's *APIKeyConfig.
+const apiKeyConfigKey = "apiKeyConfig"
+
+// APIKeyConfig is the per-key configuration from the keys file. An empty
+// AllowedCountries allows every enabled country and a zero
+// RateLimitPerMinute means unlimited. ResponseCase is the key's default for
+// the case parameter.
+type APIKeyConfig struct {
+	Label              string   `json:"label"`
+	AllowedCountries   []string `json:"allowedCountries"`
+	RateLimitPerMinute int      `json:"rateLimitPerMinute"`
+	Enrichment         bool     `json:"enrichment"`
+	ResponseCase       string   `json:"responseCase,omitempty"`
+}
+
+func (k *APIKeyConfig) allowsCountry(countryCode string) bool {
+	if len(k.AllowedCountries) == 0 {
+		return true
+	}
+	for _, allowed := range k.AllowedCountries {
+		if strings.EqualFold(allowed, countryCode) {
+			return true
+		}
+	}
+	return false
+}
+
+// HashAPIKey returns the hex SHA-256 digest under which a key is listed in
+// the keys file.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyStore holds the keys file contents, a JSON object mapping hashed
+// keys to their APIKeyConfig. Reload swaps the contents atomically and
+// keeps the previous keys if the file is invalid. Labels must be unique:
+// rate limits, usage stats and idempotency keys are scoped by label.
+type APIKeyStore struct {
+	path string
+
+	mu   sync.RWMutex
+	keys map[string]*APIKeyConfig
+
+	limiterMu sync.Mutex
+	windows   map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func LoadAPIKeyStore(path string) (*APIKeyStore, error) {
+	store := &APIKeyStore{
+		path:    path,
+		windows: map[string]*rateWindow{},
+	}
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *APIKeyStore) Reload() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	var keys map[string]*APIKeyConfig
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("invalid API keys file %s: %w", s.path, err)
+	}
+	labels := make(map[string]string, len(keys))
+	for hash, config := range keys {
+		if config == nil || config.Label == "" {
+			return fmt.Errorf("invalid API keys file %s: key %s has no label", s.path, hash)
+		}
+		if other, exists := labels[config.Label]; exists {
+			return fmt.Errorf("invalid API keys file %s: keys %s and %s share the label %q", s.path, other, hash, config.Label)
+		}
+		labels[config.Label] = hash
+		if !validResponseCase(config.ResponseCase) {
+			return fmt.Errorf("invalid API keys file %s: key %s has unknown responseCase %q", s.path, hash, config.ResponseCase)
+		}
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *APIKeyStore) Lookup(key string) (*APIKeyConfig, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	config, exists := s.keys[HashAPIKey(key)]
+	return config, exists
+}
+
+// allow counts a request against the key's fixed one-minute window and
+// reports how long until the window resets when the limit is exceeded.
+func (s *APIKeyStore) allow(config *APIKeyConfig, now time.Time) (bool, time.Duration) {
+	if config.RateLimitPerMinute <= 0 {
+		return true, 0
+	}
+
+	s.limiterMu.Lock()
+	defer s.limiterMu.Unlock()
+
+	window, exists := s.windows[config.Label]
+	if !exists || now.Sub(window.start) >= time.Minute {
+		window = &rateWindow{start: now}
+		s.windows[config.Label] = window
+	}
+	if window.count >= config.RateLimitPerMinute {
+		return false, window.start.Add(time.Minute).Sub(now)
+	}
+	window.count++
+	return true, 0
+}
+
+func WithAPIKeys(store *APIKeyStore) HandlerOption {
+	return func(h *Handler) {
+		h.apiKeys = store
+	}
+}
+
+// requireAPIKey is a no-op unless a keys file is configured.
+func (h *Handler) requireAPIKey(c *gin.Context) {
+	if h.apiKeys == nil {
+		c.Next()
+		return
+	}
+
+	config, ok := h.apiKeys.Lookup(c.GetHeader("X-API-Key"))
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": map[string]string{
+				"apiKey": "valid API key required",
+			},
+		})
+		return
+	}
+
+	c.Set(apiKeyLabelKey, config.Label)
+	c.Set(apiKeyConfigKey, config)
+	c.Next()
+}
+
+// enforceRateLimit applies the global limit, then the per-minute limit of
+// the key requireAPIKey accepted; it runs after requireAPIKey.
+func (h *Handler) enforceRateLimit(c *gin.Context) {
+	if status, retryAfter, body := h.rateLimited(c.Request.Context(), apiKeyConfig(c)); status != 0 {
+		abortWithRetryAfter(c, status, retryAfter, body)
+		return
+	}
+	c.Next()
+}
+
+// rateLimited is the status, wait and body of a request over the global
+// limit or the limit of key, which is nil without API keys, or status 0.
+// With WithRedisRateLimit the limits are counted across replicas.
+func (h *Handler) rateLimited(ctx context.Context, key *APIKeyConfig) (int, time.Duration, gin.H) {
+	now := h.now()
+	if h.globalLimit != nil {
+		allowed, retryAfter, unavailable := h.redisLimit.allow(ctx, "global", h.globalLimit.perMinute, now, func() (bool, time.Duration) {
+			return h.globalLimit.allow(now)
+		})
+		if unavailable {
+			return http.StatusServiceUnavailable, retryAfter, rateLimitUnavailable()
+		}
+		if !allowed {
+			return http.StatusTooManyRequests, retryAfter, globalLimitExceeded()
+		}
+	}
+
+	if h.apiKeys == nil || key == nil {
+		return 0, 0, nil
+	}
+	allowed, retryAfter, unavailable := h.redisLimit.allow(ctx, "key:"+key.Label, key.RateLimitPerMinute, now, func() (bool, time.Duration) {
+		return h.apiKeys.allow(key, now)
+	})
+	if unavailable {
+		return http.StatusServiceUnavailable, retryAfter, rateLimitUnavailable()
+	}
+	if !allowed {
+		return http.StatusTooManyRequests, retryAfter, gin.H{
+			"error": map[string]string{
+				"apiKey": "rate limit exceeded",
+			},
+		}
+	}
+	return 0, 0, nil
+}
+
+// apiKeyConfig returns the caller's key configuration, or nil when keys are
+// not configured.
+func apiKeyConfig(c *gin.Context) *APIKeyConfig {
+	config, _ := c.Get(apiKeyConfigKey)
+	key, _ := config.(*APIKeyConfig)
+	return key
+}
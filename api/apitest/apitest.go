@@ -0,0 +1,146 @@
+//go:build !js
+
+// Package apitest runs a real, in-process phone-api instance for
+// integration tests, built by api.NewServer exactly like production.
+package apitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"phone-api/api"
+)
+
+// Option configures NewServer and NewRouter.
+type Option func(*config)
+
+type config struct {
+	handlerOptions   []api.HandlerOption
+	validatorOptions []api.ValidatorOption
+	observe          api.MiddlewareObserver
+	keys             map[string]api.APIKeyConfig
+	clientKey        string
+}
+
+// WithHandlerOptions passes options straight to api.NewHandler. They are
+// applied after the apitest options, so an api.WithValidatorOptions here
+// replaces WithDisabledCountries.
+func WithHandlerOptions(opts ...api.HandlerOption) Option {
+	return func(c *config) {
+		c.handlerOptions = append(c.handlerOptions, opts...)
+	}
+}
+
+// WithAPIKey enables API key authentication and registers key. The
+// client returned by NewServer sends the first key registered.
+func WithAPIKey(key string, keyConfig api.APIKeyConfig) Option {
+	return func(c *config) {
+		if c.keys == nil {
+			c.keys = map[string]api.APIKeyConfig{}
+			c.clientKey = key
+		}
+		c.keys[key] = keyConfig
+	}
+}
+
+// WithDisabledCountries disables lookups for the given countries.
+func WithDisabledCountries(countryCodes ...string) Option {
+	return func(c *config) {
+		c.validatorOptions = append(c.validatorOptions, api.WithDisabledCountries(countryCodes...))
+	}
+}
+
+// WithMiddlewareObserver sets api.Config.Observe.
+func WithMiddlewareObserver(observe api.MiddlewareObserver) Option {
+	return func(c *config) {
+		c.observe = observe
+	}
+}
+
+// NewRouter returns the full, warmed-up router for use with
+// httptest.NewRecorder. Use NewServer to test over a real connection.
+func NewRouter(tb testing.TB, opts ...Option) http.Handler {
+	tb.Helper()
+	router, _ := newRouter(tb, opts)
+	return router
+}
+
+// NewServer starts the full router on a local port and returns it with a
+// client pointed at it. The server is closed when the test ends.
+func NewServer(tb testing.TB, opts ...Option) (*httptest.Server, *Client) {
+	tb.Helper()
+	router, cfg := newRouter(tb, opts)
+
+	server := httptest.NewServer(router)
+	tb.Cleanup(server.Close)
+
+	return server, &Client{
+		BaseURL:    server.URL,
+		APIKey:     cfg.clientKey,
+		HTTPClient: server.Client(),
+	}
+}
+
+func newRouter(tb testing.TB, opts []Option) (http.Handler, *config) {
+	tb.Helper()
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var handlerOptions []api.HandlerOption
+	if len(cfg.validatorOptions) > 0 {
+		handlerOptions = append(handlerOptions, api.WithValidatorOptions(cfg.validatorOptions...))
+	}
+	if cfg.keys != nil {
+		handlerOptions = append(handlerOptions, api.WithAPIKeys(writeAPIKeys(tb, cfg.keys)))
+	}
+	handlerOptions = append(handlerOptions, cfg.handlerOptions...)
+
+	gin.SetMode(gin.TestMode)
+	server, err := api.NewServer(api.Config{HandlerOptions: handlerOptions, Observe: cfg.observe})
+	if err != nil {
+		tb.Fatalf("apitest: building server: %v", err)
+	}
+	return server.Handler(), cfg
+}
+
+// writeAPIKeys stores keys in the hashed keys-file format the server
+// loads in production.
+func writeAPIKeys(tb testing.TB, keys map[string]api.APIKeyConfig) *api.APIKeyStore {
+	tb.Helper()
+	hashed := make(map[string]api.APIKeyConfig, len(keys))
+	for key, keyConfig := range keys {
+		hashed[api.HashAPIKey(key)] = keyConfig
+	}
+	data, err := json.Marshal(hashed)
+	if err != nil {
+		tb.Fatalf("apitest: encoding API keys: %v", err)
+	}
+
+	path := filepath.Join(tb.TempDir(), "keys.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		tb.Fatalf("apitest: writing API keys: %v", err)
+	}
+	store, err := api.LoadAPIKeyStore(path)
+	if err != nil {
+		tb.Fatalf("apitest: loading API keys: %v", err)
+	}
+	return store
+}
+
+// ValidNumber returns the example number of a supported country in E.164
+// format. It panics for unsupported countries so a typo fails loudly.
+func ValidNumber(countryCode string) string {
+	number, exists := api.ExampleNumber(countryCode)
+	if !exists {
+		panic("apitest: no example number for country " + countryCode)
+	}
+	return number
+}
@@ -0,0 +1,87 @@
+//go:build !js
+
+package apitest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"phone-api/api"
+)
+
+// Client calls a phone-api instance, sending APIKey as X-API-Key when
+// set.
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// LookupError is a non-2xx lookup answer with its decoded body.
+type LookupError struct {
+	Status   int
+	Response api.ErrorResponse
+}
+
+func (e *LookupError) Error() string {
+	return fmt.Sprintf("phone-api: lookup failed with status %d: %v", e.Status, e.Response.Error)
+}
+
+// Lookup validates one number. countryCode may be empty for numbers in
+// international format. Rejections are returned as *LookupError.
+func (c *Client) Lookup(ctx context.Context, phoneNumber, countryCode string) (*api.PhoneValidationResponse, error) {
+	query := url.Values{"phoneNumber": {phoneNumber}}
+	if countryCode != "" {
+		query.Set("countryCode", countryCode)
+	}
+
+	resp, err := c.Get(ctx, "/v1/phone-numbers", query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		lookupErr := &LookupError{Status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(&lookupErr.Response); err != nil {
+			return nil, fmt.Errorf("phone-api: decoding error response: %w", err)
+		}
+		return nil, lookupErr
+	}
+
+	var response api.PhoneValidationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("phone-api: decoding response: %w", err)
+	}
+	return &response, nil
+}
+
+// Get sends an authenticated GET to path, for endpoints Client has no
+// method for. The caller closes the response body.
+func (c *Client) Get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	target := strings.TrimSuffix(c.BaseURL, "/") + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Do sends req with the client's API key.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
@@ -0,0 +1,64 @@
+//go:build !js
+
+package apitest
+
+import "phone-api/api"
+
+// ErrorCode and NumberType re-export the api enums, so code that only
+// imports apitest compares LookupError.Response.Code and interpretation
+// number types against the same constants the server answers with.
+type (
+	ErrorCode  = api.ErrorCode
+	NumberType = api.NumberType
+)
+
+const (
+	NumberTypeLandline = api.NumberTypeLandline
+	NumberTypeMobile   = api.NumberTypeMobile
+	NumberTypeTollFree = api.NumberTypeTollFree
+)
+
+const (
+	ErrorAreaCodeUndetermined      = api.ErrorAreaCodeUndetermined
+	ErrorCountryCodeRequired       = api.ErrorCountryCodeRequired
+	ErrorCountryDisabled           = api.ErrorCountryDisabled
+	ErrorCountryNotAllowed         = api.ErrorCountryNotAllowed
+	ErrorDialingPrefixOnly         = api.ErrorDialingPrefixOnly
+	ErrorEnrichmentNotAllowed      = api.ErrorEnrichmentNotAllowed
+	ErrorFeatureDisabled           = api.ErrorFeatureDisabled
+	ErrorIdempotencyKeyReused      = api.ErrorIdempotencyKeyReused
+	ErrorInputTooLong              = api.ErrorInputTooLong
+	ErrorInternal                  = api.ErrorInternal
+	ErrorInvalidCharacters         = api.ErrorInvalidCharacters
+	ErrorInvalidCountryCode        = api.ErrorInvalidCountryCode
+	ErrorInvalidExtension          = api.ErrorInvalidExtension
+	ErrorInvalidLeadingDigit       = api.ErrorInvalidLeadingDigit
+	ErrorInvalidSpacing            = api.ErrorInvalidSpacing
+	ErrorJobResultsUnavailable     = api.ErrorJobResultsUnavailable
+	ErrorJobStorageFull            = api.ErrorJobStorageFull
+	ErrorLengthOutOfRange          = api.ErrorLengthOutOfRange
+	ErrorLossyNumericFormat        = api.ErrorLossyNumericFormat
+	ErrorMalformedRequest          = api.ErrorMalformedRequest
+	ErrorMalformedTelURI           = api.ErrorMalformedTelURI
+	ErrorMetadataDegraded          = api.ErrorMetadataDegraded
+	ErrorMisplacedPlus             = api.ErrorMisplacedPlus
+	ErrorMissingSubscriberNumber   = api.ErrorMissingSubscriberNumber
+	ErrorNationalNumberRequired    = api.ErrorNationalNumberRequired
+	ErrorNotAvailableInDemo        = api.ErrorNotAvailableInDemo
+	ErrorNotE164                   = api.ErrorNotE164
+	ErrorOutboundBlocked           = api.ErrorOutboundBlocked
+	ErrorPhoneNumberRequired       = api.ErrorPhoneNumberRequired
+	ErrorPossibleIntegerTruncation = api.ErrorPossibleIntegerTruncation
+	ErrorRangeCrossesBoundary      = api.ErrorRangeCrossesBoundary
+	ErrorRangeInvalid              = api.ErrorRangeInvalid
+	ErrorRangeTooLarge             = api.ErrorRangeTooLarge
+	ErrorRejectedByHook            = api.ErrorRejectedByHook
+	ErrorRouteNotFound             = api.ErrorRouteNotFound
+	ErrorSuspiciousPattern         = api.ErrorSuspiciousPattern
+	ErrorTooManyJobs               = api.ErrorTooManyJobs
+	ErrorTrailingPunctuation       = api.ErrorTrailingPunctuation
+	ErrorUnknownDialingCode        = api.ErrorUnknownDialingCode
+	ErrorUnsupportedCountry        = api.ErrorUnsupportedCountry
+	ErrorUnsupportedCountryCode    = api.ErrorUnsupportedCountryCode
+	ErrorUnsupportedMediaType      = api.ErrorUnsupportedMediaType
+)
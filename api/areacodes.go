@@ -0,0 +1,92 @@
+package api
+
+// AreaCodeNames maps the leading digits of a national significant number
+// to the geographic area they serve. Lookups use the longest matching
+// prefix, so GB and DE entries can be shorter than the fixed split length.
+var AreaCodeNames = map[string]map[string]string{
+	"US": {
+		"202": "Washington",
+		"206": "Seattle",
+		"212": "New York",
+		"213": "Los Angeles",
+		"305": "Miami",
+		"310": "Los Angeles",
+		"312": "Chicago",
+		"415": "San Francisco",
+		"512": "Austin",
+		"617": "Boston",
+		"646": "New York",
+		"713": "Houston",
+		"718": "New York",
+	},
+	"CA": {
+		"403": "Calgary",
+		"416": "Toronto",
+		"514": "Montreal",
+		"604": "Vancouver",
+		"613": "Ottawa",
+		"647": "Toronto",
+	},
+	"GB": {
+		"20":  "London",
+		"113": "Leeds",
+		"117": "Bristol",
+		"121": "Birmingham",
+		"131": "Edinburgh",
+		"141": "Glasgow",
+		"151": "Liverpool",
+		"161": "Manchester",
+		"28":  "Belfast",
+		"29":  "Cardiff",
+	},
+	"DE": {
+		"30":  "Berlin",
+		"40":  "Hamburg",
+		"69":  "Frankfurt am Main",
+		"89":  "Munich",
+		"211": "Düsseldorf",
+		"221": "Cologne",
+		"711": "Stuttgart",
+	},
+	"ES": {
+		"91":  "Madrid",
+		"93":  "Barcelona",
+		"94":  "Bizkaia",
+		"96":  "Valencia",
+		"952": "Málaga",
+		"954": "Seville",
+		"976": "Zaragoza",
+	},
+	"ZA": {
+		"11": "Johannesburg",
+		"12": "Pretoria",
+		"21": "Cape Town",
+		"31": "Durban",
+	},
+	// Abuja (9) is left out: the longest-prefix match would also name
+	// mobiles on the 90x and 91x networks.
+	"NG": {
+		"1":  "Lagos",
+		"64": "Kano",
+		"84": "Port Harcourt",
+	},
+	"KR": {
+		"2":  "Seoul",
+		"31": "Gyeonggi",
+		"32": "Incheon",
+		"51": "Busan",
+		"53": "Daegu",
+	},
+}
+
+// AreaCodeName returns the area served by a national number, or "" when the
+// country has no table or the prefix is unknown or non-geographic.
+func AreaCodeName(countryCode, nationalNumber string) string {
+	names := AreaCodeNames[countryCode]
+	for length := len(nationalNumber); length > 0; length-- {
+		if name, exists := names[nationalNumber[:length]]; exists {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+//go:build !js
+
+package api
+
+import "strings"
+
+// WithBasePath mounts every route under path, for deployments behind a
+// reverse proxy that forwards a prefix such as "/api/phone" unchanged.
+// Surrounding slashes are normalized away; "" and "/" mount at the root.
+func WithBasePath(path string) HandlerOption {
+	return func(h *Handler) {
+		h.basePath = NormalizeBasePath(path)
+	}
+}
+
+// NormalizeBasePath returns path with one leading slash and no trailing
+// slash, or "" for the root.
+func NormalizeBasePath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
@@ -0,0 +1,203 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const MaxBatchSize = 100
+
+type BatchRequest struct {
+	Items []BatchItem `json:"items"`
+}
+
+// BatchItem is a lookup request plus an optional client ID, echoed
+// verbatim on the item's result. IDs need not be unique.
+type BatchItem struct {
+	ID string `json:"id,omitempty"`
+	PhoneValidationRequest
+}
+
+// BatchItemResult carries the status the single endpoint would have
+// answered with, except that validation failures use 422 instead of 400 so
+// 400 stays reserved for a malformed envelope. Error.FieldPath names the
+// input that failed, e.g. items[17].countryCode.
+type BatchItemResult struct {
+	Index  int                      `json:"index"`
+	ID     string                   `json:"id,omitempty"`
+	Status int                      `json:"status"`
+	Result *PhoneValidationResponse `json:"result,omitempty"`
+	Error  *ErrorResponse           `json:"error,omitempty"`
+}
+
+// BatchSummary lists every item ID sent more than once in DuplicateIDs.
+type BatchSummary struct {
+	Total        int      `json:"total"`
+	ValidCount   int      `json:"validCount"`
+	FailedCount  int      `json:"failedCount"`
+	DuplicateIDs []string `json:"duplicateIds,omitempty"`
+	// FailureReasons is the /v1/stats failureReasons matrix for this batch.
+	FailureReasons map[string]map[string]int64 `json:"failureReasons,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+	Summary BatchSummary      `json:"summary"`
+
+	Deprecations []Deprecation `json:"deprecations,omitempty"`
+}
+
+// BatchLookup answers 200 when every item validated and 207 Multi-Status
+// when any item failed, including when all of them did.
+func (h *Handler) BatchLookup(c *gin.Context) {
+	if c.ContentType() == MediaTypeCSV {
+		h.batchCSV(c)
+		return
+	}
+
+	var req BatchRequest
+	if errorResponse := bindJSONBody(c, &req); errorResponse != nil {
+		c.JSON(http.StatusBadRequest, errorResponse)
+		return
+	}
+	if problem := batchSizeProblem(len(req.Items)); problem != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"items": problem,
+			},
+		})
+		return
+	}
+
+	options, errorResponse := h.resolveRequestOptions(c)
+	if errorResponse != nil {
+		c.JSON(http.StatusBadRequest, errorResponse)
+		return
+	}
+
+	status, response := h.batchLookup(ginLookupScope(c), req.Items, options)
+	response.Deprecations = requestDeprecations(c)
+	c.JSON(status, response)
+}
+
+func batchSizeProblem(items int) string {
+	if items == 0 {
+		return "request body must be {\"items\": [...]} with at least one item"
+	}
+	if items > MaxBatchSize {
+		return "at most " + strconv.Itoa(MaxBatchSize) + " items are allowed"
+	}
+	return ""
+}
+
+// batchLookup runs every item through processLookup and summarizes them.
+func (h *Handler) batchLookup(scope lookupScope, items []BatchItem, options RequestOptions) (int, BatchResponse) {
+	response := BatchResponse{
+		Results: make([]BatchItemResult, 0, len(items)),
+		Summary: BatchSummary{Total: len(items), DuplicateIDs: duplicateIDs(items)},
+	}
+	for i, item := range items {
+		outcome := h.processLookup(scope, item.PhoneValidationRequest, options)
+		result := BatchItemResult{
+			Index:  i,
+			ID:     item.ID,
+			Status: outcome.status,
+			Result: outcome.response,
+			Error:  outcome.errorResponse,
+		}
+		if result.Status == http.StatusBadRequest {
+			result.Status = http.StatusUnprocessableEntity
+		}
+		if result.Error != nil {
+			result.Error.FieldPath = itemFieldPath(i, result.Error)
+			response.Summary.FailedCount++
+			response.Summary.addFailureReason(h.failureReason(outcome))
+		} else {
+			response.Summary.ValidCount++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	status := http.StatusOK
+	if response.Summary.FailedCount > 0 {
+		status = http.StatusMultiStatus
+	}
+	return status, response
+}
+
+func (s *BatchSummary) addFailureReason(countryCode, code string) {
+	if s.FailureReasons == nil {
+		s.FailureReasons = map[string]map[string]int64{}
+	}
+	if s.FailureReasons[countryCode] == nil {
+		s.FailureReasons[countryCode] = map[string]int64{}
+	}
+	s.FailureReasons[countryCode][code]++
+}
+
+// batchItemFields holds the JSON names of BatchItem's fields.
+var batchItemFields = func() map[string]bool {
+	fields := map[string]bool{}
+	for _, typ := range []reflect.Type{reflect.TypeOf(BatchItem{}), reflect.TypeOf(PhoneValidationRequest{})} {
+		for i := 0; i < typ.NumField(); i++ {
+			if name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ","); name != "" {
+				fields[name] = true
+			}
+		}
+	}
+	return fields
+}()
+
+// errorField is the field an error is reported on; of several, the first
+// in name order.
+func errorField(errorResponse *ErrorResponse) string {
+	fields := make([]string, 0, len(errorResponse.Error))
+	for field := range errorResponse.Error {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// itemFieldPath is the path of the input a failed item is reported on,
+// such as items[17].phoneNumber. Extensions are read from phoneNumber, and
+// an error on nothing the item sent, such as a disabled feature, points
+// at the item itself.
+func itemFieldPath(index int, errorResponse *ErrorResponse) string {
+	path := "items[" + strconv.Itoa(index) + "]"
+	field := errorField(errorResponse)
+	if field == "extension" {
+		field = "phoneNumber"
+	}
+	if !batchItemFields[field] {
+		return path
+	}
+	return path + "." + field
+}
+
+// duplicateIDs returns the non-empty IDs that occur more than once, sorted.
+func duplicateIDs(items []BatchItem) []string {
+	seen := map[string]int{}
+	var duplicates []string
+	for _, item := range items {
+		if item.ID == "" {
+			continue
+		}
+		seen[item.ID]++
+		if seen[item.ID] == 2 {
+			duplicates = append(duplicates, item.ID)
+		}
+	}
+	sort.Strings(duplicates)
+	return duplicates
+}
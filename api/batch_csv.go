@@ -0,0 +1,314 @@
+//go:build !js
+
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ErrorInvalidExtension ErrorCode = "INVALID_EXTENSION"
+
+// csvBatchColumns are the recognised input columns, matched case-insensitively.
+// Only phoneNumber is required. param names the query parameter that maps
+// the column to another header, and synonyms are what ?autoDetect=true
+// matches when neither the parameter nor the column name is present.
+var csvBatchColumns = []struct {
+	name     string
+	param    string
+	synonyms []string
+}{
+	{"phoneNumber", "numberColumn", []string{"phone", "number", "phoneno", "msisdn", "mobile", "mobilenumber", "cell", "cellphone", "tel", "telephone", "telefono", "teléfono", "telefone", "téléphone", "telefon", "telefonnummer", "numero", "número", "numéro", "celular", "handy"}},
+	{"countryCode", "countryColumn", []string{"country", "countryiso", "iso", "pais", "país", "pays", "land", "paese", "region"}},
+	{"extension", "extensionColumn", []string{"ext", "extn", "durchwahl", "extensión", "ramal", "poste"}},
+	{"id", "idColumn", []string{"customerid", "clientid", "reference", "ref", "externalid", "cliente", "kundennummer"}},
+}
+
+// csvBatchOutputHeader appends new columns last so positional readers of
+// the earlier ones keep working; id is empty when the input has none.
+var csvBatchOutputHeader = []string{
+	"row", "status", "phoneNumber", "countryCode", "areaCode", "localPhoneNumber", "extension", "code", "error", "id", "ndc", "fieldPath",
+}
+
+// batchCSV is the text/csv variant of BatchLookup. A UTF-8 byte order
+// mark before the header is ignored. The extension column is validated on
+// its own and echoed in its own output column instead of
+// being merged into the number. ?lenient=true, or the lenient option,
+// applies to every row.
+func (h *Handler) batchCSV(c *gin.Context) {
+	batch, err := readCSVBatch(c.Request.Body, c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, csvBatchError(err))
+		return
+	}
+
+	options, errorResponse := h.resolveRequestOptions(c)
+	if errorResponse != nil {
+		c.JSON(http.StatusBadRequest, errorResponse)
+		return
+	}
+
+	status, output := h.csvBatchLookup(ginLookupScope(c), batch, options)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(status)
+	writeCSVBatch(c.Writer, batch.passthrough, output)
+}
+
+// csvBatchLookup returns the status and output records for the batch's
+// rows, without the header row.
+func (h *Handler) csvBatchLookup(scope lookupScope, batch *csvBatch, options RequestOptions) (int, [][]string) {
+	output := make([][]string, 0, len(batch.rows))
+	failed := 0
+	for i, row := range batch.rows {
+		record := []string{strconv.Itoa(i + 1)}
+
+		if row.extension != "" && strings.Trim(row.extension, "0123456789") != "" {
+			failed++
+			output = append(output, append(append(record, strconv.Itoa(http.StatusUnprocessableEntity), h.echoNumber(row.req.PhoneNumber), "", "", "", row.extension,
+				string(ErrorInvalidExtension), "extension: must contain only digits", row.id, "", batch.fieldPath(i, "extension")), row.passthrough...))
+			continue
+		}
+
+		outcome := h.processLookup(scope, row.req, options)
+		if outcome.errorResponse != nil {
+			failed++
+			status := outcome.status
+			if status == http.StatusBadRequest {
+				status = http.StatusUnprocessableEntity
+			}
+			output = append(output, append(append(record, strconv.Itoa(status), outcome.errorResponse.PhoneNumber, "", "", "", row.extension,
+				string(outcome.errorResponse.Code), formatErrorFields(outcome.errorResponse.Error), row.id, "", batch.fieldPath(i, errorField(outcome.errorResponse))), row.passthrough...))
+			continue
+		}
+
+		response := outcome.response
+		output = append(output, append(append(record, strconv.Itoa(outcome.status), response.PhoneNumber, response.CountryCode,
+			response.AreaCode, response.LocalPhoneNumber, row.extension, "", "", row.id, response.NDC, ""), row.passthrough...))
+	}
+
+	status := http.StatusOK
+	if failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	return status, output
+}
+
+// writeCSVBatch writes the header, with the passthrough input columns after
+// csvBatchOutputHeader, and the records.
+func writeCSVBatch(w io.Writer, passthrough []string, output [][]string) {
+	writer := csv.NewWriter(w)
+	writer.Write(append(append([]string{}, csvBatchOutputHeader...), passthrough...))
+	writer.WriteAll(output)
+}
+
+type csvBatch struct {
+	rows []csvBatchRow
+	// passthrough holds the names of the input columns that are not read,
+	// in input order.
+	passthrough []string
+	// headers maps each read column to its header in the input.
+	headers map[string]string
+}
+
+// fieldPath is the fieldPath column of the row at index i failing on
+// field: rows[3].MSISDN, numbered like the row column and naming the
+// input header, or rows[3] when field is not read from a column.
+func (b *csvBatch) fieldPath(i int, field string) string {
+	path := "rows[" + strconv.Itoa(i+1) + "]"
+	if header, ok := b.headers[field]; ok {
+		return path + "." + header
+	}
+	return path
+}
+
+type csvBatchRow struct {
+	req         PhoneValidationRequest
+	extension   string
+	id          string
+	passthrough []string
+}
+
+// CSVColumnError is returned for a mapping parameter naming a column that
+// is not in the header.
+type CSVColumnError struct {
+	Param     string
+	Column    string
+	Available []string
+}
+
+func (e *CSVColumnError) Error() string {
+	return "column " + strconv.Quote(e.Column) + " is not in the CSV header"
+}
+
+// csvBatchError is the 400 body for a CSV that cannot be read. A missing
+// mapped column is reported on its parameter, with the header's columns.
+func csvBatchError(err error) map[string]interface{} {
+	var columnErr *CSVColumnError
+	if errors.As(err, &columnErr) {
+		body := fieldError(columnErr.Param, err.Error())
+		body["availableColumns"] = columnErr.Available
+		return body
+	}
+	return fieldError("body", err.Error())
+}
+
+// csvColumnKey normalises a header for synonym matching: case, surrounding
+// space and separators are ignored.
+func csvColumnKey(name string) string {
+	return strings.NewReplacer(" ", "", "_", "", "-", "", ".", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// mapCSVColumns resolves each input column to its header index: the
+// mapping parameter wins, then the column's own name, then, with
+// autoDetect, its first synonym in header order.
+func mapCSVColumns(header []string, query url.Values) (map[string]int, error) {
+	autoDetect := false
+	if value, explicit := queryValue(query, "autoDetect"); explicit {
+		autoDetect, _ = strconv.ParseBool(value)
+	}
+
+	available := make([]string, len(header))
+	for i, name := range header {
+		available[i] = strings.TrimSpace(name)
+	}
+	find := func(names ...string) (int, bool) {
+		for i, name := range available {
+			for _, want := range names {
+				if strings.EqualFold(name, want) {
+					return i, true
+				}
+			}
+		}
+		return 0, false
+	}
+
+	columns := map[string]int{}
+	taken := map[int]bool{}
+	for _, column := range csvBatchColumns {
+		if mapped, explicit := queryValue(query, column.param); explicit && strings.TrimSpace(mapped) != "" {
+			i, ok := find(strings.TrimSpace(mapped))
+			if !ok {
+				return nil, &CSVColumnError{Param: column.param, Column: mapped, Available: available}
+			}
+			columns[column.name] = i
+			taken[i] = true
+		}
+	}
+	for _, column := range csvBatchColumns {
+		if _, mapped := columns[column.name]; mapped {
+			continue
+		}
+		if i, ok := find(column.name); ok && !taken[i] {
+			columns[column.name] = i
+			taken[i] = true
+		}
+	}
+	if autoDetect {
+		for _, column := range csvBatchColumns {
+			if _, mapped := columns[column.name]; mapped {
+				continue
+			}
+		detect:
+			for i, name := range available {
+				for _, synonym := range column.synonyms {
+					if !taken[i] && csvColumnKey(name) == synonym {
+						columns[column.name] = i
+						taken[i] = true
+						break detect
+					}
+				}
+			}
+		}
+	}
+	return columns, nil
+}
+
+func readCSVBatch(body io.Reader, query url.Values) (*csvBatch, error) {
+	reader := csv.NewReader(skipUTF8BOM(body))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("CSV body must start with a header row")
+	}
+
+	columns, err := mapCSVColumns(header, query)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := columns["phoneNumber"]; !ok {
+		return nil, errors.New("CSV header must include a phoneNumber column")
+	}
+
+	batch := &csvBatch{headers: map[string]string{}}
+	read := map[int]bool{}
+	for column, i := range columns {
+		read[i] = true
+		batch.headers[column] = strings.TrimSpace(header[i])
+	}
+	var passthrough []int
+	for i, name := range header {
+		if !read[i] {
+			passthrough = append(passthrough, i)
+			batch.passthrough = append(batch.passthrough, name)
+		}
+	}
+
+	cell := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("CSV body is malformed")
+		}
+		if len(batch.rows) == MaxBatchSize {
+			return nil, errors.New("at most " + strconv.Itoa(MaxBatchSize) + " rows are allowed")
+		}
+		row := csvBatchRow{
+			req: PhoneValidationRequest{
+				PhoneNumber: cell(record, "phoneNumber"),
+				CountryCode: cell(record, "countryCode"),
+			},
+			extension:   cell(record, "extension"),
+			id:          cell(record, "id"),
+			passthrough: make([]string, len(passthrough)),
+		}
+		for j, i := range passthrough {
+			if i < len(record) {
+				row.passthrough[j] = record[i]
+			}
+		}
+		batch.rows = append(batch.rows, row)
+	}
+	if len(batch.rows) == 0 {
+		return nil, errors.New("CSV body must contain at least one row")
+	}
+	return batch, nil
+}
+
+func formatErrorFields(fields map[string]string) string {
+	parts := make([]string, 0, len(fields))
+	for field, message := range fields {
+		parts = append(parts, field+": "+message)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,171 @@
+//go:build !js
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorMalformedRequest marks a request that could not be bound at all, as
+// opposed to one whose values failed validation.
+const ErrorMalformedRequest ErrorCode = "MALFORMED_REQUEST"
+
+// queryParameterKinds maps each form tag of PhoneValidationRequest to its
+// field kind, so bind failures can be reported per parameter.
+var queryParameterKinds = func() map[string]reflect.Kind {
+	kinds := map[string]reflect.Kind{}
+	typ := reflect.TypeOf(PhoneValidationRequest{})
+	for i := 0; i < typ.NumField(); i++ {
+		if name := typ.Field(i).Tag.Get("form"); name != "" {
+			kinds[name] = typ.Field(i).Type.Kind()
+		}
+	}
+	return kinds
+}()
+
+// bindLookupQuery binds the single-lookup query string. Gin silently keeps
+// the first of repeated values and reports type errors without naming the
+// parameter, so the raw query is checked first and every problem is listed
+// with the values that were received.
+func bindLookupQuery(c *gin.Context, req *PhoneValidationRequest) *ErrorResponse {
+	return checkLookupQuery(c.Request.URL.Query(), func() error { return c.ShouldBindQuery(req) })
+}
+
+// checkLookupQuery runs bind only once the raw query passed every check.
+func checkLookupQuery(query url.Values, bind func() error) *ErrorResponse {
+	problems := map[string]string{}
+	received := map[string][]string{}
+
+	for key, values := range query {
+		name := strings.TrimSuffix(key, "[]")
+		kind, known := queryParameterKinds[name]
+		if !known {
+			continue
+		}
+
+		switch {
+		case name != key:
+			problems[name] = "array given where a single value is expected"
+		case len(values) > 1:
+			problems[name] = "repeated parameter; a single value is expected"
+		case kind == reflect.Bool && values[0] != "":
+			if _, err := strconv.ParseBool(values[0]); err != nil {
+				problems[name] = "must be true or false"
+			}
+		default:
+			if problem := requestValueProblem(name, values[0]); problem != "" {
+				problems[name] = problem
+			}
+		}
+		if _, failed := problems[name]; failed {
+			received[name] = append(received[name], values...)
+		}
+	}
+
+	if len(problems) == 0 {
+		if err := bind(); err == nil {
+			return nil
+		}
+		problems["query"] = "invalid request parameters"
+	}
+
+	return &ErrorResponse{
+		PhoneNumber: query.Get("phoneNumber"),
+		Code:        ErrorMalformedRequest,
+		Error:       problems,
+		Received:    received,
+	}
+}
+
+// requestValueProblem checks the enumerated string parameters. It is
+// shared by query binding and processLookup, so body-decoded requests
+// reject the same values the query string does.
+func requestValueProblem(name, value string) string {
+	if value == "" {
+		return ""
+	}
+	switch {
+	case name == "inputFormat" && !strings.EqualFold(value, InputFormatE164):
+		return "must be " + InputFormatE164
+	case name == "areaCodeStyle" && !strings.EqualFold(value, AreaCodeStyleBare) && !strings.EqualFold(value, AreaCodeStyleNational):
+		return "must be " + AreaCodeStyleBare + " or " + AreaCodeStyleNational
+	case name == "sourceType" && !strings.EqualFold(value, SourceTypeNumeric):
+		return "must be " + SourceTypeNumeric
+	case name == "countryHints":
+		return countryHintsProblem(value)
+	}
+	return ""
+}
+
+// valueProblems applies requestValueProblem to every enumerated field.
+func (r PhoneValidationRequest) valueProblems() map[string]string {
+	problems := map[string]string{}
+	for name, value := range map[string]string{"inputFormat": r.InputFormat, "areaCodeStyle": r.AreaCodeStyle, "sourceType": r.SourceType, "countryHints": r.CountryHints} {
+		if problem := requestValueProblem(name, value); problem != "" {
+			problems[name] = problem
+		}
+	}
+	return problems
+}
+
+// bindJSONBody decodes the request body into v, locating syntax and type
+// errors by byte offset.
+func bindJSONBody(c *gin.Context, v interface{}) *ErrorResponse {
+	return decodeJSONBody(c.Request.Body, v)
+}
+
+func decodeJSONBody(r io.Reader, v interface{}) *ErrorResponse {
+	var body []byte
+	err := errors.New("cannot read nil body")
+	if r != nil {
+		body, err = io.ReadAll(r)
+	}
+	if err != nil {
+		return &ErrorResponse{
+			Code:  ErrorMalformedRequest,
+			Error: map[string]string{"body": "request body could not be read"},
+		}
+	}
+
+	err = json.Unmarshal(body, v)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset := syntaxErr.Offset
+		return &ErrorResponse{
+			Code:   ErrorMalformedRequest,
+			Error:  map[string]string{"body": "invalid JSON at offset " + strconv.FormatInt(offset, 10) + ": " + syntaxErr.Error()},
+			Offset: &offset,
+		}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		offset := typeErr.Offset
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		return &ErrorResponse{
+			Code:   ErrorMalformedRequest,
+			Error:  map[string]string{field: "expected " + typeErr.Type.String() + ", got " + typeErr.Value},
+			Offset: &offset,
+		}
+	}
+
+	return &ErrorResponse{
+		Code:  ErrorMalformedRequest,
+		Error: map[string]string{"body": err.Error()},
+	}
+}
@@ -0,0 +1,213 @@
+//go:build !js
+
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// The runtime image ships without a zoneinfo database.
+	_ "time/tzdata"
+)
+
+// CountryTimezones lists the IANA zones each country's numbers may be in,
+// for numbers whose prefix is not in AreaCodeTimezones.
+var CountryTimezones = map[string][]string{
+	"US": {
+		"America/New_York", "America/Chicago", "America/Denver", "America/Phoenix",
+		"America/Los_Angeles", "America/Anchorage", "Pacific/Honolulu",
+	},
+	"CA": {
+		"America/St_Johns", "America/Halifax", "America/Toronto", "America/Winnipeg",
+		"America/Regina", "America/Edmonton", "America/Vancouver",
+	},
+	"MX": {
+		"America/Cancun", "America/Mexico_City", "America/Monterrey", "America/Chihuahua",
+		"America/Mazatlan", "America/Hermosillo", "America/Tijuana",
+	},
+	"ES": {"Europe/Madrid", "Atlantic/Canary"},
+	"PT": {"Europe/Lisbon", "Atlantic/Azores"},
+	"GB": {"Europe/London"},
+	"FR": {"Europe/Paris"},
+	"DE": {"Europe/Berlin"},
+	"IT": {"Europe/Rome"},
+	"BR": {
+		"America/Noronha", "America/Sao_Paulo", "America/Fortaleza", "America/Manaus",
+		"America/Cuiaba", "America/Porto_Velho", "America/Rio_Branco",
+	},
+	"GP": {"America/Guadeloupe"},
+	"GF": {"America/Cayenne"},
+	"MQ": {"America/Martinique"},
+	"RE": {"Indian/Reunion"},
+	"ZA": {"Africa/Johannesburg"},
+	"NG": {"Africa/Lagos"},
+	"KR": {"Asia/Seoul"},
+}
+
+// AreaCodeTimezones narrows multi-zone countries by the leading digits of
+// the national number, longest prefix first like AreaCodeNames.
+var AreaCodeTimezones = map[string]map[string]string{
+	"US": {
+		"202": "America/New_York",
+		"206": "America/Los_Angeles",
+		"212": "America/New_York",
+		"213": "America/Los_Angeles",
+		"305": "America/New_York",
+		"310": "America/Los_Angeles",
+		"312": "America/Chicago",
+		"415": "America/Los_Angeles",
+		"512": "America/Chicago",
+		"602": "America/Phoenix",
+		"617": "America/New_York",
+		"646": "America/New_York",
+		"713": "America/Chicago",
+		"718": "America/New_York",
+		"720": "America/Denver",
+		"808": "Pacific/Honolulu",
+		"907": "America/Anchorage",
+	},
+	"CA": {
+		"403": "America/Edmonton",
+		"416": "America/Toronto",
+		"514": "America/Toronto",
+		"604": "America/Vancouver",
+		"613": "America/Toronto",
+		"647": "America/Toronto",
+		"709": "America/St_Johns",
+		"902": "America/Halifax",
+	},
+	"MX": {
+		"33":  "America/Mexico_City",
+		"55":  "America/Mexico_City",
+		"81":  "America/Monterrey",
+		"631": "America/Hermosillo",
+		"664": "America/Tijuana",
+		"998": "America/Cancun",
+	},
+	"ES": {
+		"922": "Atlantic/Canary",
+		"928": "Atlantic/Canary",
+		"9":   "Europe/Madrid",
+	},
+	"PT": {
+		"292": "Atlantic/Azores",
+		"295": "Atlantic/Azores",
+		"296": "Atlantic/Azores",
+		"2":   "Europe/Lisbon",
+	},
+	"BR": {
+		"11": "America/Sao_Paulo",
+		"21": "America/Sao_Paulo",
+		"61": "America/Sao_Paulo",
+		"65": "America/Cuiaba",
+		"85": "America/Fortaleza",
+		"92": "America/Manaus",
+	},
+}
+
+// CallingHours is the local time range, in minutes after midnight, within
+// which a number may be called: Start inclusive, End exclusive.
+type CallingHours struct {
+	Start int
+	End   int
+}
+
+var DefaultCallingHours = CallingHours{Start: 9 * 60, End: 20 * 60}
+
+// ParseCallingHours reads "HH:MM" boundaries; empty strings keep the
+// DefaultCallingHours boundary.
+func ParseCallingHours(start, end string) (CallingHours, error) {
+	hours := DefaultCallingHours
+	for _, boundary := range []struct {
+		value  string
+		target *int
+	}{{start, &hours.Start}, {end, &hours.End}} {
+		if boundary.value == "" {
+			continue
+		}
+		minutes, err := parseClock(boundary.value)
+		if err != nil {
+			return CallingHours{}, err
+		}
+		*boundary.target = minutes
+	}
+	if hours.Start >= hours.End {
+		return CallingHours{}, fmt.Errorf("calling hours must start before they end")
+	}
+	return hours, nil
+}
+
+func parseClock(value string) (int, error) {
+	hour, minute, found := strings.Cut(value, ":")
+	h, hourErr := strconv.Atoi(hour)
+	m, minuteErr := strconv.Atoi(minute)
+	if !found || hourErr != nil || minuteErr != nil || h < 0 || h > 24 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time of day %q, want HH:MM", value)
+	}
+	return h*60 + m, nil
+}
+
+func WithCallingHours(hours CallingHours) HandlerOption {
+	return func(h *Handler) {
+		h.callingHours = hours
+	}
+}
+
+// Timezones returns the zones a national number may be in, or nil when the
+// country has no timezone data.
+func Timezones(countryCode, nationalNumber string) []string {
+	byPrefix := AreaCodeTimezones[countryCode]
+	for length := len(nationalNumber); length > 0; length-- {
+		if zone, exists := byPrefix[nationalNumber[:length]]; exists {
+			return []string{zone}
+		}
+	}
+	return CountryTimezones[countryCode]
+}
+
+// callWindow evaluates response's candidate zones at now, or returns nil
+// when none is known.
+func (hours CallingHours) callWindow(response *PhoneValidationResponse, now time.Time) *CallWindow {
+	var window *CallWindow
+	slack := 0
+	for _, name := range Timezones(response.CountryCode, response.NDC+response.LocalPhoneNumber) {
+		location, err := loadZone(name)
+		if err != nil {
+			continue
+		}
+		local := now.In(location)
+		_, offset := local.Zone()
+		minutes := local.Hour()*60 + local.Minute()
+		// Negative slack is outside calling hours.
+		zoneSlack := min(minutes-hours.Start, hours.End-1-minutes)
+		if window == nil || zoneSlack < slack {
+			window = &CallWindow{
+				Timezone:           name,
+				UTCOffsetMinutes:   offset / 60,
+				LocalTime:          local.Format(time.RFC3339),
+				WithinCallingHours: zoneSlack >= 0,
+			}
+			slack = zoneSlack
+		}
+	}
+	return window
+}
+
+var zones sync.Map
+
+// loadZone is time.LoadLocation, which reads the zoneinfo database on
+// every call, memoised.
+func loadZone(name string) (*time.Location, error) {
+	if location, cached := zones.Load(name); cached {
+		return location.(*time.Location), nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	zones.Store(name, location)
+	return location, nil
+}
@@ -0,0 +1,107 @@
+//go:build !js
+
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCallingHours_CallWindow(t *testing.T) {
+	newYork := &PhoneValidationResponse{CountryCode: "US", NDC: "212", LocalPhoneNumber: "5690123"}
+	london := &PhoneValidationResponse{CountryCode: "GB", NDC: "20", LocalPhoneNumber: "79460958"}
+	unknownUS := &PhoneValidationResponse{CountryCode: "US", NDC: "900", LocalPhoneNumber: "5550123"}
+
+	tests := []struct {
+		name     string
+		response *PhoneValidationResponse
+		now      string
+		want     CallWindow
+	}{
+		{
+			name:     "before the spring DST change",
+			response: newYork,
+			now:      "2024-03-10T06:59:00Z",
+			want:     CallWindow{Timezone: "America/New_York", UTCOffsetMinutes: -300, LocalTime: "2024-03-10T01:59:00-05:00"},
+		},
+		{
+			name:     "after the spring DST change",
+			response: newYork,
+			now:      "2024-03-10T14:00:00Z",
+			want:     CallWindow{Timezone: "America/New_York", UTCOffsetMinutes: -240, LocalTime: "2024-03-10T10:00:00-04:00", WithinCallingHours: true},
+		},
+		{
+			name:     "summer time in London",
+			response: london,
+			now:      "2024-10-26T08:30:00Z",
+			want:     CallWindow{Timezone: "Europe/London", UTCOffsetMinutes: 60, LocalTime: "2024-10-26T09:30:00+01:00", WithinCallingHours: true},
+		},
+		{
+			name:     "the same UTC time after the autumn change",
+			response: london,
+			now:      "2024-10-27T08:30:00Z",
+			want:     CallWindow{Timezone: "Europe/London", UTCOffsetMinutes: 0, LocalTime: "2024-10-27T08:30:00Z"},
+		},
+		{
+			name:     "end of calling hours is exclusive",
+			response: london,
+			now:      "2024-10-27T20:00:00Z",
+			want:     CallWindow{Timezone: "Europe/London", UTCOffsetMinutes: 0, LocalTime: "2024-10-27T20:00:00Z"},
+		},
+		{
+			name:     "several zones, one outside calling hours",
+			response: unknownUS,
+			now:      "2024-07-01T13:30:00Z",
+			want:     CallWindow{Timezone: "Pacific/Honolulu", UTCOffsetMinutes: -600, LocalTime: "2024-07-01T03:30:00-10:00"},
+		},
+		{
+			name:     "several zones, all inside calling hours",
+			response: unknownUS,
+			now:      "2024-07-01T23:30:00Z",
+			want:     CallWindow{Timezone: "America/New_York", UTCOffsetMinutes: -240, LocalTime: "2024-07-01T19:30:00-04:00", WithinCallingHours: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, _ := time.Parse(time.RFC3339, tt.now)
+			got := DefaultCallingHours.callWindow(tt.response, now)
+			if got == nil {
+				t.Fatal("Expected a call window")
+			}
+			if *got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, *got)
+			}
+		})
+	}
+
+	if got := DefaultCallingHours.callWindow(&PhoneValidationResponse{CountryCode: "XX"}, time.Now()); got != nil {
+		t.Errorf("Expected no call window without timezone data, got %+v", *got)
+	}
+}
+
+func TestParseCallingHours(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       CallingHours
+		wantErr    bool
+	}{
+		{start: "", end: "", want: DefaultCallingHours},
+		{start: "08:30", end: "", want: CallingHours{Start: 510, End: 1200}},
+		{start: "10:00", end: "24:00", want: CallingHours{Start: 600, End: 1440}},
+		{start: "21:00", end: "", wantErr: true},
+		{start: "9am", end: "", wantErr: true},
+		{start: "", end: "24:30", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCallingHours(tt.start, tt.end)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCallingHours(%q, %q) error = %v, wantErr %v", tt.start, tt.end, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCallingHours(%q, %q) = %+v, want %+v", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
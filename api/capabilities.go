@@ -0,0 +1,111 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Endpoint struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+type CapabilityLimits struct {
+	MaxBatchSize   int `json:"maxBatchSize"`
+	MaxJobSize     int `json:"maxJobSize"`
+	MaxInputLength int `json:"maxInputLength"`
+	MaxE164Digits  int `json:"maxE164Digits"`
+}
+
+// Capabilities is assembled from the handler's live configuration on every
+// request, so it tracks runtime changes such as disabled countries.
+type Capabilities struct {
+	Endpoints         []Endpoint       `json:"endpoints"`
+	Features          map[string]bool  `json:"features"`
+	Limits            CapabilityLimits `json:"limits"`
+	Languages         []string         `json:"languages"`
+	Countries         []string         `json:"countries"`
+	DisabledCountries []string         `json:"disabledCountries"`
+	MetadataVersion   string           `json:"metadataVersion"`
+	// Degraded is set while validation runs on the minimal table.
+	Degraded bool `json:"degraded,omitempty"`
+	// Banner is only set in demo mode.
+	Banner string `json:"banner,omitempty"`
+}
+
+func (h *Handler) Capabilities(c *gin.Context) {
+	languages := make([]string, 0, len(CountryNames))
+	for language := range CountryNames {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	richMetadata := !h.validator.MetadataDegraded()
+
+	countries := make([]string, 0, len(CountryPhoneLengths))
+	for code := range CountryPhoneLengths {
+		countries = append(countries, code)
+	}
+	sort.Strings(countries)
+
+	c.JSON(http.StatusOK, Capabilities{
+		Endpoints: h.endpoints,
+		Features: map[string]bool{
+			"batch":               h.featureEnabled(FeatureBatch),
+			"jobs":                h.featureEnabled(FeatureJobs),
+			"interpretations":     richMetadata,
+			"dialingInstructions": richMetadata,
+			"webhookTest":         h.featureEnabled(FeatureWebhookTest),
+			"lenientParsing":      true,
+			"areaCodeNames":       richMetadata,
+			"enum":                h.enum != nil,
+			"enumCache":           h.enum != nil,
+			"apiKeys":             h.apiKeys != nil,
+			"admin":               h.adminToken != "",
+			"failureSampling":     h.failureSampler != nil,
+		},
+		Limits: CapabilityLimits{
+			MaxBatchSize:   MaxBatchSize,
+			MaxJobSize:     MaxJobSize,
+			MaxInputLength: h.validator.MaxInputLength(),
+			MaxE164Digits:  MaxE164Digits,
+		},
+		Languages:         languages,
+		Countries:         countries,
+		DisabledCountries: h.validator.DisabledCountries().List(),
+		MetadataVersion:   MetadataVersion(),
+		Degraded:          !richMetadata,
+		Banner:            h.banner(),
+	})
+}
+
+func (h *Handler) banner() string {
+	if h.demo {
+		return DemoBanner
+	}
+	return ""
+}
+
+// collectEndpoints records the public routes once everything but the
+// OPTIONS responders is registered.
+func (h *Handler) collectEndpoints(router *gin.Engine) {
+	var endpoints []Endpoint
+	for _, route := range router.Routes() {
+		if route.Method == http.MethodOptions || strings.HasPrefix(route.Path, h.basePath+"/admin") {
+			continue
+		}
+		endpoints = append(endpoints, Endpoint{Method: route.Method, Path: route.Path})
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Path != endpoints[j].Path {
+			return endpoints[i].Path < endpoints[j].Path
+		}
+		return endpoints[i].Method < endpoints[j].Method
+	})
+	h.endpoints = endpoints
+}
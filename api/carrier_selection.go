@@ -0,0 +1,27 @@
+package api
+
+import "strings"
+
+// carrierSelectionCountries are the countries whose long-distance numbers
+// are dialed as trunk prefix, a two-digit carrier selection code, then the
+// national number: 0 21 11 98765 4321 is carrier 21 calling DDD 11.
+var carrierSelectionCountries = map[string]bool{
+	"BR": true,
+}
+
+// stripCarrierSelectionCode removes the trunk prefix and carrier selection
+// code from national input that is exactly three digits longer than a
+// valid national number, raising WarningCarrierSelectionCodeRemoved with
+// the code as detail. Anything else is returned unchanged and validated
+// as sent.
+func (v *PhoneNumberValidator) stripCarrierSelectionCode(countryCode, nationalNumber string, warnings *warningSet) string {
+	countryCode = strings.ToUpper(countryCode)
+	if !carrierSelectionCountries[countryCode] || !strings.HasPrefix(nationalNumber, "0") || len(nationalNumber) < 3 {
+		return nationalNumber
+	}
+	if !v.lengthFits(nationalNumber[3:], countryCode) {
+		return nationalNumber
+	}
+	warnings.add(WarningCarrierSelectionCodeRemoved, nationalNumber[1:3])
+	return nationalNumber[3:]
+}
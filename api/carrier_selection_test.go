@@ -0,0 +1,53 @@
+package api
+
+import "testing"
+
+func TestPhoneNumberValidator_CarrierSelectionCode(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		countryCode string
+		carrier     string
+	}{
+		{name: "Carrier prefixed mobile", phoneNumber: "0 21 11 98765 4321", countryCode: "BR", carrier: "21"},
+		{name: "Carrier prefixed without spaces", phoneNumber: "02111987654321", countryCode: "BR", carrier: "21"},
+		{name: "Other carrier", phoneNumber: "0 15 11 98765 4321", countryCode: "BR", carrier: "15"},
+		{name: "Plain national", phoneNumber: "11 98765 4321", countryCode: "BR"},
+		{name: "International", phoneNumber: "+55 11 987654321"},
+		{name: "International with country", phoneNumber: "+5511987654321", countryCode: "BR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.PhoneNumber != "+5511987654321" || result.NDC != "11" {
+				t.Errorf("Expected +5511987654321 with DDD 11, got %s (%s)", result.PhoneNumber, result.NDC)
+			}
+			carrier := ""
+			for _, warning := range result.WarningDetails {
+				if warning.Code == WarningCarrierSelectionCodeRemoved {
+					carrier = warning.Detail
+				}
+			}
+			if carrier != tt.carrier {
+				t.Errorf("Expected carrier %q, got warnings %+v", tt.carrier, result.WarningDetails)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_CarrierSelectionCodeOnlyForBR(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	if _, err := validator.ValidatePhoneNumber("0211198765432", "MX"); err == nil {
+		t.Errorf("Expected carrier selection codes to be left alone outside BR")
+	}
+	if _, err := validator.ValidatePhoneNumber("021119876543", "BR"); err == nil {
+		t.Errorf("Expected a carrier prefixed number of invalid length to fail")
+	}
+}
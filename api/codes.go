@@ -0,0 +1,33 @@
+package api
+
+import "fmt"
+
+// ErrorCode is the code of an ErrorResponse. The Error* constants are
+// every code the API answers with; see ErrorRegistry for how each is
+// reported.
+type ErrorCode string
+
+// NumberType is a number type of NumberTypeLengths, reported as an
+// interpretation's numberType.
+type NumberType string
+
+// NumberTypes lists every NumberType.
+var NumberTypes = []NumberType{NumberTypeLandline, NumberTypeMobile, NumberTypeTollFree}
+
+// IsValid reports whether t is one of NumberTypes.
+func (t NumberType) IsValid() bool {
+	for _, known := range NumberTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNumberType returns the NumberType spelled s.
+func ParseNumberType(s string) (NumberType, error) {
+	if t := NumberType(s); t.IsValid() {
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown number type %q", s)
+}
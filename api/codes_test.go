@@ -0,0 +1,46 @@
+//go:build !js
+
+package api
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNumberTypesRoundTrip(t *testing.T) {
+	for _, numberType := range NumberTypes {
+		data, err := json.Marshal(Interpretation{NumberType: numberType})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Interpretation
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded.NumberType != numberType {
+			t.Errorf("%s: round-tripped as %q (%v)", numberType, decoded.NumberType, err)
+		}
+		if parsed, err := ParseNumberType(string(numberType)); err != nil || parsed != numberType {
+			t.Errorf("%s: parsed as %q (%v)", numberType, parsed, err)
+		}
+	}
+	if _, err := ParseNumberType("fax"); err == nil || NumberType("fax").IsValid() {
+		t.Error("Expected an unknown number type to be rejected")
+	}
+}
+
+func TestErrorCodesRoundTrip(t *testing.T) {
+	for code := range ErrorRegistry {
+		data, err := json.Marshal(ErrorResponse{Code: code})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded ErrorResponse
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded.Code != code {
+			t.Errorf("%s: round-tripped as %q (%v)", code, decoded.Code, err)
+		}
+		if parsed, err := ParseErrorCode(string(code)); err != nil || parsed != code {
+			t.Errorf("%s: parsed as %q (%v)", code, parsed, err)
+		}
+	}
+	if _, err := ParseErrorCode(OutcomeInvalidFormat); err == nil || ErrorCode(OutcomeValid).IsValid() {
+		t.Error("Expected outcomes that are not error codes to be rejected")
+	}
+}
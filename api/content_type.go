@@ -0,0 +1,91 @@
+//go:build !js
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ErrorUnsupportedMediaType ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+
+// Media types POST bodies are read as.
+const (
+	MediaTypeJSON = "application/json"
+	MediaTypeCSV  = "text/csv"
+)
+
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// WithStrictContentType answers 415 UNSUPPORTED_MEDIA_TYPE for a POST
+// body whose Content-Type is missing or not one its route reads, such as
+// JSON sent as text/plain. Without it such bodies are read as JSON.
+// Charsets other than UTF-8 are rejected either way.
+func WithStrictContentType() HandlerOption {
+	return func(h *Handler) {
+		h.strictContentType = true
+	}
+}
+
+// contentTypeProblem is the 415 body for a request body of header that a
+// route reading accepted cannot take, or nil. A charset other than UTF-8
+// (or its ASCII subset) is always refused, since the body would be read
+// as UTF-8 regardless.
+func (h *Handler) contentTypeProblem(header string, accepted []string) *ErrorResponse {
+	problem := ""
+	mediaType, params, err := mime.ParseMediaType(header)
+	switch {
+	case header == "" || err != nil:
+		if h.strictContentType {
+			problem = "must be one of " + strings.Join(accepted, ", ")
+		}
+	case params["charset"] != "" && !strings.EqualFold(params["charset"], "utf-8") && !strings.EqualFold(params["charset"], "us-ascii"):
+		problem = "charset must be utf-8"
+	case h.strictContentType && !acceptsMediaType(accepted, mediaType):
+		problem = "must be one of " + strings.Join(accepted, ", ")
+	}
+	if problem == "" {
+		return nil
+	}
+	return &ErrorResponse{
+		Code:     ErrorUnsupportedMediaType,
+		Error:    map[string]string{"contentType": problem},
+		Accepted: accepted,
+	}
+}
+
+func acceptsMediaType(accepted []string, mediaType string) bool {
+	for _, candidate := range accepted {
+		if strings.EqualFold(candidate, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// requireContentType guards a POST route whose body is one of accepted.
+func (h *Handler) requireContentType(accepted ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if errorResponse := h.contentTypeProblem(c.GetHeader("Content-Type"), accepted); errorResponse != nil {
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, errorResponse)
+			return
+		}
+		c.Next()
+	}
+}
+
+// skipUTF8BOM drops the byte order mark spreadsheet exports put in front
+// of UTF-8 CSV files, which would otherwise end up in the first header.
+func skipUTF8BOM(r io.Reader) io.Reader {
+	reader := bufio.NewReader(r)
+	if prefix, _ := reader.Peek(len(utf8BOM)); bytes.Equal(prefix, utf8BOM) {
+		reader.Discard(len(utf8BOM))
+	}
+	return reader
+}
@@ -0,0 +1,205 @@
+//go:build !js
+
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// corpusKeptDigits is how many leading digits a masked corpus entry keeps:
+// enough for the dialing code and the leading-digit rules of every
+// country. The digits after them are replaced, so lengths still match.
+const corpusKeptDigits = 5
+
+// CorpusEntry is one recorded lookup: the input as validated, the country
+// it concerns and its OutcomeValid or error code. Clean successes store the
+// E.164 number instead of the raw input. Entries recorded in privacy mode
+// carry a masked number of the same length and prefix, plus Hash, the hex
+// SHA-256 of the original input, so repeated inputs can still be counted.
+type CorpusEntry struct {
+	Request PhoneValidationRequest `json:"request"`
+	Country string                 `json:"country"`
+	Outcome string                 `json:"outcome"`
+	Masked  bool                   `json:"masked,omitempty"`
+	Hash    string                 `json:"hash,omitempty"`
+}
+
+// CorpusRecorder appends a CorpusEntry per validated lookup to a JSON Lines
+// file, for replaying real traffic shapes against candidate metadata with
+// ReplayCorpus. Key, hook and enrichment checks are not recorded: they do
+// not depend on metadata.
+type CorpusRecorder struct {
+	mu   sync.Mutex
+	file *os.File
+	out  *bufio.Writer
+}
+
+func NewCorpusRecorder(path string) (*CorpusRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &CorpusRecorder{file: file, out: bufio.NewWriter(file)}, nil
+}
+
+func WithCorpusRecorder(recorder *CorpusRecorder) HandlerOption {
+	return func(h *Handler) {
+		h.corpus = recorder
+	}
+}
+
+// Close flushes buffered entries and closes the file.
+func (r *CorpusRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.out.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+func (r *CorpusRecorder) record(req PhoneValidationRequest, response *PhoneValidationResponse, err error, masked bool) {
+	if r == nil {
+		return
+	}
+
+	entry := CorpusEntry{
+		Request: PhoneValidationRequest{
+			PhoneNumber:   req.PhoneNumber,
+			CountryCode:   req.CountryCode,
+			Lenient:       req.Lenient,
+			Truncate:      req.Truncate,
+			InputFormat:   req.InputFormat,
+			Strict:        req.Strict,
+			AreaCodeStyle: req.AreaCodeStyle,
+			SourceType:    req.SourceType,
+		},
+		Outcome: outcomeCode(err),
+	}
+	switch {
+	case err != nil:
+		entry.Country = failureCountry(err)
+		if entry.Country == "" {
+			entry.Country = requestedCountry(req)
+		}
+	case len(response.WarningDetails) == 0:
+		entry.Request = PhoneValidationRequest{PhoneNumber: response.PhoneNumber, AreaCodeStyle: req.AreaCodeStyle}
+		entry.Country = response.CountryCode
+	default:
+		entry.Country = response.CountryCode
+	}
+	if masked {
+		sum := sha256.Sum256([]byte(req.PhoneNumber))
+		entry.Hash = hex.EncodeToString(sum[:])
+		entry.Request.PhoneNumber = maskCorpusNumber(entry.Request.PhoneNumber, sum)
+		entry.Masked = true
+	}
+
+	line, marshalErr := json.Marshal(entry)
+	if marshalErr != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.out.Write(append(line, '\n'))
+}
+
+// maskCorpusNumber replaces every digit after the first corpusKeptDigits
+// with one derived from sum, keeping all other characters in place.
+func maskCorpusNumber(phoneNumber string, sum [sha256.Size]byte) string {
+	masked := []byte(phoneNumber)
+	digits := 0
+	for i, char := range masked {
+		if char < '0' || char > '9' {
+			continue
+		}
+		if digits >= corpusKeptDigits {
+			masked[i] = '0' + sum[digits%len(sum)]%10
+		}
+		digits++
+	}
+	return string(masked)
+}
+
+// LoadCorpus reads a file written by CorpusRecorder.
+func LoadCorpus(path string) ([]CorpusEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []CorpusEntry
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry CorpusEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("corpus %s line %d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// LoadCountryMetadata reads a JSON array of CountryMetadata, rejecting
+// entries that WithCandidateMetadata would.
+func LoadCountryMetadata(path string) ([]CountryMetadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []CountryMetadata
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid metadata file %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		if err := entry.validate(); err != nil {
+			return nil, fmt.Errorf("invalid metadata file %s: %s: %w", path, entry.CountryCode, err)
+		}
+	}
+	return entries, nil
+}
+
+// ReplayReport counts corpus entries whose outcome changed, by country and
+// then "OLD->NEW" outcome code.
+type ReplayReport struct {
+	Replayed int                       `json:"replayed"`
+	Changed  int                       `json:"changed"`
+	Diffs    map[string]map[string]int `json:"diffs"`
+}
+
+// ReplayCorpus re-validates entries with candidate and compares each
+// outcome with the recorded one.
+func ReplayCorpus(entries []CorpusEntry, candidate *PhoneNumberValidator) ReplayReport {
+	report := ReplayReport{Replayed: len(entries), Diffs: map[string]map[string]int{}}
+	for _, entry := range entries {
+		req := entry.Request
+		_, err := candidate.ValidatePhoneNumberWithOptions(req.PhoneNumber, req.CountryCode, req.parseOptions())
+		after := outcomeCode(err)
+		if after == entry.Outcome {
+			continue
+		}
+
+		country := entry.Country
+		if country == "" {
+			country = FailureReasonOther
+		}
+		if report.Diffs[country] == nil {
+			report.Diffs[country] = map[string]int{}
+		}
+		report.Diffs[country][entry.Outcome+"->"+after]++
+		report.Changed++
+	}
+	return report
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var CountryExampleNumbers = map[string]string{
+	"US": "+12125690123",
+	"CA": "+14165550123",
+	"MX": "+526313118150",
+	"ES": "+34915872200",
+	"PT": "+351210942000",
+	"GB": "+442079460958",
+	"FR": "+33142685300",
+	"DE": "+493012345678",
+	"IT": "+390612345678",
+	"BR": "+5511987654321",
+	"GP": "+590590271234",
+	"GF": "+594594301234",
+	"MQ": "+596596301234",
+	"RE": "+262262301234",
+	"ZA": "+27211234567",
+	"NG": "+2348012345678",
+	"KR": "+82212345678",
+}
+
+func ExampleNumber(countryCode string) (string, bool) {
+	number, exists := CountryExampleNumbers[countryCode]
+	return number, exists
+}
+
+// CountryInfo describes a supported country. AreaCodeNames reports whether
+// lookups fill in areaCodeName; Tier is the CountryCoverage tier.
+type CountryInfo struct {
+	CountryCode   string `json:"countryCode"`
+	CountryName   string `json:"countryName"`
+	DialingCode   string `json:"dialingCode"`
+	MinLength     int    `json:"minLength"`
+	MaxLength     int    `json:"maxLength"`
+	Enabled       bool   `json:"enabled"`
+	AreaCodeNames bool   `json:"areaCodeNames"`
+	Tier          string `json:"tier"`
+}
+
+// CountriesResponse lists the countries matching every filter. Total is
+// the number of supported countries and Count the number listed.
+type CountriesResponse struct {
+	Countries []CountryInfo `json:"countries"`
+	Total     int           `json:"total"`
+	Count     int           `json:"count"`
+}
+
+// CountryToggle is the runtime deny-list of countries. It is shared between
+// the validator and the admin endpoint, so changes apply without a restart.
+type CountryToggle struct {
+	mu       sync.RWMutex
+	disabled map[string]bool
+}
+
+func NewCountryToggle(codes ...string) *CountryToggle {
+	t := &CountryToggle{}
+	t.Set(codes)
+	return t
+}
+
+func (t *CountryToggle) Set(codes []string) {
+	disabled := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		disabled[strings.ToUpper(code)] = true
+	}
+
+	t.mu.Lock()
+	t.disabled = disabled
+	t.mu.Unlock()
+}
+
+func (t *CountryToggle) IsDisabled(code string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.disabled[code]
+}
+
+func (t *CountryToggle) List() []string {
+	t.mu.RLock()
+	codes := make([]string, 0, len(t.disabled))
+	for code := range t.disabled {
+		codes = append(codes, code)
+	}
+	t.mu.RUnlock()
+
+	sort.Strings(codes)
+	return codes
+}
+
+// ParseCountryList parses a comma-separated list such as "FR, de".
+func ParseCountryList(spec string) []string {
+	var codes []string
+	for _, code := range strings.Split(spec, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// MetadataVersion fingerprints the country tables, so it changes whenever
+// lengths, dialing codes or leading-digit rules do.
+func MetadataVersion() string {
+	codes := make([]string, 0, len(CountryPhoneLengths))
+	for code := range CountryPhoneLengths {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	hash := sha256.New()
+	for _, code := range codes {
+		fmt.Fprintf(hash, "%s:%v:%s:%s;", code, CountryPhoneLengths[code], CountryDialingCodes[code], CountryLeadingDigits[code])
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
@@ -0,0 +1,105 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListCountries filters with AND semantics: dialingCode matches exactly
+// (a leading + is ignored), q is a case- and accent-insensitive substring
+// of the name in the negotiated language, and capability is a
+// CountryCoverage flag that must be set.
+func (h *Handler) ListCountries(c *gin.Context) {
+	dialingCode := strings.TrimPrefix(strings.TrimSpace(c.Query("dialingCode")), "+")
+	query := foldName(strings.TrimSpace(c.Query("q")))
+	capability := c.Query("capability")
+	hasCapability, known := coverageCapabilities[capability]
+	if capability != "" && !known {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"capability": "unknown capability " + capability,
+			},
+		})
+		return
+	}
+
+	codes := make([]string, 0, len(CountryPhoneLengths))
+	for code := range CountryPhoneLengths {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	language := NegotiateLanguage(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", language)
+
+	toggle := h.validator.DisabledCountries()
+	countries := make([]CountryInfo, 0, len(codes))
+	for _, code := range codes {
+		name := CountryName(code, language)
+		coverage := h.validator.Coverage(code)
+		if dialingCode != "" && CountryDialingCodes[code] != dialingCode {
+			continue
+		}
+		if query != "" && !strings.Contains(foldName(name), query) {
+			continue
+		}
+		if hasCapability != nil && !hasCapability(coverage) {
+			continue
+		}
+
+		lengths := CountryPhoneLengths[code]
+		countries = append(countries, CountryInfo{
+			CountryCode:   code,
+			CountryName:   name,
+			DialingCode:   CountryDialingCodes[code],
+			MinLength:     lengths[0],
+			MaxLength:     lengths[1],
+			Enabled:       !toggle.IsDisabled(code),
+			AreaCodeNames: len(AreaCodeNames[code]) > 0,
+			Tier:          coverage.Tier,
+		})
+	}
+
+	c.JSON(http.StatusOK, CountriesResponse{Countries: countries, Total: len(codes), Count: len(countries)})
+}
+
+type disabledCountriesRequest struct {
+	Countries []string `json:"countries"`
+}
+
+func (h *Handler) SetDisabledCountries(c *gin.Context) {
+	var req disabledCountriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"countries": "invalid request body",
+			},
+		})
+		return
+	}
+
+	for i, code := range req.Countries {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if _, exists := CountryPhoneLengths[code]; !exists {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": map[string]string{
+					"countries": "unsupported country code " + code,
+				},
+			})
+			return
+		}
+		req.Countries[i] = code
+	}
+
+	toggle := h.validator.DisabledCountries()
+	toggle.Set(req.Countries)
+
+	c.JSON(http.StatusOK, gin.H{
+		"disabledCountries": toggle.List(),
+	})
+}
@@ -0,0 +1,73 @@
+//go:build !js
+
+package api
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MaxCountryHints caps the countryHints list, since every hint can cost a
+// validation.
+const MaxCountryHints = 10
+
+// CountryCodeSourceHint is PhoneValidationResponse.CountryCodeSource for a
+// country taken from countryHints.
+const CountryCodeSourceHint = "hint"
+
+// CountryHintsError is the missing-country error of a national number no
+// hint validated. It reports like the error it wraps.
+type CountryHintsError struct {
+	Err      error
+	Failures []CountryHintFailure
+}
+
+func (e *CountryHintsError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CountryHintsError) Unwrap() error {
+	return e.Err
+}
+
+func countryHintsProblem(value string) string {
+	hints := ParseCountryList(value)
+	if len(hints) > MaxCountryHints {
+		return "at most " + strconv.Itoa(MaxCountryHints) + " countries are allowed"
+	}
+	for _, hint := range hints {
+		if len(hint) != 2 {
+			return "must be a comma-separated list of ISO 3166-1 alpha-2 codes"
+		}
+	}
+	return ""
+}
+
+// validateWithHints retries a national number that failed for lack of a
+// country under each of req.CountryHints in order, returning the first
+// that validates. Hints the API key may not use are skipped. When none
+// validates, err comes back as a CountryHintsError.
+func (h *Handler) validateWithHints(scope lookupScope, req PhoneValidationRequest, err error) (*PhoneValidationResponse, cacheHit, error) {
+	hintsErr := &CountryHintsError{Err: err}
+	for _, hint := range ParseCountryList(req.CountryHints) {
+		if scope.key != nil && !scope.key.allowsCountry(hint) {
+			hintsErr.Failures = append(hintsErr.Failures, CountryHintFailure{CountryCode: hint, Reason: string(ErrorCountryNotAllowed)})
+			continue
+		}
+		hinted := req
+		hinted.CountryCode, hinted.CountryHints = hint, ""
+		response, cached, hintErr := h.validate(hinted)
+		if hintErr == nil {
+			response.CountryCodeSource = CountryCodeSourceHint
+			return response, cached, nil
+		}
+		hintsErr.Failures = append(hintsErr.Failures, CountryHintFailure{CountryCode: hint, Reason: outcomeCode(hintErr)})
+	}
+	return nil, cacheMiss, hintsErr
+}
+
+// usesCountryHints reports whether err is a national number without a
+// country that req has hints for.
+func usesCountryHints(req PhoneValidationRequest, err error) bool {
+	return err == errCountryRequired && strings.TrimSpace(req.CountryHints) != ""
+}
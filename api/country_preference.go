@@ -0,0 +1,50 @@
+//go:build !js
+
+package api
+
+import "strings"
+
+// CountryCodeSourcePreference is PhoneValidationResponse.CountryCodeSource
+// for a country taken from the WithCountryPreference order.
+const CountryCodeSourcePreference = "preference"
+
+// WithCountryPreference resolves lenient national numbers sent without a
+// country, a plus or countryHints by trying countries in order and
+// accepting the first that validates, with warning COUNTRY_FROM_PREFERENCE.
+// A countryCode or countryHints in the request always wins, and when no
+// country validates the missing-country error is returned as without it.
+func WithCountryPreference(countries ...string) HandlerOption {
+	return func(h *Handler) {
+		h.countryPreference = nil
+		for _, country := range countries {
+			if country = strings.ToUpper(strings.TrimSpace(country)); country != "" {
+				h.countryPreference = append(h.countryPreference, country)
+			}
+		}
+	}
+}
+
+// usesCountryPreference reports whether err is a lenient national number
+// without a country or hints that the preference order applies to.
+func (h *Handler) usesCountryPreference(req PhoneValidationRequest, err error) bool {
+	return len(h.countryPreference) > 0 && err == errCountryRequired && req.Lenient && strings.TrimSpace(req.CountryHints) == ""
+}
+
+// validateWithPreference retries req under each preferred country the API
+// key may use, returning the first that validates, or err.
+func (h *Handler) validateWithPreference(scope lookupScope, req PhoneValidationRequest, err error) (*PhoneValidationResponse, cacheHit, error) {
+	for _, country := range h.countryPreference {
+		if scope.key != nil && !scope.key.allowsCountry(country) {
+			continue
+		}
+		preferred := req
+		preferred.CountryCode = country
+		response, cached, preferredErr := h.validate(preferred)
+		if preferredErr == nil {
+			response.CountryCodeSource = CountryCodeSourcePreference
+			response.addWarning(WarningCountryFromPreference, country)
+			return response, cached, nil
+		}
+	}
+	return nil, cacheMiss, err
+}
@@ -0,0 +1,86 @@
+package api
+
+import "sort"
+
+// Metadata tiers reported by CountryCoverage.
+const (
+	CoverageTierFull    = "FULL"
+	CoverageTierPartial = "PARTIAL"
+	CoverageTierMinimal = "MINIMAL"
+)
+
+// CountryCoverage reports which parts of a country's metadata are loaded.
+// A MINIMAL country is validated by length range alone; a FULL country has
+// every capability.
+type CountryCoverage struct {
+	CountryCode        string `json:"countryCode"`
+	LengthRange        bool   `json:"lengthRange"`
+	Pattern            bool   `json:"pattern"`
+	AreaCodeTable      bool   `json:"areaCodeTable"`
+	TypeClassification bool   `json:"typeClassification"`
+	Geocoding          bool   `json:"geocoding"`
+	ExampleNumber      bool   `json:"exampleNumber"`
+	Tier               string `json:"tier"`
+}
+
+type MetadataCoverageResponse struct {
+	Countries []CountryCoverage `json:"countries"`
+}
+
+// Coverage inspects the tables this validator uses for countryCode,
+// including any metadata overrides.
+func (v *PhoneNumberValidator) Coverage(countryCode string) CountryCoverage {
+	_, lengthRange := v.countryLengths(countryCode)
+	_, pattern := v.countryLeadingDigits(countryCode)
+	_, areaCodeTable := CountryAreaCodeLengths[countryCode]
+	_, exampleNumber := ExampleNumber(countryCode)
+
+	coverage := CountryCoverage{
+		CountryCode:        countryCode,
+		LengthRange:        lengthRange,
+		Pattern:            pattern,
+		AreaCodeTable:      areaCodeTable,
+		TypeClassification: len(NumberTypeLengths[countryCode]) > 0,
+		Geocoding:          len(AreaCodeNames[countryCode]) > 0,
+		ExampleNumber:      exampleNumber,
+	}
+	coverage.Tier = coverage.tier()
+	return coverage
+}
+
+// MetadataCoverage reports Coverage for every supported country, sorted
+// by country code.
+func (v *PhoneNumberValidator) MetadataCoverage() []CountryCoverage {
+	codes := make([]string, 0, len(CountryPhoneLengths))
+	for code := range CountryPhoneLengths {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	countries := make([]CountryCoverage, 0, len(codes))
+	for _, code := range codes {
+		countries = append(countries, v.Coverage(code))
+	}
+	return countries
+}
+
+// coverageCapabilities names the CountryCoverage flags accepted by the
+// countries endpoint's capability filter.
+var coverageCapabilities = map[string]func(CountryCoverage) bool{
+	"lengthRange":        func(c CountryCoverage) bool { return c.LengthRange },
+	"pattern":            func(c CountryCoverage) bool { return c.Pattern },
+	"areaCodeTable":      func(c CountryCoverage) bool { return c.AreaCodeTable },
+	"typeClassification": func(c CountryCoverage) bool { return c.TypeClassification },
+	"geocoding":          func(c CountryCoverage) bool { return c.Geocoding },
+	"exampleNumber":      func(c CountryCoverage) bool { return c.ExampleNumber },
+}
+
+func (c CountryCoverage) tier() string {
+	switch {
+	case c.LengthRange && c.Pattern && c.AreaCodeTable && c.TypeClassification && c.Geocoding && c.ExampleNumber:
+		return CoverageTierFull
+	case !c.Pattern && !c.AreaCodeTable && !c.TypeClassification && !c.Geocoding:
+		return CoverageTierMinimal
+	}
+	return CoverageTierPartial
+}
@@ -0,0 +1,65 @@
+package api
+
+import "testing"
+
+// addSyntheticCountry loads metadata for a country code no real country
+// uses and removes it when the test ends.
+func addSyntheticCountry(t *testing.T, code string, lengths [2]int, full bool) {
+	t.Helper()
+	CountryPhoneLengths[code] = lengths
+	if full {
+		CountryLeadingDigits[code] = "23"
+		CountryAreaCodeLengths[code] = 2
+		NumberTypeLengths[code] = []NumberTypeLength{{Type: NumberTypeLandline, LeadingDigits: "2", Lengths: lengths}}
+		AreaCodeNames[code] = map[string]string{"21": "Capital"}
+		CountryExampleNumbers[code] = "+9992123456"
+	}
+	t.Cleanup(func() {
+		delete(CountryPhoneLengths, code)
+		delete(CountryLeadingDigits, code)
+		delete(CountryAreaCodeLengths, code)
+		delete(NumberTypeLengths, code)
+		delete(AreaCodeNames, code)
+		delete(CountryExampleNumbers, code)
+	})
+}
+
+func TestCoverage(t *testing.T) {
+	addSyntheticCountry(t, "XA", [2]int{7, 7}, true)
+	addSyntheticCountry(t, "XB", [2]int{6, 9}, false)
+	validator := NewPhoneNumberValidator()
+
+	full := validator.Coverage("XA")
+	want := CountryCoverage{
+		CountryCode:        "XA",
+		LengthRange:        true,
+		Pattern:            true,
+		AreaCodeTable:      true,
+		TypeClassification: true,
+		Geocoding:          true,
+		ExampleNumber:      true,
+		Tier:               CoverageTierFull,
+	}
+	if full != want {
+		t.Errorf("Expected %+v, got %+v", want, full)
+	}
+
+	minimal := validator.Coverage("XB")
+	want = CountryCoverage{CountryCode: "XB", LengthRange: true, Tier: CoverageTierMinimal}
+	if minimal != want {
+		t.Errorf("Expected %+v, got %+v", want, minimal)
+	}
+
+	if tier := validator.Coverage("US").Tier; tier != CoverageTierPartial {
+		t.Errorf("Expected US to be %s, got %s", CoverageTierPartial, tier)
+	}
+}
+
+func TestCoverage_MetadataOverride(t *testing.T) {
+	addSyntheticCountry(t, "XB", [2]int{6, 9}, false)
+
+	validator := NewPhoneNumberValidator(WithCountryMetadata(CountryMetadata{CountryCode: "XB", MinLength: 6, MaxLength: 9, LeadingDigits: "5"}))
+	if coverage := validator.Coverage("XB"); !coverage.Pattern || coverage.Tier != CoverageTierPartial {
+		t.Errorf("Expected the override's leading digits to count as a pattern, got %+v", coverage)
+	}
+}
@@ -0,0 +1,88 @@
+//go:build !js
+
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DemoRateLimitPerMinute is the global limit of demo mode, shared by
+	// every caller.
+	DemoRateLimitPerMinute = 30
+
+	DemoBanner = "This is a public demo of phone-api. Requests are rate limited, batch, job, webhook and admin endpoints are disabled, and numbers are not logged."
+)
+
+// WithDemoMode sets up a public instance: no admin routes, no batch, job
+// or webhook test endpoints, a global rate limit of DemoRateLimitPerMinute
+// and privacy mode. Every response carries X-Demo-Mode: true and the
+// capabilities document a banner. Apply it after the other options so
+// they cannot re-enable what it turns off.
+func WithDemoMode() HandlerOption {
+	return func(h *Handler) {
+		for _, opt := range []HandlerOption{
+			WithAdminToken(""),
+			WithDisabledFeatures(FeatureBatch, FeatureJobs, FeatureWebhookTest),
+			WithGlobalRateLimit(DemoRateLimitPerMinute),
+			WithPrivacyMode(),
+		} {
+			opt(h)
+		}
+		h.demo = true
+	}
+}
+
+func (h *Handler) markDemo(c *gin.Context) {
+	c.Header("X-Demo-Mode", "true")
+	c.Next()
+}
+
+// globalLimit is a fixed one-minute window shared by every caller, checked
+// before any per-key limit.
+type globalLimit struct {
+	perMinute int
+
+	mu     sync.Mutex
+	window rateWindow
+}
+
+// WithGlobalRateLimit caps /v1 requests from all callers together at
+// perMinute.
+func WithGlobalRateLimit(perMinute int) HandlerOption {
+	return func(h *Handler) {
+		if perMinute <= 0 {
+			h.globalLimit = nil
+			return
+		}
+		h.globalLimit = &globalLimit{perMinute: perMinute}
+	}
+}
+
+func (g *globalLimit) allow(now time.Time) (bool, time.Duration) {
+	if g == nil {
+		return true, 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if now.Sub(g.window.start) >= time.Minute {
+		g.window = rateWindow{start: now}
+	}
+	if g.window.count >= g.perMinute {
+		return false, g.window.start.Add(time.Minute).Sub(now)
+	}
+	g.window.count++
+	return true, 0
+}
+
+func globalLimitExceeded() map[string]interface{} {
+	return gin.H{
+		"error": map[string]string{
+			"rateLimit": "global rate limit exceeded",
+		},
+	}
+}
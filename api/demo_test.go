@@ -0,0 +1,42 @@
+//go:build !js
+
+package api
+
+import (
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestWithPrivacyMode_OverridesRetention(t *testing.T) {
+	h := NewHandler(
+		WithPrivacyMode(),
+		WithRecentLookups(10),
+		WithFailureSampling(1, 10, log.New(io.Discard, "", 0)),
+	)
+	if h.recent != nil {
+		t.Error("Expected recent lookups to be off in privacy mode")
+	}
+	if h.failureSampler != nil {
+		t.Error("Expected failure sampling to be off in privacy mode")
+	}
+}
+
+func TestGlobalLimit_FixedWindow(t *testing.T) {
+	limit := &globalLimit{perMinute: 2}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limit.allow(start); !allowed {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+	}
+	allowed, retryAfter := limit.allow(start.Add(20 * time.Second))
+	if allowed || retryAfter != 40*time.Second {
+		t.Errorf("Expected a refusal with 40s to wait, got %v and %s", allowed, retryAfter)
+	}
+	if allowed, _ := limit.allow(start.Add(time.Minute)); !allowed {
+		t.Error("Expected a new window after a minute")
+	}
+}
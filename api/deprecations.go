@@ -0,0 +1,85 @@
+//go:build !js
+
+package api
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deprecationsKey is where markDeprecated collects the request's
+// deprecations for enveloped responses.
+const deprecationsKey = "deprecations"
+
+const DeprecationParamAlias = "PARAM_ALIAS"
+
+// deprecationDocs is the registry of deprecation keys and the page that
+// explains each migration. Marking an unregistered key panics, so no
+// warning ships without documentation.
+var deprecationDocs = map[string]string{
+	DeprecationParamAlias: "https://github.com/Shyam1089/phone-api#parameter-aliases",
+}
+
+// Deprecation is something slated for removal that the request used.
+type Deprecation struct {
+	Key              string `json:"key"`
+	Message          string `json:"message"`
+	DocumentationURL string `json:"documentationUrl"`
+}
+
+// DeprecationCounter counts requests per deprecation key so remaining
+// usage can be measured before removal.
+type DeprecationCounter struct {
+	counts sync.Map
+}
+
+func NewDeprecationCounter() *DeprecationCounter {
+	return &DeprecationCounter{}
+}
+
+func (d *DeprecationCounter) observe(key string) {
+	counter, _ := d.counts.LoadOrStore(key, new(atomic.Int64))
+	counter.(*atomic.Int64).Add(1)
+}
+
+func (d *DeprecationCounter) Counts() map[string]int64 {
+	counts := map[string]int64{}
+	d.counts.Range(func(key, value any) bool {
+		counts[key.(string)] = value.(*atomic.Int64).Load()
+		return true
+	})
+	return counts
+}
+
+// markDeprecated records that the request used a deprecated parameter or
+// field: a 299 Warning header, an entry for the response envelope and a
+// count for /v1/stats.
+func (h *Handler) markDeprecated(c *gin.Context, key, message string) {
+	deprecation := h.deprecation(key, message)
+	c.Writer.Header().Add("Warning", deprecation.headerValue())
+	c.Set(deprecationsKey, append(requestDeprecations(c), deprecation))
+}
+
+// deprecation counts one use of key and describes it.
+func (h *Handler) deprecation(key, message string) Deprecation {
+	documentationURL, registered := deprecationDocs[key]
+	if !registered {
+		panic("unregistered deprecation " + key)
+	}
+	h.deprecations.observe(key)
+	return Deprecation{Key: key, Message: message, DocumentationURL: documentationURL}
+}
+
+func (d Deprecation) headerValue() string {
+	return fmt.Sprintf(`299 phone-api "%s; see %s"`, d.Message, d.DocumentationURL)
+}
+
+// requestDeprecations returns what markDeprecated collected for c.
+func requestDeprecations(c *gin.Context) []Deprecation {
+	deprecations, _ := c.Get(deprecationsKey)
+	list, _ := deprecations.([]Deprecation)
+	return list
+}
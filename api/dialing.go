@@ -0,0 +1,110 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DialingInstructionsResponse struct {
+	PhoneNumber string `json:"phoneNumber"`
+	CountryCode string `json:"countryCode"`
+	FromCountry string `json:"fromCountry"`
+	Dial        string `json:"dial"`
+	IDDPrefix   string `json:"iddPrefix,omitempty"`
+}
+
+// OriginIDDPrefixes lists the IDD prefixes of countries that calls can be
+// dialed from but whose own numbers are not supported. They are kept out
+// of CountryIDDPrefixes, which parsing national input also reads.
+var OriginIDDPrefixes = map[string]string{
+	"AU": "0011",
+}
+
+// originIDDPrefix is the IDD prefix dialed from fromCountry, if known.
+func originIDDPrefix(fromCountry string) (string, bool) {
+	if prefix, ok := CountryIDDPrefixes[fromCountry]; ok {
+		return prefix, true
+	}
+	prefix, ok := OriginIDDPrefixes[fromCountry]
+	return prefix, ok
+}
+
+// DialString returns the digits to dial for a national significant number
+// of countryCode when calling from fromCountry, and the IDD prefix used if
+// the call is international. Inside the country the trunk prefix is
+// added; between countries sharing a dialing code, such as US and CA, the
+// dialing code is dialed without an IDD prefix. ok is false when
+// fromCountry has no known IDD prefix.
+func DialString(countryCode, nationalNumber, fromCountry string) (dial, iddPrefix string, ok bool) {
+	countryCode, fromCountry = strings.ToUpper(countryCode), strings.ToUpper(fromCountry)
+	iddPrefix, ok = originIDDPrefix(fromCountry)
+	if !ok {
+		return "", "", false
+	}
+
+	dialingCode := CountryDialingCodes[countryCode]
+	switch {
+	case fromCountry == countryCode:
+		return CountryTrunkPrefixes[countryCode] + nationalNumber, "", true
+	case CountryDialingCodes[fromCountry] == dialingCode:
+		return dialingCode + nationalNumber, "", true
+	}
+	return iddPrefix + dialingCode + nationalNumber, iddPrefix, true
+}
+
+// DialingInstructions answers GET /v1/phone-numbers/dialing-instructions.
+// The number is validated exactly as by the lookup endpoint; fromCountry
+// may be any country with a known IDD prefix, including ones whose own
+// numbers are not supported.
+func (h *Handler) DialingInstructions(c *gin.Context) {
+	var req PhoneValidationRequest
+	if errorResponse := bindLookupQuery(c, &req); errorResponse != nil {
+		c.JSON(http.StatusBadRequest, h.echo(errorResponse))
+		return
+	}
+
+	fromCountry := strings.ToUpper(c.Query("fromCountry"))
+	if fromCountry == "" {
+		c.JSON(http.StatusBadRequest, h.echo(&ErrorResponse{
+			PhoneNumber: req.PhoneNumber,
+			Error:       map[string]string{"fromCountry": "required value is missing"},
+		}))
+		return
+	}
+	if _, known := originIDDPrefix(fromCountry); !known {
+		c.JSON(http.StatusBadRequest, h.echo(&ErrorResponse{
+			PhoneNumber: req.PhoneNumber,
+			Error:       map[string]string{"fromCountry": "unsupported origin country"},
+		}))
+		return
+	}
+
+	response, err := h.validator.ValidatePhoneNumberWithOptions(req.PhoneNumber, req.CountryCode, req.parseOptions())
+	if err != nil {
+		status, errorResponse := h.validationFailure(req.PhoneNumber, err)
+		c.JSON(status, h.echo(errorResponse))
+		return
+	}
+	if key := apiKeyConfig(c); key != nil && !key.allowsCountry(response.CountryCode) {
+		c.JSON(http.StatusForbidden, h.echo(&ErrorResponse{
+			PhoneNumber: req.PhoneNumber,
+			Code:        ErrorCountryNotAllowed,
+			Error:       map[string]string{"countryCode": "not allowed for this API key"},
+		}))
+		return
+	}
+
+	nationalNumber := strings.TrimPrefix(response.PhoneNumber, "+"+CountryDialingCodes[response.CountryCode])
+	dial, iddPrefix, _ := DialString(response.CountryCode, nationalNumber, fromCountry)
+	c.JSON(http.StatusOK, DialingInstructionsResponse{
+		PhoneNumber: response.PhoneNumber,
+		CountryCode: response.CountryCode,
+		FromCountry: fromCountry,
+		Dial:        dial,
+		IDDPrefix:   iddPrefix,
+	})
+}
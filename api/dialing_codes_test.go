@@ -0,0 +1,63 @@
+//go:build !js
+
+package api
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestITUDialingCodes(t *testing.T) {
+	for code := range DialingCodeToCountry {
+		if _, assigned := ITUDialingCodes[code]; !assigned {
+			t.Errorf("Supported dialing code %s is missing from ITUDialingCodes", code)
+		}
+	}
+	// Prefix freedom is what makes the first match in the trie the code.
+	for code := range ITUDialingCodes {
+		for other := range ITUDialingCodes {
+			if code != other && strings.HasPrefix(other, code) {
+				t.Errorf("Dialing code %s is a prefix of %s", code, other)
+			}
+		}
+	}
+}
+
+func TestPhoneNumberValidator_DialingCodeOutcomes(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		phoneNumber     string
+		wantCode        ErrorCode
+		wantRegion      string
+		wantDialingCode string
+	}{
+		{"+12125690123", "", "", ""},
+		{"+81312345678", ErrorUnsupportedCountry, "JP", "81"},
+		{"+80012345678", ErrorUnsupportedCountry, RegionNonGeographic, "800"},
+		{"+999123456789", ErrorUnknownDialingCode, "", "999"},
+		{"+2812345678", ErrorUnknownDialingCode, "", "281"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			_, err := validator.ValidatePhoneNumber(tt.phoneNumber, "")
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			var dialingCodeErr *DialingCodeError
+			if !errors.As(err, &dialingCodeErr) {
+				t.Fatalf("Expected a DialingCodeError, got %v", err)
+			}
+			if dialingCodeErr.Code() != tt.wantCode || dialingCodeErr.RegionCode != tt.wantRegion || dialingCodeErr.DialingCode != tt.wantDialingCode {
+				t.Errorf("Expected %s %q +%s, got %+v", tt.wantCode, tt.wantRegion, tt.wantDialingCode, dialingCodeErr)
+			}
+			if errorCode(err) != tt.wantCode {
+				t.Errorf("Expected error code %s, got %q", tt.wantCode, errorCode(err))
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+//go:build !js
+
+package api
+
+import "testing"
+
+func TestDialString(t *testing.T) {
+	tests := []struct {
+		name           string
+		countryCode    string
+		nationalNumber string
+		fromCountry    string
+		wantDial       string
+		wantIDD        string
+	}{
+		{"US to US", "US", "2125690123", "US", "2125690123", ""},
+		{"DE to US", "US", "2125690123", "DE", "0012125690123", "00"},
+		{"US to GB", "GB", "2079460958", "US", "011442079460958", "011"},
+		{"GB to GB adds trunk prefix", "GB", "2079460958", "GB", "02079460958", ""},
+		{"AU to US", "US", "2125690123", "AU", "001112125690123", "0011"},
+		{"AU to DE", "DE", "30123456", "au", "00114930123456", "0011"},
+		{"US to CA shares dialing code", "CA", "4165550123", "US", "14165550123", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dial, iddPrefix, ok := DialString(tt.countryCode, tt.nationalNumber, tt.fromCountry)
+			if !ok {
+				t.Fatalf("Expected %s to be a known origin", tt.fromCountry)
+			}
+			if dial != tt.wantDial || iddPrefix != tt.wantIDD {
+				t.Errorf("Expected %s (IDD %q), got %s (IDD %q)", tt.wantDial, tt.wantIDD, dial, iddPrefix)
+			}
+		})
+	}
+
+	if _, _, ok := DialString("US", "2125690123", "XX"); ok {
+		t.Errorf("Expected unknown origin country to be rejected")
+	}
+	if _, parsed := CountryIDDPrefixes["AU"]; parsed {
+		t.Errorf("Expected AU to be an origin only, unknown to parsing")
+	}
+}
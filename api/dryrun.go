@@ -0,0 +1,169 @@
+//go:build !js
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const MaxDryRunSamples = 1000
+
+// OutcomeValid is the dry-run outcome of a sample that validates.
+const OutcomeValid = "VALID"
+
+// Outcomes of failures the lookup endpoint reports without a code.
+const (
+	OutcomeInvalidCountry = "INVALID_COUNTRY"
+	OutcomeInvalidFormat  = "INVALID_FORMAT"
+)
+
+type MetadataDryRunRequest struct {
+	Candidate CountryMetadata          `json:"candidate"`
+	Samples   []PhoneValidationRequest `json:"samples"`
+	UseRecent bool                     `json:"useRecent"`
+}
+
+// MetadataDryRunChange is a sample whose outcome differs between the
+// current and candidate metadata. Numbers replayed from the recent-lookups
+// buffer are masked.
+type MetadataDryRunChange struct {
+	PhoneNumber string `json:"phoneNumber"`
+	CountryCode string `json:"countryCode,omitempty"`
+	Before      string `json:"before"`
+	After       string `json:"after"`
+}
+
+// MetadataDryRunResponse groups changed samples by "OLD->NEW" outcome code.
+type MetadataDryRunResponse struct {
+	Candidate   CountryMetadata                   `json:"candidate"`
+	Sampled     int                               `json:"sampled"`
+	Changed     int                               `json:"changed"`
+	Transitions map[string][]MetadataDryRunChange `json:"transitions"`
+}
+
+// MetadataDryRun validates samples against the current metadata and a
+// candidate entry for one country and reports which outcomes would change.
+// Nothing is recorded and the live validator is left untouched.
+func (h *Handler) MetadataDryRun(c *gin.Context) {
+	var req MetadataDryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"candidate": "invalid request body",
+			},
+		})
+		return
+	}
+
+	req.Candidate.CountryCode = strings.ToUpper(strings.TrimSpace(req.Candidate.CountryCode))
+	candidate, err := h.validator.WithCandidateMetadata(req.Candidate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"candidate": err.Error(),
+			},
+		})
+		return
+	}
+
+	samples := req.Samples
+	masked := false
+	if req.UseRecent {
+		if h.recent == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": map[string]string{
+					"useRecent": "recent lookups are not recorded",
+				},
+			})
+			return
+		}
+		samples = append(samples, h.recent.snapshot()...)
+		masked = true
+	}
+	if len(samples) == 0 || len(samples) > MaxDryRunSamples {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"samples": "between 1 and " + strconv.Itoa(MaxDryRunSamples) + " samples are required",
+			},
+		})
+		return
+	}
+
+	response := MetadataDryRunResponse{
+		Candidate:   req.Candidate,
+		Sampled:     len(samples),
+		Transitions: map[string][]MetadataDryRunChange{},
+	}
+	for i, sample := range samples {
+		opts := sample.parseOptions()
+		_, beforeErr := h.validator.ValidatePhoneNumberWithOptions(sample.PhoneNumber, sample.CountryCode, opts)
+		_, afterErr := candidate.ValidatePhoneNumberWithOptions(sample.PhoneNumber, sample.CountryCode, opts)
+
+		before, after := outcomeCode(beforeErr), outcomeCode(afterErr)
+		if before == after {
+			continue
+		}
+
+		phoneNumber := sample.PhoneNumber
+		if masked && i >= len(req.Samples) {
+			phoneNumber = maskPhoneNumber(phoneNumber)
+		}
+		key := before + "->" + after
+		response.Transitions[key] = append(response.Transitions[key], MetadataDryRunChange{
+			PhoneNumber: phoneNumber,
+			CountryCode: sample.CountryCode,
+			Before:      before,
+			After:       after,
+		})
+		response.Changed++
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// MetadataCoverage reports, per country, which metadata is loaded and the
+// resulting tier, so operators can tell fully supported countries from
+// length-only ones.
+func (h *Handler) MetadataCoverage(c *gin.Context) {
+	c.JSON(http.StatusOK, MetadataCoverageResponse{Countries: h.validator.MetadataCoverage()})
+}
+
+// outcomeCode names a validation result for grouping: OutcomeValid, the
+// error code the lookup endpoint would return, or a code derived from the
+// failing rule when the endpoint sends none.
+func outcomeCode(err error) string {
+	if err == nil {
+		return OutcomeValid
+	}
+
+	var leadingDigitErr *LeadingDigitError
+	if errors.As(err, &leadingDigitErr) {
+		return string(ErrorInvalidLeadingDigit)
+	}
+	var inputErr *InputFormatError
+	if errors.As(err, &inputErr) {
+		return string(inputErr.Code)
+	}
+	var lengthErr *LengthError
+	if errors.As(err, &lengthErr) {
+		return string(ErrorLengthOutOfRange)
+	}
+	var dialingCodeErr *DialingCodeError
+	if errors.As(err, &dialingCodeErr) {
+		return string(dialingCodeErr.Code())
+	}
+
+	message := err.Error()
+	switch {
+	case message == "country is disabled":
+		return string(ErrorCountryDisabled)
+	case strings.Contains(message, "country code"), strings.Contains(message, "countryCode"):
+		return OutcomeInvalidCountry
+	}
+	return OutcomeInvalidFormat
+}
@@ -0,0 +1,58 @@
+package api
+
+// InputFormatE164 is the inputFormat value that accepts only strict E.164.
+const InputFormatE164 = "e164"
+
+const ErrorNotE164 ErrorCode = "NOT_E164"
+
+var errNotE164 = &InputFormatError{Code: ErrorNotE164, Message: "phone number is not in E.164 format"}
+
+// WithStrictE164Input makes every lookup behave as if it asked for
+// inputFormat=e164.
+func WithStrictE164Input() ValidatorOption {
+	return func(v *PhoneNumberValidator) {
+		v.strictE164 = true
+	}
+}
+
+// parseStrictE164 accepts exactly ^\+[1-9]\d{1,14}$ and returns the digits
+// after the plus. It is a byte loop rather than a regexp because strict
+// callers use it on their hot path.
+func parseStrictE164(phoneNumber string) (string, error) {
+	if len(phoneNumber) < 3 || len(phoneNumber) > MaxE164Digits+1 || phoneNumber[0] != '+' || phoneNumber[1] == '0' {
+		return "", errNotE164
+	}
+	for i := 1; i < len(phoneNumber); i++ {
+		if phoneNumber[i] < '0' || phoneNumber[i] > '9' {
+			return "", errNotE164
+		}
+	}
+	return phoneNumber[1:], nil
+}
+
+// ValidateStrictE164 validates phoneNumber as a strict=true lookup does,
+// also on validators without WithStrictE164Input: anything but
+// ^\+[1-9]\d{1,14}$ fails with NOT_E164 before any cleaning.
+func (v *PhoneNumberValidator) ValidateStrictE164(phoneNumber string) (*PhoneValidationResponse, error) {
+	return v.ValidatePhoneNumberWithOptions(phoneNumber, "", ParseOptions{StrictE164: true})
+}
+
+// validateStrictE164 skips normalization, cleaning, spacing checks and the
+// countryCode parameter: the country comes from the dialing code alone.
+func (v *PhoneNumberValidator) validateStrictE164(phoneNumber string, opts ParseOptions) (*PhoneValidationResponse, error) {
+	digits, err := parseStrictE164(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	dialingCode, nationalNumber, err := v.extractDialingCode(digits)
+	if err != nil {
+		return nil, err
+	}
+	countryCode, exists := DialingCodeToCountry[dialingCode]
+	if !exists {
+		return nil, unsupportedDialingCode(digits)
+	}
+
+	return v.validateNationalNumber(countryCode, nationalNumber, opts, nil)
+}
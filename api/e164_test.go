@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPhoneNumberValidator_StrictE164(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+	strict := ParseOptions{StrictE164: true}
+
+	accepted := []struct {
+		phoneNumber string
+		countryCode string
+		wantCountry string
+		wantArea    string
+	}{
+		{"+12125690123", "", "US", "212"},
+		{"+442079460958", "", "GB", "2079"},
+		{"+821012345678", "", "KR", "10"},
+		{"+34915872200", "US", "ES", "91"},
+	}
+	for _, tt := range accepted {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumberWithOptions(tt.phoneNumber, tt.countryCode, strict)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.CountryCode != tt.wantCountry || result.NDC != tt.wantArea {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.wantCountry, tt.wantArea, result.CountryCode, result.NDC)
+			}
+			if result.PhoneNumber != tt.phoneNumber {
+				t.Errorf("Expected %s, got %s", tt.phoneNumber, result.PhoneNumber)
+			}
+		})
+	}
+
+	rejected := []struct {
+		name        string
+		phoneNumber string
+	}{
+		{"no plus", "12125690123"},
+		{"national", "2125690123"},
+		{"spaces", "+1 212 569 0123"},
+		{"leading zero", "+012125690123"},
+		{"duplicate plus", "++12125690123"},
+		{"trailing punctuation", "+12125690123."},
+		{"sixteen digits", "+1212569012345678"},
+		{"plus only", "+"},
+		{"single digit", "+1"},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validator.ValidatePhoneNumberWithOptions(tt.phoneNumber, "US", strict)
+			var inputErr *InputFormatError
+			if !errors.As(err, &inputErr) || inputErr.Code != ErrorNotE164 {
+				t.Errorf("Expected %s, got %v", ErrorNotE164, err)
+			}
+		})
+	}
+
+	// E.164-shaped input still has to pass the country rules.
+	if _, err := validator.ValidatePhoneNumberWithOptions("+1212", "", strict); err == nil || err.Error() != "phone number length is invalid for country US" {
+		t.Errorf("Expected a length error, got %v", err)
+	}
+
+	if _, err := validator.ValidateStrictE164("+1 212 569 0123"); !errors.Is(err, errNotE164) {
+		t.Errorf("Expected ValidateStrictE164 to reject spaced input, got %v", err)
+	}
+	if result, err := validator.ValidateStrictE164("+12125690123"); err != nil || result.CountryCode != "US" {
+		t.Errorf("Expected ValidateStrictE164 to accept E.164 input, got %v", err)
+	}
+
+	always := NewPhoneNumberValidator(WithStrictE164Input())
+	if _, err := always.ValidatePhoneNumber("2125690123", "US"); !errors.Is(err, errNotE164) {
+		t.Errorf("Expected WithStrictE164Input to reject national input, got %v", err)
+	}
+}
+
+func BenchmarkValidatePhoneNumber_InputFormat(b *testing.B) {
+	validator := NewPhoneNumberValidator()
+	modes := []struct {
+		name string
+		opts ParseOptions
+	}{
+		{"default", ParseOptions{}},
+		{"e164", ParseOptions{StrictE164: true}},
+	}
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				validator.ValidatePhoneNumberWithOptions("+442079460958", "", mode.opts)
+			}
+		})
+	}
+}
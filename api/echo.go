@@ -0,0 +1,81 @@
+//go:build !js
+
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultEchoLength is how many characters of a rejected input are echoed
+// back before the rest is replaced with echoEllipsis.
+const DefaultEchoLength = 32
+
+const echoEllipsis = "…"
+
+// WithEchoLimit sets how many characters of the input error responses echo.
+// Zero keeps DefaultEchoLength and a negative limit echoes inputs whole;
+// control and non-printable characters are stripped either way.
+func WithEchoLimit(limit int) HandlerOption {
+	return func(h *Handler) {
+		if limit != 0 {
+			h.echoLimit = limit
+		}
+	}
+}
+
+// echoInput is input as it may be reflected in a response: printable
+// characters only, cut to the echo limit.
+func (h *Handler) echoInput(input string) string {
+	var echo strings.Builder
+	kept := 0
+	for _, r := range input {
+		if r == utf8.RuneError || unicode.IsControl(r) || !unicode.IsPrint(r) {
+			continue
+		}
+		if h.echoLimit > 0 && kept == h.echoLimit {
+			echo.WriteString(echoEllipsis)
+			break
+		}
+		echo.WriteRune(r)
+		kept++
+	}
+	return echo.String()
+}
+
+// echoNumber is echoInput for a phone number, which privacy mode replaces
+// with the SHA-256 of the raw input so callers can still match it against
+// their own records.
+func (h *Handler) echoNumber(phoneNumber string) string {
+	if h.privacy && phoneNumber != "" {
+		sum := sha256.Sum256([]byte(phoneNumber))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return h.echoInput(phoneNumber)
+}
+
+// echo applies the echo policy to the phone number and received values of
+// errorResponse in place and returns it. Every error response carrying
+// input reaches the client through echo exactly once, since truncation and
+// hashing do not compose.
+func (h *Handler) echo(errorResponse *ErrorResponse) *ErrorResponse {
+	if errorResponse == nil {
+		return nil
+	}
+	errorResponse.PhoneNumber = h.echoNumber(errorResponse.PhoneNumber)
+	for name, values := range errorResponse.Received {
+		echoed := make([]string, len(values))
+		for i, value := range values {
+			if name == "phoneNumber" {
+				echoed[i] = h.echoNumber(value)
+			} else {
+				echoed[i] = h.echoInput(value)
+			}
+		}
+		errorResponse.Received[name] = echoed
+	}
+	return errorResponse
+}
@@ -0,0 +1,376 @@
+//go:build !js
+
+package api
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	DefaultEnumSuffix   = "e164.arpa"
+	DefaultEnumTimeout  = 2 * time.Second
+	DefaultEnumCacheTTL = time.Minute
+	// DefaultEnumCacheSize caps the cached domains; the least recently
+	// used one is dropped to make room.
+	DefaultEnumCacheSize = 10000
+
+	typeNAPTR dnsmessage.Type = 35
+)
+
+type NAPTRRecord struct {
+	Order       uint16
+	Preference  uint16
+	Flags       string
+	Service     string
+	Regexp      string
+	Replacement string
+}
+
+// NAPTRResolver resolves the NAPTR records of a domain. A domain that does
+// not exist must return no records and no error.
+type NAPTRResolver interface {
+	LookupNAPTR(ctx context.Context, name string) ([]NAPTRRecord, error)
+}
+
+type enumCacheEntry struct {
+	domain  string
+	result  *EnumResult
+	expires time.Time
+}
+
+type EnumLookup struct {
+	resolver NAPTRResolver
+	suffix   string
+	timeout  time.Duration
+	ttl      time.Duration
+	size     int
+	now      func() time.Time
+
+	mu    sync.Mutex
+	order *list.List
+	cache map[string]*list.Element
+}
+
+func NewEnumLookup(resolver NAPTRResolver, suffix string) *EnumLookup {
+	if suffix == "" {
+		suffix = DefaultEnumSuffix
+	}
+	return &EnumLookup{
+		resolver: resolver,
+		suffix:   strings.Trim(suffix, "."),
+		timeout:  DefaultEnumTimeout,
+		ttl:      DefaultEnumCacheTTL,
+		size:     DefaultEnumCacheSize,
+		now:      time.Now,
+		order:    list.New(),
+		cache:    map[string]*list.Element{},
+	}
+}
+
+func WithEnumLookup(lookup *EnumLookup) HandlerOption {
+	return func(h *Handler) {
+		h.enum = lookup
+	}
+}
+
+// CheckHealth resolves the suffix itself, which any working resolver
+// answers, within the lookup timeout.
+func (l *EnumLookup) CheckHealth(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	_, err := l.resolver.LookupNAPTR(ctx, l.suffix)
+	return err
+}
+
+// EnumDomain reverses the digits of an E.164 number under suffix, e.g.
+// +12125690123 becomes 3.2.1.0.9.6.5.2.1.2.1.e164.arpa.
+func EnumDomain(e164, suffix string) string {
+	digits := strings.TrimPrefix(e164, "+")
+	labels := make([]string, 0, len(digits)+1)
+	for i := len(digits) - 1; i >= 0; i-- {
+		labels = append(labels, digits[i:i+1])
+	}
+	return strings.Join(append(labels, suffix), ".")
+}
+
+// Lookup resolves the ENUM records of a validated number. Successful
+// lookups, including empty ones, are cached; failures are not so the next
+// request retries.
+func (l *EnumLookup) Lookup(ctx context.Context, e164 string) (*EnumResult, error) {
+	result, _, err := l.LookupCached(ctx, e164)
+	return result, err
+}
+
+// LookupCached is Lookup that also reports whether the result came from
+// the cache.
+func (l *EnumLookup) LookupCached(ctx context.Context, e164 string) (*EnumResult, bool, error) {
+	domain := EnumDomain(e164, l.suffix)
+
+	if result, cached := l.cached(domain); cached {
+		return result, true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+
+	records, err := l.resolver.LookupNAPTR(ctx, domain)
+	if err != nil {
+		return &EnumResult{Domain: domain, Records: []EnumRecord{}}, false, err
+	}
+
+	result := &EnumResult{Domain: domain, Records: []EnumRecord{}}
+	for _, record := range records {
+		if !strings.EqualFold(record.Flags, "u") {
+			continue
+		}
+		service, ok := enumService(record.Service)
+		if !ok {
+			continue
+		}
+		uri, err := applyNAPTRRegexp(record.Regexp, e164)
+		if err != nil {
+			continue
+		}
+		result.Records = append(result.Records, EnumRecord{
+			Service:    service,
+			URI:        uri,
+			Order:      record.Order,
+			Preference: record.Preference,
+		})
+	}
+
+	l.store(domain, result)
+	return result, false, nil
+}
+
+// cached returns the unexpired result for domain, dropping an expired one.
+func (l *EnumLookup) cached(domain string) (*EnumResult, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, exists := l.cache[domain]
+	if !exists {
+		return nil, false
+	}
+	entry := element.Value.(*enumCacheEntry)
+	if !l.now().Before(entry.expires) {
+		l.order.Remove(element)
+		delete(l.cache, domain)
+		return nil, false
+	}
+	l.order.MoveToFront(element)
+	return entry.result, true
+}
+
+func (l *EnumLookup) store(domain string, result *EnumResult) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := &enumCacheEntry{domain: domain, result: result, expires: l.now().Add(l.ttl)}
+	if element, exists := l.cache[domain]; exists {
+		element.Value = entry
+		l.order.MoveToFront(element)
+		return
+	}
+	l.cache[domain] = l.order.PushFront(entry)
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.cache, oldest.Value.(*enumCacheEntry).domain)
+	}
+}
+
+// enumService maps "E2U+sip" or "E2U+email:mailto" to "sip" / "mailto".
+func enumService(service string) (string, bool) {
+	rest, ok := strings.CutPrefix(strings.ToUpper(service), "E2U+")
+	if !ok {
+		return "", false
+	}
+	rest = strings.ToLower(rest)
+	if i := strings.LastIndex(rest, ":"); i >= 0 {
+		rest = rest[i+1:]
+	}
+	switch rest {
+	case "sip", "mailto":
+		return rest, true
+	}
+	return "", false
+}
+
+// applyNAPTRRegexp applies an RFC 3402 substitution expression such as
+// "!^.*$!sip:info@example.com!" to the number. Records come from DNS, so a
+// malformed expression or one that does not match is an error, never a
+// panic.
+func applyNAPTRRegexp(expr, e164 string) (string, error) {
+	if len(expr) < 3 {
+		return "", fmt.Errorf("malformed NAPTR regexp %q", expr)
+	}
+	parts := strings.Split(expr[1:], expr[:1])
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed NAPTR regexp %q", expr)
+	}
+
+	pattern, err := regexp.Compile(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid NAPTR regexp %q: %w", expr, err)
+	}
+	if !pattern.MatchString(e164) {
+		return "", fmt.Errorf("NAPTR regexp %q does not match %s", expr, e164)
+	}
+	backreference, err := regexp.Compile(`\\(\d)`)
+	if err != nil {
+		return "", err
+	}
+	replacement := backreference.ReplaceAllString(parts[1], "$${$1}")
+	return pattern.ReplaceAllString(e164, replacement), nil
+}
+
+// DNSResolver queries NAPTR records over UDP from a single server.
+type DNSResolver struct {
+	Server string
+}
+
+func (r *DNSResolver) LookupNAPTR(ctx context.Context, name string) ([]NAPTRRecord, error) {
+	qname, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, err
+	}
+
+	// The query ID is the only defence against spoofed answers over UDP,
+	// so it must not be predictable.
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: qname, Type: typeNAPTR, Class: dnsmessage.ClassINET},
+		},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", r.Server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(packed); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNAPTRResponse(buf[:n], id)
+}
+
+func parseNAPTRResponse(msg []byte, id uint16) ([]NAPTRRecord, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(msg)
+	if err != nil {
+		return nil, err
+	}
+	if header.ID != id {
+		return nil, errors.New("dns response id mismatch")
+	}
+	switch header.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("dns query failed: %v", header.RCode)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, err
+	}
+
+	var records []NAPTRRecord
+	for {
+		answer, err := parser.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if answer.Type != typeNAPTR {
+			if err := parser.SkipAnswer(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		resource, err := parser.UnknownResource()
+		if err != nil {
+			return nil, err
+		}
+		record, err := parseNAPTRData(resource.Data)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseNAPTRData decodes NAPTR RDATA (RFC 3403 section 4.1). The replacement
+// name is never compressed, so it can be read without the full message.
+func parseNAPTRData(data []byte) (NAPTRRecord, error) {
+	errMalformed := errors.New("malformed NAPTR record")
+	if len(data) < 4 {
+		return NAPTRRecord{}, errMalformed
+	}
+	record := NAPTRRecord{
+		Order:      uint16(data[0])<<8 | uint16(data[1]),
+		Preference: uint16(data[2])<<8 | uint16(data[3]),
+	}
+	data = data[4:]
+
+	fields := []*string{&record.Flags, &record.Service, &record.Regexp}
+	for _, field := range fields {
+		if len(data) < 1 || len(data) < 1+int(data[0]) {
+			return NAPTRRecord{}, errMalformed
+		}
+		*field = string(data[1 : 1+int(data[0])])
+		data = data[1+int(data[0]):]
+	}
+
+	var labels []string
+	for {
+		if len(data) < 1 || len(data) < 1+int(data[0]) {
+			return NAPTRRecord{}, errMalformed
+		}
+		length := int(data[0])
+		if length == 0 {
+			break
+		}
+		labels = append(labels, string(data[1:1+length]))
+		data = data[1+length:]
+	}
+	record.Replacement = strings.Join(labels, ".")
+
+	return record, nil
+}
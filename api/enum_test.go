@@ -0,0 +1,223 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func naptrData(order, preference uint16, flags, service, regexp string) []byte {
+	data := []byte{byte(order >> 8), byte(order), byte(preference >> 8), byte(preference)}
+	for _, field := range []string{flags, service, regexp} {
+		data = append(data, byte(len(field)))
+		data = append(data, field...)
+	}
+	return append(data, 0)
+}
+
+// startFakeDNS answers NAPTR queries from records; names it doesn't know get
+// NXDOMAIN. With silent set it never answers.
+func startFakeDNS(t *testing.T, records map[string][][]byte, silent bool) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if silent {
+				continue
+			}
+
+			var parser dnsmessage.Parser
+			header, err := parser.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			question, err := parser.Question()
+			if err != nil {
+				continue
+			}
+
+			answers, known := records[question.Name.String()]
+			header.Response = true
+			if !known {
+				header.RCode = dnsmessage.RCodeNameError
+			}
+
+			builder := dnsmessage.NewBuilder(nil, header)
+			builder.StartQuestions()
+			builder.Question(question)
+			builder.StartAnswers()
+			for _, data := range answers {
+				builder.UnknownResource(dnsmessage.ResourceHeader{
+					Name: question.Name, Type: typeNAPTR, Class: dnsmessage.ClassINET, TTL: 60,
+				}, dnsmessage.UnknownResource{Type: typeNAPTR, Data: data})
+			}
+			response, err := builder.Finish()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(response, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestEnumDomain(t *testing.T) {
+	if got := EnumDomain("+12125690123", DefaultEnumSuffix); got != "3.2.1.0.9.6.5.2.1.2.1.e164.arpa" {
+		t.Errorf("Unexpected ENUM domain %s", got)
+	}
+	if got := EnumDomain("+4930", "e164.example.net"); got != "0.3.9.4.e164.example.net" {
+		t.Errorf("Unexpected ENUM domain %s", got)
+	}
+}
+
+func TestEnumLookup_WithRecords(t *testing.T) {
+	server := startFakeDNS(t, map[string][][]byte{
+		"3.2.1.0.9.6.5.2.1.2.1.e164.arpa.": {
+			naptrData(100, 10, "u", "E2U+sip", "!^.*$!sip:info@example.com!"),
+			naptrData(100, 20, "u", "E2U+email:mailto", `!^\+(.*)$!mailto:\1@example.com!`),
+			naptrData(100, 30, "u", "E2U+web:http", "!^.*$!http://example.com!"),
+		},
+	}, false)
+
+	lookup := NewEnumLookup(&DNSResolver{Server: server}, "")
+	result, err := lookup.Lookup(context.Background(), "+12125690123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []EnumRecord{
+		{Service: "sip", URI: "sip:info@example.com", Order: 100, Preference: 10},
+		{Service: "mailto", URI: "mailto:12125690123@example.com", Order: 100, Preference: 20},
+	}
+	if len(result.Records) != len(expected) {
+		t.Fatalf("Expected %d records, got %+v", len(expected), result.Records)
+	}
+	for i, record := range expected {
+		if result.Records[i] != record {
+			t.Errorf("Record %d: expected %+v, got %+v", i, record, result.Records[i])
+		}
+	}
+}
+
+func TestApplyNAPTRRegexp(t *testing.T) {
+	uri, err := applyNAPTRRegexp(`!^\+(.*)$!sip:\1@example.com!`, "+12125690123")
+	if err != nil || uri != "sip:12125690123@example.com" {
+		t.Errorf("Expected the substituted URI, got %q, %v", uri, err)
+	}
+
+	for _, expr := range []string{"", "!^.*$!", "!^(.*$!sip:x@example.com!", "!^\\+44!sip:x@example.com!"} {
+		if uri, err := applyNAPTRRegexp(expr, "+12125690123"); err == nil {
+			t.Errorf("Expected an error for %q, got %q", expr, uri)
+		}
+	}
+}
+
+func TestEnumLookup_WithoutRecords(t *testing.T) {
+	server := startFakeDNS(t, map[string][][]byte{}, false)
+
+	lookup := NewEnumLookup(&DNSResolver{Server: server}, "")
+	result, err := lookup.Lookup(context.Background(), "+34915872200")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Records) != 0 {
+		t.Errorf("Expected no records, got %+v", result.Records)
+	}
+}
+
+func TestEnumLookup_Timeout(t *testing.T) {
+	server := startFakeDNS(t, nil, true)
+
+	lookup := NewEnumLookup(&DNSResolver{Server: server}, "")
+	lookup.timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	result, err := lookup.Lookup(context.Background(), "+12125690123")
+	if err == nil {
+		t.Fatal("Expected a timeout error")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Lookup did not respect its timeout")
+	}
+	if result == nil || len(result.Records) != 0 {
+		t.Errorf("Expected an empty result on failure, got %+v", result)
+	}
+}
+
+type countingResolver struct {
+	calls int
+}
+
+func (r *countingResolver) LookupNAPTR(ctx context.Context, name string) ([]NAPTRRecord, error) {
+	r.calls++
+	return []NAPTRRecord{{Flags: "u", Service: "E2U+sip", Regexp: "!^.*$!sip:a@b.c!"}}, nil
+}
+
+func TestEnumLookup_Cache(t *testing.T) {
+	now := time.Now()
+	resolver := &countingResolver{}
+	lookup := NewEnumLookup(resolver, "")
+	lookup.now = func() time.Time { return now }
+
+	lookup.Lookup(context.Background(), "+12125690123")
+	lookup.Lookup(context.Background(), "+12125690123")
+	if resolver.calls != 1 {
+		t.Errorf("Expected a cached second lookup, got %d resolver calls", resolver.calls)
+	}
+
+	now = now.Add(DefaultEnumCacheTTL)
+	lookup.Lookup(context.Background(), "+12125690123")
+	if resolver.calls != 2 {
+		t.Errorf("Expected the cache entry to expire, got %d resolver calls", resolver.calls)
+	}
+}
+
+func TestEnumLookup_CacheIsBounded(t *testing.T) {
+	now := time.Now()
+	resolver := &countingResolver{}
+	lookup := NewEnumLookup(resolver, "")
+	lookup.now = func() time.Time { return now }
+	lookup.size = 2
+
+	lookup.Lookup(context.Background(), "+12125690123")
+	lookup.Lookup(context.Background(), "+12125690124")
+	lookup.Lookup(context.Background(), "+12125690123")
+	lookup.Lookup(context.Background(), "+12125690125")
+	if len(lookup.cache) != 2 || lookup.order.Len() != 2 {
+		t.Errorf("Expected the cache to hold 2 domains, got %d", len(lookup.cache))
+	}
+
+	calls := resolver.calls
+	lookup.Lookup(context.Background(), "+12125690123")
+	if resolver.calls != calls {
+		t.Errorf("Expected the recently used domain to stay cached")
+	}
+	lookup.Lookup(context.Background(), "+12125690124")
+	if resolver.calls != calls+1 {
+		t.Errorf("Expected the least recently used domain to be evicted")
+	}
+
+	now = now.Add(DefaultEnumCacheTTL)
+	domain := EnumDomain("+12125690124", DefaultEnumSuffix)
+	if _, cached := lookup.cached(domain); cached {
+		t.Errorf("Expected the entry to expire")
+	}
+	if _, exists := lookup.cache[domain]; exists {
+		t.Errorf("Expected the expired entry to be dropped")
+	}
+}
@@ -0,0 +1,175 @@
+//go:build !js
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// LookupErrorCodes are the codes a lookup can fail with on any transport,
+// and the only ones an error messages file may override.
+var LookupErrorCodes = []ErrorCode{
+	ErrorMalformedRequest,
+	ErrorCountryDisabled,
+	ErrorCountryNotAllowed,
+	ErrorEnrichmentNotAllowed,
+	ErrorInvalidLeadingDigit,
+	ErrorLengthOutOfRange,
+	ErrorInputTooLong,
+	ErrorPhoneNumberRequired,
+	ErrorInvalidCharacters,
+	ErrorInvalidSpacing,
+	ErrorCountryCodeRequired,
+	ErrorInvalidCountryCode,
+	ErrorUnsupportedCountryCode,
+	ErrorDialingPrefixOnly,
+	ErrorMalformedTelURI,
+	ErrorAreaCodeUndetermined,
+	ErrorInternal,
+	ErrorNotE164,
+	ErrorMisplacedPlus,
+	ErrorTrailingPunctuation,
+	ErrorLossyNumericFormat,
+	ErrorMissingSubscriberNumber,
+	ErrorSuspiciousPattern,
+	ErrorPossibleIntegerTruncation,
+	ErrorUnknownDialingCode,
+	ErrorUnsupportedCountry,
+	ErrorRejectedByHook,
+}
+
+// ErrorMessageData is what an override template can use. Message is the
+// built-in message it replaces; the length fields are only set for
+// LENGTH_OUT_OF_RANGE.
+type ErrorMessageData struct {
+	Code          ErrorCode
+	Field         string
+	Message       string
+	Country       string
+	ExpectedMin   int
+	ExpectedMax   int
+	Actual        int
+	ExampleNumber string
+}
+
+// ErrorMessageStore holds operator overrides loaded from a JSON file
+// mapping error code to language to a text/template message, e.g.
+// {"LENGTH_OUT_OF_RANGE": {"en": "{{.Country}} numbers have {{.ExpectedMin}} digits"}}.
+// Reload swaps the overrides atomically and keeps the previous ones if the
+// file is invalid.
+type ErrorMessageStore struct {
+	path string
+
+	mu        sync.RWMutex
+	templates map[ErrorCode]map[string]*template.Template
+}
+
+func LoadErrorMessages(path string) (*ErrorMessageStore, error) {
+	store := &ErrorMessageStore{path: path}
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *ErrorMessageStore) Reload() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	var messages map[ErrorCode]map[string]string
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return fmt.Errorf("invalid error messages file %s: %w", s.path, err)
+	}
+	templates, err := parseErrorMessages(messages)
+	if err != nil {
+		return fmt.Errorf("invalid error messages file %s: %w", s.path, err)
+	}
+
+	s.mu.Lock()
+	s.templates = templates
+	s.mu.Unlock()
+	return nil
+}
+
+// parseErrorMessages rejects unknown codes and languages, and templates
+// that fail to parse or to render sample data.
+func parseErrorMessages(messages map[ErrorCode]map[string]string) (map[ErrorCode]map[string]*template.Template, error) {
+	codes := make([]ErrorCode, 0, len(messages))
+	for code := range messages {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	templates := make(map[ErrorCode]map[string]*template.Template, len(messages))
+	for _, code := range codes {
+		if !knownFailureCodes[code] {
+			return nil, fmt.Errorf("unknown error code %s", code)
+		}
+		templates[code] = map[string]*template.Template{}
+		for language, text := range messages[code] {
+			if _, supported := CountryNames[language]; !supported {
+				return nil, fmt.Errorf("%s: unsupported language %s", code, language)
+			}
+			tmpl, err := template.New(string(code) + "." + language).Parse(text)
+			if err == nil {
+				err = tmpl.Execute(&strings.Builder{}, ErrorMessageData{})
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", code, language, err)
+			}
+			templates[code][language] = tmpl
+		}
+	}
+	return templates, nil
+}
+
+func WithErrorMessages(store *ErrorMessageStore) HandlerOption {
+	return func(h *Handler) {
+		h.errorMessages = store
+	}
+}
+
+// apply rewrites every field message of errorResponse from the override
+// for its code in language, falling back to English. Codes without an
+// override keep the built-in messages.
+func (s *ErrorMessageStore) apply(errorResponse *ErrorResponse, language, countryCode string) {
+	if s == nil || errorResponse.Code == "" {
+		return
+	}
+
+	s.mu.RLock()
+	byLanguage := s.templates[errorResponse.Code]
+	s.mu.RUnlock()
+	tmpl, exists := byLanguage[language]
+	if !exists {
+		tmpl, exists = byLanguage[DefaultLanguage]
+	}
+	if !exists {
+		return
+	}
+
+	for field, message := range errorResponse.Error {
+		var rendered strings.Builder
+		err := tmpl.Execute(&rendered, ErrorMessageData{
+			Code:          errorResponse.Code,
+			Field:         field,
+			Message:       message,
+			Country:       countryCode,
+			ExpectedMin:   errorResponse.ExpectedMin,
+			ExpectedMax:   errorResponse.ExpectedMax,
+			Actual:        errorResponse.Actual,
+			ExampleNumber: errorResponse.ExampleNumber,
+		})
+		if err == nil {
+			errorResponse.Error[field] = rendered.String()
+		}
+	}
+}
@@ -0,0 +1,89 @@
+//go:build !js
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeErrorMessages(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// messagesFor returns a messages file overriding a single code.
+func messagesFor(code ErrorCode, languages string) string {
+	return `{"` + string(code) + `": ` + languages + `}`
+}
+
+func TestLoadErrorMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.json")
+
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{"Valid", messagesFor(ErrorLengthOutOfRange, `{"en": "{{.Country}} needs {{.ExpectedMin}} digits", "de": "{{.Message}}"}`), ""},
+		{"Unknown Code", messagesFor("NO_SUCH_CODE", `{"en": "oops"}`), "unknown error code NO_SUCH_CODE"},
+		{"Unsupported Language", messagesFor(ErrorNotE164, `{"ja": "oops"}`), "unsupported language ja"},
+		{"Unparseable Template", messagesFor(ErrorNotE164, `{"en": "{{.Message"}`), string(ErrorNotE164) + ".en"},
+		{"Unknown Variable", messagesFor(ErrorNotE164, `{"en": "{{.Ticket}}"}`), string(ErrorNotE164) + ".en"},
+		{"Not JSON", `["` + string(ErrorNotE164) + `"]`, "invalid error messages file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeErrorMessages(t, path, tt.contents)
+			_, err := LoadErrorMessages(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestErrorMessageStore_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.json")
+	writeErrorMessages(t, path, messagesFor(ErrorNotE164, `{"en": "first"}`))
+	store, err := LoadErrorMessages(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	render := func() string {
+		errorResponse := &ErrorResponse{Code: ErrorNotE164, Error: map[string]string{"phoneNumber": "default"}}
+		store.apply(errorResponse, "fr", "")
+		return errorResponse.Error["phoneNumber"]
+	}
+	if got := render(); got != "first" {
+		t.Errorf("Expected the English override as fallback, got %q", got)
+	}
+
+	writeErrorMessages(t, path, messagesFor(ErrorNotE164, `{"en": "second"}`))
+	if err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := render(); got != "second" {
+		t.Errorf("Expected the reloaded override, got %q", got)
+	}
+
+	writeErrorMessages(t, path, `{"BOGUS": {"en": "third"}}`)
+	if err := store.Reload(); err == nil {
+		t.Error("Expected reload to reject an unknown code")
+	}
+	if got := render(); got != "second" {
+		t.Errorf("Expected the previous override to survive a bad reload, got %q", got)
+	}
+}
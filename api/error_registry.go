@@ -0,0 +1,170 @@
+//go:build !js
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+const (
+	ErrorCountryNotAllowed    ErrorCode = "COUNTRY_NOT_ALLOWED"
+	ErrorEnrichmentNotAllowed ErrorCode = "ENRICHMENT_NOT_ALLOWED"
+	ErrorInvalidLeadingDigit  ErrorCode = "INVALID_LEADING_DIGIT"
+	ErrorLengthOutOfRange     ErrorCode = "LENGTH_OUT_OF_RANGE"
+	ErrorInternal             ErrorCode = "INTERNAL_ERROR"
+	ErrorRouteNotFound        ErrorCode = "ROUTE_NOT_FOUND"
+)
+
+// ErrorMapping is how clients see an error code: the HTTP status of a
+// single request failing with it, and the error field and message it is
+// reported with. Field or Message is empty when it depends on the request,
+// such as the parameter a MALFORMED_REQUEST names or the digit of an
+// INVALID_LEADING_DIGIT.
+type ErrorMapping struct {
+	Status  int
+	Field   string
+	Message string
+}
+
+// ErrorRegistry holds every declared error code; a test fails when a code
+// constant has no entry or an entry has no constant, so a new error cannot
+// ship without deciding how it is reported.
+var ErrorRegistry = map[ErrorCode]ErrorMapping{
+	ErrorMalformedRequest:          {Status: http.StatusBadRequest},
+	ErrorCountryDisabled:           {Status: http.StatusForbidden, Field: "countryCode", Message: "processing for this country is disabled"},
+	ErrorCountryNotAllowed:         {Status: http.StatusForbidden, Field: "countryCode", Message: "not allowed for this API key"},
+	ErrorEnrichmentNotAllowed:      {Status: http.StatusForbidden, Field: "enum", Message: "enrichment is not permitted for this API key"},
+	ErrorInvalidLeadingDigit:       {Status: http.StatusBadRequest, Field: "phoneNumber"},
+	ErrorPhoneNumberRequired:       {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "required value is missing"},
+	ErrorInvalidCountryCode:        {Status: http.StatusBadRequest, Field: "countryCode", Message: "invalid format (must be ISO 3166-1 alpha-2)"},
+	ErrorUnsupportedCountryCode:    {Status: http.StatusBadRequest, Field: "countryCode", Message: "unsupported country code"},
+	ErrorDialingPrefixOnly:         {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "contains only an international dialing prefix"},
+	ErrorMalformedTelURI:           {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "is not a valid tel: URI (RFC 3966)"},
+	ErrorAreaCodeUndetermined:      {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "area code cannot be determined"},
+	ErrorNationalNumberRequired:    {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "must be a national number without a plus sign"},
+	ErrorLengthOutOfRange:          {Status: http.StatusBadRequest, Field: "phoneNumber"},
+	ErrorInputTooLong:              {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "input exceeds maximum length"},
+	ErrorInvalidCharacters:         {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "contains invalid characters"},
+	ErrorInvalidSpacing:            {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "invalid spacing pattern"},
+	ErrorCountryCodeRequired:       {Status: http.StatusBadRequest, Field: "countryCode", Message: "required value is missing"},
+	ErrorInternal:                  {Status: http.StatusInternalServerError, Field: "phoneNumber", Message: "number could not be split; this is a server bug"},
+	ErrorNotE164:                   {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "must be in E.164 format (a plus sign followed by up to 15 digits, no spaces)"},
+	ErrorMisplacedPlus:             {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "plus sign is only allowed at the start"},
+	ErrorTrailingPunctuation:       {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "ends with punctuation"},
+	ErrorLossyNumericFormat:        {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "number was rounded by a spreadsheet numeric format and cannot be recovered"},
+	ErrorMissingSubscriberNumber:   {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "contains only a dialing code"},
+	ErrorSuspiciousPattern:         {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "digits are all identical or a trivial sequence"},
+	ErrorPossibleIntegerTruncation: {Status: http.StatusBadRequest, Field: "phoneNumber"},
+	ErrorInvalidExtension:          {Status: http.StatusUnprocessableEntity, Field: "extension", Message: "must contain only digits"},
+	ErrorMetadataDegraded:          {Status: http.StatusServiceUnavailable, Field: "metadata", Message: "unavailable while validation is degraded to the minimal table"},
+	ErrorFeatureDisabled:           {Status: http.StatusForbidden, Field: "feature"},
+	ErrorNotAvailableInDemo:        {Status: http.StatusForbidden, Field: "feature"},
+	ErrorRejectedByHook:            {Status: http.StatusBadRequest, Field: "phoneNumber"},
+	ErrorRouteNotFound:             {Status: http.StatusNotFound, Field: "route", Message: "no route matches the requested path"},
+	ErrorTooManyJobs:               {Status: http.StatusTooManyRequests, Field: "jobs", Message: "too many jobs are running; retry when one completes"},
+	ErrorJobStorageFull:            {Status: http.StatusInsufficientStorage, Field: "jobs", Message: "no storage is left for job results"},
+	ErrorJobResultsUnavailable:     {Status: http.StatusInternalServerError, Field: "id", Message: "job results could not be read"},
+	ErrorIdempotencyKeyReused:      {Status: http.StatusConflict, Field: "idempotencyKey", Message: "was already used for a job with a different request"},
+	ErrorUnsupportedMediaType:      {Status: http.StatusUnsupportedMediaType, Field: "contentType"},
+	ErrorUnknownDialingCode:        {Status: http.StatusBadRequest, Field: "phoneNumber", Message: "dialing code is not assigned to any country"},
+	ErrorUnsupportedCountry:        {Status: http.StatusBadRequest, Field: "phoneNumber"},
+	ErrorRangeInvalid:              {Status: http.StatusBadRequest},
+	ErrorRangeCrossesBoundary:      {Status: http.StatusUnprocessableEntity, Field: "range"},
+	ErrorRangeTooLarge:             {Status: http.StatusUnprocessableEntity, Field: "range"},
+	// Reported on a webhook delivery result, not as an error response.
+	ErrorOutboundBlocked: {Status: http.StatusOK, Field: "callbackUrl"},
+}
+
+// IsValid reports whether c is a declared error code.
+func (c ErrorCode) IsValid() bool {
+	_, exists := ErrorRegistry[c]
+	return exists
+}
+
+// ParseErrorCode returns the declared error code spelled s.
+func ParseErrorCode(s string) (ErrorCode, error) {
+	if c := ErrorCode(s); c.IsValid() {
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown error code %q", s)
+}
+
+// errorCode is the code err is reported with. Every error the validator
+// returns carries one; "" means an error from outside the validator.
+func errorCode(err error) ErrorCode {
+	var inputErr *InputFormatError
+	if errors.As(err, &inputErr) {
+		return inputErr.Code
+	}
+	var leadingDigitErr *LeadingDigitError
+	if errors.As(err, &leadingDigitErr) {
+		return ErrorInvalidLeadingDigit
+	}
+	var lengthErr *LengthError
+	if errors.As(err, &lengthErr) {
+		return ErrorLengthOutOfRange
+	}
+	var truncationErr *IntegerTruncationError
+	if errors.As(err, &truncationErr) {
+		return ErrorPossibleIntegerTruncation
+	}
+	var nationalSplitErr *NationalNumberSplitError
+	if errors.As(err, &nationalSplitErr) {
+		return ErrorAreaCodeUndetermined
+	}
+	var splitErr *SplitInvariantError
+	if errors.As(err, &splitErr) {
+		return ErrorInternal
+	}
+	var dialingCodeErr *DialingCodeError
+	if errors.As(err, &dialingCodeErr) {
+		return dialingCodeErr.Code()
+	}
+	return ""
+}
+
+// mapValidationError is the error field and message for a validator
+// error, looked up by its code in ErrorRegistry. Codes whose message
+// depends on the error take it from the typed error's data. An error
+// without a code falls back to "invalid format", logged and counted in
+// /v1/stats unmappedErrors so the gap is noticed.
+func (h *Handler) mapValidationError(err error) map[string]string {
+	code := errorCode(err)
+	mapping := ErrorRegistry[code]
+	if mapping.Field != "" && mapping.Message != "" {
+		return map[string]string{mapping.Field: mapping.Message}
+	}
+
+	switch code {
+	case ErrorLengthOutOfRange:
+		var lengthErr *LengthError
+		errors.As(err, &lengthErr)
+		message := "length is invalid for country"
+		if detail := lengthErr.Detail(); detail != "" {
+			message += ": " + detail
+		}
+		return map[string]string{mapping.Field: message}
+	case ErrorInvalidLeadingDigit:
+		var leadingDigitErr *LeadingDigitError
+		errors.As(err, &leadingDigitErr)
+		return map[string]string{mapping.Field: "cannot start with digit " + leadingDigitErr.Digit}
+	case ErrorPossibleIntegerTruncation:
+		var truncationErr *IntegerTruncationError
+		errors.As(err, &truncationErr)
+		if truncationErr.Ambiguous {
+			return map[string]string{mapping.Field: "is one digit short, as if stored as an integer, and several leading digits would restore a valid number"}
+		}
+		return map[string]string{mapping.Field: "is one digit short, as if stored as an integer, and no leading digit restores a valid number"}
+	case ErrorUnsupportedCountry:
+		var dialingCodeErr *DialingCodeError
+		errors.As(err, &dialingCodeErr)
+		return map[string]string{mapping.Field: "dialing code +" + dialingCodeErr.DialingCode + " belongs to " + dialingCodeErr.RegionCode + ", which is not supported"}
+	}
+
+	h.unmappedErrors.Add(1)
+	log.Printf("unmapped validation error %q reported as invalid format", err.Error())
+	return map[string]string{"phoneNumber": "invalid format"}
+}
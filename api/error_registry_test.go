@@ -0,0 +1,186 @@
+//go:build !js
+
+package api
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var errorCodeConstant = regexp.MustCompile(`^Error[A-Z]`)
+
+// declaredErrorCodes returns the value of every string constant named
+// Error* in the package, keyed by value, with the constant's name.
+func declaredErrorCodes(t *testing.T) map[string]string {
+	t.Helper()
+	paths, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes := map[string]string{}
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				value := spec.(*ast.ValueSpec)
+				for i, name := range value.Names {
+					if !errorCodeConstant.MatchString(name.Name) || i >= len(value.Values) {
+						continue
+					}
+					literal, ok := value.Values[i].(*ast.BasicLit)
+					if !ok || literal.Kind != token.STRING {
+						continue
+					}
+					code, _ := strconv.Unquote(literal.Value)
+					codes[code] = name.Name
+				}
+			}
+		}
+	}
+	return codes
+}
+
+func TestErrorRegistryCoversDeclaredCodes(t *testing.T) {
+	declared := declaredErrorCodes(t)
+	if len(declared) == 0 {
+		t.Fatal("Expected to find error code constants")
+	}
+
+	for code, name := range declared {
+		mapping, exists := ErrorRegistry[ErrorCode(code)]
+		if !exists {
+			t.Errorf("%s (%s) has no ErrorRegistry entry", name, code)
+			continue
+		}
+		if mapping.Status == 0 {
+			t.Errorf("%s (%s) has no status", name, code)
+		}
+	}
+	for code := range ErrorRegistry {
+		if _, exists := declared[string(code)]; !exists {
+			t.Errorf("ErrorRegistry entry %s has no Error* constant", code)
+		}
+	}
+}
+
+func TestMapValidationErrorFallback(t *testing.T) {
+	h := NewHandler()
+
+	mapped := h.mapValidationError(&InputFormatError{Code: ErrorMisplacedPlus, Message: "plus sign is only allowed at the start"})
+	if mapped["phoneNumber"] != ErrorRegistry[ErrorMisplacedPlus].Message || h.unmappedErrors.Load() != 0 {
+		t.Errorf("Expected the registry message, got %v", mapped)
+	}
+
+	mapped = h.mapValidationError(errors.New("a validator error nobody mapped"))
+	if mapped["phoneNumber"] != "invalid format" {
+		t.Errorf("Expected the generic fallback, got %v", mapped)
+	}
+	if got := h.unmappedErrors.Load(); got != 1 {
+		t.Errorf("Expected one unmapped error, got %d", got)
+	}
+}
+
+func TestValidatorErrorsHaveRegisteredCodes(t *testing.T) {
+	h := NewHandler()
+	validator := NewPhoneNumberValidator(WithDisabledCountries("ES"))
+	rejecting := NewPhoneNumberValidator(WithSuspiciousPatterns(SuspiciousPatternsReject))
+
+	tests := []struct {
+		name        string
+		validator   *PhoneNumberValidator
+		phoneNumber string
+		countryCode string
+		opts        ParseOptions
+		want        ErrorCode
+	}{
+		{name: "Missing Number", phoneNumber: "", countryCode: "US", want: ErrorPhoneNumberRequired},
+		{name: "Missing Country", phoneNumber: "2125690123", want: ErrorCountryCodeRequired},
+		{name: "Malformed Country", phoneNumber: "2125690123", countryCode: "USA", want: ErrorInvalidCountryCode},
+		{name: "Unsupported Country", phoneNumber: "2125690123", countryCode: "ZZ", want: ErrorUnsupportedCountryCode},
+		{name: "Disabled Country", phoneNumber: "+34915872200", want: ErrorCountryDisabled},
+		{name: "Invalid Characters", phoneNumber: "212abc0123", countryCode: "US", want: ErrorInvalidCharacters},
+		{name: "Invalid Spacing", phoneNumber: "212 569 01 23", countryCode: "US", want: ErrorInvalidSpacing},
+		{name: "Too Long", phoneNumber: strings.Repeat("2", 65), countryCode: "US", want: ErrorInputTooLong},
+		{name: "Dialing Prefix Only", phoneNumber: "011", countryCode: "US", want: ErrorDialingPrefixOnly},
+		{name: "Malformed Tel URI", phoneNumber: "tel:", want: ErrorMalformedTelURI},
+		{name: "Misplaced Plus", phoneNumber: "1+2125690123", countryCode: "US", want: ErrorMisplacedPlus},
+		{name: "Trailing Punctuation", phoneNumber: "+12125690123.", want: ErrorTrailingPunctuation},
+		{name: "Length", phoneNumber: "+1212", want: ErrorLengthOutOfRange},
+		{name: "Leading Digit", phoneNumber: "+10123456789", want: ErrorInvalidLeadingDigit},
+		{name: "Dialing Code Only", phoneNumber: "+1", opts: ParseOptions{Lenient: true}, want: ErrorMissingSubscriberNumber},
+		{name: "Unsupported Dialing Code", phoneNumber: "+81312345678", want: ErrorUnsupportedCountry},
+		{name: "Unknown Dialing Code", phoneNumber: "+99912345678", want: ErrorUnknownDialingCode},
+		{name: "Not E164", phoneNumber: "12125690123", opts: ParseOptions{StrictE164: true}, want: ErrorNotE164},
+		{name: "Lossy Numeric", phoneNumber: "2.12569E9", countryCode: "US", opts: ParseOptions{NumericSource: true}, want: ErrorLossyNumericFormat},
+		{name: "Integer Truncation", phoneNumber: "551234567", countryCode: "MX", opts: ParseOptions{NumericSource: true}, want: ErrorPossibleIntegerTruncation},
+		{name: "Ambiguous Integer Truncation", phoneNumber: "125690123", countryCode: "US", opts: ParseOptions{NumericSource: true}, want: ErrorPossibleIntegerTruncation},
+		{name: "Suspicious Pattern", validator: rejecting, phoneNumber: "+12222222222", want: ErrorSuspiciousPattern},
+	}
+
+	check := func(t *testing.T, err error, want ErrorCode) {
+		t.Helper()
+		if err == nil {
+			t.Fatalf("Expected %s, got no error", want)
+		}
+		if got := errorCode(err); got != want || !got.IsValid() {
+			t.Errorf("Expected registered code %s for %q, got %q", want, err, got)
+		}
+		before := h.unmappedErrors.Load()
+		if mapped := h.mapValidationError(err); len(mapped) == 0 || h.unmappedErrors.Load() != before {
+			t.Errorf("Expected %q to map by its code, got %v", err, mapped)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.validator
+			if v == nil {
+				v = validator
+			}
+			_, err := v.ValidatePhoneNumberWithOptions(tt.phoneNumber, tt.countryCode, tt.opts)
+			check(t, err, tt.want)
+		})
+	}
+
+	t.Run("Interpretations", func(t *testing.T) {
+		_, err := validator.Interpretations("+12125690123")
+		check(t, err, ErrorNationalNumberRequired)
+	})
+
+	t.Run("Range Endpoint", func(t *testing.T) {
+		_, err := validator.ValidateRange("+1212", "+1213")
+		check(t, err, ErrorLengthOutOfRange)
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		_, err := validator.ValidateRange("+12125690123", "+12125690100")
+		var rangeErr *RangeError
+		if !errors.As(err, &rangeErr) || !rangeErr.Code.IsValid() {
+			t.Errorf("Expected a range error with a registered code, got %v", err)
+		}
+	})
+
+	t.Run("Split", func(t *testing.T) {
+		_, _, err := validator.splitNationalNumber("212", "US")
+		check(t, err, ErrorAreaCodeUndetermined)
+	})
+}
@@ -0,0 +1,71 @@
+//go:build !js
+
+package api
+
+import "sync"
+
+// FailureReasonOther buckets countries the validator does not know, or
+// failures with no country, and codes outside LookupErrorCodes, so the
+// matrix stays small whatever callers send. UNSUPPORTED_COUNTRY is the
+// exception: it is counted under the region of ITUDialingCodes it names,
+// which shows where demand for new countries comes from.
+const FailureReasonOther = "other"
+
+// FailureReasons counts failed lookups by country and error code.
+type FailureReasons struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int64
+}
+
+func NewFailureReasons() *FailureReasons {
+	return &FailureReasons{counts: map[string]map[string]int64{}}
+}
+
+// Record expects a country and code already bucketed by failureReason.
+func (f *FailureReasons) Record(countryCode, code string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	byCode, exists := f.counts[countryCode]
+	if !exists {
+		byCode = map[string]int64{}
+		f.counts[countryCode] = byCode
+	}
+	byCode[code]++
+}
+
+// Counts returns a copy of the matrix, country then code.
+func (f *FailureReasons) Counts() map[string]map[string]int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	counts := make(map[string]map[string]int64, len(f.counts))
+	for countryCode, byCode := range f.counts {
+		counts[countryCode] = make(map[string]int64, len(byCode))
+		for code, count := range byCode {
+			counts[countryCode][code] = count
+		}
+	}
+	return counts
+}
+
+var knownFailureCodes = func() map[ErrorCode]bool {
+	known := make(map[ErrorCode]bool, len(LookupErrorCodes))
+	for _, code := range LookupErrorCodes {
+		known[code] = true
+	}
+	return known
+}()
+
+// failureReason buckets a failed outcome's country and code.
+func (h *Handler) failureReason(outcome lookupOutcome) (string, string) {
+	countryCode := outcome.countryCode
+	code := outcome.errorResponse.Code
+	if _, known := h.validator.countryLengths(countryCode); !known && (code != ErrorUnsupportedCountry || countryCode == "") {
+		countryCode = FailureReasonOther
+	}
+	if !knownFailureCodes[code] {
+		return countryCode, FailureReasonOther
+	}
+	return countryCode, string(code)
+}
@@ -0,0 +1,66 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Features that WithDisabledFeatures can switch off. The names match the
+// capabilities document's features map.
+const (
+	FeatureBatch       = "batch"
+	FeatureJobs        = "jobs"
+	FeatureWebhookTest = "webhookTest"
+)
+
+const (
+	ErrorFeatureDisabled    ErrorCode = "FEATURE_DISABLED"
+	ErrorNotAvailableInDemo ErrorCode = "NOT_AVAILABLE_IN_DEMO"
+)
+
+// WithDisabledFeatures keeps the routes of the given features registered,
+// so they stay discoverable, but answers them with 403 FEATURE_DISABLED.
+// FeatureBatch covers JSON and CSV batches; FeatureJobs covers creating,
+// polling and streaming jobs.
+func WithDisabledFeatures(features ...string) HandlerOption {
+	return func(h *Handler) {
+		if h.disabledFeatures == nil {
+			h.disabledFeatures = map[string]bool{}
+		}
+		for _, feature := range features {
+			h.disabledFeatures[feature] = true
+		}
+	}
+}
+
+func (h *Handler) featureEnabled(feature string) bool {
+	return !h.disabledFeatures[feature]
+}
+
+// featureUnavailable is the 403 body for a disabled feature, or nil.
+func (h *Handler) featureUnavailable(feature string) *ErrorResponse {
+	if h.featureEnabled(feature) {
+		return nil
+	}
+	if h.demo {
+		return &ErrorResponse{Code: ErrorNotAvailableInDemo, Error: map[string]string{
+			"feature": feature + " is not available in demo mode",
+		}}
+	}
+	return &ErrorResponse{Code: ErrorFeatureDisabled, Error: map[string]string{
+		"feature": feature + " is disabled",
+	}}
+}
+
+func (h *Handler) requireFeature(feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if errorResponse := h.featureUnavailable(feature); errorResponse != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse)
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,20 +1,112 @@
+//go:build !js
+
 package api
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
-	validator *PhoneNumberValidator
+	validator    *PhoneNumberValidator
+	paramAliases map[string]string
+	adminToken   string
+	maintenance  *maintenanceMode
+
+	failureSampler *failureSampler
+	hooks          []Hook
+	enum           *EnumLookup
+	portability    PortabilityResolver
+	stats          *UsageStats
+	latency        *LatencyStats
+	deprecations   *DeprecationCounter
+	apiKeys        *APIKeyStore
+	recent         *recentLookups
+	jobs           *jobStore
+	fixPlus        bool
+	basePath       string
+	now            func() time.Time
+	warmedUp       atomic.Bool
+	endpoints      []Endpoint
+	health         *healthChecks
+	observe        MiddlewareObserver
+	webhooks       *webhooks
+	errorMessages  *ErrorMessageStore
+	failureReasons *FailureReasons
+	resultCache    *resultCache
+	negativeCache  *negativeCache
+	cacheSeed      *cacheSeed
+
+	disabledFeatures  map[string]bool
+	globalLimit       *globalLimit
+	redisLimit        *redisLimit
+	countryPreference []string
+	privacy           bool
+	unmappedErrors    atomic.Int64
+	echoLimit         int
+	demo              bool
+	callingHours      CallingHours
+	corpus            *CorpusRecorder
+	shadow            *shadow
+	strictContentType bool
+}
+
+type HandlerOption func(*Handler)
+
+func WithParamAliases(aliases map[string]string) HandlerOption {
+	return func(h *Handler) {
+		h.paramAliases = aliases
+	}
 }
 
-func NewHandler() *Handler {
-	return &Handler{
-		validator: NewPhoneNumberValidator(),
+func WithHooks(hooks ...Hook) HandlerOption {
+	return func(h *Handler) {
+		h.hooks = append(h.hooks, hooks...)
 	}
 }
 
+func WithValidatorOptions(opts ...ValidatorOption) HandlerOption {
+	return func(h *Handler) {
+		h.validator = NewPhoneNumberValidator(opts...)
+	}
+}
+
+func NewHandler(opts ...HandlerOption) *Handler {
+	h := &Handler{
+		validator:      NewPhoneNumberValidator(),
+		maintenance:    &maintenanceMode{},
+		stats:          NewUsageStats(),
+		latency:        NewLatencyStats(),
+		deprecations:   NewDeprecationCounter(),
+		jobs:           newJobStore(),
+		health:         newHealthChecks(),
+		webhooks:       newWebhooks(),
+		failureReasons: NewFailureReasons(),
+		callingHours:   DefaultCallingHours,
+		echoLimit:      DefaultEchoLength,
+		portability:    NoPortability{},
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.privacy {
+		h.recent, h.failureSampler = nil, nil
+		if h.shadow != nil {
+			h.shadow.keepExample = false
+		}
+	}
+	h.health.checks = append(h.builtinHealthChecks(), h.health.checks...)
+	return h
+}
+
 func (h *Handler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status": "healthy",
@@ -24,77 +116,369 @@ func (h *Handler) HealthCheck(c *gin.Context) {
 
 func (h *Handler) PhoneNumberLookup(c *gin.Context) {
 	var req PhoneValidationRequest
-	
-	if err := c.ShouldBindQuery(&req); err != nil {
+
+	if h.exceedsInputLimit(c.Request.URL.Query()) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
-			PhoneNumber: req.PhoneNumber,
-			Error: map[string]string{
-				"validation": "invalid request parameters",
-			},
+			Code:  ErrorInputTooLong,
+			Error: h.mapValidationError(errInputTooLong),
 		})
 		return
 	}
 
-	response, err := h.validator.ValidatePhoneNumber(req.PhoneNumber, req.CountryCode)
-	if err != nil {
-		errorMsg := h.mapValidationError(err.Error())
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			PhoneNumber: req.PhoneNumber,
-			Error:       errorMsg,
-		})
+	h.applyParamAliases(c)
+
+	if errorResponse := bindLookupQuery(c, &req); errorResponse != nil {
+		c.JSON(http.StatusBadRequest, h.echo(errorResponse))
+		return
+	}
+
+	options, errorResponse := h.resolveRequestOptions(c)
+	if errorResponse != nil {
+		c.JSON(http.StatusBadRequest, errorResponse)
+		return
+	}
+
+	outcome := h.processLookup(ginLookupScope(c), req, options)
+	if status := h.cacheHeader(outcome); status != "" {
+		c.Header("X-Cache", status)
+	}
+	if outcome.errorResponse != nil {
+		c.JSON(outcome.status, outcome.errorResponse)
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.Header("Content-Language", NegotiateLanguage(c.GetHeader("Accept-Language")))
+	for _, warning := range outcome.response.WarningDetails {
+		c.Writer.Header().Add("Warning", warning.headerValue())
+	}
+
+	c.JSON(outcome.status, outcome.response)
+}
+
+// lookupOutcome is the status and body the single lookup endpoint answers
+// with; exactly one of response and errorResponse is set.
+// lookupOutcome.countryCode is the country a failure concerns: the one the
+// validator determined, else the one provided, else the one the dialing
+// code names.
+type lookupOutcome struct {
+	status        int
+	response      *PhoneValidationResponse
+	errorResponse *ErrorResponse
+	countryCode   string
+	cached        cacheHit
 }
 
-func (h *Handler) mapValidationError(errMsg string) map[string]string {
-	switch {
-	case errMsg == "phoneNumber is required":
-		return map[string]string{
-			"phoneNumber": "required value is missing",
+// lookupScope is what processLookup needs from the HTTP request, so the gin
+// routes and NewStdHandler share it. requestID is only called when a
+// failure is sampled.
+type lookupScope struct {
+	ctx       context.Context
+	language  string
+	key       *APIKeyConfig
+	keyLabel  string
+	requestID func() string
+}
+
+func ginLookupScope(c *gin.Context) lookupScope {
+	return lookupScope{
+		ctx:       c.Request.Context(),
+		language:  NegotiateLanguage(c.GetHeader("Accept-Language")),
+		key:       apiKeyConfig(c),
+		keyLabel:  c.GetString(apiKeyLabelKey),
+		requestID: func() string { return requestIDFor(c) },
+	}
+}
+
+// processLookup is the transport-independent core of every lookup. The
+// GET query, batch JSON, batch CSV and job transports only decode input
+// into a request, resolve RequestOptions once per HTTP request and encode
+// the outcome, so an item behaves exactly like the equivalent single
+// request. Options switch settings on; they never turn off a setting the
+// item asked for.
+func (h *Handler) processLookup(scope lookupScope, req PhoneValidationRequest, options RequestOptions) lookupOutcome {
+	req.Lenient = req.Lenient || options.Lenient
+	req.Enum = req.Enum || options.Enum
+	req.Truncate = req.Truncate || options.Truncate
+
+	if problems := req.valueProblems(); len(problems) > 0 {
+		errorResponse := &ErrorResponse{
+			PhoneNumber: req.PhoneNumber,
+			Code:        ErrorMalformedRequest,
+			Error:       problems,
 		}
-	case errMsg == "countryCode is required for numbers without country code":
-		return map[string]string{
-			"countryCode": "required value is missing",
+		outcome := lookupOutcome{status: http.StatusBadRequest, errorResponse: errorResponse, countryCode: requestedCountry(req)}
+		h.failureReasons.Record(h.failureReason(outcome))
+		h.errorMessages.apply(errorResponse, scope.language, outcome.countryCode)
+		h.echo(errorResponse)
+		return outcome
+	}
+
+	var warnings warningSet
+	// Strict E.164 input must arrive with its plus, so none is recovered.
+	if options.FixPlus && !options.Strict && !req.Strict {
+		var recovered bool
+		if req.PhoneNumber, recovered = h.recoverPlus(req.PhoneNumber); recovered {
+			warnings.add(WarningPlusSignRecovered, "")
 		}
-	case errMsg == "country code must be 2 characters (ISO 3166-1 alpha-2)":
-		return map[string]string{
-			"countryCode": "invalid format (must be ISO 3166-1 alpha-2)",
+	}
+
+	outcome := h.lookup(scope, req)
+	if outcome.response != nil && len(warnings) > 0 {
+		outcome.response.setWarnings(append(warnings, outcome.response.WarningDetails...))
+	}
+	if outcome.errorResponse != nil {
+		if outcome.countryCode == "" {
+			outcome.countryCode = requestedCountry(req)
+		}
+		h.failureReasons.Record(h.failureReason(outcome))
+		h.errorMessages.apply(outcome.errorResponse, scope.language, outcome.countryCode)
+		h.echo(outcome.errorResponse)
+	}
+	return outcome
+}
+
+// lookup runs one prepared request through hooks, validation, key checks,
+// stats and enrichment. Only processLookup calls it.
+func (h *Handler) lookup(scope lookupScope, req PhoneValidationRequest) lookupOutcome {
+	key := scope.key
+	if req.Enum && key != nil && !key.Enrichment {
+		return lookupOutcome{status: http.StatusForbidden, errorResponse: &ErrorResponse{
+			PhoneNumber: req.PhoneNumber,
+			Code:        ErrorEnrichmentNotAllowed,
+			Error: map[string]string{
+				"enum": "enrichment is not permitted for this API key",
+			},
+		}}
+	}
+
+	h.recent.add(req)
+
+	ctx := withValidationStart(scope.ctx, time.Now())
+	if err := runBeforeHooks(ctx, h.hooks, &req); err != nil {
+		runAfterHooks(ctx, h.hooks, req, nil, err)
+		h.recordUsage(scope.keyLabel, requestedUsageCountry(req.CountryCode), true)
+		return hookRejection(req, err)
+	}
+
+	response, cached, err := h.validate(req)
+	if usesCountryHints(req, err) {
+		response, cached, err = h.validateWithHints(scope, req, err)
+	} else if h.usesCountryPreference(req, err) {
+		response, cached, err = h.validateWithPreference(scope, req, err)
+	}
+	h.corpus.record(req, response, err, h.privacy)
+	if err == nil {
+		if err := runResultChecks(ctx, h.hooks, req, response); err != nil {
+			runAfterHooks(ctx, h.hooks, req, nil, err)
+			h.recordUsage(scope.keyLabel, response.CountryCode, true)
+			return hookRejection(req, err)
 		}
-	case errMsg == "unsupported country code":
-		return map[string]string{
-			"countryCode": "unsupported country code",
+	}
+	runAfterHooks(ctx, h.hooks, req, response, err)
+	h.shadow.observe(scope.requestID, req, response, err)
+	if err != nil {
+		h.failureSampler.observe(scope.requestID, req, err)
+		h.recordUsage(scope.keyLabel, requestedUsageCountry(req.CountryCode), true)
+
+		status, errorResponse := h.validationFailure(req.PhoneNumber, err)
+		return lookupOutcome{status: status, errorResponse: errorResponse, countryCode: failureCountry(err), cached: cached}
+	}
+
+	if key != nil && !key.allowsCountry(response.CountryCode) {
+		h.recordUsage(scope.keyLabel, response.CountryCode, true)
+		return lookupOutcome{status: http.StatusForbidden, errorResponse: &ErrorResponse{
+			PhoneNumber: req.PhoneNumber,
+			Code:        ErrorCountryNotAllowed,
+			Error: map[string]string{
+				"countryCode": "not allowed for this API key",
+			},
+		}, countryCode: response.CountryCode}
+	}
+
+	h.recordUsage(scope.keyLabel, response.CountryCode, false)
+	if start, ok := ValidationStart(ctx); ok {
+		h.latency.ObserveCountry(response.CountryCode, time.Since(start))
+	}
+
+	response.CountryName = CountryName(response.CountryCode, scope.language)
+	if req.CallWindow {
+		response.CallWindow = h.callingHours.callWindow(response, h.now())
+	}
+
+	if req.Enum {
+		h.attachEnum(scope, response)
+	}
+	if req.Porting {
+		h.attachPorting(scope, response)
+	}
+
+	return lookupOutcome{status: http.StatusOK, response: response, cached: cached}
+}
+
+// attachEnum never fails the lookup: DNS problems degrade to an empty
+// record list plus a warning.
+func (h *Handler) attachEnum(scope lookupScope, response *PhoneValidationResponse) {
+	if h.enum == nil {
+		response.addWarning(WarningEnumNotEnabled, "")
+		return
+	}
+
+	result, cached, err := h.enum.LookupCached(scope.ctx, response.PhoneNumber)
+	if err != nil {
+		response.addWarning(WarningEnumLookupFailed, "")
+	} else {
+		h.stats.RecordEnrichment(time.Now(), scope.keyLabel, cached)
+	}
+	response.Enum = result
+}
+
+// validationFailure is the status and body for a validator error. The
+// code and status come from ErrorRegistry; lengths add the expected range.
+func (h *Handler) validationFailure(phoneNumber string, err error) (int, *ErrorResponse) {
+	errorResponse := &ErrorResponse{
+		PhoneNumber: phoneNumber,
+		Code:        errorCode(err),
+		Error:       h.mapValidationError(err),
+	}
+	status := http.StatusBadRequest
+	if mapping, exists := ErrorRegistry[errorResponse.Code]; exists {
+		status = mapping.Status
+	}
+	var hintsErr *CountryHintsError
+	if errors.As(err, &hintsErr) {
+		errorResponse.HintFailures = hintsErr.Failures
+	}
+	var lengthErr *LengthError
+	if errors.As(err, &lengthErr) {
+		errorResponse.ExpectedMin, errorResponse.ExpectedMax = lengthErr.ExpectedMin, lengthErr.ExpectedMax
+		errorResponse.Actual = lengthErr.Actual
+		errorResponse.ExampleNumber, _ = ExampleNumber(lengthErr.CountryCode)
+	}
+	var dialingCodeErr *DialingCodeError
+	if errors.As(err, &dialingCodeErr) {
+		errorResponse.RegionCode = dialingCodeErr.RegionCode
+	}
+	var splitErr *SplitInvariantError
+	if errors.As(err, &splitErr) {
+		log.Printf("Split rule for %s broke national number %q into areaCode=%q and a %d-digit localPhoneNumber",
+			splitErr.CountryCode, maskPhoneNumber(splitErr.NationalNumber), splitErr.AreaCode, len(splitErr.LocalPhoneNumber))
+	}
+	return status, errorResponse
+}
+
+// failureCountry returns the country a validation error names, if any.
+func failureCountry(err error) string {
+	var lengthErr *LengthError
+	if errors.As(err, &lengthErr) {
+		return lengthErr.CountryCode
+	}
+	var leadingDigitErr *LeadingDigitError
+	if errors.As(err, &leadingDigitErr) {
+		return leadingDigitErr.CountryCode
+	}
+	var dialingCodeErr *DialingCodeError
+	if errors.As(err, &dialingCodeErr) {
+		return dialingCodeErr.RegionCode
+	}
+	return ""
+}
+
+// requestedCountry is the provided country, else the country of an
+// international number's dialing code, for failures that stopped before
+// the validator resolved one.
+func requestedCountry(req PhoneValidationRequest) string {
+	if req.CountryCode != "" {
+		return strings.ToUpper(req.CountryCode)
+	}
+	digits, international := strings.CutPrefix(strings.TrimSpace(req.PhoneNumber), "+")
+	if !international {
+		return ""
+	}
+	if end := strings.IndexFunc(digits, func(r rune) bool { return r < '0' || r > '9' }); end >= 0 {
+		digits = digits[:end]
+	}
+	for length := 3; length > 0; length-- {
+		if len(digits) >= length {
+			if countryCode, exists := DialingCodeToCountry[digits[:length]]; exists {
+				return countryCode
+			}
 		}
-	case errMsg == "phone number contains invalid characters":
-		return map[string]string{
-			"phoneNumber": "contains invalid characters",
+	}
+	return ""
+}
+
+func hookRejection(req PhoneValidationRequest, err error) lookupOutcome {
+	status, field := http.StatusBadRequest, "phoneNumber"
+	var rejection *HookRejection
+	if errors.As(err, &rejection) {
+		if rejection.Status != 0 {
+			status = rejection.Status
 		}
-	case errMsg == "invalid spacing pattern":
-		return map[string]string{
-			"phoneNumber": "invalid spacing pattern",
+		if rejection.Field != "" {
+			field = rejection.Field
 		}
-	case errMsg == "unsupported country dialing code":
-		return map[string]string{
-			"phoneNumber": "unsupported country dialing code",
+	}
+
+	return lookupOutcome{status: status, errorResponse: &ErrorResponse{
+		PhoneNumber: req.PhoneNumber,
+		Code:        ErrorRejectedByHook,
+		Error: map[string]string{
+			field: err.Error(),
+		},
+	}}
+}
+
+// exceedsInputLimit checks the raw phone number, sent as phoneNumber or an
+// alias of it, before binding so oversized input is never copied into the
+// request struct or echoed back. Other parameters are validated as usual.
+func (h *Handler) exceedsInputLimit(query url.Values) bool {
+	for name, values := range query {
+		if name != "phoneNumber" && h.paramAliases[name] != "phoneNumber" {
+			continue
 		}
-	default:
-		if len(errMsg) > 30 && errMsg[:30] == "phone number length is invalid" {
-			return map[string]string{
-				"phoneNumber": "length is invalid for country",
+		for _, value := range values {
+			if len(value) > h.validator.MaxInputLength() {
+				return true
 			}
 		}
-		return map[string]string{
-			"phoneNumber": "invalid format",
-		}
 	}
+	return false
 }
 
+// SetupRoutes registers every route under the handler's base path (see
+// WithBasePath). Paths outside it fall through to the NoRoute handler.
 func (h *Handler) SetupRoutes(router *gin.Engine) {
-	router.GET("/health", h.HealthCheck)
-	
-	v1 := router.Group("/v1")
+	rootHandlers := []gin.HandlerFunc{h.responseCase}
+	if h.demo {
+		rootHandlers = append(rootHandlers, h.markDemo)
+	}
+	root := router.Group(h.basePath, rootHandlers...)
+	root.GET("/health", h.HealthCheck)
+	root.GET("/livez", h.Livez)
+	root.GET("/readyz", h.Readyz)
+
+	v1 := root.Group("/v1", h.recordLatency, h.maintenanceGuard,
+		h.stage(StageAuth, h.requireAPIKey), h.stage(StageLimits, h.enforceRateLimit), h.recordTraffic)
 	{
 		v1.GET("/phone-numbers", h.PhoneNumberLookup)
+		v1.POST("/phone-numbers/batch", h.requireFeature(FeatureBatch), h.requireContentType(MediaTypeJSON, MediaTypeCSV), h.BatchLookup)
+		v1.GET("/phone-numbers/interpretations", h.requireRichMetadata, h.Interpretations)
+		v1.GET("/phone-numbers/dialing-instructions", h.requireRichMetadata, h.DialingInstructions)
+		v1.GET("/phone-numbers/range", h.requireRichMetadata, h.NumberRange)
+		v1.POST("/jobs", h.requireFeature(FeatureJobs), h.requireContentType(MediaTypeJSON), h.CreateJob)
+		v1.POST("/webhooks/test", h.requireFeature(FeatureWebhookTest), h.requireContentType(MediaTypeJSON), h.TestWebhook)
+		v1.GET("/jobs/:id", h.requireFeature(FeatureJobs), h.GetJob)
+		v1.GET("/jobs/:id/events", h.requireFeature(FeatureJobs), h.JobEvents)
+		v1.GET("/jobs/:id/results", h.requireFeature(FeatureJobs), h.GetJobResults)
+		v1.DELETE("/jobs/:id", h.requireFeature(FeatureJobs), h.DeleteJob)
+		v1.GET("/countries", h.ListCountries)
+		v1.GET("/stats", h.Stats)
+		v1.GET("/capabilities", h.Capabilities)
 	}
+
+	h.setupAdminRoutes(root)
+	h.collectEndpoints(router)
+
+	h.registerOptionsRoutes(router)
+	h.registerNoRoute(router)
 }
@@ -0,0 +1,174 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultHealthCheckTimeout = time.Second
+	DefaultHealthCacheTTL     = 2 * time.Second
+)
+
+// HealthChecker is a dependency /readyz verifies. CheckHealth should
+// respect ctx; a checker that does not is abandoned once its timeout
+// elapses and reported as failing.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// HealthCheckerFunc adapts a function to HealthChecker.
+type HealthCheckerFunc func(ctx context.Context) error
+
+func (f HealthCheckerFunc) CheckHealth(ctx context.Context) error {
+	return f(ctx)
+}
+
+// HealthCheck registers a checker with /readyz. A failing hard check
+// marks the service not ready; a failing soft check is only reported.
+type HealthCheck struct {
+	Name    string
+	Checker HealthChecker
+	Hard    bool
+	Timeout time.Duration
+}
+
+// DependencyStatus is one check's entry in the readiness response.
+type DependencyStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Hard      bool    `json:"hard"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ReadinessResponse.Degraded marks an instance that is ready but validating
+// on the minimal table because its metadata failed to load.
+type ReadinessResponse struct {
+	Status       string             `json:"status"`
+	Reason       string             `json:"reason,omitempty"`
+	Degraded     bool               `json:"degraded,omitempty"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// WithHealthChecks registers dependency checks in addition to the built-in
+// ones: the jobs store (hard) and, when configured, ENUM DNS (soft).
+func WithHealthChecks(checks ...HealthCheck) HandlerOption {
+	return func(h *Handler) {
+		h.health.checks = append(h.health.checks, checks...)
+	}
+}
+
+// WithHealthCacheTTL sets how long /readyz reuses check results, so probe
+// storms do not reach dependencies. Zero checks on every request.
+func WithHealthCacheTTL(ttl time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.health.ttl = ttl
+	}
+}
+
+type healthChecks struct {
+	checks []HealthCheck
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	results   []DependencyStatus
+	checkedAt time.Time
+}
+
+func newHealthChecks() *healthChecks {
+	return &healthChecks{ttl: DefaultHealthCacheTTL}
+}
+
+// run returns every check's status, in registration order, and whether
+// all hard checks passed. Concurrent callers share one round of checks.
+func (hc *healthChecks) run(ctx context.Context, now time.Time) ([]DependencyStatus, bool) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if hc.results == nil || now.Sub(hc.checkedAt) >= hc.ttl {
+		results := make([]DependencyStatus, len(hc.checks))
+		var wg sync.WaitGroup
+		for i, check := range hc.checks {
+			wg.Add(1)
+			go func(i int, check HealthCheck) {
+				defer wg.Done()
+				results[i] = runHealthCheck(ctx, check)
+			}(i, check)
+		}
+		wg.Wait()
+		hc.results, hc.checkedAt = results, now
+	}
+
+	ready := true
+	for _, result := range hc.results {
+		if result.Hard && result.Status != "healthy" {
+			ready = false
+		}
+	}
+	return hc.results, ready
+}
+
+func runHealthCheck(ctx context.Context, check HealthCheck) DependencyStatus {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	// Start the clock before the deadline so a check that times out never
+	// reports less latency than its timeout.
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Checker.CheckHealth(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errors.New("timed out after " + timeout.String())
+	}
+
+	status := DependencyStatus{
+		Name:      check.Name,
+		Status:    "healthy",
+		Hard:      check.Hard,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status, status.Error = "unhealthy", err.Error()
+	}
+	return status
+}
+
+// builtinHealthChecks covers the subsystems the handler owns.
+func (h *Handler) builtinHealthChecks() []HealthCheck {
+	checks := []HealthCheck{{Name: "jobs", Checker: h.jobs, Hard: true}}
+	if h.enum != nil {
+		checks = append(checks, HealthCheck{Name: "enum", Checker: h.enum})
+	}
+	return checks
+}
+
+func (h *Handler) writeReadiness(c *gin.Context) {
+	dependencies, ready := h.health.run(c.Request.Context(), h.now())
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, ReadinessResponse{
+			Status:       "not ready",
+			Reason:       "dependency unavailable",
+			Dependencies: dependencies,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, ReadinessResponse{Status: "ready", Degraded: h.validator.MetadataDegraded(), Dependencies: dependencies})
+}
@@ -0,0 +1,170 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Hook observes the validation lifecycle of a lookup. BeforeValidate may
+// rewrite the request or reject it by returning an error; AfterValidate sees
+// the final outcome, including rejections. Hooks run in registration order
+// and a panicking hook is logged and skipped.
+type Hook interface {
+	BeforeValidate(ctx context.Context, req *PhoneValidationRequest) error
+	AfterValidate(ctx context.Context, req PhoneValidationRequest, result *PhoneValidationResponse, err error)
+}
+
+// ErrorRejectedByHook is a lookup a BeforeValidate hook or a
+// ResultChecker refused.
+const ErrorRejectedByHook ErrorCode = "REJECTED_BY_HOOK"
+
+// HookRejection lets a hook choose the status and error field of its
+// rejection. Any other error is answered with 400 on phoneNumber. Both
+// carry code REJECTED_BY_HOOK and the hook's message.
+type HookRejection struct {
+	Status  int
+	Field   string
+	Message string
+}
+
+func (r *HookRejection) Error() string {
+	return r.Message
+}
+
+type validationStartKey struct{}
+
+func withValidationStart(ctx context.Context, start time.Time) context.Context {
+	return context.WithValue(ctx, validationStartKey{}, start)
+}
+
+// ValidationStart returns when the lookup entered the hook pipeline.
+func ValidationStart(ctx context.Context) (time.Time, bool) {
+	start, ok := ctx.Value(validationStartKey{}).(time.Time)
+	return start, ok
+}
+
+func runBeforeHooks(ctx context.Context, hooks []Hook, req *PhoneValidationRequest) error {
+	for _, hook := range hooks {
+		if err := callBeforeHook(ctx, hook, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func callBeforeHook(ctx context.Context, hook Hook, req *PhoneValidationRequest) (err error) {
+	snapshot := *req
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("hook %T panicked in BeforeValidate: %v", hook, r)
+			*req = snapshot
+			err = nil
+		}
+	}()
+	return hook.BeforeValidate(ctx, req)
+}
+
+// ResultChecker is implemented by hooks that reject on the validated
+// number rather than the raw input. CheckResult runs after a successful
+// validation, before AfterValidate, and its error is answered like a
+// BeforeValidate rejection.
+type ResultChecker interface {
+	CheckResult(ctx context.Context, req PhoneValidationRequest, result *PhoneValidationResponse) error
+}
+
+func runResultChecks(ctx context.Context, hooks []Hook, req PhoneValidationRequest, result *PhoneValidationResponse) error {
+	for _, hook := range hooks {
+		checker, ok := hook.(ResultChecker)
+		if !ok {
+			continue
+		}
+		if err := callResultCheck(ctx, checker, req, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func callResultCheck(ctx context.Context, checker ResultChecker, req PhoneValidationRequest, result *PhoneValidationResponse) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("hook %T panicked in CheckResult: %v", checker, r)
+			err = nil
+		}
+	}()
+	return checker.CheckResult(ctx, req, result)
+}
+
+func runAfterHooks(ctx context.Context, hooks []Hook, req PhoneValidationRequest, result *PhoneValidationResponse, err error) {
+	for _, hook := range hooks {
+		callAfterHook(ctx, hook, req, result, err)
+	}
+}
+
+func callAfterHook(ctx context.Context, hook Hook, req PhoneValidationRequest, result *PhoneValidationResponse, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("hook %T panicked in AfterValidate: %v", hook, r)
+		}
+	}()
+	hook.AfterValidate(ctx, req, result, err)
+}
+
+// PrefixDenyListHook rejects numbers starting with any configured E.164
+// prefix (e.g. "+1900"). It checks the validated number, so separators,
+// IDD prefixes, tel: URIs and national input cannot slip past it.
+type PrefixDenyListHook struct {
+	prefixes []string
+}
+
+func NewPrefixDenyListHook(prefixes ...string) *PrefixDenyListHook {
+	return &PrefixDenyListHook{prefixes: prefixes}
+}
+
+func (h *PrefixDenyListHook) BeforeValidate(ctx context.Context, req *PhoneValidationRequest) error {
+	return nil
+}
+
+func (h *PrefixDenyListHook) CheckResult(ctx context.Context, req PhoneValidationRequest, result *PhoneValidationResponse) error {
+	for _, prefix := range h.prefixes {
+		if prefix != "" && strings.HasPrefix(result.PhoneNumber, prefix) {
+			return &HookRejection{
+				Status:  http.StatusForbidden,
+				Field:   "phoneNumber",
+				Message: "number prefix is not allowed",
+			}
+		}
+	}
+	return nil
+}
+
+func (h *PrefixDenyListHook) AfterValidate(ctx context.Context, req PhoneValidationRequest, result *PhoneValidationResponse, err error) {
+}
+
+// TimingHook reports how long each lookup took, with the resolved country
+// (empty on failure), to Record.
+type TimingHook struct {
+	Record func(countryCode string, duration time.Duration, err error)
+}
+
+func (h *TimingHook) BeforeValidate(ctx context.Context, req *PhoneValidationRequest) error {
+	return nil
+}
+
+func (h *TimingHook) AfterValidate(ctx context.Context, req PhoneValidationRequest, result *PhoneValidationResponse, err error) {
+	start, ok := ValidationStart(ctx)
+	if !ok || h.Record == nil {
+		return
+	}
+
+	countryCode := ""
+	if result != nil {
+		countryCode = result.CountryCode
+	}
+	h.Record(countryCode, time.Since(start), err)
+}
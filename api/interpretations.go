@@ -0,0 +1,154 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorNationalNumberRequired is an interpretations request for a number
+// that already has a plus sign.
+const ErrorNationalNumberRequired ErrorCode = "NATIONAL_NUMBER_REQUIRED"
+
+var errNotNationalNumber = &InputFormatError{Code: ErrorNationalNumberRequired, Message: "interpretations need a national number"}
+
+// Interpretation is one country under which a national number validates.
+// NDC and AreaCode follow PhoneValidationResponse. Plausibility ranks
+// interpretations: a number-type rule match counts 2 and a known area code
+// name counts 1.
+type Interpretation struct {
+	CountryCode      string     `json:"countryCode"`
+	CountryName      string     `json:"countryName"`
+	PhoneNumber      string     `json:"phoneNumber"`
+	NDC              string     `json:"ndc"`
+	AreaCode         string     `json:"areaCode"`
+	LocalPhoneNumber string     `json:"localPhoneNumber"`
+	AreaCodeName     string     `json:"areaCodeName"`
+	NumberType       NumberType `json:"numberType,omitempty"`
+	Plausibility     int        `json:"plausibility"`
+}
+
+type InterpretationsResponse struct {
+	PhoneNumber     string           `json:"phoneNumber"`
+	Interpretations []Interpretation `json:"interpretations"`
+}
+
+// Interpretations returns every enabled country under which a national
+// number without a plus sign validates, most plausible first and
+// alphabetically within equal plausibility. Only countries whose length
+// range admits the number are checked.
+func (v *PhoneNumberValidator) Interpretations(phoneNumber string) ([]Interpretation, error) {
+	if phoneNumber == "" {
+		return nil, errPhoneNumberRequired
+	}
+	if err := v.validateInputSize(phoneNumber); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(phoneNumber, "+") {
+		return nil, errNotNationalNumber
+	}
+	phoneNumber, err := stripFormatting(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.validateSpacing(phoneNumber); err != nil {
+		return nil, err
+	}
+	cleanedNumber, err := v.cleanPhoneNumber(phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	countries := make([]string, 0, len(CountryPhoneLengths))
+	for code := range CountryPhoneLengths {
+		countries = append(countries, code)
+	}
+	sort.Strings(countries)
+
+	interpretations := []Interpretation{}
+	for _, countryCode := range countries {
+		if v.disabledCountries.IsDisabled(countryCode) {
+			continue
+		}
+		nationalNumber := cleanedNumber
+		if trunkPrefix, exists := CountryTrunkPrefixes[countryCode]; exists {
+			nationalNumber = strings.TrimPrefix(nationalNumber, trunkPrefix)
+		}
+		if !v.lengthFits(nationalNumber, countryCode) {
+			continue
+		}
+		if v.validatePhoneLength(nationalNumber, countryCode) != nil || v.validateLeadingDigit(nationalNumber, countryCode) != nil {
+			continue
+		}
+		ndc, localNumber, err := v.splitNationalNumber(nationalNumber, countryCode)
+		if err != nil || checkSplit(countryCode, nationalNumber, ndc, localNumber) != nil {
+			continue
+		}
+
+		interpretation := Interpretation{
+			CountryCode:      countryCode,
+			CountryName:      CountryName(countryCode, DefaultLanguage),
+			PhoneNumber:      v.formatPhoneNumber(countryCode, ndc, localNumber),
+			NDC:              ndc,
+			LocalPhoneNumber: localNumber,
+			AreaCodeName:     AreaCodeName(countryCode, nationalNumber),
+		}
+		if v.isGeographic(nationalNumber, countryCode) {
+			interpretation.AreaCode = ndc
+		}
+		if numberType, _, typed, _ := v.lengthRange(nationalNumber, countryCode); typed {
+			interpretation.NumberType = numberType
+			interpretation.Plausibility += 2
+		}
+		if interpretation.AreaCodeName != "" {
+			interpretation.Plausibility++
+		}
+		interpretations = append(interpretations, interpretation)
+	}
+
+	sort.SliceStable(interpretations, func(i, j int) bool {
+		return interpretations[i].Plausibility > interpretations[j].Plausibility
+	})
+	return interpretations, nil
+}
+
+// Interpretations answers GET /v1/phone-numbers/interpretations. A number
+// valid nowhere is not an error: the list is simply empty.
+func (h *Handler) Interpretations(c *gin.Context) {
+	var req PhoneValidationRequest
+	if errorResponse := bindLookupQuery(c, &req); errorResponse != nil {
+		c.JSON(http.StatusBadRequest, h.echo(errorResponse))
+		return
+	}
+
+	interpretations, err := h.validator.Interpretations(req.PhoneNumber)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, h.echo(&ErrorResponse{
+			PhoneNumber: req.PhoneNumber,
+			Code:        errorCode(err),
+			Error:       h.mapValidationError(err),
+		}))
+		return
+	}
+
+	key := apiKeyConfig(c)
+	language := NegotiateLanguage(c.GetHeader("Accept-Language"))
+	allowed := interpretations[:0]
+	for _, interpretation := range interpretations {
+		if key != nil && !key.allowsCountry(interpretation.CountryCode) {
+			continue
+		}
+		interpretation.CountryName = CountryName(interpretation.CountryCode, language)
+		allowed = append(allowed, interpretation)
+	}
+
+	c.Header("Content-Language", language)
+	c.JSON(http.StatusOK, InterpretationsResponse{
+		PhoneNumber:     req.PhoneNumber,
+		Interpretations: allowed,
+	})
+}
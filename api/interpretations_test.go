@@ -0,0 +1,40 @@
+//go:build !js
+
+package api
+
+import "testing"
+
+func TestPhoneNumberValidator_Interpretations(t *testing.T) {
+	validator := NewPhoneNumberValidator(WithDisabledCountries("DE"))
+
+	interpretations, err := validator.Interpretations("3123456789")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(interpretations) < 3 {
+		t.Fatalf("Expected several interpretations, got %v", interpretations)
+	}
+	// A typed rule plus an area name (KR, Gyeonggi) outranks a typed rule
+	// alone (IT mobile), which outranks an area name alone (US, Chicago);
+	// equal plausibility stays alphabetical.
+	for i, want := range []string{"KR", "IT", "US"} {
+		if interpretations[i].CountryCode != want {
+			t.Errorf("Expected %s at position %d, got %+v", want, i, interpretations[i])
+		}
+	}
+	for i, interpretation := range interpretations {
+		if interpretation.CountryCode == "DE" {
+			t.Errorf("Disabled country DE was interpreted")
+		}
+		if i > 0 && interpretation.Plausibility == interpretations[i-1].Plausibility && interpretation.CountryCode < interpretations[i-1].CountryCode {
+			t.Errorf("Expected equal plausibility in alphabetical order, got %s after %s", interpretation.CountryCode, interpretations[i-1].CountryCode)
+		}
+	}
+
+	if interpretations, _ := validator.Interpretations("12345"); len(interpretations) != 0 {
+		t.Errorf("Expected no interpretations, got %v", interpretations)
+	}
+	if _, err := validator.Interpretations("+12125690123"); err == nil {
+		t.Errorf("Expected international input to be rejected")
+	}
+}
@@ -0,0 +1,259 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const ErrorIdempotencyKeyReused ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+
+const (
+	// IdempotencyKeyHeader names the header that makes POST /v1/jobs
+	// safe to retry.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayHeader is set to "true" on a response that returns
+	// the job an earlier request with the same key created.
+	IdempotentReplayHeader = "X-Idempotent-Replay"
+
+	// MaxIdempotencyKeyLength caps Idempotency-Key values.
+	MaxIdempotencyKeyLength = 255
+
+	// DefaultIdempotencyWindow is how long a key is remembered when
+	// WithJobIdempotency is given no window.
+	DefaultIdempotencyWindow = 24 * time.Hour
+
+	// RedisIdempotencyPrefix starts the Redis key of every idempotency key.
+	RedisIdempotencyPrefix = "phone-api:idempotency:"
+)
+
+// idempotencyRecord is the job an idempotency key created. Fingerprint
+// identifies the request, so the same key with another payload can be
+// refused; Status and Total are reported when the job runs on another
+// replica, which updates Status when the job completes.
+type idempotencyRecord struct {
+	JobID       string `json:"jobId"`
+	Fingerprint string `json:"fingerprint"`
+	Status      string `json:"status"`
+	Total       int    `json:"total"`
+	expires     time.Time
+}
+
+// WithJobIdempotency honours an Idempotency-Key header on POST /v1/jobs
+// for window (zero keeps DefaultIdempotencyWindow). It is off by default.
+// The first request with a key creates the job; a retry with the same key
+// and payload gets that job back with X-Idempotent-Replay: true, and one
+// with another payload is refused with 409 IDEMPOTENCY_KEY_REUSED. A key is
+// forgotten with its job, when the job is deleted or expires. Keys are
+// scoped to the API key. With WithRedisRateLimit they are kept in Redis so
+// every replica sees them, otherwise in the job store.
+func WithJobIdempotency(window time.Duration) HandlerOption {
+	return func(h *Handler) {
+		if window <= 0 {
+			window = DefaultIdempotencyWindow
+		}
+		h.jobs.idempotencyWindow = window
+	}
+}
+
+func idempotencyKeyProblem(key string) string {
+	if len(key) > MaxIdempotencyKeyLength {
+		return "must be at most 255 characters"
+	}
+	for _, r := range key {
+		if r < ' ' || r > '~' {
+			return "must contain only printable ASCII characters"
+		}
+	}
+	return ""
+}
+
+// jobFingerprint identifies a job request by its items and options.
+func jobFingerprint(items []BatchItem, options RequestOptions) string {
+	data, _ := json.Marshal(struct {
+		Items   []BatchItem
+		Options RequestOptions
+	}{items, options})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// submitJob starts a job for items like startJob. With idempotency on and
+// a key, a key already used for the same payload returns its job with
+// replay set instead, and one used for another payload is rejected.
+func (h *Handler) submitJob(scope lookupScope, idempotencyKey string, items []BatchItem, options RequestOptions) (JobProgress, bool, ErrorCode) {
+	if h.jobs.idempotencyWindow <= 0 || idempotencyKey == "" {
+		progress, rejected := h.startJob(scope, newJobID(), "", items, options)
+		return progress, false, rejected
+	}
+
+	key := scope.keyLabel + "\x00" + idempotencyKey
+	record := idempotencyRecord{JobID: newJobID(), Fingerprint: jobFingerprint(items, options), Status: JobStatusRunning, Total: len(items)}
+	existing, claimed := h.claimIdempotencyKey(scope.ctx, key, record)
+	if !claimed {
+		if existing.Fingerprint != record.Fingerprint {
+			return JobProgress{}, false, ErrorIdempotencyKeyReused
+		}
+		return h.replayedJob(existing), true, ""
+	}
+
+	progress, rejected := h.startJob(scope, record.JobID, key, items, options)
+	if rejected != "" {
+		h.releaseIdempotencyKey(scope.ctx, key, record.JobID)
+	}
+	return progress, false, rejected
+}
+
+// replayedJob is the current progress of record's job, or the status its
+// replica last recorded when another replica runs it.
+func (h *Handler) replayedJob(record idempotencyRecord) JobProgress {
+	if j, exists := h.jobs.get(record.JobID); exists {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		return j.progress
+	}
+	return JobProgress{ID: record.JobID, Status: record.Status, Total: record.Total}
+}
+
+// claimIdempotencyKey stores record under key unless a live record is
+// there already, which is returned instead. Redis is used when configured;
+// if it fails, the replica's own store decides.
+func (h *Handler) claimIdempotencyKey(ctx context.Context, key string, record idempotencyRecord) (idempotencyRecord, bool) {
+	if h.redisLimit != nil {
+		existing, claimed, err := h.redisLimit.claimIdempotencyKey(ctx, key, record, h.jobs.idempotencyWindow)
+		if err == nil {
+			return existing, claimed
+		}
+		log.Printf("Idempotency key check against Redis failed, using this replica's job store: %v", err)
+	}
+	return h.jobs.claimIdempotencyKey(key, record, h.now())
+}
+
+func (h *Handler) releaseIdempotencyKey(ctx context.Context, key, jobID string) {
+	if h.redisLimit != nil {
+		h.redisLimit.releaseIdempotencyKey(ctx, key, jobID)
+	}
+	h.jobs.releaseIdempotencyKey(key, jobID)
+}
+
+// completeIdempotencyKey records that j completed. Its Redis key is kept
+// no longer than the job, which expires DefaultJobRetention after it
+// completes; the job store forgets its own key when the job expires.
+func (h *Handler) completeIdempotencyKey(ctx context.Context, j *job) {
+	if h.redisLimit != nil && j.idempotencyKey != "" {
+		h.redisLimit.completeIdempotencyKey(ctx, j.idempotencyKey, j.progress.ID)
+	}
+}
+
+// claimIdempotencyKey claims key for record unless a record whose window
+// has not passed and whose job has not expired is there already, which is
+// returned instead. Records are removed with their job, so only key's own
+// record needs checking.
+func (s *jobStore) claimIdempotencyKey(key string, record idempotencyRecord, now time.Time) (idempotencyRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, exists := s.idempotency[key]; exists && now.Before(existing.expires) && s.jobLive(existing.JobID, now) {
+		return existing, false
+	}
+	record.expires = now.Add(s.idempotencyWindow)
+	s.idempotency[key] = record
+	return record, true
+}
+
+func (s *jobStore) releaseIdempotencyKey(key, jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forgetIdempotencyKey(key, jobID)
+}
+
+// jobLive reports whether job id is kept at now: it is running or
+// completed no more than DefaultJobRetention ago. s.mu must be held.
+func (s *jobStore) jobLive(id string, now time.Time) bool {
+	j, exists := s.jobs[id]
+	if !exists {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.finished.IsZero() || now.Sub(j.finished) <= DefaultJobRetention
+}
+
+// forgetIdempotencyKey removes key if it still belongs to jobID. s.mu must
+// be held.
+func (s *jobStore) forgetIdempotencyKey(key, jobID string) {
+	if existing, exists := s.idempotency[key]; exists && existing.JobID == jobID {
+		delete(s.idempotency, key)
+	}
+}
+
+// redisIdempotencyKey hashes key, which holds client-chosen bytes.
+func redisIdempotencyKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return RedisIdempotencyPrefix + hex.EncodeToString(sum[:])
+}
+
+func (r *redisLimit) claimIdempotencyKey(ctx context.Context, key string, record idempotencyRecord, window time.Duration) (idempotencyRecord, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	data, _ := json.Marshal(record)
+	claimed, err := r.client.SetNX(ctx, redisIdempotencyKey(key), data, window).Result()
+	if err != nil || claimed {
+		return record, claimed, err
+	}
+	stored, err := r.client.Get(ctx, redisIdempotencyKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return record, false, errors.New("idempotency key expired while it was read")
+	}
+	if err != nil {
+		return record, false, err
+	}
+	var existing idempotencyRecord
+	if err := json.Unmarshal(stored, &existing); err != nil {
+		return record, false, err
+	}
+	return existing, false, nil
+}
+
+// completeIdempotencyKey marks key's record complete and shortens its
+// expiry to DefaultJobRetention if more of the window is left.
+func (r *redisLimit) completeIdempotencyKey(ctx context.Context, key, jobID string) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	redisKey := redisIdempotencyKey(key)
+	stored, err := r.client.Get(ctx, redisKey).Bytes()
+	var record idempotencyRecord
+	if err != nil || json.Unmarshal(stored, &record) != nil || record.JobID != jobID {
+		return
+	}
+	ttl, err := r.client.PTTL(ctx, redisKey).Result()
+	if err != nil || ttl <= 0 {
+		return
+	}
+	record.Status = JobStatusComplete
+	data, _ := json.Marshal(record)
+	if err := r.client.SetXX(ctx, redisKey, data, min(ttl, DefaultJobRetention)).Err(); err != nil {
+		log.Printf("Failed to record job completion for its idempotency key in Redis: %v", err)
+	}
+}
+
+func (r *redisLimit) releaseIdempotencyKey(ctx context.Context, key, jobID string) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	stored, err := r.client.Get(ctx, redisIdempotencyKey(key)).Bytes()
+	var existing idempotencyRecord
+	if err == nil && json.Unmarshal(stored, &existing) == nil && existing.JobID == jobID {
+		r.client.Del(ctx, redisIdempotencyKey(key))
+	}
+}
@@ -0,0 +1,311 @@
+//go:build !js
+
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ErrorTooManyJobs           ErrorCode = "TOO_MANY_JOBS"
+	ErrorJobStorageFull        ErrorCode = "JOB_STORAGE_FULL"
+	ErrorJobResultsUnavailable ErrorCode = "JOB_RESULTS_UNAVAILABLE"
+)
+
+const (
+	// DefaultJobMemoryResults is how many of a spilled job's most recent
+	// results stay in memory.
+	DefaultJobMemoryResults = 1000
+
+	DefaultJobResultsPage = 100
+	MaxJobResultsPage     = 1000
+)
+
+// errJobReleased is reading a job whose results were deleted or expired
+// after it was looked up.
+var errJobReleased = errors.New("job results were released")
+
+// WithJobSpill writes job results to one NDJSON file per job in dir,
+// keeping only the memoryResults most recent ones in memory (zero keeps
+// DefaultJobMemoryResults). The files of a previous process are orphans,
+// since jobs do not survive a restart, and are removed here.
+func WithJobSpill(dir string, memoryResults int) HandlerOption {
+	return func(h *Handler) {
+		if dir == "" {
+			return
+		}
+		h.jobs.spillDir = dir
+		if memoryResults > 0 {
+			h.jobs.memoryResults = memoryResults
+		}
+		removed, err := cleanJobSpillDir(dir)
+		if err != nil {
+			log.Printf("Failed to clean job results directory %s: %v", dir, err)
+		}
+		if removed > 0 {
+			log.Printf("Removed %d orphaned job result files from %s", removed, dir)
+		}
+	}
+}
+
+// WithJobLimits rejects new jobs with 429 TOO_MANY_JOBS while maxRunning
+// jobs are running, and with 507 JOB_STORAGE_FULL once spilled results
+// take maxDiskBytes. Zero disables a limit. The disk limit is checked when
+// a job is created, so running jobs may take it past the limit.
+func WithJobLimits(maxRunning int, maxDiskBytes int64) HandlerOption {
+	return func(h *Handler) {
+		h.jobs.maxRunning = maxRunning
+		h.jobs.maxDiskBytes = maxDiskBytes
+	}
+}
+
+func jobSpillPath(dir, id string) string {
+	return filepath.Join(dir, "job-"+id+".ndjson")
+}
+
+func cleanJobSpillDir(dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return 0, err
+	}
+	orphans, err := filepath.Glob(jobSpillPath(dir, "*"))
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, path := range orphans {
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// jobSpill is a job's results file. offsets holds where each result's line
+// starts, so a page is one contiguous read. After a write error no more
+// results are stored and reads fail with err.
+type jobSpill struct {
+	path    string
+	file    *os.File
+	writer  *bufio.Writer
+	offsets []int64
+	size    int64
+	err     error
+}
+
+func createJobSpill(dir, id string) (*jobSpill, error) {
+	path := jobSpillPath(dir, id)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &jobSpill{path: path, file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+// append writes result and returns how many bytes it took.
+func (s *jobSpill) append(result BatchItemResult) int64 {
+	if s.err != nil {
+		return 0
+	}
+	line, err := json.Marshal(result)
+	if err == nil {
+		line = append(line, '\n')
+		_, err = s.writer.Write(line)
+	}
+	if err != nil {
+		s.err = err
+		return 0
+	}
+	s.offsets = append(s.offsets, s.size)
+	s.size += int64(len(line))
+	return int64(len(line))
+}
+
+// read streams results from up to, but not including, to.
+func (s *jobSpill) read(from, to int) ([]BatchItemResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if err := s.writer.Flush(); err != nil {
+		s.err = err
+		return nil, err
+	}
+	end := s.size
+	if to < len(s.offsets) {
+		end = s.offsets[to]
+	}
+	decoder := json.NewDecoder(io.NewSectionReader(s.file, s.offsets[from], end-s.offsets[from]))
+	results := make([]BatchItemResult, 0, to-from)
+	for i := from; i < to; i++ {
+		var result BatchItemResult
+		if err := decoder.Decode(&result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *jobSpill) remove() {
+	s.file.Close()
+	if err := os.Remove(s.path); err != nil {
+		log.Printf("Failed to remove job results file: %v", err)
+	}
+}
+
+// appendResult stores the next result, on disk when the job spills, and
+// trims the in-memory window. Callers hold j.mu.
+func (j *job) appendResult(s *jobStore, result BatchItemResult) {
+	if j.spill != nil {
+		s.diskBytes.Add(j.spill.append(result))
+		if len(j.results) == s.memoryResults {
+			j.results = j.results[1:]
+			j.firstResult++
+		}
+	}
+	j.results = append(j.results, result)
+}
+
+// resultRange returns up to limit processed results from offset, from the
+// in-memory window when it still holds them and from disk otherwise.
+// Callers hold j.mu.
+func (j *job) resultRange(offset, limit int) ([]BatchItemResult, error) {
+	end := min(offset+limit, j.progress.Processed)
+	if offset >= end {
+		return []BatchItemResult{}, nil
+	}
+	if offset >= j.firstResult {
+		return j.results[offset-j.firstResult : end-j.firstResult], nil
+	}
+	if j.spill == nil {
+		return nil, errJobReleased
+	}
+	return j.spill.read(offset, end)
+}
+
+// release deletes the job's results file and gives back its disk usage.
+// Callers hold j.mu.
+func (j *job) release(s *jobStore) {
+	j.released = true
+	if j.spill == nil {
+		return
+	}
+	j.spill.remove()
+	s.diskBytes.Add(-j.spill.size)
+	j.spill = nil
+}
+
+// JobResultsPage is GET /v1/jobs/:id/results. NextOffset is set while
+// results past this page have been processed.
+type JobResultsPage struct {
+	Offset     int               `json:"offset"`
+	Processed  int               `json:"processed"`
+	NextOffset *int              `json:"nextOffset,omitempty"`
+	Results    []BatchItemResult `json:"results"`
+}
+
+// GetJobResults pages through the results processed so far, so complete
+// and running jobs alike can be read without loading every result.
+func (h *Handler) GetJobResults(c *gin.Context) {
+	offset, errorResponse := pageParameter(c, "offset", 0, -1)
+	if errorResponse == nil {
+		var limit int
+		limit, errorResponse = pageParameter(c, "limit", DefaultJobResultsPage, MaxJobResultsPage)
+		if errorResponse == nil {
+			h.writeJobResultsPage(c, offset, limit)
+			return
+		}
+	}
+	c.JSON(http.StatusBadRequest, h.echo(errorResponse))
+}
+
+func (h *Handler) writeJobResultsPage(c *gin.Context, offset, limit int) {
+	j, exists := h.jobs.get(c.Param("id"))
+	if !exists {
+		jobNotFound(c)
+		return
+	}
+
+	j.mu.Lock()
+	page := JobResultsPage{Offset: offset, Processed: j.progress.Processed}
+	results, err := j.resultRange(offset, limit)
+	j.mu.Unlock()
+	if err != nil {
+		h.jobResultsFailed(c, err)
+		return
+	}
+	page.Results = results
+	if next := offset + len(results); len(results) > 0 && next < page.Processed {
+		page.NextOffset = &next
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// pageParameter reads a non-negative integer query parameter of at most
+// max (unbounded when max is negative).
+func pageParameter(c *gin.Context, name string, fallback, max int) (int, *ErrorResponse) {
+	raw, sent := c.GetQuery(name)
+	if !sent {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	problem := ""
+	switch {
+	case err != nil || value < 0:
+		problem = "must be a non-negative integer"
+	case max >= 0 && value > max:
+		problem = "must be at most " + strconv.Itoa(max)
+	}
+	if problem != "" {
+		return 0, &ErrorResponse{
+			Code:     ErrorMalformedRequest,
+			Error:    map[string]string{name: problem},
+			Received: map[string][]string{name: {raw}},
+		}
+	}
+	return value, nil
+}
+
+// DeleteJob forgets a job and deletes its results; a running job stops
+// before its next item.
+func (h *Handler) DeleteJob(c *gin.Context) {
+	j, exists := h.jobs.remove(c.Param("id"))
+	if !exists {
+		jobNotFound(c)
+		return
+	}
+	j.mu.Lock()
+	j.release(h.jobs)
+	j.mu.Unlock()
+	if j.idempotencyKey != "" {
+		h.releaseIdempotencyKey(c.Request.Context(), j.idempotencyKey, j.progress.ID)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// jobResultsFailed answers a results read that failed: 404 when the job
+// was deleted meanwhile, 500 when its results file cannot be read.
+func (h *Handler) jobResultsFailed(c *gin.Context, err error) {
+	if errors.Is(err, errJobReleased) {
+		jobNotFound(c)
+		return
+	}
+	log.Printf("Failed to read job results: %v", err)
+	c.JSON(http.StatusInternalServerError, jobError(ErrorJobResultsUnavailable))
+}
+
+// jobError is the body of an error reported entirely from ErrorRegistry.
+func jobError(code ErrorCode) ErrorResponse {
+	mapping := ErrorRegistry[code]
+	return ErrorResponse{Code: code, Error: map[string]string{mapping.Field: mapping.Message}}
+}
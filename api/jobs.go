@@ -0,0 +1,418 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MaxJobSize = 10000
+
+	DefaultJobProgressItems    = 100
+	DefaultJobProgressInterval = time.Second
+	DefaultJobRetention        = time.Hour
+
+	JobStatusRunning  = "running"
+	JobStatusComplete = "complete"
+)
+
+// JobProgress is the state of an async batch job. Summary is set once the
+// job is complete.
+type JobProgress struct {
+	ID           string        `json:"id"`
+	Status       string        `json:"status"`
+	Total        int           `json:"total"`
+	Processed    int           `json:"processed"`
+	InvalidCount int           `json:"invalidCount"`
+	Summary      *BatchSummary `json:"summary,omitempty"`
+}
+
+// JobResponse is GET /v1/jobs/:id; Results is only filled in once the job
+// is complete. Spilled results are read back from disk for it, so large
+// jobs are better read with GET /v1/jobs/:id/results.
+type JobResponse struct {
+	JobProgress
+	Results []BatchItemResult `json:"results,omitempty"`
+}
+
+// jobEvent is one entry of a job's event log. Every SSE subscriber replays
+// the log from the start (or from Last-Event-ID), so late subscribers see
+// the same sequence as early ones.
+type jobEvent struct {
+	name     string
+	progress JobProgress
+}
+
+// job holds every result in results, or only the most recent ones from
+// index firstResult when it spills to disk.
+type job struct {
+	mu          sync.Mutex
+	progress    JobProgress
+	results     []BatchItemResult
+	firstResult int
+	spill       *jobSpill
+	released    bool
+	events      []jobEvent
+	changed     chan struct{}
+	finished    time.Time
+
+	// idempotencyKey is the scoped Idempotency-Key that created the job,
+	// or "".
+	idempotencyKey string
+}
+
+func (j *job) publish(name string) {
+	j.events = append(j.events, jobEvent{name: name, progress: j.progress})
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// eventsSince returns the events after index next and a channel closed on
+// the next publish.
+func (j *job) eventsSince(next int) ([]jobEvent, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if next > len(j.events) {
+		next = len(j.events)
+	}
+	return j.events[next:], j.changed
+}
+
+type jobStore struct {
+	progressItems     int
+	progressInterval  time.Duration
+	heartbeat         time.Duration
+	spillDir          string
+	memoryResults     int
+	maxRunning        int
+	maxDiskBytes      int64
+	diskBytes         atomic.Int64
+	idempotencyWindow time.Duration
+
+	mu          sync.Mutex
+	jobs        map[string]*job
+	idempotency map[string]idempotencyRecord
+}
+
+func newJobStore() *jobStore {
+	return &jobStore{
+		progressItems:    DefaultJobProgressItems,
+		progressInterval: DefaultJobProgressInterval,
+		heartbeat:        15 * time.Second,
+		memoryResults:    DefaultJobMemoryResults,
+		jobs:             map[string]*job{},
+		idempotency:      map[string]idempotencyRecord{},
+	}
+}
+
+// WithJobProgress sets how often job event streams report progress: every
+// items processed items or every interval, whichever comes first, with an
+// SSE heartbeat comment every heartbeat while nothing else is sent.
+func WithJobProgress(items int, interval, heartbeat time.Duration) HandlerOption {
+	return func(h *Handler) {
+		if items > 0 {
+			h.jobs.progressItems = items
+		}
+		if interval > 0 {
+			h.jobs.progressInterval = interval
+		}
+		if heartbeat > 0 {
+			h.jobs.heartbeat = heartbeat
+		}
+	}
+}
+
+// add expires old jobs and registers j, or returns the code it is
+// rejected with when the store's limits are reached.
+func (s *jobStore) add(j *job, now time.Time) ErrorCode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	running := 0
+	for id, existing := range s.jobs {
+		existing.mu.Lock()
+		if existing.finished.IsZero() {
+			running++
+		} else if now.Sub(existing.finished) > DefaultJobRetention {
+			existing.release(s)
+			delete(s.jobs, id)
+			s.forgetIdempotencyKey(existing.idempotencyKey, id)
+		}
+		existing.mu.Unlock()
+	}
+
+	if s.maxRunning > 0 && running >= s.maxRunning {
+		return ErrorTooManyJobs
+	}
+	if s.maxDiskBytes > 0 && s.diskBytes.Load() >= s.maxDiskBytes {
+		return ErrorJobStorageFull
+	}
+	if s.spillDir != "" {
+		spill, err := createJobSpill(s.spillDir, j.progress.ID)
+		if err != nil {
+			log.Printf("Failed to create job results file: %v", err)
+			return ErrorJobStorageFull
+		}
+		j.spill = spill
+	}
+	s.jobs[j.progress.ID] = j
+	return ""
+}
+
+// CheckHealth proves the store is not wedged: the jobs live in memory, so
+// being able to take the lock is all there is to check.
+func (s *jobStore) CheckHealth(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jobs == nil {
+		return errors.New("jobs store is not initialized")
+	}
+	return nil
+}
+
+func (s *jobStore) get(id string) (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, exists := s.jobs[id]
+	return j, exists
+}
+
+func (s *jobStore) remove(id string) (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, exists := s.jobs[id]
+	if exists {
+		delete(s.jobs, id)
+		s.forgetIdempotencyKey(j.idempotencyKey, id)
+	}
+	return j, exists
+}
+
+// CreateJob accepts a batch too large to answer synchronously and
+// validates it in the background. Items behave like batch items, except
+// that enum enrichment is not performed.
+func (h *Handler) CreateJob(c *gin.Context) {
+	var req BatchRequest
+	if errorResponse := bindJSONBody(c, &req); errorResponse != nil {
+		c.JSON(http.StatusBadRequest, errorResponse)
+		return
+	}
+	if problem := jobSizeProblem(len(req.Items)); problem != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"items": problem,
+			},
+		})
+		return
+	}
+
+	options, errorResponse := h.resolveRequestOptions(c)
+	if errorResponse != nil {
+		c.JSON(http.StatusBadRequest, errorResponse)
+		return
+	}
+
+	idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+	if problem := idempotencyKeyProblem(idempotencyKey); problem != "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: ErrorMalformedRequest, Error: map[string]string{"idempotencyKey": problem}})
+		return
+	}
+
+	progress, replay, rejected := h.submitJob(ginLookupScope(c), idempotencyKey, req.Items, options)
+	if rejected != "" {
+		c.JSON(ErrorRegistry[rejected].Status, jobError(rejected))
+		return
+	}
+	c.Header("Location", h.basePath+"/v1/jobs/"+progress.ID)
+	if replay {
+		c.Header(IdempotentReplayHeader, "true")
+		c.JSON(http.StatusOK, progress)
+		return
+	}
+	c.JSON(http.StatusAccepted, progress)
+}
+
+func jobSizeProblem(items int) string {
+	if items == 0 || items > MaxJobSize {
+		return "between 1 and " + strconv.Itoa(MaxJobSize) + " items are required"
+	}
+	return ""
+}
+
+func newJobID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// startJob registers job id for items and starts its worker, or returns
+// the code the job is rejected with.
+func (h *Handler) startJob(scope lookupScope, id, idempotencyKey string, items []BatchItem, options RequestOptions) (JobProgress, ErrorCode) {
+	options.Enum = false
+
+	j := &job{
+		progress:       JobProgress{ID: id, Status: JobStatusRunning, Total: len(items)},
+		changed:        make(chan struct{}),
+		idempotencyKey: idempotencyKey,
+	}
+	if rejected := h.jobs.add(j, h.now()); rejected != "" {
+		return JobProgress{}, rejected
+	}
+
+	// The worker outlives the request: its context is never cancelled, and
+	// the request ID is fixed now so nothing writes to the finished
+	// response.
+	requestID := scope.requestID()
+	scope.ctx = context.WithoutCancel(scope.ctx)
+	scope.requestID = func() string { return requestID }
+	progress := j.progress
+	go h.runJob(scope, j, items, options)
+	return progress, ""
+}
+
+func (h *Handler) runJob(scope lookupScope, j *job, items []BatchItem, options RequestOptions) {
+	lastPublished := time.Now()
+	summary := BatchSummary{Total: len(items), DuplicateIDs: duplicateIDs(items)}
+	for i, item := range items {
+		item.Enum = false
+		outcome := h.processLookup(scope, item.PhoneValidationRequest, options)
+		result := BatchItemResult{Index: i, ID: item.ID, Status: outcome.status, Result: outcome.response, Error: outcome.errorResponse}
+		if result.Status == http.StatusBadRequest {
+			result.Status = http.StatusUnprocessableEntity
+		}
+		if result.Error != nil {
+			result.Error.FieldPath = itemFieldPath(i, result.Error)
+			summary.addFailureReason(h.failureReason(outcome))
+		}
+
+		j.mu.Lock()
+		if j.released {
+			j.mu.Unlock()
+			return
+		}
+		j.appendResult(h.jobs, result)
+		j.progress.Processed++
+		if result.Error != nil {
+			j.progress.InvalidCount++
+		}
+		if j.progress.Processed%h.jobs.progressItems == 0 || time.Since(lastPublished) >= h.jobs.progressInterval {
+			j.publish("progress")
+			lastPublished = time.Now()
+		}
+		j.mu.Unlock()
+	}
+
+	j.mu.Lock()
+	if j.released {
+		j.mu.Unlock()
+		return
+	}
+	j.progress.Status = JobStatusComplete
+	summary.ValidCount = j.progress.Total - j.progress.InvalidCount
+	summary.FailedCount = j.progress.InvalidCount
+	j.progress.Summary = &summary
+	j.finished = h.now()
+	j.publish("complete")
+	j.mu.Unlock()
+	h.completeIdempotencyKey(scope.ctx, j)
+}
+
+func (h *Handler) GetJob(c *gin.Context) {
+	response, exists, err := h.jobResponse(c.Param("id"))
+	if err != nil {
+		h.jobResultsFailed(c, err)
+		return
+	}
+	if !exists {
+		jobNotFound(c)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) jobResponse(id string) (JobResponse, bool, error) {
+	j, exists := h.jobs.get(id)
+	if !exists {
+		return JobResponse{}, false, nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	response := JobResponse{JobProgress: j.progress}
+	if j.progress.Status == JobStatusComplete {
+		results, err := j.resultRange(0, j.progress.Processed)
+		if err != nil {
+			return JobResponse{}, true, err
+		}
+		response.Results = results
+	}
+	return response, true, nil
+}
+
+// JobEvents streams a job's progress as Server-Sent Events and closes the
+// stream after the complete event. Event IDs are positions in the job's
+// event log, so a reconnecting client resumes with Last-Event-ID.
+func (h *Handler) JobEvents(c *gin.Context) {
+	j, exists := h.jobs.get(c.Param("id"))
+	if !exists {
+		jobNotFound(c)
+		return
+	}
+
+	next := 0
+	if lastID, err := strconv.Atoi(c.GetHeader("Last-Event-ID")); err == nil && lastID >= 0 {
+		next = lastID + 1
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.jobs.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		events, changed := j.eventsSince(next)
+		for _, event := range events {
+			data, _ := json.Marshal(event.progress)
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", next, event.name, data)
+			next++
+			if event.name == "complete" {
+				c.Writer.Flush()
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		select {
+		case <-changed:
+		case <-heartbeat.C:
+			c.Writer.WriteString(": heartbeat\n\n")
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+func jobNotFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": map[string]string{
+			"id": "job not found",
+		},
+	})
+}
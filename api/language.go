@@ -0,0 +1,108 @@
+package api
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultLanguage = "en"
+
+// CountryNames holds localized country display names by language. Missing
+// entries fall back to English.
+var CountryNames = map[string]map[string]string{
+	"en": {
+		"US": "United States", "CA": "Canada", "MX": "Mexico", "ES": "Spain", "PT": "Portugal",
+		"GB": "United Kingdom", "FR": "France", "DE": "Germany", "IT": "Italy", "BR": "Brazil",
+		"GP": "Guadeloupe", "GF": "French Guiana", "MQ": "Martinique", "RE": "Réunion",
+		"ZA": "South Africa", "NG": "Nigeria", "KR": "South Korea",
+	},
+	"es": {
+		"US": "Estados Unidos", "CA": "Canadá", "MX": "México", "ES": "España", "PT": "Portugal",
+		"GB": "Reino Unido", "FR": "Francia", "DE": "Alemania", "IT": "Italia", "BR": "Brasil",
+		"GP": "Guadalupe", "GF": "Guayana Francesa", "MQ": "Martinica", "RE": "Reunión",
+		"ZA": "Sudáfrica", "NG": "Nigeria", "KR": "Corea del Sur",
+	},
+	"pt": {
+		"US": "Estados Unidos", "CA": "Canadá", "MX": "México", "ES": "Espanha", "PT": "Portugal",
+		"GB": "Reino Unido", "FR": "França", "DE": "Alemanha", "IT": "Itália", "BR": "Brasil",
+		"GP": "Guadalupe", "GF": "Guiana Francesa", "MQ": "Martinica", "RE": "Reunião",
+		"ZA": "África do Sul", "NG": "Nigéria", "KR": "Coreia do Sul",
+	},
+	"fr": {
+		"US": "États-Unis", "CA": "Canada", "MX": "Mexique", "ES": "Espagne", "PT": "Portugal",
+		"GB": "Royaume-Uni", "FR": "France", "DE": "Allemagne", "IT": "Italie", "BR": "Brésil",
+		"GP": "Guadeloupe", "GF": "Guyane", "MQ": "Martinique", "RE": "La Réunion",
+		"ZA": "Afrique du Sud", "NG": "Nigeria", "KR": "Corée du Sud",
+	},
+	"de": {
+		"US": "Vereinigte Staaten", "CA": "Kanada", "MX": "Mexiko", "ES": "Spanien", "PT": "Portugal",
+		"GB": "Vereinigtes Königreich", "FR": "Frankreich", "DE": "Deutschland", "IT": "Italien", "BR": "Brasilien",
+		"GP": "Guadeloupe", "GF": "Französisch-Guayana", "MQ": "Martinique", "RE": "Réunion",
+		"ZA": "Südafrika", "NG": "Nigeria", "KR": "Südkorea",
+	},
+}
+
+// CountryName returns the display name of a country in language, falling
+// back to English.
+func CountryName(countryCode, language string) string {
+	if name, exists := CountryNames[language][countryCode]; exists {
+		return name
+	}
+	return CountryNames[DefaultLanguage][countryCode]
+}
+
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n", "ß", "ss",
+)
+
+// foldName lowercases name and strips the accents used in the localized
+// country names, so "etats" matches "États-Unis".
+func foldName(name string) string {
+	return accentFolder.Replace(strings.ToLower(name))
+}
+
+// NegotiateLanguage picks the supported language with the highest quality
+// from an Accept-Language header such as "es-MX,es;q=0.9,en;q=0.5". Region
+// subtags are ignored and anything unsupported yields DefaultLanguage.
+func NegotiateLanguage(header string) string {
+	type candidate struct {
+		language string
+		quality  float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, supported := CountryNames[language]; !supported {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{language: language, quality: quality})
+	}
+
+	if len(candidates) == 0 {
+		return DefaultLanguage
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+	return candidates[0].language
+}
@@ -0,0 +1,62 @@
+package api
+
+import "testing"
+
+func TestNegotiateLanguage(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"es-MX,es;q=0.9,en;q=0.5", "es"},
+		{"ja,pt-BR;q=0.8,fr;q=0.7", "pt"},
+		{"en;q=0.3, de;q=0.9", "de"},
+		{"fr;q=0, es;q=0.1", "es"},
+		{"ja, zh-CN", "en"},
+		{"*", "en"},
+		{"FR-ca", "fr"},
+		{"de;q=abc, es;q=0.5", "es"},
+	}
+
+	for _, tt := range tests {
+		if got := NegotiateLanguage(tt.header); got != tt.expected {
+			t.Errorf("NegotiateLanguage(%q): expected %s, got %s", tt.header, tt.expected, got)
+		}
+	}
+}
+
+func TestCountryName(t *testing.T) {
+	for countryCode := range CountryPhoneLengths {
+		if _, exists := CountryNames[DefaultLanguage][countryCode]; !exists {
+			t.Errorf("No English name for %s", countryCode)
+		}
+	}
+
+	if got := CountryName("ES", "es"); got != "España" {
+		t.Errorf("Expected España, got %s", got)
+	}
+	if got := CountryName("ES", "ja"); got != "Spain" {
+		t.Errorf("Expected English fallback, got %s", got)
+	}
+}
+
+func TestFoldName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"États-Unis", "etats-unis"},
+		{"Vereinigtes Königreich", "vereinigtes konigreich"},
+		{"Corée du Sud", "coree du sud"},
+		{"Südafrika", "sudafrika"},
+		{"España", "espana"},
+		{"ÁFRICA DO SUL", "africa do sul"},
+	}
+
+	for _, tt := range tests {
+		if got := foldName(tt.name); got != tt.expected {
+			t.Errorf("foldName(%q): expected %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the histogram buckets; a final
+// overflow bucket catches anything slower.
+var latencyBuckets = [...]time.Duration{
+	10 * time.Microsecond, 25 * time.Microsecond, 50 * time.Microsecond,
+	100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 2500 * time.Microsecond, 5 * time.Millisecond,
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// LatencyHistogram is a fixed-bucket histogram whose counters are updated
+// atomically, so recording never takes a lock.
+type LatencyHistogram struct {
+	counts [len(latencyBuckets) + 1]atomic.Uint64
+	total  atomic.Uint64
+}
+
+func (h *LatencyHistogram) Observe(d time.Duration) {
+	i := sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })
+	h.counts[i].Add(1)
+	h.total.Add(1)
+}
+
+func (h *LatencyHistogram) Count() uint64 {
+	return h.total.Load()
+}
+
+// Percentile estimates the q-th quantile (0 < q <= 1) by interpolating
+// linearly inside the bucket holding that rank. Observations in the
+// overflow bucket are reported as the largest bound.
+func (h *LatencyHistogram) Percentile(q float64) time.Duration {
+	var counts [len(latencyBuckets) + 1]uint64
+	var total uint64
+	for i := range counts {
+		counts[i] = h.counts[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	var cumulative uint64
+	for i, count := range counts {
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+		if i == len(latencyBuckets) {
+			break
+		}
+		lower := time.Duration(0)
+		if i > 0 {
+			lower = latencyBuckets[i-1]
+		}
+		fraction := (rank - float64(cumulative)) / float64(count)
+		return lower + time.Duration(fraction*float64(latencyBuckets[i]-lower))
+	}
+	return latencyBuckets[len(latencyBuckets)-1]
+}
+
+type LatencySummary struct {
+	Count uint64  `json:"count"`
+	P50Ms float64 `json:"p50Ms"`
+	P90Ms float64 `json:"p90Ms"`
+	P99Ms float64 `json:"p99Ms"`
+}
+
+func (h *LatencyHistogram) Summary() LatencySummary {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return LatencySummary{
+		Count: h.Count(),
+		P50Ms: ms(h.Percentile(0.50)),
+		P90Ms: ms(h.Percentile(0.90)),
+		P99Ms: ms(h.Percentile(0.99)),
+	}
+}
+
+// LatencyStats keeps one histogram per route and one per resolved country.
+// Histograms are created on first use and then shared lock-free.
+type LatencyStats struct {
+	routes    sync.Map
+	countries sync.Map
+}
+
+func NewLatencyStats() *LatencyStats {
+	return &LatencyStats{}
+}
+
+func histogramFor(m *sync.Map, key string) *LatencyHistogram {
+	if h, ok := m.Load(key); ok {
+		return h.(*LatencyHistogram)
+	}
+	h, _ := m.LoadOrStore(key, &LatencyHistogram{})
+	return h.(*LatencyHistogram)
+}
+
+func (s *LatencyStats) ObserveRoute(route string, d time.Duration) {
+	histogramFor(&s.routes, route).Observe(d)
+}
+
+func (s *LatencyStats) ObserveCountry(countryCode string, d time.Duration) {
+	histogramFor(&s.countries, countryCode).Observe(d)
+}
+
+type LatencyReport struct {
+	Routes    map[string]LatencySummary `json:"routes"`
+	Countries map[string]LatencySummary `json:"countries"`
+}
+
+func (s *LatencyStats) Report() LatencyReport {
+	report := LatencyReport{
+		Routes:    map[string]LatencySummary{},
+		Countries: map[string]LatencySummary{},
+	}
+	s.routes.Range(func(key, value any) bool {
+		report.Routes[key.(string)] = value.(*LatencyHistogram).Summary()
+		return true
+	})
+	s.countries.Range(func(key, value any) bool {
+		report.Countries[key.(string)] = value.(*LatencyHistogram).Summary()
+		return true
+	})
+	return report
+}
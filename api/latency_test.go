@@ -0,0 +1,69 @@
+package api
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram_Percentiles(t *testing.T) {
+	var histogram LatencyHistogram
+	if got := histogram.Percentile(0.5); got != 0 {
+		t.Errorf("Expected 0 for an empty histogram, got %v", got)
+	}
+
+	// 1000 observations spread uniformly over 0-100ms.
+	for i := 1; i <= 1000; i++ {
+		histogram.Observe(time.Duration(i) * 100 * time.Microsecond)
+	}
+	if histogram.Count() != 1000 {
+		t.Fatalf("Expected 1000 observations, got %d", histogram.Count())
+	}
+
+	tests := []struct {
+		q        float64
+		expected time.Duration
+	}{
+		{0.50, 50 * time.Millisecond},
+		{0.90, 90 * time.Millisecond},
+		{0.99, 99 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got := histogram.Percentile(tt.q)
+		if math.Abs(float64(got-tt.expected)) > float64(time.Millisecond) {
+			t.Errorf("p%.0f: expected about %v, got %v", tt.q*100, tt.expected, got)
+		}
+	}
+
+	histogram.Observe(time.Minute)
+	if got := histogram.Percentile(1); got != 10*time.Second {
+		t.Errorf("Expected overflow to report the largest bound, got %v", got)
+	}
+}
+
+func TestLatencyStats_Report(t *testing.T) {
+	stats := NewLatencyStats()
+	stats.ObserveRoute("/v1/phone-numbers", time.Millisecond)
+	stats.ObserveCountry("GB", 2*time.Millisecond)
+	stats.ObserveCountry("GB", 3*time.Millisecond)
+
+	report := stats.Report()
+	if report.Routes["/v1/phone-numbers"].Count != 1 {
+		t.Errorf("Expected one route observation, got %+v", report.Routes)
+	}
+	if report.Countries["GB"].Count != 2 {
+		t.Errorf("Expected two GB observations, got %+v", report.Countries)
+	}
+}
+
+func BenchmarkLatencyStats_ObserveParallel(b *testing.B) {
+	stats := NewLatencyStats()
+	countries := []string{"US", "GB", "DE", "ES"}
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			stats.ObserveCountry(countries[i%len(countries)], time.Duration(i%5000)*time.Microsecond)
+			i++
+		}
+	})
+}
@@ -0,0 +1,146 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMaintenanceMessage    = "service is under maintenance"
+	defaultMaintenanceRetryAfter = time.Minute
+)
+
+type MaintenanceState struct {
+	Enabled           bool   `json:"enabled"`
+	Message           string `json:"message,omitempty"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds,omitempty"`
+}
+
+// maintenanceMode tracks both operator maintenance and the shutdown drain.
+// Each stores an absolute deadline so Retry-After counts down instead of
+// repeating the configured value.
+type maintenanceMode struct {
+	mu         sync.RWMutex
+	state      MaintenanceState
+	retryAt    time.Time
+	drainUntil time.Time
+}
+
+func (m *maintenanceMode) get() MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+func (m *maintenanceMode) set(state MaintenanceState, now time.Time) {
+	if !state.Enabled {
+		state = MaintenanceState{}
+	} else if state.Message == "" {
+		state.Message = defaultMaintenanceMessage
+	}
+
+	retryAfter := time.Duration(state.RetryAfterSeconds) * time.Second
+	if retryAfter == 0 {
+		retryAfter = defaultMaintenanceRetryAfter
+	}
+
+	m.mu.Lock()
+	m.state = state
+	m.retryAt = now.Add(retryAfter)
+	m.mu.Unlock()
+}
+
+func (m *maintenanceMode) drain(deadline time.Time) {
+	m.mu.Lock()
+	m.drainUntil = deadline
+	m.mu.Unlock()
+}
+
+// unavailable reports why the service is refusing work and how long until
+// it expects to recover; draining takes precedence over maintenance.
+func (m *maintenanceMode) unavailable(now time.Time) (string, time.Duration, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.drainUntil.IsZero() {
+		return "draining", m.drainUntil.Sub(now), true
+	}
+	if m.state.Enabled {
+		return "maintenance", m.retryAt.Sub(now), true
+	}
+	return "", 0, false
+}
+
+// Drain makes /v1 and /readyz answer 503 until the process exits, advising
+// clients to retry once the shutdown deadline has passed.
+func (h *Handler) Drain(deadline time.Time) {
+	h.maintenance.drain(deadline)
+}
+
+func (h *Handler) maintenanceGuard(c *gin.Context) {
+	body, wait, unavailable := h.unavailability()
+	if !unavailable {
+		c.Next()
+		return
+	}
+	abortWithRetryAfter(c, http.StatusServiceUnavailable, wait, body)
+}
+
+// unavailability is the 503 body and wait while maintenance or a drain is
+// in effect.
+func (h *Handler) unavailability() (map[string]interface{}, time.Duration, bool) {
+	reason, wait, unavailable := h.maintenance.unavailable(h.now())
+	if !unavailable {
+		return nil, 0, false
+	}
+
+	message := "service is shutting down"
+	if reason == "maintenance" {
+		message = h.maintenance.get().Message
+	}
+	return map[string]interface{}{
+		"status":  reason,
+		"message": message,
+	}, wait, true
+}
+
+func (h *Handler) SetMaintenance(c *gin.Context) {
+	var state MaintenanceState
+	if err := c.ShouldBindJSON(&state); err != nil || state.RetryAfterSeconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"maintenance": "invalid request body",
+			},
+		})
+		return
+	}
+
+	h.maintenance.set(state, h.now())
+	c.JSON(http.StatusOK, h.maintenance.get())
+}
+
+func (h *Handler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "alive",
+	})
+}
+
+func (h *Handler) Readyz(c *gin.Context) {
+	if h.warmingUp(c) {
+		return
+	}
+	if reason, wait, unavailable := h.maintenance.unavailable(h.now()); unavailable {
+		abortWithRetryAfter(c, http.StatusServiceUnavailable, wait, gin.H{
+			"status": "not ready",
+			"reason": reason,
+		})
+		return
+	}
+
+	h.writeReadiness(c)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"errors"
+	"sync"
+)
+
+// CountryMetadata is a candidate replacement for a country's entries in
+// CountryPhoneLengths and CountryLeadingDigits. An empty LeadingDigits keeps
+// the current leading-digit rule.
+type CountryMetadata struct {
+	CountryCode   string `json:"countryCode"`
+	MinLength     int    `json:"minLength"`
+	MaxLength     int    `json:"maxLength"`
+	LeadingDigits string `json:"leadingDigits,omitempty"`
+}
+
+func (m CountryMetadata) validate() error {
+	if _, exists := CountryPhoneLengths[m.CountryCode]; !exists {
+		return errors.New("unsupported country code")
+	}
+	if m.MinLength < 1 || m.MaxLength < m.MinLength || m.MaxLength > MaxE164Digits {
+		return errors.New("length range is invalid")
+	}
+	for i := 0; i < len(m.LeadingDigits); i++ {
+		if m.LeadingDigits[i] < '0' || m.LeadingDigits[i] > '9' {
+			return errors.New("leading digits must be digits")
+		}
+	}
+	return nil
+}
+
+// WithCountryMetadata overrides the package tables for the given countries
+// on this validator only. An override replaces the whole length range, so
+// per-type ranges from NumberTypeLengths no longer apply to that country.
+func WithCountryMetadata(entries ...CountryMetadata) ValidatorOption {
+	return func(v *PhoneNumberValidator) {
+		overrides := make(map[string]CountryMetadata, len(v.metadata)+len(entries))
+		for code, entry := range v.metadata {
+			overrides[code] = entry
+		}
+		for _, entry := range entries {
+			overrides[entry.CountryCode] = entry
+		}
+		v.metadata = overrides
+	}
+}
+
+// WithCandidateMetadata returns an ephemeral copy of the validator that
+// uses entry for its country. The copy shares the disabled-country toggle
+// and never changes v.
+func (v *PhoneNumberValidator) WithCandidateMetadata(entry CountryMetadata) (*PhoneNumberValidator, error) {
+	if err := entry.validate(); err != nil {
+		return nil, err
+	}
+	candidate := *v
+	WithCountryMetadata(entry)(&candidate)
+	return &candidate, nil
+}
+
+// MetadataSource is country metadata that can change at runtime, shared by
+// a validator and its copies like CountryToggle. It holds the entries of a
+// metadata file and whether loading the file failed, leaving validation on
+// the minimal table: dialing codes and E.164 length bounds.
+type MetadataSource struct {
+	mu       sync.RWMutex
+	entries  map[string]CountryMetadata
+	degraded bool
+}
+
+func NewMetadataSource() *MetadataSource {
+	return &MetadataSource{}
+}
+
+// Set replaces the entries and leaves degraded mode.
+func (s *MetadataSource) Set(entries []CountryMetadata) {
+	byCountry := make(map[string]CountryMetadata, len(entries))
+	for _, entry := range entries {
+		byCountry[entry.CountryCode] = entry
+	}
+
+	s.mu.Lock()
+	s.entries, s.degraded = byCountry, false
+	s.mu.Unlock()
+}
+
+// Degrade switches validation to the minimal table until the next Set.
+func (s *MetadataSource) Degrade() {
+	s.mu.Lock()
+	s.entries, s.degraded = nil, true
+	s.mu.Unlock()
+}
+
+func (s *MetadataSource) Degraded() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.degraded
+}
+
+func (s *MetadataSource) entry(countryCode string) (CountryMetadata, bool) {
+	if s == nil {
+		return CountryMetadata{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, exists := s.entries[countryCode]
+	return entry, exists
+}
+
+// WithMetadataSource makes the validator follow source. Entries set with
+// WithCountryMetadata, including candidates, take precedence over it.
+func WithMetadataSource(source *MetadataSource) ValidatorOption {
+	return func(v *PhoneNumberValidator) {
+		v.source = source
+	}
+}
+
+// MetadataDegraded reports whether validation runs on the minimal table.
+func (v *PhoneNumberValidator) MetadataDegraded() bool {
+	return v.source.Degraded()
+}
+
+// metadataEntry is the override for countryCode, if any.
+func (v *PhoneNumberValidator) metadataEntry(countryCode string) (CountryMetadata, bool) {
+	if entry, exists := v.metadata[countryCode]; exists {
+		return entry, true
+	}
+	return v.source.entry(countryCode)
+}
+
+func (v *PhoneNumberValidator) countryLengths(countryCode string) ([2]int, bool) {
+	if entry, exists := v.metadataEntry(countryCode); exists {
+		return [2]int{entry.MinLength, entry.MaxLength}, true
+	}
+	lengths, exists := CountryPhoneLengths[countryCode]
+	return lengths, exists
+}
+
+func (v *PhoneNumberValidator) countryLeadingDigits(countryCode string) (string, bool) {
+	if entry, exists := v.metadataEntry(countryCode); exists && entry.LeadingDigits != "" {
+		return entry.LeadingDigits, true
+	}
+	allowed, exists := CountryLeadingDigits[countryCode]
+	return allowed, exists
+}
@@ -0,0 +1,71 @@
+//go:build !js
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetadataFallbackMinimal is the METADATA_FALLBACK mode that keeps serving
+// on the minimal table when the metadata file cannot be loaded.
+const MetadataFallbackMinimal = "minimal"
+
+const ErrorMetadataDegraded ErrorCode = "METADATA_DEGRADED"
+
+// MetadataFile feeds a MetadataSource from a JSON list of CountryMetadata.
+// Reload swaps the entries atomically; a bad file keeps the previous
+// entries, or the minimal table while still degraded.
+type MetadataFile struct {
+	path   string
+	source *MetadataSource
+}
+
+// LoadMetadataFile loads path into a new source. With fallback
+// MetadataFallbackMinimal a file that fails to load degrades the source
+// instead, and the load error is returned along with the file.
+func LoadMetadataFile(path, fallback string) (*MetadataFile, error) {
+	if fallback != "" && fallback != MetadataFallbackMinimal {
+		return nil, fmt.Errorf("unknown metadata fallback %q", fallback)
+	}
+
+	file := &MetadataFile{path: path, source: NewMetadataSource()}
+	if err := file.Reload(); err != nil {
+		if fallback != MetadataFallbackMinimal {
+			return nil, err
+		}
+		file.source.Degrade()
+		return file, err
+	}
+	return file, nil
+}
+
+func (f *MetadataFile) Reload() error {
+	entries, err := LoadCountryMetadata(f.path)
+	if err != nil {
+		return err
+	}
+	f.source.Set(entries)
+	return nil
+}
+
+func (f *MetadataFile) Source() *MetadataSource {
+	return f.source
+}
+
+// requireRichMetadata answers 503 METADATA_DEGRADED on routes that need
+// number types, area codes or geocoding while validation is degraded.
+func (h *Handler) requireRichMetadata(c *gin.Context) {
+	if h.validator.MetadataDegraded() {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, ErrorResponse{
+			Code: ErrorMetadataDegraded,
+			Error: map[string]string{
+				"metadata": "unavailable while validation is degraded to the minimal table",
+			},
+		})
+		return
+	}
+	c.Next()
+}
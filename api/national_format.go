@@ -0,0 +1,116 @@
+package api
+
+import "strings"
+
+// NationalFormat is how numbers of a country starting with LeadingDigits
+// are written for people inside it. Each X in Pattern takes the next digit
+// of the national significant number; digits left over join the last
+// group, so one pattern covers a variable-length plan. Length, when set,
+// limits the rule to numbers of that many digits.
+type NationalFormat struct {
+	LeadingDigits string
+	Length        int
+	Pattern       string
+}
+
+// CountryNationalFormats lists each country's formats, matched in order.
+// The country's trunk prefix from CountryTrunkPrefixes is written before
+// the pattern. Countries without an entry, or numbers no rule matches, are
+// written as the NDC and local number separated by a space.
+var CountryNationalFormats = map[string][]NationalFormat{
+	"US": {{Pattern: "(XXX) XXX-XXXX"}},
+	"CA": {{Pattern: "(XXX) XXX-XXXX"}},
+	// Mexico City, Guadalajara and Monterrey have two-digit area codes,
+	// everywhere else three.
+	"MX": {
+		{LeadingDigits: "55", Pattern: "XX XXXX XXXX"},
+		{LeadingDigits: "33", Pattern: "XX XXXX XXXX"},
+		{LeadingDigits: "81", Pattern: "XX XXXX XXXX"},
+		{Pattern: "XXX XXX XXXX"},
+	},
+	// Spain dropped its trunk prefix in 1998; mobiles group after three
+	// digits, landlines after their two-digit province code.
+	"ES": {
+		{LeadingDigits: "6", Pattern: "XXX XX XX XX"},
+		{LeadingDigits: "7", Pattern: "XXX XX XX XX"},
+		{Pattern: "XX XXX XX XX"},
+	},
+	"PT": {{Pattern: "XXX XXX XXX"}},
+	"FR": {{Pattern: "X XX XX XX XX"}},
+	"GB": {
+		{LeadingDigits: "20", Pattern: "XX XXXX XXXX"},
+		{LeadingDigits: "11", Pattern: "XXX XXX XXXX"},
+		{LeadingDigits: "121", Pattern: "XXX XXX XXXX"},
+		{LeadingDigits: "131", Pattern: "XXX XXX XXXX"},
+		{LeadingDigits: "141", Pattern: "XXX XXX XXXX"},
+		{LeadingDigits: "151", Pattern: "XXX XXX XXXX"},
+		{LeadingDigits: "161", Pattern: "XXX XXX XXXX"},
+		{LeadingDigits: "800", Pattern: "XXX XXX XXXX"},
+		{Pattern: "XXXX XXXXXX"},
+	},
+	// Only the metropolitan areas have two-digit area codes.
+	"DE": {
+		{LeadingDigits: "30", Pattern: "XX XXXXXXXX"},
+		{LeadingDigits: "40", Pattern: "XX XXXXXXXX"},
+		{LeadingDigits: "69", Pattern: "XX XXXXXXXX"},
+		{LeadingDigits: "89", Pattern: "XX XXXXXXXX"},
+		{Pattern: "XXX XXXXXXX"},
+	},
+	"IT": {
+		{LeadingDigits: "3", Pattern: "XXX XXX XXXX"},
+		{LeadingDigits: "0", Pattern: "XX XXXX XXXX"},
+	},
+	"BR": {
+		{Length: 11, Pattern: "(XX) XXXXX-XXXX"},
+		{Length: 10, Pattern: "(XX) XXXX-XXXX"},
+	},
+	"ZA": {{Pattern: "XX XXX XXXX"}},
+	// Mobiles and toll-free numbers have ten digits; of the eight-digit
+	// landlines, Lagos and Abuja have one-digit area codes.
+	"NG": {
+		{Length: 10, Pattern: "XXX XXX XXXX"},
+		{LeadingDigits: "1", Pattern: "X XXX XXXX"},
+		{LeadingDigits: "9", Pattern: "X XXX XXXX"},
+		{Pattern: "XX XXX XXX"},
+	},
+	// Korean numbers are hyphenated, and the middle group has three or
+	// four digits depending on the length.
+	"KR": {
+		{LeadingDigits: "2", Length: 8, Pattern: "X-XXX-XXXX"},
+		{LeadingDigits: "2", Pattern: "X-XXXX-XXXX"},
+		{Length: 9, Pattern: "XX-XXX-XXXX"},
+		{Pattern: "XX-XXXX-XXXX"},
+	},
+}
+
+// formatNational writes a number the way it is dialed and written inside
+// its country, such as (212) 569-0123 or 020 7946 0958.
+func (v *PhoneNumberValidator) formatNational(countryCode, nationalNumber, areaCode, localNumber string) string {
+	for _, format := range CountryNationalFormats[countryCode] {
+		if !strings.HasPrefix(nationalNumber, format.LeadingDigits) || (format.Length != 0 && format.Length != len(nationalNumber)) {
+			continue
+		}
+		return CountryTrunkPrefixes[countryCode] + applyNationalPattern(format.Pattern, nationalNumber)
+	}
+	if areaCode == "" {
+		return localNumber
+	}
+	return areaCode + " " + localNumber
+}
+
+// applyNationalPattern fills the Xs of pattern with digits. A number
+// shorter than the pattern ends at its last digit.
+func applyNationalPattern(pattern, digits string) string {
+	var formatted strings.Builder
+	next := 0
+	for i := 0; i < len(pattern) && next < len(digits); i++ {
+		if pattern[i] != 'X' {
+			formatted.WriteByte(pattern[i])
+			continue
+		}
+		formatted.WriteByte(digits[next])
+		next++
+	}
+	formatted.WriteString(digits[next:])
+	return formatted.String()
+}
@@ -0,0 +1,68 @@
+package api
+
+import "testing"
+
+func TestPhoneNumberValidator_NationalFormat(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		phoneNumber string
+		want        string
+	}{
+		{"+12125690123", "(212) 569-0123"},
+		{"+14165550123", "(416) 555-0123"},
+		{"+525512345678", "55 1234 5678"},
+		{"+523312345678", "33 1234 5678"},
+		{"+522221234567", "222 123 4567"},
+		{"+34915872200", "91 587 22 00"},
+		{"+34612345678", "612 34 56 78"},
+		{"+442079460958", "020 7946 0958"},
+		{"+441134960000", "0113 496 0000"},
+		{"+447400123456", "07400 123456"},
+		{"+493012345678", "030 12345678"},
+		{"+4915123456789", "0151 23456789"},
+		{"+4921112345678", "0211 12345678"},
+		{"+390612345678", "06 1234 5678"},
+		{"+33142685300", "01 42 68 53 00"},
+		{"+33612345678", "06 12 34 56 78"},
+		{"+2348031234567", "0803 123 4567"},
+		{"+23412345678", "01 234 5678"},
+		{"+23464123456", "064 123 456"},
+		{"+5511912345678", "(11) 91234-5678"},
+		{"+821012345678", "010-1234-5678"},
+		{"+8221234567", "02-123-4567"},
+		{"+82212345678", "02-1234-5678"},
+		{"+82311234567", "031-123-4567"},
+		{"+823112345678", "031-1234-5678"},
+		{"+82801234567", "080-123-4567"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, "")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.NationalFormat != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, result.NationalFormat)
+			}
+		})
+	}
+}
+
+func TestApplyNationalPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		digits  string
+		want    string
+	}{
+		{"XX XXXX", "123456", "12 3456"},
+		{"XX XXXX", "12345678", "12 345678"},
+		{"(XX) XXXX", "123", "(12) 3"},
+		{"(XX) XXXX", "12", "(12"},
+	}
+	for _, tt := range tests {
+		if got := applyNationalPattern(tt.pattern, tt.digits); got != tt.want {
+			t.Errorf("applyNationalPattern(%q, %q) = %q, want %q", tt.pattern, tt.digits, got, tt.want)
+		}
+	}
+}
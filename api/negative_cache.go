@@ -0,0 +1,136 @@
+//go:build !js
+
+package api
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultNegativeCacheTTL is how long a failed validation is remembered
+// when WithNegativeCache is given no TTL.
+const DefaultNegativeCacheTTL = 30 * time.Second
+
+// NegativeCacheStats is the negativeCache entry of /v1/stats.
+type NegativeCacheStats struct {
+	Capacity int   `json:"capacity"`
+	Size     int   `json:"size"`
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+}
+
+type negativeCacheEntry struct {
+	key     resultCacheKey
+	err     error
+	expires time.Time
+}
+
+// negativeCache remembers validation failures for a short while so a
+// client retrying the same invalid number in a loop is not parsed again.
+// It is keyed like the result cache and stores the validator's error, from
+// which the error response is rebuilt as on a miss.
+type negativeCache struct {
+	capacity int
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[resultCacheKey]*list.Element
+	stats   NegativeCacheStats
+}
+
+// WithNegativeCache remembers up to capacity failed lookups for ttl (zero
+// keeps DefaultNegativeCacheTTL). It is off by default; a hit answers with
+// X-Cache: HIT-NEGATIVE. Only failures the same input always produces are
+// cached: a missing parameter, a disabled country or an internal error is
+// validated again every time.
+func WithNegativeCache(capacity int, ttl time.Duration) HandlerOption {
+	return func(h *Handler) {
+		if capacity <= 0 {
+			h.negativeCache = nil
+			return
+		}
+		if ttl <= 0 {
+			ttl = DefaultNegativeCacheTTL
+		}
+		h.negativeCache = &negativeCache{
+			capacity: capacity,
+			ttl:      ttl,
+			order:    list.New(),
+			entries:  map[resultCacheKey]*list.Element{},
+		}
+	}
+}
+
+// negativeCacheable reports whether err is a deterministic validation
+// failure rather than a missing parameter or a failure that depends on
+// runtime state.
+func negativeCacheable(err error) bool {
+	if errors.Is(err, errPhoneNumberRequired) || errors.Is(err, errCountryRequired) {
+		return false
+	}
+	switch errorCode(err) {
+	case ErrorCountryDisabled, ErrorInternal:
+		return false
+	}
+	return true
+}
+
+// get returns the cached failure for req unless it expired by now.
+func (c *negativeCache) get(req PhoneValidationRequest, now time.Time) (error, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exists := c.entries[cacheKey(req)]
+	if exists && now.After(element.Value.(*negativeCacheEntry).expires) {
+		c.order.Remove(element)
+		delete(c.entries, element.Value.(*negativeCacheEntry).key)
+		exists = false
+	}
+	if !exists {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*negativeCacheEntry).err, true
+}
+
+func (c *negativeCache) put(req PhoneValidationRequest, err error, now time.Time) {
+	if c == nil || !negativeCacheable(err) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(req)
+	entry := &negativeCacheEntry{key: key, err: err, expires: now.Add(c.ttl)}
+	if element, exists := c.entries[key]; exists {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*negativeCacheEntry).key)
+	}
+}
+
+func (c *negativeCache) report() *NegativeCacheStats {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Capacity, stats.Size = c.capacity, c.order.Len()
+	return &stats
+}
@@ -0,0 +1,35 @@
+//go:build !js
+
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNegativeCache_SkipsNonDeterministicFailures(t *testing.T) {
+	h := NewHandler(WithNegativeCache(2, time.Minute))
+	now := time.Now()
+	req := PhoneValidationRequest{PhoneNumber: "+33123456789"}
+
+	for _, err := range []error{
+		errCountryDisabled,
+		errCountryRequired,
+		errPhoneNumberRequired,
+		&SplitInvariantError{},
+	} {
+		h.negativeCache.put(req, err, now)
+		if _, hit := h.negativeCache.get(req, now); hit {
+			t.Errorf("Expected %q not to be cached", err)
+		}
+	}
+
+	lengthErr := &LengthError{CountryCode: "FR"}
+	h.negativeCache.put(req, lengthErr, now)
+	if err, hit := h.negativeCache.get(req, now); !hit || err != lengthErr {
+		t.Errorf("Expected the length error to be cached, got %v", err)
+	}
+	if _, hit := h.negativeCache.get(req, now.Add(time.Minute+time.Second)); hit {
+		t.Error("Expected the entry to expire after the TTL")
+	}
+}
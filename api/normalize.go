@@ -0,0 +1,186 @@
+package api
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	ErrorMisplacedPlus       ErrorCode = "MISPLACED_PLUS"
+	ErrorTrailingPunctuation ErrorCode = "TRAILING_PUNCTUATION"
+	ErrorLossyNumericFormat  ErrorCode = "LOSSY_NUMERIC_FORMAT"
+	ErrorInvalidCharacters   ErrorCode = "INVALID_CHARACTERS"
+)
+
+// ParseOptions adjusts how tolerant parsing is of messy input. The zero
+// value is the default behaviour.
+type ParseOptions struct {
+	Lenient       bool
+	Truncate      bool
+	StrictE164    bool
+	AreaCodeStyle string
+	// NumericSource is sourceType=numeric: lenient, plus restoring a
+	// leading digit lost to integer storage, see restoreLeadingDigit.
+	NumericSource bool
+}
+
+// Area code styles for ParseOptions.AreaCodeStyle; the empty string is
+// AreaCodeStyleBare.
+const (
+	AreaCodeStyleBare     = "bare"
+	AreaCodeStyleNational = "national"
+)
+
+// InputFormatError is a rejection of the raw input's shape, carrying the
+// error code returned to clients.
+type InputFormatError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *InputFormatError) Error() string {
+	return e.Message
+}
+
+const trailingPunctuation = ".,;:!?"
+
+var (
+	parenthesizedTrunkPrefix = regexp.MustCompile(`^(\+?)(\d{1,3}) ?\(0\) ?`)
+	scientificNotation       = regexp.MustCompile(`^([1-9])(?:\.(\d+))?[eE]\+?(\d{1,2})$`)
+	trailingDecimalZeros     = regexp.MustCompile(`^(\+?\d+)\.0+$`)
+
+	// formattingCharacters group digits the way numbers are usually
+	// written, as in "(212) 569-0123" or "212.569.0123".
+	formattingCharacters = strings.NewReplacer("-", "", ".", "", "(", "", ")", "")
+)
+
+var errInvalidCharacters = &InputFormatError{Code: ErrorInvalidCharacters, Message: "phone number contains invalid characters"}
+
+// recoverSpreadsheetNumber undoes the float formatting spreadsheets apply
+// to numeric cells: "2125690123.0" and "2.125690123E9" both become
+// "2125690123". It reports false when the input is not such a number and
+// an error when the mantissa has fewer digits than the exponent needs, i.e.
+// the spreadsheet already rounded the number away.
+func recoverSpreadsheetNumber(phoneNumber string) (string, bool, error) {
+	if match := trailingDecimalZeros.FindStringSubmatch(phoneNumber); match != nil {
+		return match[1], true, nil
+	}
+
+	match := scientificNotation.FindStringSubmatch(phoneNumber)
+	if match == nil {
+		return "", false, nil
+	}
+
+	fraction := match[2]
+	exponent := 0
+	for _, digit := range match[3] {
+		exponent = exponent*10 + int(digit-'0')
+	}
+
+	if len(fraction) < exponent {
+		return "", false, &InputFormatError{Code: ErrorLossyNumericFormat, Message: "numeric format has lost digits"}
+	}
+	if strings.Trim(fraction[exponent:], "0") != "" {
+		return "", false, &InputFormatError{Code: ErrorLossyNumericFormat, Message: "numeric format has lost digits"}
+	}
+
+	return match[1] + fraction[:exponent], true, nil
+}
+
+// normalizeInput applies the stray-character rules before spacing and
+// character validation:
+//   - in lenient mode, spreadsheet float formats are converted back to
+//     digits when that is lossless;
+//   - a single trailing punctuation mark is removed in lenient mode and
+//     rejected otherwise;
+//   - repeated leading plus signs are collapsed in lenient mode and treated
+//     as misplaced otherwise;
+//   - a plus anywhere but the first position is rejected;
+//   - "(0)" right after a supported dialing code is dropped as a trunk
+//     prefix, e.g. "+44 (0) 20 7946 0958".
+func normalizeInput(phoneNumber string, opts ParseOptions) (string, warningSet, error) {
+	var warnings warningSet
+
+	if opts.Lenient {
+		recovered, ok, err := recoverSpreadsheetNumber(phoneNumber)
+		if err != nil {
+			return "", nil, err
+		}
+		if ok {
+			phoneNumber = recovered
+			warnings.add(WarningExcelFormatRecovered, "")
+		}
+	}
+
+	if n := len(phoneNumber); n > 1 && strings.IndexByte(trailingPunctuation, phoneNumber[n-1]) >= 0 {
+		if !opts.Lenient {
+			return "", nil, &InputFormatError{Code: ErrorTrailingPunctuation, Message: "phone number ends with punctuation"}
+		}
+		phoneNumber = phoneNumber[:n-1]
+		warnings.add(WarningTrailingPunctuationRemoved, "")
+	}
+
+	if strings.HasPrefix(phoneNumber, "++") && opts.Lenient {
+		phoneNumber = "+" + strings.TrimLeft(phoneNumber, "+")
+		warnings.add(WarningDuplicatePlusCollapsed, "")
+	}
+
+	if strings.Contains(phoneNumber[1:], "+") {
+		return "", nil, &InputFormatError{Code: ErrorMisplacedPlus, Message: "plus sign is only allowed at the start"}
+	}
+
+	if match := parenthesizedTrunkPrefix.FindStringSubmatch(phoneNumber); match != nil {
+		if _, exists := DialingCodeToCountry[match[2]]; exists {
+			phoneNumber = match[1] + match[2] + " " + phoneNumber[len(match[0]):]
+			warnings.add(WarningTrunkPrefixDropped, "")
+		}
+	}
+
+	return phoneNumber, warnings, nil
+}
+
+// stripFormatting drops hyphens, dots and parentheses, keeping spaces so
+// the spacing check sees the digit groups. Parentheses must pair up
+// without nesting, and a hyphen or dot must separate two groups rather
+// than start, end or double up. A ".0" suffix is a spreadsheet float, not
+// a separator (lenient mode has already recovered it), and input with no
+// digits left is rejected like any other invalid characters.
+func stripFormatting(phoneNumber string) (string, error) {
+	if !strings.ContainsAny(phoneNumber, "-.()") {
+		return phoneNumber, nil
+	}
+	if trailingDecimalZeros.MatchString(phoneNumber) {
+		return "", errInvalidCharacters
+	}
+
+	depth := 0
+	for i := 0; i < len(phoneNumber); i++ {
+		switch phoneNumber[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '-', '.':
+			if i == 0 || i == len(phoneNumber)-1 || !groupsDigits(phoneNumber[i-1]) || !groupsDigits(phoneNumber[i+1]) {
+				return "", errInvalidCharacters
+			}
+		}
+		if depth < 0 || depth > 1 {
+			return "", errInvalidCharacters
+		}
+	}
+	if depth != 0 {
+		return "", errInvalidCharacters
+	}
+
+	stripped := formattingCharacters.Replace(phoneNumber)
+	if !strings.ContainsAny(stripped, "0123456789") {
+		return "", errInvalidCharacters
+	}
+	return stripped, nil
+}
+
+// groupsDigits reports whether c may stand next to a hyphen or dot.
+func groupsDigits(c byte) bool {
+	return c >= '0' && c <= '9' || c == '(' || c == ')' || c == ' '
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPhoneNumberValidator_StrayCharacters(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		lenient     bool
+		expected    string
+		warning     string
+		errorCode   ErrorCode
+	}{
+		{name: "Duplicate plus collapsed in lenient mode", phoneNumber: "++12125690123", lenient: true, expected: "+12125690123", warning: WarningDuplicatePlusCollapsed},
+		{name: "Duplicate plus rejected by default", phoneNumber: "++12125690123", errorCode: ErrorMisplacedPlus},
+		{name: "Plus inside number", phoneNumber: "1212+5690123", errorCode: ErrorMisplacedPlus},
+		{name: "Plus inside number in lenient mode", phoneNumber: "+1212+5690123", lenient: true, errorCode: ErrorMisplacedPlus},
+		{name: "Trailing period stripped in lenient mode", phoneNumber: "+12125690123.", lenient: true, expected: "+12125690123", warning: WarningTrailingPunctuationRemoved},
+		{name: "Trailing period rejected by default", phoneNumber: "+12125690123.", errorCode: ErrorTrailingPunctuation},
+		{name: "Only one trailing mark stripped", phoneNumber: "+12125690123..", lenient: true, errorCode: ""},
+		{name: "Parenthesized trunk prefix dropped", phoneNumber: "+1 (0) 212 5690123", expected: "+12125690123", warning: WarningTrunkPrefixDropped},
+		{name: "Parenthesized trunk prefix without spaces", phoneNumber: "+44(0)2079460958", expected: "+442079460958", warning: WarningTrunkPrefixDropped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumberWithOptions(tt.phoneNumber, "", ParseOptions{Lenient: tt.lenient})
+
+			if tt.expected == "" {
+				if err == nil {
+					t.Fatalf("Expected error, got %+v", result)
+				}
+				if tt.errorCode != "" {
+					inputErr, ok := err.(*InputFormatError)
+					if !ok || inputErr.Code != tt.errorCode {
+						t.Errorf("Expected error code %s, got %v", tt.errorCode, err)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.PhoneNumber != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result.PhoneNumber)
+			}
+			if len(result.Warnings) != 1 || result.Warnings[0] != tt.warning {
+				t.Errorf("Expected warning %s, got %v", tt.warning, result.Warnings)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_SpreadsheetRecovery(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		countryCode string
+		lenient     bool
+		expected    string
+		errorCode   ErrorCode
+	}{
+		{name: "Scientific notation", phoneNumber: "2.125690123E9", countryCode: "US", lenient: true, expected: "+12125690123"},
+		{name: "Scientific notation with trailing zeros", phoneNumber: "2.1256901230E9", countryCode: "US", lenient: true, expected: "+12125690123"},
+		{name: "Scientific notation with dialing code", phoneNumber: "1.2125690123E+10", lenient: true, expected: "+12125690123"},
+		{name: "Trailing decimal zero", phoneNumber: "34915872200.0", lenient: true, expected: "+34915872200"},
+		{name: "Rounded mantissa", phoneNumber: "2.12569E+9", countryCode: "US", lenient: true, errorCode: ErrorLossyNumericFormat},
+		{name: "Fractional value", phoneNumber: "2.1256901234E9", countryCode: "US", lenient: true, errorCode: ErrorLossyNumericFormat},
+		{name: "Strict mode unchanged", phoneNumber: "2.125690123E9", countryCode: "US"},
+		{name: "Strict mode trailing decimal unchanged", phoneNumber: "34915872200.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumberWithOptions(tt.phoneNumber, tt.countryCode, ParseOptions{Lenient: tt.lenient})
+
+			if tt.expected == "" {
+				if err == nil {
+					t.Fatalf("Expected error, got %+v", result)
+				}
+				inputErr, isInputErr := err.(*InputFormatError)
+				if tt.errorCode != "" && (!isInputErr || inputErr.Code != tt.errorCode) {
+					t.Errorf("Expected error code %s, got %v", tt.errorCode, err)
+				}
+				if tt.errorCode == "" && err.Error() != "phone number contains invalid characters" {
+					t.Errorf("Expected strict mode to reject invalid characters, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.PhoneNumber != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result.PhoneNumber)
+			}
+			if len(result.Warnings) != 1 || result.Warnings[0] != WarningExcelFormatRecovered {
+				t.Errorf("Expected %s warning, got %v", WarningExcelFormatRecovered, result.Warnings)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_FormattingCharacters(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		countryCode string
+		plain       string
+	}{
+		{name: "Parentheses and hyphen", phoneNumber: "+1 (212) 569-0123", plain: "+12125690123"},
+		{name: "National parentheses", phoneNumber: "(212) 569-0123", countryCode: "US", plain: "2125690123"},
+		{name: "Dots", phoneNumber: "212.569.0123", countryCode: "US", plain: "2125690123"},
+		{name: "Hyphens", phoneNumber: "212-569-0123", countryCode: "US", plain: "2125690123"},
+		{name: "Mixed punctuation", phoneNumber: "+1-212.569 0123", plain: "+12125690123"},
+		{name: "Parenthesized area code after dialing code", phoneNumber: "+44 (20) 7946-0958", plain: "+442079460958"},
+		{name: "Parenthesized trunk prefix", phoneNumber: "+44 (0) 20-7946-0958", plain: "+442079460958"},
+		{name: "Unclosed parenthesis", phoneNumber: "(212 569-0123", countryCode: "US"},
+		{name: "Unopened parenthesis", phoneNumber: "212) 569-0123", countryCode: "US"},
+		{name: "Nested parentheses", phoneNumber: "((212)) 569-0123", countryCode: "US"},
+		{name: "Doubled hyphen", phoneNumber: "212--569-0123", countryCode: "US"},
+		{name: "Leading dot", phoneNumber: ".212.569.0123", countryCode: "US"},
+		{name: "Letters with hyphens", phoneNumber: "212-ABC-0123", countryCode: "US"},
+		{name: "Hyphens only", phoneNumber: "---", countryCode: "US"},
+		{name: "Parentheses only", phoneNumber: "( )", countryCode: "US"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+
+			if tt.plain == "" {
+				if err == nil || err.Error() != "phone number contains invalid characters" {
+					t.Fatalf("Expected invalid characters, got %+v, %v", result, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expected, err := validator.ValidatePhoneNumber(tt.plain, tt.countryCode)
+			if err != nil {
+				t.Fatalf("Unexpected error for plain form: %v", err)
+			}
+			expected.Warnings, expected.WarningDetails = result.Warnings, result.WarningDetails
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("Expected %+v, got %+v", expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxEchoedPathLength = 256
+
+type RouteNotFoundResponse struct {
+	Code       ErrorCode         `json:"code"`
+	Path       string            `json:"path"`
+	DidYouMean string            `json:"didYouMean,omitempty"`
+	Error      map[string]string `json:"error"`
+}
+
+// registerNoRoute must run after every other route is registered so the
+// suggestions cover the full route table.
+func (h *Handler) registerNoRoute(router *gin.Engine) {
+	seen := map[string]bool{}
+	var paths []string
+	for _, route := range router.Routes() {
+		if !seen[route.Path] {
+			seen[route.Path] = true
+			paths = append(paths, route.Path)
+		}
+	}
+	sort.Strings(paths)
+
+	router.NoRoute(h.responseCase, func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, h.routeNotFound(c.Request.URL.Path, paths))
+	})
+}
+
+func (h *Handler) routeNotFound(path string, paths []string) RouteNotFoundResponse {
+	if len(path) > maxEchoedPathLength {
+		path = path[:maxEchoedPathLength]
+	}
+
+	// Unprefixed paths are not redirected: a 404 that names the mounted
+	// path makes a missing proxy prefix obvious without hiding it.
+	suggestion := suggestRoute(path, paths)
+	if h.basePath != "" && !strings.HasPrefix(path, h.basePath+"/") {
+		if mounted := suggestRoute(h.basePath+path, paths); mounted != "" {
+			suggestion = mounted
+		}
+	}
+
+	return RouteNotFoundResponse{
+		Code:       ErrorRouteNotFound,
+		Path:       path,
+		DidYouMean: suggestion,
+		Error: map[string]string{
+			"route": "no route matches the requested path",
+		},
+	}
+}
+
+// suggestRoute returns the closest registered path when it is within a
+// quarter of the path's length in edit distance (at least 2), else "".
+func suggestRoute(path string, paths []string) string {
+	best, bestDistance := "", -1
+	for _, candidate := range paths {
+		distance := levenshtein(path, candidate)
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	threshold := len(best) / 4
+	if threshold < 2 {
+		threshold = 2
+	}
+	if bestDistance < 0 || bestDistance > threshold {
+		return ""
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
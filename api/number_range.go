@@ -0,0 +1,268 @@
+//go:build !js
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ErrorRangeInvalid         ErrorCode = "RANGE_INVALID"
+	ErrorRangeCrossesBoundary ErrorCode = "RANGE_CROSSES_BOUNDARY"
+	ErrorRangeTooLarge        ErrorCode = "RANGE_TOO_LARGE"
+)
+
+// MaxRangeSize is the most numbers a range may cover, as many as a whole
+// NANP area code holds.
+const MaxRangeSize = 10_000_000
+
+// NumberRange is a block of consecutive numbers that shares one country,
+// NDC and number type rule. InvalidCount is how many members would still
+// fail a lookup, which only suspicious patterns can cause once the block's
+// endpoints validate; AllValid is InvalidCount == 0.
+type NumberRange struct {
+	Start        string     `json:"start"`
+	End          string     `json:"end"`
+	Size         int64      `json:"size"`
+	SharedPrefix string     `json:"sharedPrefix"`
+	CountryCode  string     `json:"countryCode"`
+	CountryName  string     `json:"countryName"`
+	NDC          string     `json:"ndc"`
+	AreaCode     string     `json:"areaCode"`
+	AreaCodeName string     `json:"areaCodeName"`
+	NumberType   NumberType `json:"numberType,omitempty"`
+	AllValid     bool       `json:"allValid"`
+	InvalidCount int64      `json:"invalidCount"`
+}
+
+// RangeError is a range that cannot be validated as one block. Field is
+// the parameter it is reported on: start, end or range.
+type RangeError struct {
+	Code    ErrorCode
+	Field   string
+	Message string
+}
+
+func (e *RangeError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// RangeEndpointError is an endpoint of a range that fails validation on
+// its own. PhoneNumber is the endpoint, with any wildcards filled in, and
+// Err the lookup error it fails with.
+type RangeEndpointError struct {
+	Field       string
+	PhoneNumber string
+	Err         error
+}
+
+func (e *RangeEndpointError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RangeEndpointError) Unwrap() error {
+	return e.Err
+}
+
+// rangeBounds reads the endpoints of a range. Without end, start must end
+// in X wildcards, so +349158722XX is +34915872200 to +34915872299.
+func rangeBounds(start, end string) (string, string, error) {
+	wildcards := len(start) - len(strings.TrimRight(start, "Xx"))
+	switch {
+	case end == "" && wildcards == 0:
+		return "", "", &RangeError{Code: ErrorRangeInvalid, Field: "end", Message: "is required unless start ends in X wildcards"}
+	case end != "" && wildcards > 0:
+		return "", "", &RangeError{Code: ErrorRangeInvalid, Field: "start", Message: "cannot end in X wildcards when end is given"}
+	case wildcards > 0:
+		prefix := start[:len(start)-wildcards]
+		start = prefix + strings.Repeat("0", wildcards)
+		end = prefix + strings.Repeat("9", wildcards)
+	}
+
+	if _, err := parseStrictE164(start); err != nil {
+		return "", "", &RangeEndpointError{Field: "start", PhoneNumber: start, Err: err}
+	}
+	if _, err := parseStrictE164(end); err != nil {
+		return "", "", &RangeEndpointError{Field: "end", PhoneNumber: end, Err: err}
+	}
+	if len(start) != len(end) {
+		return "", "", &RangeError{Code: ErrorRangeInvalid, Field: "range", Message: "start and end must have the same number of digits"}
+	}
+	// Equal lengths make the string order the numeric one.
+	if start > end {
+		return "", "", &RangeError{Code: ErrorRangeInvalid, Field: "range", Message: "end is before start"}
+	}
+	return start, end, nil
+}
+
+// ValidateRange checks a block of numbers from its endpoints alone: both
+// must validate, and the block must not cross a country, an NDC or a
+// number type rule, so every member splits and is checked the same way.
+// Nothing is iterated; only suspicious patterns are counted, as at most
+// twenty numbers of a length can be one.
+func (v *PhoneNumberValidator) ValidateRange(start, end string) (*NumberRange, error) {
+	start, end, err := rangeBounds(start, end)
+	if err != nil {
+		return nil, err
+	}
+	first, _ := strconv.ParseInt(start[1:], 10, 64)
+	last, _ := strconv.ParseInt(end[1:], 10, 64)
+	size := last - first + 1
+	if size > MaxRangeSize {
+		return nil, &RangeError{Code: ErrorRangeTooLarge, Field: "range", Message: fmt.Sprintf("covers %d numbers, more than the %d a range may have", size, MaxRangeSize)}
+	}
+
+	startResponse, err := v.validateStrictE164(start, ParseOptions{})
+	if err != nil {
+		return nil, &RangeEndpointError{Field: "start", PhoneNumber: start, Err: err}
+	}
+	endResponse, err := v.validateStrictE164(end, ParseOptions{})
+	if err != nil {
+		return nil, &RangeEndpointError{Field: "end", PhoneNumber: end, Err: err}
+	}
+
+	countryCode := startResponse.CountryCode
+	switch {
+	case endResponse.CountryCode != countryCode:
+		return nil, &RangeError{Code: ErrorRangeCrossesBoundary, Field: "range", Message: "starts in " + countryCode + " and ends in " + endResponse.CountryCode}
+	case endResponse.NDC != startResponse.NDC:
+		return nil, &RangeError{Code: ErrorRangeCrossesBoundary, Field: "range", Message: "starts in NDC " + startResponse.NDC + " and ends in NDC " + endResponse.NDC}
+	}
+
+	dialingCode := CountryDialingCodes[countryCode]
+	startNational, endNational := start[1+len(dialingCode):], end[1+len(dialingCode):]
+	shared := sharedPrefix(startNational, endNational)
+	if crossesNumberTypeRule(countryCode, startNational, endNational, shared) {
+		return nil, &RangeError{Code: ErrorRangeCrossesBoundary, Field: "range", Message: "spans more than one number type rule"}
+	}
+
+	invalid := int64(0)
+	if v.suspiciousPatternMode(ParseOptions{}) == SuspiciousPatternsReject {
+		invalid = suspiciousPatternsBetween(startNational, endNational)
+	}
+
+	numberRange := &NumberRange{
+		Start:        startResponse.PhoneNumber,
+		End:          endResponse.PhoneNumber,
+		Size:         size,
+		SharedPrefix: "+" + dialingCode + shared,
+		CountryCode:  countryCode,
+		CountryName:  startResponse.CountryName,
+		NDC:          startResponse.NDC,
+		AreaCode:     startResponse.AreaCode,
+		AreaCodeName: startResponse.AreaCodeName,
+		AllValid:     invalid == 0,
+		InvalidCount: invalid,
+	}
+	if numberType, _, typed, _ := v.lengthRange(startNational, countryCode); typed {
+		numberRange.NumberType = numberType
+	}
+	return numberRange, nil
+}
+
+func sharedPrefix(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:n]
+}
+
+// crossesNumberTypeRule reports whether members of the block between
+// start and end match different number type rules: the endpoints do, or a
+// rule more specific than the digits they share covers part of the block.
+func crossesNumberTypeRule(countryCode, start, end, shared string) bool {
+	startRule, _ := numberTypeRule(start, countryCode)
+	endRule, _ := numberTypeRule(end, countryCode)
+	if startRule != endRule {
+		return true
+	}
+	for _, rule := range NumberTypeLengths[countryCode] {
+		leading := rule.LeadingDigits
+		if len(leading) <= len(shared) || len(leading) > len(start) || !strings.HasPrefix(leading, shared) {
+			continue
+		}
+		padding := len(start) - len(leading)
+		if leading+strings.Repeat("0", padding) <= end && leading+strings.Repeat("9", padding) >= start {
+			return true
+		}
+	}
+	return false
+}
+
+// suspiciousPatternsBetween counts the national numbers from start to end,
+// both of the same length, that isSuspiciousPattern matches.
+func suspiciousPatternsBetween(start, end string) int64 {
+	seen := map[string]bool{}
+	for first := byte(0); first < 10; first++ {
+		identical, ascending := make([]byte, len(start)), make([]byte, len(start))
+		for i := range identical {
+			identical[i] = '0' + first
+			ascending[i] = '0' + (first+byte(i))%10
+		}
+		for _, candidate := range []string{string(identical), string(ascending)} {
+			if candidate >= start && candidate <= end {
+				seen[candidate] = true
+			}
+		}
+	}
+	return int64(len(seen))
+}
+
+// NumberRange answers GET /v1/phone-numbers/range. Endpoints must be
+// E.164; a leading space is read as a plus that was not percent-encoded.
+// An endpoint failing validation is reported as a lookup failure with
+// fieldPath start or end.
+func (h *Handler) NumberRange(c *gin.Context) {
+	start, end := c.Query("start"), c.Query("end")
+	if start == "" {
+		c.JSON(http.StatusBadRequest, h.echo(&ErrorResponse{
+			Error: map[string]string{"start": "required value is missing"},
+		}))
+		return
+	}
+	if strings.HasPrefix(start, " ") {
+		start = "+" + start[1:]
+	}
+	if strings.HasPrefix(end, " ") {
+		end = "+" + end[1:]
+	}
+
+	numberRange, err := h.validator.ValidateRange(start, end)
+	var rangeErr *RangeError
+	var endpointErr *RangeEndpointError
+	switch {
+	case errors.As(err, &rangeErr):
+		c.JSON(ErrorRegistry[rangeErr.Code].Status, h.echo(&ErrorResponse{
+			PhoneNumber: start,
+			Code:        rangeErr.Code,
+			Error:       map[string]string{rangeErr.Field: rangeErr.Message},
+		}))
+		return
+	case errors.As(err, &endpointErr):
+		status, errorResponse := h.validationFailure(endpointErr.PhoneNumber, endpointErr.Err)
+		errorResponse.FieldPath = endpointErr.Field
+		c.JSON(status, h.echo(errorResponse))
+		return
+	}
+
+	if key := apiKeyConfig(c); key != nil && !key.allowsCountry(numberRange.CountryCode) {
+		c.JSON(http.StatusForbidden, h.echo(&ErrorResponse{
+			PhoneNumber: start,
+			Code:        ErrorCountryNotAllowed,
+			Error:       map[string]string{"countryCode": "not allowed for this API key"},
+		}))
+		return
+	}
+
+	language := NegotiateLanguage(c.GetHeader("Accept-Language"))
+	numberRange.CountryName = CountryName(numberRange.CountryCode, language)
+	c.Header("Content-Language", language)
+	c.JSON(http.StatusOK, numberRange)
+}
@@ -0,0 +1,44 @@
+//go:build !js
+
+package api
+
+import "testing"
+
+func TestCrossesNumberTypeRule(t *testing.T) {
+	tests := []struct {
+		countryCode string
+		start       string
+		end         string
+		want        bool
+	}{
+		{"IT", "800000000", "800999999", false},
+		{"IT", "801000000", "809999999", false},
+		{"IT", "800500000", "809999999", true},
+		{"IT", "790000000", "809999999", true},
+		{"NG", "7000000000", "7099999999", false},
+	}
+	for _, tt := range tests {
+		shared := sharedPrefix(tt.start, tt.end)
+		if got := crossesNumberTypeRule(tt.countryCode, tt.start, tt.end, shared); got != tt.want {
+			t.Errorf("crossesNumberTypeRule(%s, %s, %s) = %v, want %v", tt.countryCode, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestSuspiciousPatternsBetween(t *testing.T) {
+	tests := []struct {
+		start string
+		end   string
+		want  int64
+	}{
+		{"2222222200", "2222222299", 1},
+		{"2125690100", "2125690199", 0},
+		{"0000000000", "9999999999", 20},
+		{"1234567890", "1234567890", 1},
+	}
+	for _, tt := range tests {
+		if got := suspiciousPatternsBetween(tt.start, tt.end); got != tt.want {
+			t.Errorf("suspiciousPatternsBetween(%s, %s) = %d, want %d", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import "strings"
+
+// Numeral systems for formatted output. NumeralsLatin is the default and
+// leaves digits as ASCII.
+const (
+	NumeralsLatin  = "latin"
+	NumeralsArabic = "arabic"
+)
+
+// numeralDigits holds the digits zero to nine of every numeral system
+// other than Latin; supporting another system is one more entry.
+var numeralDigits = map[string][10]rune{
+	NumeralsArabic: {'٠', '١', '٢', '٣', '٤', '٥', '٦', '٧', '٨', '٩'},
+}
+
+// IsNumeralSystem reports whether system, in any case, can be passed to
+// TransliterateDigits. The empty string is Latin.
+func IsNumeralSystem(system string) bool {
+	system = strings.ToLower(system)
+	if system == "" || system == NumeralsLatin {
+		return true
+	}
+	_, exists := numeralDigits[system]
+	return exists
+}
+
+// TransliterateDigits writes the ASCII digits of a formatted number in
+// system, keeping the plus sign, separators and anything else as they are.
+// It is meant for display fields only: E.164 and other canonical values
+// stay ASCII. Latin and unknown systems return formatted unchanged.
+func TransliterateDigits(formatted, system string) string {
+	digits, exists := numeralDigits[strings.ToLower(system)]
+	if !exists {
+		return formatted
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return digits[r-'0']
+		}
+		return r
+	}, formatted)
+}
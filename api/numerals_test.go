@@ -0,0 +1,35 @@
+package api
+
+import "testing"
+
+func TestTransliterateDigits(t *testing.T) {
+	tests := []struct {
+		formatted string
+		system    string
+		expected  string
+	}{
+		{"+44 20 7946 0958", NumeralsArabic, "+٤٤ ٢٠ ٧٩٤٦ ٠٩٥٨"},
+		{"(212) 569-0123", NumeralsArabic, "(٢١٢) ٥٦٩-٠١٢٣"},
+		{"+33 1 23 45 67 89", "Arabic", "+٣٣ ١ ٢٣ ٤٥ ٦٧ ٨٩"},
+		{"+44 20 7946 0958", NumeralsLatin, "+44 20 7946 0958"},
+		{"+44 20 7946 0958", "", "+44 20 7946 0958"},
+		{"+44 20 7946 0958", "roman", "+44 20 7946 0958"},
+	}
+
+	for _, tt := range tests {
+		if got := TransliterateDigits(tt.formatted, tt.system); got != tt.expected {
+			t.Errorf("TransliterateDigits(%q, %q): expected %s, got %s", tt.formatted, tt.system, tt.expected, got)
+		}
+	}
+}
+
+func TestIsNumeralSystem(t *testing.T) {
+	for _, system := range []string{"", "latin", "arabic", "ARABIC"} {
+		if !IsNumeralSystem(system) {
+			t.Errorf("IsNumeralSystem(%q): expected true", system)
+		}
+	}
+	if IsNumeralSystem("roman") {
+		t.Error(`IsNumeralSystem("roman"): expected false`)
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import "strings"
+
+// SourceTypeNumeric is the sourceType value for numbers that were stored
+// as integers upstream and may have lost their leading digit.
+const SourceTypeNumeric = "numeric"
+
+const ErrorPossibleIntegerTruncation ErrorCode = "POSSIBLE_INTEGER_TRUNCATION"
+
+// IntegerTruncationError is a national number one digit short, as if
+// stored as an integer, that no leading digit restores. Ambiguous is set
+// when several leading digits would.
+type IntegerTruncationError struct {
+	Ambiguous bool
+}
+
+func (e *IntegerTruncationError) Error() string {
+	if e.Ambiguous {
+		return "phone number may have lost one of several leading digits"
+	}
+	return "phone number may have lost a leading digit"
+}
+
+var (
+	errIntegerTruncation          = &IntegerTruncationError{}
+	errAmbiguousIntegerTruncation = &IntegerTruncationError{Ambiguous: true}
+)
+
+// restoreLeadingDigit undoes the digit loss of integer storage for a
+// national number with a provided country. It only applies when digits are
+// exactly one shorter than the shortest number as dialed nationally, i.e.
+// with the trunk prefix. Each of the trunk prefix and the country's
+// allowed leading digits is tried in front; exactly one valid result is
+// returned with WarningLeadingZeroRestored, anything else fails with
+// POSSIBLE_INTEGER_TRUNCATION. Other digits are returned unchanged.
+func (v *PhoneNumberValidator) restoreLeadingDigit(digits, countryCode string, warnings *warningSet) (string, error) {
+	countryCode = strings.ToUpper(countryCode)
+	lengths, exists := v.countryLengths(countryCode)
+	if !exists || strings.HasPrefix(digits, "+") {
+		return digits, nil
+	}
+	trunkPrefix := CountryTrunkPrefixes[countryCode]
+	if len(digits) != lengths[0]+len(trunkPrefix)-1 || (trunkPrefix != "" && strings.HasPrefix(digits, trunkPrefix)) {
+		return digits, nil
+	}
+
+	prefixes := []string{}
+	if trunkPrefix != "" {
+		prefixes = append(prefixes, trunkPrefix)
+	}
+	if leadingDigits, exists := v.countryLeadingDigits(countryCode); exists {
+		for _, digit := range leadingDigits {
+			prefixes = append(prefixes, string(digit))
+		}
+	}
+
+	var restored []string
+	for _, prefix := range prefixes {
+		if _, err := v.ValidatePhoneNumber(prefix+digits, countryCode); err == nil {
+			restored = append(restored, prefix)
+		}
+	}
+
+	switch len(restored) {
+	case 0:
+		return "", errIntegerTruncation
+	case 1:
+		warnings.add(WarningLeadingZeroRestored, restored[0])
+		return restored[0] + digits, nil
+	}
+	return "", errAmbiguousIntegerTruncation
+}
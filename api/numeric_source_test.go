@@ -0,0 +1,64 @@
+package api
+
+import "testing"
+
+func TestPhoneNumberValidator_NumericSource(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		countryCode string
+		opts        ParseOptions
+		expected    string
+		restored    string
+		wantErr     error
+	}{
+		{name: "FR trunk zero restored", phoneNumber: "142685300", countryCode: "FR", opts: ParseOptions{NumericSource: true}, expected: "+33142685300", restored: "0"},
+		{name: "GB without trunk zero is a full number", phoneNumber: "7911123456", countryCode: "GB", opts: ParseOptions{NumericSource: true}, expected: "+447911123456"},
+		{name: "GB landline without trunk zero is a full number", phoneNumber: "2079460958", countryCode: "GB", opts: ParseOptions{NumericSource: true}, expected: "+442079460958"},
+		{name: "GB with trunk prefix kept", phoneNumber: "07911123456", countryCode: "GB", opts: ParseOptions{NumericSource: true}, expected: "+447911123456"},
+		{name: "GB default unchanged", phoneNumber: "7911123456", countryCode: "GB", expected: "+447911123456"},
+		{name: "US ambiguous leading digit", phoneNumber: "125690123", countryCode: "US", opts: ParseOptions{NumericSource: true}, wantErr: errAmbiguousIntegerTruncation},
+		{name: "MX has nothing to restore", phoneNumber: "551234567", countryCode: "MX", opts: ParseOptions{NumericSource: true}, wantErr: errIntegerTruncation},
+		{name: "Full length US untouched", phoneNumber: "2125690123", countryCode: "US", opts: ParseOptions{NumericSource: true}, expected: "+12125690123"},
+		{name: "International number untouched", phoneNumber: "+447911123456", countryCode: "GB", opts: ParseOptions{NumericSource: true}, expected: "+447911123456"},
+		{name: "Spreadsheet format recovered first", phoneNumber: "1.42685301E8", countryCode: "FR", opts: ParseOptions{NumericSource: true}, expected: "+33142685301", restored: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumberWithOptions(tt.phoneNumber, tt.countryCode, tt.opts)
+			if tt.wantErr != nil {
+				if err != tt.wantErr {
+					t.Fatalf("Expected %v, got %v (%+v)", tt.wantErr, err, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.PhoneNumber != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result.PhoneNumber)
+			}
+			restored := ""
+			for _, warning := range result.WarningDetails {
+				if warning.Code == WarningLeadingZeroRestored {
+					restored = warning.Detail
+				}
+			}
+			if restored != tt.restored {
+				t.Errorf("Expected restored digit %q, got warnings %+v", tt.restored, result.WarningDetails)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_NumericSourceDefaultFails(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	_, err := validator.ValidatePhoneNumber("125690123", "US")
+	if _, ok := err.(*LengthError); !ok {
+		t.Errorf("Expected a length error without sourceType, got %v", err)
+	}
+}
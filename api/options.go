@@ -0,0 +1,119 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RouteParameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+}
+
+type RouteCapabilities struct {
+	Path       string           `json:"path"`
+	Methods    []string         `json:"methods"`
+	Parameters []RouteParameter `json:"parameters"`
+}
+
+var routeParameters = map[string][]RouteParameter{
+	"/v1/phone-numbers": {
+		{Name: "phoneNumber", In: "query", Required: true},
+		{Name: "countryCode", In: "query", Required: false},
+		{Name: "enum", In: "query", Required: false},
+		{Name: "lenient", In: "query", Required: false},
+		{Name: "truncate", In: "query", Required: false},
+		{Name: "fixPlus", In: "query", Required: false},
+		{Name: "inputFormat", In: "query", Required: false},
+		{Name: "strict", In: "query", Required: false},
+		{Name: "areaCodeStyle", In: "query", Required: false},
+		{Name: "callWindow", In: "query", Required: false},
+		{Name: "sourceType", In: "query", Required: false},
+		{Name: "porting", In: "query", Required: false},
+		{Name: "countryHints", In: "query", Required: false},
+		{Name: "case", In: "query", Required: false},
+		{Name: "options", In: "query", Required: false},
+		{Name: RequestOptionsHeader, In: "header", Required: false},
+	},
+	"/v1/phone-numbers/interpretations": {
+		{Name: "phoneNumber", In: "query", Required: true},
+	},
+	"/v1/phone-numbers/dialing-instructions": {
+		{Name: "phoneNumber", In: "query", Required: true},
+		{Name: "countryCode", In: "query", Required: false},
+		{Name: "fromCountry", In: "query", Required: true},
+	},
+	"/v1/phone-numbers/range": {
+		{Name: "start", In: "query", Required: true},
+		{Name: "end", In: "query", Required: false},
+	},
+	"/v1/phone-numbers/batch": {
+		{Name: "items", In: "body", Required: true},
+		{Name: "numberColumn", In: "query", Required: false},
+		{Name: "countryColumn", In: "query", Required: false},
+		{Name: "extensionColumn", In: "query", Required: false},
+		{Name: "idColumn", In: "query", Required: false},
+		{Name: "autoDetect", In: "query", Required: false},
+	},
+	"/v1/jobs": {
+		{Name: "items", In: "body", Required: true},
+	},
+	"/v1/jobs/:id/results": {
+		{Name: "offset", In: "query", Required: false},
+		{Name: "limit", In: "query", Required: false},
+	},
+	"/v1/countries": {
+		{Name: "dialingCode", In: "query", Required: false},
+		{Name: "q", In: "query", Required: false},
+		{Name: "capability", In: "query", Required: false},
+	},
+	"/v1/webhooks/test": {
+		{Name: "callbackUrl", In: "body", Required: true},
+	},
+}
+
+// registerOptionsRoutes must run after every other route is registered: the
+// Allow header for each path is computed from the engine's route table.
+func (h *Handler) registerOptionsRoutes(router *gin.Engine) {
+	methodsByPath := map[string][]string{}
+	for _, route := range router.Routes() {
+		if route.Method == http.MethodOptions {
+			continue
+		}
+		methodsByPath[route.Path] = append(methodsByPath[route.Path], route.Method)
+	}
+
+	for path, methods := range methodsByPath {
+		methods = append(methods, http.MethodOptions)
+		sort.Strings(methods)
+
+		capabilities := RouteCapabilities{
+			Path:       path,
+			Methods:    methods,
+			Parameters: routeParameters[strings.TrimPrefix(path, h.basePath)],
+		}
+		if capabilities.Parameters == nil {
+			capabilities.Parameters = []RouteParameter{}
+		}
+
+		router.OPTIONS(path, optionsResponder(capabilities))
+	}
+}
+
+func optionsResponder(capabilities RouteCapabilities) gin.HandlerFunc {
+	allow := strings.Join(capabilities.Methods, ", ")
+	return func(c *gin.Context) {
+		c.Header("Allow", allow)
+		if strings.Contains(c.GetHeader("Accept"), "application/json") {
+			c.JSON(http.StatusOK, capabilities)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
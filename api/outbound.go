@@ -0,0 +1,171 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultOutboundTimeout          = 5 * time.Second
+	DefaultOutboundMaxRedirects     = 3
+	DefaultOutboundMaxResponseBytes = 1 << 20
+
+	// ErrorOutboundBlocked is the code reported when a user-supplied URL is
+	// refused by the outbound policy.
+	ErrorOutboundBlocked ErrorCode = "OUTBOUND_URL_BLOCKED"
+)
+
+var ErrResponseTooLarge = errors.New("outbound response exceeds size limit")
+
+// OutboundBlockedError is a request the outbound policy refused before or
+// while connecting. It is reported to clients as ErrorOutboundBlocked.
+type OutboundBlockedError struct {
+	Target string
+	Reason string
+}
+
+func (e *OutboundBlockedError) Error() string {
+	return "outbound request to " + e.Target + " blocked: " + e.Reason
+}
+
+// OutboundPolicy configures OutboundClient. Zero fields take the defaults:
+// https only, no private destinations, three redirects, 1 MiB responses and
+// a five second timeout for the whole exchange.
+type OutboundPolicy struct {
+	AllowedSchemes   []string
+	AllowedNetworks  []netip.Prefix
+	MaxRedirects     int
+	MaxResponseBytes int64
+	Timeout          time.Duration
+}
+
+// OutboundClient is the one HTTP client for requests to user-supplied URLs.
+// Destinations are checked against the resolved IP at dial time, so a
+// hostname that resolves, or later rebinds, to an internal address is
+// refused unless its network is allow-listed.
+type OutboundClient struct {
+	policy OutboundPolicy
+	client *http.Client
+}
+
+func NewOutboundClient(policy OutboundPolicy) *OutboundClient {
+	if len(policy.AllowedSchemes) == 0 {
+		policy.AllowedSchemes = []string{"https"}
+	}
+	if policy.MaxRedirects <= 0 {
+		policy.MaxRedirects = DefaultOutboundMaxRedirects
+	}
+	if policy.MaxResponseBytes <= 0 {
+		policy.MaxResponseBytes = DefaultOutboundMaxResponseBytes
+	}
+	if policy.Timeout <= 0 {
+		policy.Timeout = DefaultOutboundTimeout
+	}
+
+	o := &OutboundClient{policy: policy}
+	dialer := &net.Dialer{Timeout: policy.Timeout, Control: o.checkDestination}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   policy.Timeout,
+		ResponseHeaderTimeout: policy.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	o.client = &http.Client{
+		Transport: transport,
+		Timeout:   policy.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > policy.MaxRedirects {
+				return &OutboundBlockedError{Target: req.URL.Redacted(), Reason: fmt.Sprintf("more than %d redirects", policy.MaxRedirects)}
+			}
+			return o.checkScheme(req)
+		},
+	}
+	return o
+}
+
+// Do sends req under the policy. The response body fails with
+// ErrResponseTooLarge once more than MaxResponseBytes have been read.
+func (o *OutboundClient) Do(req *http.Request) (*http.Response, error) {
+	if err := o.checkScheme(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &limitedBody{body: resp.Body, remaining: o.policy.MaxResponseBytes}
+	return resp, nil
+}
+
+func (o *OutboundClient) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return o.Do(req)
+}
+
+func (o *OutboundClient) checkScheme(req *http.Request) error {
+	for _, scheme := range o.policy.AllowedSchemes {
+		if req.URL.Scheme == scheme {
+			return nil
+		}
+	}
+	return &OutboundBlockedError{Target: req.URL.Redacted(), Reason: "scheme " + req.URL.Scheme + " is not allowed"}
+}
+
+// checkDestination runs as the dialer's Control hook, after resolution and
+// before the connection is made.
+func (o *OutboundClient) checkDestination(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return &OutboundBlockedError{Target: address, Reason: "address cannot be parsed"}
+	}
+	addr := addrPort.Addr().Unmap()
+
+	for _, prefix := range o.policy.AllowedNetworks {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return &OutboundBlockedError{Target: address, Reason: "destination is an internal address"}
+	}
+	return nil
+}
+
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), ErrResponseTooLarge
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
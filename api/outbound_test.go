@@ -0,0 +1,94 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestOutboundClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 2048)))
+		case "/redirect":
+			http.Redirect(w, r, "/redirect", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	loopback := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+	t.Run("Loopback Refused", func(t *testing.T) {
+		client := NewOutboundClient(OutboundPolicy{AllowedSchemes: []string{"http"}})
+		_, err := client.Get(context.Background(), server.URL)
+
+		var blocked *OutboundBlockedError
+		if !errors.As(err, &blocked) {
+			t.Fatalf("Expected OutboundBlockedError, got %v", err)
+		}
+		if !strings.Contains(blocked.Target, "127.0.0.1") {
+			t.Errorf("Expected target to name the resolved address, got %q", blocked.Target)
+		}
+	})
+
+	t.Run("Scheme Refused", func(t *testing.T) {
+		client := NewOutboundClient(OutboundPolicy{AllowedNetworks: loopback})
+		_, err := client.Get(context.Background(), server.URL)
+
+		var blocked *OutboundBlockedError
+		if !errors.As(err, &blocked) {
+			t.Fatalf("Expected OutboundBlockedError, got %v", err)
+		}
+	})
+
+	t.Run("Allow-List Override", func(t *testing.T) {
+		client := NewOutboundClient(OutboundPolicy{AllowedSchemes: []string{"http"}, AllowedNetworks: loopback})
+		resp, err := client.Get(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil || string(body) != "ok" {
+			t.Errorf("Expected body ok, got %q, %v", body, err)
+		}
+	})
+
+	t.Run("Response Size Cap", func(t *testing.T) {
+		client := NewOutboundClient(OutboundPolicy{AllowedSchemes: []string{"http"}, AllowedNetworks: loopback, MaxResponseBytes: 1024})
+		resp, err := client.Get(context.Background(), server.URL+"/large")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if !errors.Is(err, ErrResponseTooLarge) {
+			t.Errorf("Expected ErrResponseTooLarge, got %v", err)
+		}
+		if len(body) != 1024 {
+			t.Errorf("Expected 1024 bytes before the cap, got %d", len(body))
+		}
+	})
+
+	t.Run("Redirect Cap", func(t *testing.T) {
+		client := NewOutboundClient(OutboundPolicy{AllowedSchemes: []string{"http"}, AllowedNetworks: loopback, MaxRedirects: 2})
+		_, err := client.Get(context.Background(), server.URL+"/redirect")
+
+		var blocked *OutboundBlockedError
+		if !errors.As(err, &blocked) {
+			t.Fatalf("Expected OutboundBlockedError, got %v", err)
+		}
+	})
+}
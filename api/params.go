@@ -0,0 +1,75 @@
+//go:build !js
+
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var lookupParams = map[string]bool{
+	"phoneNumber": true,
+	"countryCode": true,
+}
+
+// ParseParamAliases parses "alias:canonical" pairs separated by commas, e.g.
+// "msisdn:phoneNumber,country:countryCode". Malformed pairs and aliases that
+// point at unknown parameters are dropped.
+func ParseParamAliases(spec string) map[string]string {
+	aliases := map[string]string{}
+	for _, pair := range strings.Split(spec, ",") {
+		alias, canonical, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		alias = strings.TrimSpace(alias)
+		canonical = strings.TrimSpace(canonical)
+		if !ok || alias == "" || alias == canonical || !lookupParams[canonical] || lookupParams[alias] {
+			continue
+		}
+		aliases[alias] = canonical
+	}
+	return aliases
+}
+
+func (h *Handler) applyParamAliases(c *gin.Context) {
+	if len(h.paramAliases) == 0 {
+		return
+	}
+
+	query := c.Request.URL.Query()
+	messages, rewritten := h.aliasParams(query)
+	for _, message := range messages {
+		h.markDeprecated(c, DeprecationParamAlias, message)
+	}
+	if rewritten {
+		c.Request.URL.RawQuery = query.Encode()
+	}
+}
+
+// aliasParams copies aliased parameters to their canonical names in query,
+// unless the canonical one was sent too, and returns a deprecation message
+// per alias used.
+func (h *Handler) aliasParams(query url.Values) ([]string, bool) {
+	aliases := make([]string, 0, len(h.paramAliases))
+	for alias := range h.paramAliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	var messages []string
+	rewritten := false
+	for _, alias := range aliases {
+		if !query.Has(alias) {
+			continue
+		}
+		canonical := h.paramAliases[alias]
+		if !query.Has(canonical) {
+			query[canonical] = query[alias]
+			rewritten = true
+		}
+		messages = append(messages, fmt.Sprintf("parameter %s is an alias, use %s", alias, canonical))
+	}
+	return messages, rewritten
+}
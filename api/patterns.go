@@ -0,0 +1,74 @@
+package api
+
+const (
+	ErrorMissingSubscriberNumber ErrorCode = "MISSING_SUBSCRIBER_NUMBER"
+	ErrorSuspiciousPattern       ErrorCode = "SUSPICIOUS_PATTERN"
+)
+
+// Modes for WithSuspiciousPatterns. The empty mode warns on lenient
+// lookups and ignores the pattern otherwise.
+const (
+	SuspiciousPatternsOff    = "off"
+	SuspiciousPatternsWarn   = "warn"
+	SuspiciousPatternsReject = "reject"
+)
+
+var (
+	errMissingSubscriberNumber = &InputFormatError{Code: ErrorMissingSubscriberNumber, Message: "phone number has no subscriber number"}
+	errSuspiciousPattern       = &InputFormatError{Code: ErrorSuspiciousPattern, Message: "phone number is a suspicious pattern"}
+)
+
+// WithSuspiciousPatterns sets how numbers whose significant digits are all
+// identical or a trivial ascending run are handled: SuspiciousPatternsOff,
+// SuspiciousPatternsWarn or SuspiciousPatternsReject. Unknown modes are
+// ignored.
+func WithSuspiciousPatterns(mode string) ValidatorOption {
+	return func(v *PhoneNumberValidator) {
+		switch mode {
+		case SuspiciousPatternsOff, SuspiciousPatternsWarn, SuspiciousPatternsReject:
+			v.suspiciousPatterns = mode
+		}
+	}
+}
+
+func (v *PhoneNumberValidator) suspiciousPatternMode(opts ParseOptions) string {
+	if v.suspiciousPatterns != "" {
+		return v.suspiciousPatterns
+	}
+	if opts.Lenient {
+		return SuspiciousPatternsWarn
+	}
+	return SuspiciousPatternsOff
+}
+
+// checkSuspiciousPattern rejects or warns about nationalNumber according
+// to the validator's mode.
+func (v *PhoneNumberValidator) checkSuspiciousPattern(nationalNumber string, opts ParseOptions, warnings *warningSet) error {
+	mode := v.suspiciousPatternMode(opts)
+	if mode == SuspiciousPatternsOff || !isSuspiciousPattern(nationalNumber) {
+		return nil
+	}
+	if mode == SuspiciousPatternsReject {
+		return errSuspiciousPattern
+	}
+	warnings.add(WarningSuspiciousPattern, "")
+	return nil
+}
+
+// isSuspiciousPattern reports whether digits are all the same (0000000000)
+// or each one more than the last, wrapping from 9 to 0 (1234567890).
+func isSuspiciousPattern(digits string) bool {
+	if len(digits) < 2 {
+		return false
+	}
+	identical, ascending := true, true
+	for i := 1; i < len(digits); i++ {
+		if digits[i] != digits[0] {
+			identical = false
+		}
+		if digits[i] != '0'+(digits[i-1]-'0'+1)%10 {
+			ascending = false
+		}
+	}
+	return identical || ascending
+}
@@ -0,0 +1,93 @@
+package api
+
+import "testing"
+
+func TestIsSuspiciousPattern(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   bool
+	}{
+		{"0000000000", true},
+		{"1111111111", true},
+		{"222222222", true},
+		{"1234567890", true},
+		{"2345678901", true},
+		{"3456789012", true},
+		{"9876543210", false},
+		{"2125690123", false},
+		{"2222222223", false},
+		{"7", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isSuspiciousPattern(tt.digits); got != tt.want {
+			t.Errorf("isSuspiciousPattern(%q) = %v, want %v", tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestPhoneNumberValidator_MissingSubscriberNumber(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	for _, tt := range []struct {
+		phoneNumber string
+		countryCode string
+		opts        ParseOptions
+	}{
+		{"+1", "", ParseOptions{}},
+		{"+34", "", ParseOptions{}},
+		{"+590", "", ParseOptions{}},
+		{"+44", "", ParseOptions{StrictE164: true}},
+		{"0", "ZA", ParseOptions{}},
+	} {
+		_, err := validator.ValidatePhoneNumberWithOptions(tt.phoneNumber, tt.countryCode, tt.opts)
+		if err != errMissingSubscriberNumber {
+			t.Errorf("%s: expected %s, got %v", tt.phoneNumber, ErrorMissingSubscriberNumber, err)
+		}
+	}
+}
+
+func TestPhoneNumberValidator_SuspiciousPatterns(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []ValidatorOption
+		parse       ParseOptions
+		wantErr     bool
+		wantWarning bool
+	}{
+		{"Default Strict Ignores", nil, ParseOptions{}, false, false},
+		{"Default Lenient Warns", nil, ParseOptions{Lenient: true}, false, true},
+		{"Off", []ValidatorOption{WithSuspiciousPatterns(SuspiciousPatternsOff)}, ParseOptions{Lenient: true}, false, false},
+		{"Warn", []ValidatorOption{WithSuspiciousPatterns(SuspiciousPatternsWarn)}, ParseOptions{}, false, true},
+		{"Reject", []ValidatorOption{WithSuspiciousPatterns(SuspiciousPatternsReject)}, ParseOptions{Lenient: true}, true, false},
+		{"Unknown Mode Ignored", []ValidatorOption{WithSuspiciousPatterns("loud")}, ParseOptions{}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := NewPhoneNumberValidator(tt.opts...)
+
+			for _, phoneNumber := range []string{"+12222222222", "+12345678901"} {
+				result, err := validator.ValidatePhoneNumberWithOptions(phoneNumber, "", tt.parse)
+				if tt.wantErr {
+					if err != errSuspiciousPattern {
+						t.Errorf("%s: expected %s, got %v", phoneNumber, ErrorSuspiciousPattern, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("%s: unexpected error: %v", phoneNumber, err)
+				}
+				warned := len(result.Warnings) == 1 && result.Warnings[0] == WarningSuspiciousPattern
+				if warned != tt.wantWarning {
+					t.Errorf("%s: expected warning %v, got %v", phoneNumber, tt.wantWarning, result.Warnings)
+				}
+			}
+
+			if _, err := validator.ValidatePhoneNumberWithOptions("+12125690123", "", tt.parse); err != nil {
+				t.Errorf("Expected an ordinary number to pass, got %v", err)
+			}
+		})
+	}
+}
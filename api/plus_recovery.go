@@ -0,0 +1,39 @@
+//go:build !js
+
+package api
+
+import "strings"
+
+// WithPlusRecovery turns on plus-sign recovery for every lookup, as if it
+// sent fixPlus=true. Requests can still opt out with fixPlus=false.
+func WithPlusRecovery() HandlerOption {
+	return func(h *Handler) {
+		h.fixPlus = true
+	}
+}
+
+// recoverPlus undoes the most common client bug: a "+" that was not
+// percent-encoded reaches us as a space. A leading space is only read as a
+// plus when the digits start with a known dialing code and the rest has a
+// valid length for that country, so national numbers with stray
+// whitespace are returned unchanged.
+func (h *Handler) recoverPlus(phoneNumber string) (string, bool) {
+	rest, ok := strings.CutPrefix(phoneNumber, " ")
+	if !ok || rest == "" || rest[0] < '0' || rest[0] > '9' {
+		return phoneNumber, false
+	}
+	digits := strings.ReplaceAll(rest, " ", "")
+	if strings.Trim(digits, "0123456789") != "" {
+		return phoneNumber, false
+	}
+
+	dialingCode, nationalNumber, err := h.validator.extractDialingCode(digits)
+	if err != nil {
+		return phoneNumber, false
+	}
+	country, exists := DialingCodeToCountry[dialingCode]
+	if !exists || !h.validator.lengthFits(nationalNumber, country) {
+		return phoneNumber, false
+	}
+	return "+" + rest, true
+}
@@ -0,0 +1,169 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PortingInfo is what a PortabilityResolver knows about a number. Carrier
+// is the carrier the number was ported to and is empty when Ported is
+// false.
+type PortingInfo struct {
+	Ported  bool
+	Carrier string
+}
+
+// PortabilityResolver looks up whether an E.164 number has been ported.
+// Numbers the resolver has no record of are not ported, not an error.
+type PortabilityResolver interface {
+	Resolve(ctx context.Context, e164 string) (PortingInfo, error)
+}
+
+// NoPortability is the default resolver: no number is ported.
+type NoPortability struct{}
+
+func (NoPortability) Resolve(context.Context, string) (PortingInfo, error) {
+	return PortingInfo{}, nil
+}
+
+// WithPortability sets the resolver consulted for porting=true; nil keeps
+// NoPortability.
+func WithPortability(resolver PortabilityResolver) HandlerOption {
+	return func(h *Handler) {
+		if resolver != nil {
+			h.portability = resolver
+		}
+	}
+}
+
+// attachPorting never fails the lookup: a resolver error leaves the
+// response unported with a warning.
+func (h *Handler) attachPorting(scope lookupScope, response *PhoneValidationResponse) {
+	info, err := h.portability.Resolve(scope.ctx, response.PhoneNumber)
+	if err != nil {
+		response.addWarning(WarningPortingLookupFailed, "")
+		info = PortingInfo{}
+	}
+	response.Ported = &info.Ported
+	if info.Ported {
+		response.PortedToCarrier = info.Carrier
+	}
+}
+
+type portedRange struct {
+	from, to string
+	carrier  string
+}
+
+// PortedRanges is the reference PortabilityResolver: a CSV file of
+// from,to,carrier rows, where from and to are E.164 numbers of the same
+// length bounding an inclusive range (equal for a single number). An
+// optional header row starting with "from" is skipped, and ranges must
+// not overlap. Reload swaps the ranges atomically and keeps the previous
+// ones if the file is invalid.
+type PortedRanges struct {
+	path string
+
+	mu     sync.RWMutex
+	ranges []portedRange
+}
+
+func LoadPortedRanges(path string) (*PortedRanges, error) {
+	ranges := &PortedRanges{path: path}
+	if err := ranges.Reload(); err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+func (p *PortedRanges) Reload() error {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ranges, err := readPortedRanges(file)
+	if err != nil {
+		return fmt.Errorf("invalid ported ranges file %s: %w", p.path, err)
+	}
+
+	p.mu.Lock()
+	p.ranges = ranges
+	p.mu.Unlock()
+	return nil
+}
+
+func readPortedRanges(r io.Reader) ([]portedRange, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var ranges []portedRange
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "from") {
+			continue
+		}
+
+		entry := portedRange{from: strings.TrimSpace(record[0]), to: strings.TrimSpace(record[1]), carrier: strings.TrimSpace(record[2])}
+		_, fromErr := parseStrictE164(entry.from)
+		_, toErr := parseStrictE164(entry.to)
+		if fromErr != nil || toErr != nil || len(entry.from) != len(entry.to) || entry.from > entry.to {
+			return nil, fmt.Errorf("line %d: %s-%s is not a range of E.164 numbers of one length", line, entry.from, entry.to)
+		}
+		if entry.carrier == "" {
+			return nil, fmt.Errorf("line %d: carrier is required", line)
+		}
+		ranges = append(ranges, entry)
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return portedBefore(ranges[i].from, ranges[j].from) })
+	for i := 1; i < len(ranges); i++ {
+		previous := ranges[i-1]
+		if len(previous.to) == len(ranges[i].from) && previous.to >= ranges[i].from {
+			return nil, fmt.Errorf("ranges %s-%s and %s-%s overlap", previous.from, previous.to, ranges[i].from, ranges[i].to)
+		}
+	}
+	return ranges, nil
+}
+
+// portedBefore orders numbers by length, then digits, so a range's
+// numbers are contiguous.
+func portedBefore(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+func (p *PortedRanges) Resolve(_ context.Context, e164 string) (PortingInfo, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	// The first range starting after e164; only its predecessor can hold it.
+	i := sort.Search(len(p.ranges), func(i int) bool { return portedBefore(e164, p.ranges[i].from) })
+	if i == 0 {
+		return PortingInfo{}, nil
+	}
+	candidate := p.ranges[i-1]
+	if len(candidate.to) == len(e164) && e164 <= candidate.to {
+		return PortingInfo{Ported: true, Carrier: candidate.carrier}, nil
+	}
+	return PortingInfo{}, nil
+}
@@ -0,0 +1,54 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReadPortedRanges(t *testing.T) {
+	ranges, err := readPortedRanges(strings.NewReader("+447911123456,+447911123456,Blue\n+12125690100,+12125690199,Acme\n+12125690200,+12125690299,Zeta\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resolver := &PortedRanges{ranges: ranges}
+
+	tests := []struct {
+		number  string
+		carrier string
+	}{
+		{"+12125690100", "Acme"},
+		{"+12125690199", "Acme"},
+		{"+12125690250", "Zeta"},
+		{"+12125690300", ""},
+		{"+12125690099", ""},
+		{"+447911123456", "Blue"},
+		{"+447911123457", ""},
+		{"+1212569010", ""},
+	}
+	for _, tt := range tests {
+		info, err := resolver.Resolve(context.Background(), tt.number)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.Ported != (tt.carrier != "") || info.Carrier != tt.carrier {
+			t.Errorf("%s: expected carrier %q, got %+v", tt.number, tt.carrier, info)
+		}
+	}
+}
+
+func TestReadPortedRangesRejectsInvalidFiles(t *testing.T) {
+	for _, data := range []string{
+		"+12125690100,+1212569019,Acme\n",
+		"+12125690199,+12125690100,Acme\n",
+		"2125690100,2125690199,Acme\n",
+		"+12125690100,+12125690199,\n",
+		"+12125690100,+12125690199,Acme\n+12125690150,+12125690250,Zeta\n",
+	} {
+		if _, err := readPortedRanges(strings.NewReader(data)); err == nil {
+			t.Errorf("Expected %q to be rejected", data)
+		}
+	}
+}
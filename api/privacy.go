@@ -0,0 +1,36 @@
+//go:build !js
+
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WithPrivacyMode keeps numbers out of everything that outlives a
+// request: recent lookups and failure sampling are switched off whatever
+// other options say, and request logs omit query strings.
+func WithPrivacyMode() HandlerOption {
+	return func(h *Handler) {
+		h.privacy = true
+	}
+}
+
+// privacyLogFormatter is gin's default log line without the query string.
+func privacyLogFormatter(param gin.LogFormatterParams) string {
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	path := param.Request.URL.Path
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		path,
+		param.ErrorMessage,
+	)
+}
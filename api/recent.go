@@ -0,0 +1,63 @@
+//go:build !js
+
+package api
+
+import "sync"
+
+// recentLookups is a fixed-size ring of the most recent lookup requests,
+// kept only so admins can replay real traffic through a metadata dry run.
+type recentLookups struct {
+	mu    sync.Mutex
+	items []PhoneValidationRequest
+	next  int
+	full  bool
+}
+
+// WithRecentLookups keeps the last size lookup requests in memory. It is
+// off by default because the buffer holds unmasked numbers.
+func WithRecentLookups(size int) HandlerOption {
+	return func(h *Handler) {
+		if size <= 0 {
+			h.recent = nil
+			return
+		}
+		h.recent = &recentLookups{items: make([]PhoneValidationRequest, size)}
+	}
+}
+
+func (r *recentLookups) add(req PhoneValidationRequest) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items[r.next] = PhoneValidationRequest{
+		PhoneNumber:   req.PhoneNumber,
+		CountryCode:   req.CountryCode,
+		Lenient:       req.Lenient,
+		Truncate:      req.Truncate,
+		InputFormat:   req.InputFormat,
+		Strict:        req.Strict,
+		AreaCodeStyle: req.AreaCodeStyle,
+		CallWindow:    req.CallWindow,
+		SourceType:    req.SourceType,
+	}
+	r.next = (r.next + 1) % len(r.items)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the buffered requests, oldest first.
+func (r *recentLookups) snapshot() []PhoneValidationRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]PhoneValidationRequest(nil), r.items[:r.next]...)
+	}
+	snapshot := make([]PhoneValidationRequest, 0, len(r.items))
+	snapshot = append(snapshot, r.items[r.next:]...)
+	return append(snapshot, r.items[:r.next]...)
+}
@@ -0,0 +1,132 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultRedisTimeout bounds what a rate limit check against Redis adds
+	// to a request when WithRedisRateLimit is given no timeout.
+	DefaultRedisTimeout = 50 * time.Millisecond
+
+	// RedisRateLimitPrefix starts the Redis key of every rate limit bucket.
+	RedisRateLimitPrefix = "phone-api:ratelimit:"
+)
+
+// tokenBucketScript takes a token from the bucket KEYS[1], which holds
+// ARGV[1] tokens and refills them evenly over a minute, at time ARGV[2] in
+// milliseconds. It returns 0 when a token was taken, else how many
+// milliseconds until one is available. Replicas pass their own clock; a
+// time before the bucket's last refill refills nothing.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local now = tonumber(ARGV[2])
+local interval = 60000 / capacity
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(bucket[1])
+local at = tonumber(bucket[2])
+if tokens == nil or at == nil then
+	tokens, at = capacity, now
+end
+if now > at then
+	tokens = math.min(capacity, tokens + (now - at) / interval)
+	at = now
+end
+if tokens < 1 then
+	return math.ceil((1 - tokens) * interval)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens - 1), 'at', tostring(at))
+redis.call('PEXPIRE', KEYS[1], 60000)
+return 0
+`)
+
+// RedisRateLimitStats is the redisRateLimit entry of /v1/stats. Errors
+// counts checks Redis did not answer in time, which were decided by the
+// replica's own limiter or, with FailClosed, refused.
+type RedisRateLimitStats struct {
+	FailClosed bool  `json:"failClosed"`
+	Checks     int64 `json:"checks"`
+	Errors     int64 `json:"errors"`
+}
+
+// redisLimit counts the global and per-key rate limits in Redis so every
+// replica behind a load balancer draws from the same buckets.
+type redisLimit struct {
+	client     redis.UniversalClient
+	timeout    time.Duration
+	failClosed bool
+
+	checks atomic.Int64
+	errors atomic.Int64
+}
+
+// WithRedisRateLimit counts the global rate limit and API key limits in
+// client as token buckets, so they hold across replicas. The in-process
+// limiters keep running as a backstop for each replica. A check Redis does
+// not answer within timeout (zero keeps DefaultRedisTimeout) falls back to
+// the in-process limiters, or with failClosed is refused with 503.
+func WithRedisRateLimit(client redis.UniversalClient, timeout time.Duration, failClosed bool) HandlerOption {
+	return func(h *Handler) {
+		if client == nil {
+			h.redisLimit = nil
+			return
+		}
+		if timeout <= 0 {
+			timeout = DefaultRedisTimeout
+		}
+		h.redisLimit = &redisLimit{client: client, timeout: timeout, failClosed: failClosed}
+	}
+}
+
+// allow applies a perMinute limit counted in Redis under name, then local,
+// the replica's own limiter for it. When Redis fails, local decides alone,
+// or with failClosed the request is refused with unavailable set.
+func (r *redisLimit) allow(ctx context.Context, name string, perMinute int, now time.Time, local func() (bool, time.Duration)) (allowed bool, retryAfter time.Duration, unavailable bool) {
+	if r == nil || perMinute <= 0 {
+		allowed, retryAfter = local()
+		return allowed, retryAfter, false
+	}
+
+	r.checks.Add(1)
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	wait, err := tokenBucketScript.Run(ctx, r.client, []string{RedisRateLimitPrefix + name}, perMinute, now.UnixMilli()).Int64()
+	if err != nil {
+		r.errors.Add(1)
+		if r.failClosed {
+			return false, time.Second, true
+		}
+		allowed, retryAfter = local()
+		return allowed, retryAfter, false
+	}
+	if wait > 0 {
+		return false, time.Duration(wait) * time.Millisecond, false
+	}
+	allowed, retryAfter = local()
+	return allowed, retryAfter, false
+}
+
+func (r *redisLimit) report() *RedisRateLimitStats {
+	if r == nil {
+		return nil
+	}
+	return &RedisRateLimitStats{
+		FailClosed: r.failClosed,
+		Checks:     r.checks.Load(),
+		Errors:     r.errors.Load(),
+	}
+}
+
+func rateLimitUnavailable() map[string]interface{} {
+	return map[string]interface{}{
+		"error": map[string]string{
+			"rateLimit": "rate limiter is unavailable",
+		},
+	}
+}
@@ -0,0 +1,134 @@
+//go:build !js
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RequestOptionsHeader = "X-Phone-Api-Options"
+
+// requestOptionsKey is where resolveRequestOptions stores the request's
+// RequestOptions.
+const requestOptionsKey = "requestOptions"
+
+// RequestOptions are the per-request toggles, set as a token list in the
+// options query parameter or the X-Phone-Api-Options header. Strict makes
+// unknown tokens an error instead of a warning.
+type RequestOptions struct {
+	Lenient  bool
+	Enum     bool
+	Truncate bool
+	FixPlus  bool
+	Strict   bool
+}
+
+var requestOptionTokens = map[string]func(*RequestOptions){
+	"lenient":  func(o *RequestOptions) { o.Lenient = true },
+	"enum":     func(o *RequestOptions) { o.Enum = true },
+	"truncate": func(o *RequestOptions) { o.Truncate = true },
+	"fixplus":  func(o *RequestOptions) { o.FixPlus = true },
+	"strict":   func(o *RequestOptions) { o.Strict = true },
+}
+
+// RequestOptionTokens lists the known option tokens in sorted order.
+func RequestOptionTokens() []string {
+	tokens := make([]string, 0, len(requestOptionTokens))
+	for token := range requestOptionTokens {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// ParseRequestOptions parses a comma-separated, case-insensitive token
+// list such as "lenient, enum". Empty entries are skipped and unknown
+// tokens are returned in the order given.
+func ParseRequestOptions(list string) (RequestOptions, []string) {
+	var options RequestOptions
+	var unknown []string
+	for _, token := range strings.Split(list, ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		if token == "" {
+			continue
+		}
+		set, known := requestOptionTokens[token]
+		if !known {
+			unknown = append(unknown, token)
+			continue
+		}
+		set(&options)
+	}
+	return options, unknown
+}
+
+// resolveRequestOptions is the one place per-request options are read.
+// The options query parameter replaces the header when both are sent, and
+// an explicit lenient, enum, truncate or fixPlus query parameter overrides
+// the list either way. Unknown tokens fail the request in strict mode and
+// are otherwise ignored with a Warning header.
+func (h *Handler) resolveRequestOptions(c *gin.Context) (RequestOptions, *ErrorResponse) {
+	options, errorResponse := h.requestOptions(c.Request.URL.Query(), c.Request.Header, c.Writer.Header())
+	if errorResponse == nil {
+		c.Set(requestOptionsKey, options)
+	}
+	return options, errorResponse
+}
+
+// requestOptions is resolveRequestOptions over the raw query and headers;
+// unknown-token warnings are added to responseHeader.
+func (h *Handler) requestOptions(query url.Values, header, responseHeader http.Header) (RequestOptions, *ErrorResponse) {
+	list, fromQuery := queryValue(query, "options")
+	if !fromQuery {
+		list = header.Get(RequestOptionsHeader)
+	}
+
+	options, unknown := ParseRequestOptions(list)
+	if len(unknown) > 0 {
+		if options.Strict {
+			return options, h.echo(&ErrorResponse{
+				PhoneNumber: query.Get("phoneNumber"),
+				Code:        ErrorMalformedRequest,
+				Error: map[string]string{
+					"options": "unknown option " + strings.Join(unknown, ", "),
+				},
+				Received: map[string][]string{
+					"options": {list},
+				},
+			})
+		}
+		for _, token := range unknown {
+			responseHeader.Add("Warning", fmt.Sprintf(`299 phone-api "unknown option %s ignored"`, token))
+		}
+	}
+
+	if value, explicit := queryValue(query, "lenient"); explicit {
+		options.Lenient, _ = strconv.ParseBool(value)
+	}
+	if value, explicit := queryValue(query, "enum"); explicit {
+		options.Enum, _ = strconv.ParseBool(value)
+	}
+	if value, explicit := queryValue(query, "truncate"); explicit {
+		options.Truncate, _ = strconv.ParseBool(value)
+	}
+	options.FixPlus = options.FixPlus || h.fixPlus
+	if value, explicit := queryValue(query, "fixPlus"); explicit {
+		options.FixPlus, _ = strconv.ParseBool(value)
+	}
+	return options, nil
+}
+
+// queryValue is gin's GetQuery: the first value and whether key was sent.
+func queryValue(query url.Values, key string) (string, bool) {
+	if values, exists := query[key]; exists && len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
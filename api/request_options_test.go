@@ -0,0 +1,34 @@
+//go:build !js
+
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRequestOptions(t *testing.T) {
+	tests := []struct {
+		list        string
+		wantOptions RequestOptions
+		wantUnknown []string
+	}{
+		{"", RequestOptions{}, nil},
+		{"lenient", RequestOptions{Lenient: true}, nil},
+		{" Lenient , ENUM ", RequestOptions{Lenient: true, Enum: true}, nil},
+		{"lenient,,strict,", RequestOptions{Lenient: true, Strict: true}, nil},
+		{"lenint,enum,explain", RequestOptions{Enum: true}, []string{"lenint", "explain"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			options, unknown := ParseRequestOptions(tt.list)
+			if options != tt.wantOptions {
+				t.Errorf("Expected %+v, got %+v", tt.wantOptions, options)
+			}
+			if !reflect.DeepEqual(unknown, tt.wantUnknown) {
+				t.Errorf("Expected unknown %v, got %v", tt.wantUnknown, unknown)
+			}
+		})
+	}
+}
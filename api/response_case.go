@@ -0,0 +1,197 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response key cases for the case query parameter and
+// APIKeyConfig.ResponseCase; the empty string is ResponseCaseCamel.
+const (
+	ResponseCaseCamel = "camel"
+	ResponseCaseSnake = "snake"
+)
+
+func validResponseCase(value string) bool {
+	return value == "" || value == ResponseCaseCamel || value == ResponseCaseSnake
+}
+
+// snakeCaser re-keys a JSON document to snake_case as it streams through,
+// so a body split across writes is handled like one written at once and
+// every field of every type is covered without a second set of structs.
+// Only object keys starting with a lowercase letter are field names;
+// data keys such as country codes, error codes and paths pass unchanged,
+// as do string values.
+type snakeCaser struct {
+	containers []byte
+	expectKey  bool
+	inString   bool
+	inKey      bool
+	escaped    bool
+	key        []byte
+}
+
+// transform returns the output for the next chunk of input. A key is held
+// back until its closing quote arrives.
+func (s *snakeCaser) transform(p []byte) []byte {
+	out := make([]byte, 0, len(p)+len(p)/8)
+	for _, ch := range p {
+		if s.inString {
+			closing := ch == '"' && !s.escaped
+			s.escaped = ch == '\\' && !s.escaped
+			switch {
+			case closing && s.inKey:
+				out = append(appendSnakeCase(out, s.key), '"')
+				s.inString, s.inKey = false, false
+			case s.inKey:
+				s.key = append(s.key, ch)
+			default:
+				out = append(out, ch)
+				s.inString = !closing
+			}
+			continue
+		}
+
+		switch ch {
+		case '"':
+			s.inString, s.inKey = true, s.expectKey
+			s.expectKey = false
+			s.key = s.key[:0]
+		case '{':
+			s.containers = append(s.containers, ch)
+			s.expectKey = true
+		case '[':
+			s.containers = append(s.containers, ch)
+		case '}', ']':
+			if len(s.containers) > 0 {
+				s.containers = s.containers[:len(s.containers)-1]
+			}
+		case ',':
+			s.expectKey = len(s.containers) > 0 && s.containers[len(s.containers)-1] == '{'
+		}
+		out = append(out, ch)
+	}
+	return out
+}
+
+// appendSnakeCase appends key as snake_case: an underscore goes before an
+// upper case letter that follows a lower case letter or digit, or that
+// starts a word after an acronym (retryAfterSeconds, p50Ms, ndc).
+func appendSnakeCase(out, key []byte) []byte {
+	if len(key) == 0 || key[0] < 'a' || key[0] > 'z' || strings.IndexByte(string(key), '\\') >= 0 {
+		return append(out, key...)
+	}
+	for i, ch := range key {
+		if ch >= 'A' && ch <= 'Z' {
+			previous := key[i-1]
+			lowerBefore := previous >= 'a' && previous <= 'z' || previous >= '0' && previous <= '9'
+			lowerAfter := i+1 < len(key) && key[i+1] >= 'a' && key[i+1] <= 'z'
+			if lowerBefore || previous >= 'A' && previous <= 'Z' && lowerAfter {
+				out = append(out, '_')
+			}
+			ch += 'a' - 'A'
+		}
+		out = append(out, ch)
+	}
+	return out
+}
+
+// isJSONResponse reports whether header announces a JSON body.
+func isJSONResponse(header http.Header) bool {
+	return mediaType(header.Get("Content-Type")) == "application/json"
+}
+
+// invalidResponseCase is the 400 body for an unknown case parameter.
+func invalidResponseCase(value string) *ErrorResponse {
+	return &ErrorResponse{
+		Code:     ErrorMalformedRequest,
+		Error:    map[string]string{"case": "must be " + ResponseCaseCamel + " or " + ResponseCaseSnake},
+		Received: map[string][]string{"case": {value}},
+	}
+}
+
+// responseCase installs the snake_case renderer when ?case=snake is sent,
+// or when no case is sent and the caller's API key defaults to snake. The
+// key is only known once requireAPIKey has run, so the choice is made at
+// the first JSON write.
+func (h *Handler) responseCase(c *gin.Context) {
+	requested, explicit := queryValue(c.Request.URL.Query(), "case")
+	requested = strings.ToLower(requested)
+	if !validResponseCase(requested) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, invalidResponseCase(requested))
+		return
+	}
+
+	c.Writer = &caseResponseWriter{ResponseWriter: c.Writer, snake: func() bool {
+		if explicit {
+			return requested == ResponseCaseSnake
+		}
+		key := apiKeyConfig(c)
+		return key != nil && key.ResponseCase == ResponseCaseSnake
+	}}
+	c.Next()
+}
+
+// caseResponseWriter passes non-JSON bodies, and JSON ones in camelCase,
+// through byte for byte.
+type caseResponseWriter struct {
+	gin.ResponseWriter
+	snake   func() bool
+	decided bool
+	caser   *snakeCaser
+}
+
+func (w *caseResponseWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		if isJSONResponse(w.Header()) && w.snake() {
+			w.caser = &snakeCaser{}
+		}
+	}
+	if w.caser == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	if _, err := w.ResponseWriter.Write(w.caser.transform(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *caseResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// stdCaseResponseWriter is caseResponseWriter for NewStdHandler. v1 sets
+// keyCase once the API key is known.
+type stdCaseResponseWriter struct {
+	http.ResponseWriter
+	requested string
+	explicit  bool
+	keyCase   string
+	decided   bool
+	caser     *snakeCaser
+}
+
+func (w *stdCaseResponseWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		responseCase := w.keyCase
+		if w.explicit {
+			responseCase = w.requested
+		}
+		if isJSONResponse(w.Header()) && responseCase == ResponseCaseSnake {
+			w.caser = &snakeCaser{}
+		}
+	}
+	if w.caser == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	if _, err := w.ResponseWriter.Write(w.caser.transform(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,50 @@
+//go:build !js
+
+package api
+
+import "testing"
+
+func TestAppendSnakeCase(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"phoneNumber", "phone_number"},
+		{"countryCode", "country_code"},
+		{"ndc", "ndc"},
+		{"p50Ms", "p50_ms"},
+		{"retryAfterSeconds", "retry_after_seconds"},
+		{"utcOffsetMinutes", "utc_offset_minutes"},
+		{"duplicateIds", "duplicate_ids"},
+		{"iddPrefix", "idd_prefix"},
+		{"US", "US"},
+		{string(ErrorLengthOutOfRange), string(ErrorLengthOutOfRange)},
+		{"/v1/phone-numbers", "/v1/phone-numbers"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := string(appendSnakeCase(nil, []byte(tt.key))); got != tt.want {
+			t.Errorf("appendSnakeCase(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestSnakeCaserTransform(t *testing.T) {
+	input := `{"phoneNumber":"+1 \"areaCode\"","warningDetails":[{"code":"X","detail":"a\\"}],"failureReasons":{"US":{"` + string(ErrorLengthOutOfRange) + `":1}},"summary":{"validCount":1,"duplicateIds":["countryCode"]}}`
+	want := `{"phone_number":"+1 \"areaCode\"","warning_details":[{"code":"X","detail":"a\\"}],"failure_reasons":{"US":{"` + string(ErrorLengthOutOfRange) + `":1}},"summary":{"valid_count":1,"duplicate_ids":["countryCode"]}}`
+
+	if got := string((&snakeCaser{}).transform([]byte(input))); got != want {
+		t.Errorf("transform:\n got %s\nwant %s", got, want)
+	}
+
+	// Split at every byte, the output must not change.
+	caser := &snakeCaser{}
+	var chunked []byte
+	for i := 0; i < len(input); i++ {
+		chunked = append(chunked, caser.transform([]byte(input[i:i+1]))...)
+	}
+	if string(chunked) != want {
+		t.Errorf("chunked transform:\n got %s\nwant %s", chunked, want)
+	}
+}
@@ -0,0 +1,311 @@
+//go:build !js
+
+package api
+
+import (
+	"bufio"
+	"container/list"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultResultCacheSize is the capacity used when a seed file is set
+	// without WithResultCache.
+	DefaultResultCacheSize = 10000
+
+	// DefaultCacheSeedBudget bounds how long seeding may delay startup.
+	DefaultCacheSeedBudget = 10 * time.Second
+
+	cacheSeedProgressEvery = 1000
+)
+
+// ResultCacheStats is the resultCache entry of /v1/stats. Seeded counts
+// entries inserted from the seed file; SeedSkipped counts seed lines that
+// failed validation.
+type ResultCacheStats struct {
+	Capacity    int   `json:"capacity"`
+	Size        int   `json:"size"`
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Seeded      int64 `json:"seeded"`
+	SeedSkipped int64 `json:"seedSkipped"`
+}
+
+type resultCacheKey struct {
+	phoneNumber string
+	countryCode string
+	options     ParseOptions
+}
+
+type resultCacheEntry struct {
+	key      resultCacheKey
+	response PhoneValidationResponse
+}
+
+// resultCache is a least-recently-used cache of successful validations,
+// keyed on the raw input, the provided country and the parse options.
+// Failures are not cached, and responses are stored before the per-request
+// country name, enrichment and key checks are applied.
+type resultCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[resultCacheKey]*list.Element
+	stats   ResultCacheStats
+}
+
+type cacheSeed struct {
+	path   string
+	budget time.Duration
+	logger *log.Logger
+}
+
+func newResultCache(capacity int) *resultCache {
+	return &resultCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  map[resultCacheKey]*list.Element{},
+	}
+}
+
+// WithResultCache caches up to capacity successful lookups. It is off by
+// default; a hit answers with X-Cache: HIT.
+func WithResultCache(capacity int) HandlerOption {
+	return func(h *Handler) {
+		if capacity <= 0 {
+			h.resultCache = nil
+			return
+		}
+		h.resultCache = newResultCache(capacity)
+	}
+}
+
+// WithCacheSeed makes WarmUp validate the numbers in path, one per line
+// as "number" or "number,country", and insert them into the result cache
+// until it is full or budget has passed. Blank lines and lines starting
+// with # are ignored, invalid numbers are skipped and counted. The cache
+// is enabled at DefaultResultCacheSize unless WithResultCache sized it.
+// NewStdHandler does not warm up, so it starts with an empty cache.
+func WithCacheSeed(path string, budget time.Duration, logger *log.Logger) HandlerOption {
+	return func(h *Handler) {
+		if path == "" {
+			h.cacheSeed = nil
+			return
+		}
+		if budget <= 0 {
+			budget = DefaultCacheSeedBudget
+		}
+		h.cacheSeed = &cacheSeed{path: path, budget: budget, logger: logger}
+	}
+}
+
+func cacheKey(req PhoneValidationRequest) resultCacheKey {
+	return resultCacheKey{
+		phoneNumber: req.PhoneNumber,
+		countryCode: strings.ToUpper(req.CountryCode),
+		options:     req.parseOptions(),
+	}
+}
+
+// get returns a copy of the cached response for req.
+func (c *resultCache) get(req PhoneValidationRequest) (*PhoneValidationResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exists := c.entries[cacheKey(req)]
+	if !exists {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(element)
+	return cloneResponse(&element.Value.(*resultCacheEntry).response), true
+}
+
+// cachedResult is a cache hit for req, unless the country was disabled
+// since the entry was stored.
+// cachedResult is bypassed while validation is degraded, so every answer
+// carries the degraded warning and none outlives recovery.
+func (h *Handler) cachedResult(req PhoneValidationRequest) (*PhoneValidationResponse, bool) {
+	if h.validator.MetadataDegraded() {
+		return nil, false
+	}
+	response, cached := h.resultCache.get(req)
+	if !cached || h.validator.DisabledCountries().IsDisabled(response.CountryCode) {
+		return nil, false
+	}
+	return response, true
+}
+
+// cacheHit is which cache, if any, answered a validation.
+type cacheHit int
+
+const (
+	cacheMiss cacheHit = iota
+	cacheHitResult
+	cacheHitNegative
+)
+
+// validate is the cached or fresh validation of req. Neither cache is
+// used while validation is degraded.
+func (h *Handler) validate(req PhoneValidationRequest) (*PhoneValidationResponse, cacheHit, error) {
+	if response, cached := h.cachedResult(req); cached {
+		return response, cacheHitResult, nil
+	}
+	degraded := h.validator.MetadataDegraded()
+	if !degraded {
+		if err, cached := h.negativeCache.get(req, h.now()); cached {
+			return nil, cacheHitNegative, err
+		}
+	}
+	response, err := h.validator.ValidatePhoneNumberWithOptions(req.PhoneNumber, req.CountryCode, req.parseOptions())
+	switch {
+	case degraded:
+	case err == nil:
+		h.resultCache.put(req, response)
+	default:
+		h.negativeCache.put(req, err, h.now())
+	}
+	return response, cacheMiss, err
+}
+
+// cacheHeader is the X-Cache value for outcome, or "" when no enabled
+// cache could have answered it.
+func (h *Handler) cacheHeader(outcome lookupOutcome) string {
+	enabled := h.resultCache != nil
+	if outcome.errorResponse != nil {
+		enabled = h.negativeCache != nil
+	}
+	if !enabled {
+		return ""
+	}
+	switch outcome.cached {
+	case cacheHitResult:
+		return "HIT"
+	case cacheHitNegative:
+		return "HIT-NEGATIVE"
+	}
+	return "MISS"
+}
+
+func (c *resultCache) put(req PhoneValidationRequest, response *PhoneValidationResponse) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey(req)
+	if element, exists := c.entries[key]; exists {
+		element.Value.(*resultCacheEntry).response = *cloneResponse(response)
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&resultCacheEntry{key: key, response: *cloneResponse(response)})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*resultCacheEntry).key)
+	}
+}
+
+func (c *resultCache) full() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len() >= c.capacity
+}
+
+func (c *resultCache) report() *ResultCacheStats {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Capacity, stats.Size = c.capacity, c.order.Len()
+	return &stats
+}
+
+// seed fills the cache from s.path. Only an unreadable file is an error.
+func (c *resultCache) seed(validator *PhoneNumberValidator, s *cacheSeed) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("cache seed file: %w", err)
+	}
+	defer file.Close()
+
+	logger := s.logger
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+
+	start := time.Now()
+	var seeded, skipped int64
+	stopped := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if c.full() {
+			stopped = "cache full"
+			break
+		}
+		if time.Since(start) > s.budget {
+			stopped = "time budget exceeded"
+			break
+		}
+
+		number, country, _ := strings.Cut(line, ",")
+		req := PhoneValidationRequest{PhoneNumber: strings.TrimSpace(number), CountryCode: strings.TrimSpace(country)}
+		response, err := validator.ValidatePhoneNumberWithOptions(req.PhoneNumber, req.CountryCode, req.parseOptions())
+		if err != nil {
+			skipped++
+			continue
+		}
+		c.put(req, response)
+		seeded++
+		if seeded%cacheSeedProgressEvery == 0 {
+			logger.Printf("Cache seed: %d entries inserted, %d lines skipped", seeded, skipped)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cache seed file: %w", err)
+	}
+
+	c.mu.Lock()
+	c.stats.Seeded += seeded
+	c.stats.SeedSkipped += skipped
+	c.mu.Unlock()
+
+	if stopped != "" {
+		logger.Printf("Cache seed stopped early (%s): %d entries inserted, %d lines skipped in %s", stopped, seeded, skipped, time.Since(start))
+	} else {
+		logger.Printf("Cache seed completed: %d entries inserted, %d lines skipped in %s", seeded, skipped, time.Since(start))
+	}
+	return nil
+}
+
+func cloneResponse(response *PhoneValidationResponse) *PhoneValidationResponse {
+	clone := *response
+	clone.Warnings = append([]string(nil), response.Warnings...)
+	clone.WarningDetails = append([]Warning(nil), response.WarningDetails...)
+	if clone.Enum != nil {
+		enum := *clone.Enum
+		enum.Records = append([]EnumRecord(nil), enum.Records...)
+		clone.Enum = &enum
+	}
+	return &clone
+}
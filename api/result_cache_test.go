@@ -0,0 +1,45 @@
+//go:build !js
+
+package api
+
+import "testing"
+
+func TestResultCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newResultCache(2)
+	us := PhoneValidationRequest{PhoneNumber: "+12125690123"}
+	gb := PhoneValidationRequest{PhoneNumber: "2079460958", CountryCode: "gb"}
+	de := PhoneValidationRequest{PhoneNumber: "+493012345678"}
+
+	cache.put(us, &PhoneValidationResponse{CountryCode: "US"})
+	cache.put(gb, &PhoneValidationResponse{CountryCode: "GB"})
+	if _, hit := cache.get(us); !hit {
+		t.Fatal("Expected a hit for the US number")
+	}
+	cache.put(de, &PhoneValidationResponse{CountryCode: "DE"})
+
+	if _, hit := cache.get(gb); hit {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	if _, hit := cache.get(PhoneValidationRequest{PhoneNumber: "+12125690123", Lenient: true}); hit {
+		t.Error("Expected parse options to be part of the key")
+	}
+	response, hit := cache.get(PhoneValidationRequest{PhoneNumber: "+12125690123"})
+	if !hit {
+		t.Fatal("Expected the recently used entry to stay cached")
+	}
+	response.CountryName = "changed"
+	if again, _ := cache.get(us); again.CountryName != "" {
+		t.Errorf("Expected callers to get copies, got %q", again.CountryName)
+	}
+}
+
+func TestHandler_CachedResultSkipsDisabledCountries(t *testing.T) {
+	h := NewHandler(WithResultCache(10))
+	req := PhoneValidationRequest{PhoneNumber: "+12125690123"}
+	h.resultCache.put(req, &PhoneValidationResponse{CountryCode: "US"})
+
+	h.validator.DisabledCountries().Set([]string{"US"})
+	if _, hit := h.cachedResult(req); hit {
+		t.Error("Expected no hit for a country disabled after caching")
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !js
+
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WithClock(now func() time.Time) HandlerOption {
+	return func(h *Handler) {
+		h.now = now
+	}
+}
+
+// retryAfterSeconds rounds up so clients never retry before the window
+// actually resets, and never advertises less than one second.
+func retryAfterSeconds(wait time.Duration) int {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// abortWithRetryAfter is used by every 429 and 503 response so the header
+// and the body's retryAfterSeconds always agree.
+func abortWithRetryAfter(c *gin.Context, status int, wait time.Duration, body gin.H) {
+	seconds := retryAfterSeconds(wait)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	body["retryAfterSeconds"] = seconds
+	c.AbortWithStatusJSON(status, body)
+}
@@ -0,0 +1,129 @@
+//go:build !js
+
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"hash/fnv"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const DefaultFailureSamplesPerMinute = 60
+
+type failureSampler struct {
+	rate      float64
+	perMinute int
+	logger    *log.Logger
+	now       func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+// WithFailureSampling logs a deterministic sample of validation failures,
+// keyed by request ID, capped at perMinute entries per minute.
+func WithFailureSampling(rate float64, perMinute int, logger *log.Logger) HandlerOption {
+	return func(h *Handler) {
+		if rate <= 0 || logger == nil {
+			h.failureSampler = nil
+			return
+		}
+		if perMinute <= 0 {
+			perMinute = DefaultFailureSamplesPerMinute
+		}
+		h.failureSampler = &failureSampler{
+			rate:      math.Min(rate, 1),
+			perMinute: perMinute,
+			logger:    logger,
+			now:       time.Now,
+		}
+	}
+}
+
+func (s *failureSampler) observe(requestID func() string, req PhoneValidationRequest, err error) {
+	if s == nil {
+		return
+	}
+
+	id := requestID()
+	if !s.sampled(id) || !s.allow() {
+		return
+	}
+
+	// The error code rather than the message: messages can quote the input.
+	s.logger.Printf("validation failure requestId=%q phoneNumber=%q countryCode=%q code=%q",
+		id, maskPhoneNumber(req.PhoneNumber), req.CountryCode, errorCode(err))
+}
+
+func (s *failureSampler) sampled(requestID string) bool {
+	return sampledBy(s.rate, requestID)
+}
+
+// sampledBy deterministically picks a rate share of keys, so the same key
+// is always sampled or always skipped.
+func sampledBy(rate float64, key string) bool {
+	if rate >= 1 {
+		return true
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return float64(hash.Sum64())/float64(math.MaxUint64) < rate
+}
+
+func (s *failureSampler) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.windowStart) >= time.Minute {
+		s.windowStart = now
+		s.count = 0
+	}
+	if s.count >= s.perMinute {
+		return false
+	}
+	s.count++
+	return true
+}
+
+// requestIDFor returns the caller's X-Request-ID, generating and echoing one
+// when none was sent so a sampled failure can be traced back.
+func requestIDFor(c *gin.Context) string {
+	if id := c.GetString("requestId"); id != "" {
+		return id
+	}
+
+	id := requestIDFrom(c.GetHeader("X-Request-ID"))
+	c.Set("requestId", id)
+	c.Header("X-Request-ID", id)
+	return id
+}
+
+// requestIDFrom keeps the caller's X-Request-ID, or generates one.
+func requestIDFrom(header string) string {
+	if header != "" {
+		return header
+	}
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// maskPhoneNumber keeps the first three and last two characters and masks
+// every digit in between.
+func maskPhoneNumber(phoneNumber string) string {
+	masked := []byte(phoneNumber)
+	for i := 3; i < len(masked)-2; i++ {
+		if masked[i] >= '0' && masked[i] <= '9' {
+			masked[i] = '*'
+		}
+	}
+	return string(masked)
+}
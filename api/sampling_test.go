@@ -0,0 +1,41 @@
+//go:build !js
+
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailureSampler_WindowResets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sampler := &failureSampler{rate: 1, perMinute: 2, now: func() time.Time { return now }}
+
+	for i, expected := range []bool{true, true, false} {
+		if got := sampler.allow(); got != expected {
+			t.Errorf("Call %d: expected allow=%v, got %v", i, expected, got)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if !sampler.allow() {
+		t.Errorf("Expected the cap to reset after a minute")
+	}
+}
+
+func TestMaskPhoneNumber(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "+12125690123", expected: "+12*******23"},
+		{input: "+52 631 3118150", expected: "+52 *** *****50"},
+		{input: "12", expected: "12"},
+	}
+
+	for _, tt := range tests {
+		if got := maskPhoneNumber(tt.input); got != tt.expected {
+			t.Errorf("maskPhoneNumber(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
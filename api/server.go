@@ -0,0 +1,317 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+const ShutdownTimeout = 10 * time.Second
+
+// Middleware stages, in the order every request passes through them.
+// Recovery, request ID, logging and CORS wrap the whole engine; auth and
+// limits are installed on the route groups that need them.
+const (
+	StageRecovery  = "recovery"
+	StageRequestID = "requestID"
+	StageLogging   = "logging"
+	StageCORS      = "cors"
+	StageAuth      = "auth"
+	StageLimits    = "limits"
+)
+
+// MiddlewareObserver is called as a request enters each middleware stage.
+type MiddlewareObserver func(c *gin.Context, stage string)
+
+// Config is everything NewServer needs besides the handler options.
+type Config struct {
+	// Addr is the TCP address Run listens on when Addrs and Listeners
+	// are empty.
+	Addr string
+	// Addrs are addresses to bind, each host:port or "unix:" followed by
+	// a socket path, served alongside Listeners.
+	Addrs []string
+	// Listeners are already-open sockets, e.g. from systemd activation.
+	Listeners []net.Listener
+	// CORSOrigins defaults to every origin.
+	CORSOrigins    []string
+	HandlerOptions []HandlerOption
+	// Logger receives request and lifecycle logs; nil discards them.
+	Logger *log.Logger
+	// Observe, when set, sees every stage a request enters; tests use it
+	// to assert the middleware order.
+	Observe MiddlewareObserver
+}
+
+// Server is the fully wired engine: middleware, routes and a warmed-up
+// handler.
+type Server struct {
+	cfg     Config
+	handler *Handler
+	engine  *gin.Engine
+	logger  *log.Logger
+
+	mu         sync.Mutex
+	listeners  []net.Listener
+	httpServer *http.Server
+
+	handoff     chan struct{}
+	handoffOnce sync.Once
+}
+
+// loggerWriter sends gin's request log through the logger's own Print,
+// which holds the logger's lock, so request lines never interleave with
+// the server's lifecycle messages written to the same output.
+type loggerWriter struct {
+	logger *log.Logger
+}
+
+func (w loggerWriter) Write(p []byte) (int, error) {
+	w.logger.Print(string(p))
+	return len(p), nil
+}
+
+// NewServer builds and warms up the engine. It never listens; call Run.
+func NewServer(cfg Config) (*Server, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+
+	handler := NewHandler(append(cfg.HandlerOptions, withMiddlewareObserver(cfg.Observe))...)
+
+	origins := cfg.CORSOrigins
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = origins
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", IdempotencyKeyHeader}
+	if err := corsConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	logging := func(c *gin.Context) { c.Next() }
+	if cfg.Logger != nil {
+		output := loggerWriter{cfg.Logger}
+		logging = gin.LoggerWithWriter(output)
+		if handler.privacy {
+			logging = gin.LoggerWithConfig(gin.LoggerConfig{Output: output, Formatter: privacyLogFormatter})
+		}
+	}
+
+	engine := gin.New()
+	engine.Use(
+		handler.stage(StageRecovery, gin.Recovery()),
+		handler.stage(StageRequestID, assignRequestID),
+		handler.stage(StageLogging, logging),
+		handler.stage(StageCORS, cors.New(corsConfig)),
+	)
+	handler.SetupRoutes(engine)
+
+	warmUp, err := handler.WarmUp()
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("Warm-up completed in %s", warmUp)
+
+	return &Server{cfg: cfg, handler: handler, engine: engine, logger: logger, handoff: make(chan struct{})}, nil
+}
+
+// Handler returns the engine for use with httptest or another server.
+func (s *Server) Handler() http.Handler {
+	return s.engine
+}
+
+// Listen binds every address in Addrs, or Addr when neither Addrs nor
+// Listeners is set. Binding is all or nothing: when one address fails the
+// ones already bound are closed. Run calls Listen if it has not been
+// called, so it is only needed to learn the addresses before serving.
+func (s *Server) Listen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listeners != nil {
+		return nil
+	}
+
+	addrs := s.cfg.Addrs
+	if len(addrs) == 0 && len(s.cfg.Listeners) == 0 {
+		addrs = []string{s.cfg.Addr}
+	}
+	var bound []net.Listener
+	for _, addr := range addrs {
+		listener, err := listen(addr)
+		if err != nil {
+			for _, listener := range bound {
+				listener.Close()
+			}
+			return fmt.Errorf("listen on %s: %w", addr, err)
+		}
+		bound = append(bound, listener)
+	}
+	s.listeners = append(append([]net.Listener{}, s.cfg.Listeners...), bound...)
+	return nil
+}
+
+func listen(addr string) (net.Listener, error) {
+	if path, isUnix := strings.CutPrefix(addr, "unix:"); isUnix {
+		return net.Listen("unix", path)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Addrs returns the addresses being listened on, or nil before Listen.
+func (s *Server) Addrs() []net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var addrs []net.Addr
+	for _, listener := range s.listeners {
+		addrs = append(addrs, listener.Addr())
+	}
+	return addrs
+}
+
+// Listeners returns the sockets being served, or nil before Listen.
+func (s *Server) Listeners() []net.Listener {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]net.Listener(nil), s.listeners...)
+}
+
+// Handoff makes Run return once in-flight requests are done, for when
+// another process serving the same sockets has taken over. Unlike a
+// shutdown there is no drain: requests on open connections are answered
+// normally, as new connections already reach the other process.
+func (s *Server) Handoff() {
+	s.handoffOnce.Do(func() { close(s.handoff) })
+}
+
+// Run serves on every listener until ctx is cancelled or one fails, then
+// drains and shuts all of them down gracefully; after Handoff it stops
+// without draining. It returns once every listener has stopped, with the
+// first serve error, if any.
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           s.engine,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	s.mu.Lock()
+	listeners := s.listeners
+	s.httpServer = server
+	s.mu.Unlock()
+
+	var serving sync.WaitGroup
+	errs := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		s.logger.Printf("Serving on %s %s", listener.Addr().Network(), listener.Addr())
+		serving.Add(1)
+		go func(listener net.Listener) {
+			defer serving.Done()
+			// A handoff closes the listeners itself.
+			err := server.Serve(listener)
+			if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+				errs <- err
+			}
+		}(listener)
+	}
+
+	var serveErr error
+	shutdown := s.Shutdown
+	select {
+	case <-ctx.Done():
+	case <-s.handoff:
+		s.logger.Printf("Handing off to the new process")
+		shutdown = func(ctx context.Context) error {
+			return handOff(ctx, server, listeners)
+		}
+	case serveErr = <-errs:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := shutdown(shutdownCtx); err != nil && serveErr == nil {
+		serveErr = err
+	}
+	serving.Wait()
+	return serveErr
+}
+
+// handoffGrace is how long connections accepted just before a handoff
+// have to send their first request.
+const handoffGrace = time.Second
+
+// handOff stops accepting and closes each connection once its current
+// request is answered. Shutdown alone would drop the first request of a
+// connection accepted just before it, which clients do not retry; idle
+// keep-alive connections it closes are retried, on the new process.
+func handOff(ctx context.Context, server *http.Server, listeners []net.Listener) error {
+	server.SetKeepAlivesEnabled(false)
+	for _, listener := range listeners {
+		listener.Close()
+	}
+	select {
+	case <-time.After(handoffGrace):
+	case <-ctx.Done():
+	}
+	return server.Shutdown(ctx)
+}
+
+// Shutdown fails readiness and new lookups with 503 until ctx's deadline,
+// then stops the listeners and waits for in-flight requests. It is a
+// no-op before Run.
+func (s *Server) Shutdown(ctx context.Context) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(ShutdownTimeout)
+	}
+	s.handler.Drain(deadline)
+
+	s.mu.Lock()
+	server := s.httpServer
+	s.mu.Unlock()
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+func withMiddlewareObserver(observe MiddlewareObserver) HandlerOption {
+	return func(h *Handler) {
+		h.observe = observe
+	}
+}
+
+// stage returns middleware unchanged unless an observer is configured.
+func (h *Handler) stage(name string, middleware gin.HandlerFunc) gin.HandlerFunc {
+	if h.observe == nil {
+		return middleware
+	}
+	return func(c *gin.Context) {
+		h.observe(c, name)
+		middleware(c)
+	}
+}
+
+// assignRequestID gives every request an X-Request-ID before anything
+// logs it.
+func assignRequestID(c *gin.Context) {
+	requestIDFor(c)
+	c.Next()
+}
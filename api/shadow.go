@@ -0,0 +1,233 @@
+//go:build !js
+
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultShadowQueue is how many comparisons may wait for the shadow
+	// worker; lookups arriving while it is full are not compared.
+	DefaultShadowQueue = 1000
+
+	// DefaultShadowExamples is how many recent mismatches the shadow
+	// report lists.
+	DefaultShadowExamples = 20
+
+	// OutcomePanic is the shadow outcome of a secondary validator that
+	// panicked.
+	OutcomePanic = "PANIC"
+)
+
+// Validator validates one number. PhoneNumberValidator implements it; other
+// implementations are compared against it with WithShadowValidator.
+type Validator interface {
+	ValidatePhoneNumberWithOptions(phoneNumber, countryCode string, opts ParseOptions) (*PhoneValidationResponse, error)
+}
+
+// shadowResult is the part of a validation result both validators must
+// agree on, captured before enrichment changes the response.
+type shadowResult struct {
+	outcome string
+	fields  map[string]string
+}
+
+func newShadowResult(response *PhoneValidationResponse, err error) shadowResult {
+	result := shadowResult{outcome: outcomeCode(err)}
+	if err == nil && response != nil {
+		result.fields = map[string]string{
+			"phoneNumber":      response.PhoneNumber,
+			"countryCode":      response.CountryCode,
+			"ndc":              response.NDC,
+			"areaCode":         response.AreaCode,
+			"localPhoneNumber": response.LocalPhoneNumber,
+			"areaCodeName":     response.AreaCodeName,
+		}
+	}
+	return result
+}
+
+// differingFields lists the fields on which two results disagree, with
+// "outcome" for a different validity or error code.
+func (r shadowResult) differingFields(other shadowResult) []string {
+	if r.outcome != other.outcome {
+		return []string{"outcome"}
+	}
+	var fields []string
+	for _, name := range []string{"phoneNumber", "countryCode", "ndc", "areaCode", "localPhoneNumber", "areaCodeName"} {
+		if r.fields[name] != other.fields[name] {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+type shadowTask struct {
+	phoneNumber string
+	countryCode string
+	opts        ParseOptions
+	primary     shadowResult
+}
+
+// ShadowMismatch is one input the validators disagreed on. The number is
+// masked.
+type ShadowMismatch struct {
+	PhoneNumber string   `json:"phoneNumber"`
+	CountryCode string   `json:"countryCode,omitempty"`
+	Fields      []string `json:"fields"`
+	Primary     string   `json:"primary"`
+	Secondary   string   `json:"secondary"`
+}
+
+// ShadowReport is GET /admin/shadow-report. Fields counts mismatches by
+// differing field and Transitions by "PRIMARY->SECONDARY" outcome.
+type ShadowReport struct {
+	Enabled     bool             `json:"enabled"`
+	SampleRate  float64          `json:"sampleRate"`
+	Compared    int64            `json:"compared"`
+	Mismatched  int64            `json:"mismatched"`
+	Dropped     int64            `json:"dropped"`
+	Fields      map[string]int64 `json:"fields"`
+	Transitions map[string]int64 `json:"transitions"`
+	Examples    []ShadowMismatch `json:"examples"`
+}
+
+// shadow runs sampled lookups through a secondary validator on its own
+// goroutine and tallies where it disagrees with the primary.
+type shadow struct {
+	secondary Validator
+	rate      float64
+	queue     chan shadowTask
+
+	mu          sync.Mutex
+	compared    int64
+	mismatched  int64
+	dropped     int64
+	fields      map[string]int64
+	transitions map[string]int64
+	examples    []ShadowMismatch
+	keepExample bool
+}
+
+// WithShadowValidator compares a rate share of lookups, sampled by request
+// ID and number, against secondary. Comparisons run after the response is
+// decided and off the request goroutine, so the secondary never changes
+// what clients see; mismatches are reported by /admin/shadow-report.
+// Privacy mode keeps the counts but no examples.
+func WithShadowValidator(secondary Validator, rate float64) HandlerOption {
+	return func(h *Handler) {
+		if secondary == nil || rate <= 0 {
+			h.shadow = nil
+			return
+		}
+		h.shadow = &shadow{
+			secondary:   secondary,
+			rate:        math.Min(rate, 1),
+			queue:       make(chan shadowTask, DefaultShadowQueue),
+			fields:      map[string]int64{},
+			transitions: map[string]int64{},
+			keepExample: true,
+		}
+		go h.shadow.run()
+	}
+}
+
+// observe queues req for comparison if it is sampled. It never blocks: a
+// full queue drops the comparison.
+func (s *shadow) observe(requestID func() string, req PhoneValidationRequest, response *PhoneValidationResponse, err error) {
+	if s == nil || !sampledBy(s.rate, requestID()+"\x00"+req.PhoneNumber) {
+		return
+	}
+	task := shadowTask{
+		phoneNumber: req.PhoneNumber,
+		countryCode: req.CountryCode,
+		opts:        req.parseOptions(),
+		primary:     newShadowResult(response, err),
+	}
+	select {
+	case s.queue <- task:
+	default:
+		s.mu.Lock()
+		s.dropped++
+		s.mu.Unlock()
+	}
+}
+
+func (s *shadow) run() {
+	for task := range s.queue {
+		s.compare(task, s.validate(task))
+	}
+}
+
+func (s *shadow) validate(task shadowTask) (result shadowResult) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result = shadowResult{outcome: OutcomePanic}
+		}
+	}()
+	return newShadowResult(s.secondary.ValidatePhoneNumberWithOptions(task.phoneNumber, task.countryCode, task.opts))
+}
+
+func (s *shadow) compare(task shadowTask, secondary shadowResult) {
+	fields := task.primary.differingFields(secondary)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compared++
+	if len(fields) == 0 {
+		return
+	}
+	s.mismatched++
+	for _, field := range fields {
+		s.fields[field]++
+	}
+	if task.primary.outcome != secondary.outcome {
+		s.transitions[fmt.Sprintf("%s->%s", task.primary.outcome, secondary.outcome)]++
+	}
+	if !s.keepExample {
+		return
+	}
+	if len(s.examples) == DefaultShadowExamples {
+		s.examples = s.examples[1:]
+	}
+	s.examples = append(s.examples, ShadowMismatch{
+		PhoneNumber: maskPhoneNumber(task.phoneNumber),
+		CountryCode: task.countryCode,
+		Fields:      fields,
+		Primary:     task.primary.outcome,
+		Secondary:   secondary.outcome,
+	})
+}
+
+func (s *shadow) report() ShadowReport {
+	report := ShadowReport{Fields: map[string]int64{}, Transitions: map[string]int64{}, Examples: []ShadowMismatch{}}
+	if s == nil {
+		return report
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report.Enabled = true
+	report.SampleRate = s.rate
+	report.Compared, report.Mismatched, report.Dropped = s.compared, s.mismatched, s.dropped
+	for field, count := range s.fields {
+		report.Fields[field] = count
+	}
+	for transition, count := range s.transitions {
+		report.Transitions[transition] = count
+	}
+	report.Examples = append(report.Examples, s.examples...)
+	return report
+}
+
+// ShadowReport lists how often the shadow validator disagreed with the
+// primary one, with the most recent mismatches as masked examples.
+func (h *Handler) ShadowReport(c *gin.Context) {
+	c.JSON(http.StatusOK, h.shadow.report())
+}
@@ -0,0 +1,34 @@
+//go:build !js
+
+package api
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHandler_SplitInvariantIsLogged(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	err := &SplitInvariantError{CountryCode: "ES", NationalNumber: "915872200", AreaCode: "91", LocalPhoneNumber: "58722"}
+	status, errorResponse := NewHandler().validationFailure("+34915872200", err)
+	if status != http.StatusInternalServerError || errorResponse.Code != ErrorInternal {
+		t.Errorf("Expected 500 %s, got %d %s", ErrorInternal, status, errorResponse.Code)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"Split rule for ES", `"915****00"`, `areaCode="91"`, "5-digit localPhoneNumber"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %s in the log, got %q", want, output)
+		}
+	}
+	if strings.Contains(output, "915872200") {
+		t.Errorf("Expected the national number to be masked, got %q", output)
+	}
+}
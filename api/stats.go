@@ -0,0 +1,264 @@
+package api
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	usageDayLayout      = "2006-01-02"
+	usageRetentionDays  = 62
+	anonymousAPIKey     = "anonymous"
+	unknownUsageCountry = "unknown"
+)
+
+type UsageRow struct {
+	Day         string
+	APIKey      string
+	CountryCode string
+	Validations int64
+	Errors      int64
+}
+
+// BillingRow is one day of billable usage for one API key. Items counts
+// every validated number, whichever endpoint it came through; cached
+// enrichments are included in Enrichments and also counted on their own.
+type BillingRow struct {
+	Day               string
+	APIKey            string
+	Items             int64
+	Errors            int64
+	Enrichments       int64
+	CachedEnrichments int64
+	BytesIn           int64
+	BytesOut          int64
+}
+
+type trafficKey struct {
+	day    string
+	apiKey string
+}
+
+type trafficCounts struct {
+	enrichments       int64
+	cachedEnrichments int64
+	bytesIn           int64
+	bytesOut          int64
+}
+
+type usageKey struct {
+	day         string
+	apiKey      string
+	countryCode string
+}
+
+type usageCounts struct {
+	validations int64
+	errors      int64
+}
+
+// UsageStats keeps per-day, per-key, per-country validation counts in
+// memory for the last usageRetentionDays days.
+type UsageStats struct {
+	mu      sync.Mutex
+	counts  map[usageKey]*usageCounts
+	traffic map[trafficKey]*trafficCounts
+	since   time.Time
+	lastDay string
+}
+
+func NewUsageStats() *UsageStats {
+	return &UsageStats{
+		counts:  map[usageKey]*usageCounts{},
+		traffic: map[trafficKey]*trafficCounts{},
+		since:   time.Now(),
+	}
+}
+
+func (s *UsageStats) Record(at time.Time, apiKey, countryCode string, failed bool) {
+	if countryCode == "" {
+		countryCode = unknownUsageCountry
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day, apiKey := s.advance(at, apiKey)
+	key := usageKey{day: day, apiKey: apiKey, countryCode: countryCode}
+	counts, exists := s.counts[key]
+	if !exists {
+		counts = &usageCounts{}
+		s.counts[key] = counts
+	}
+	counts.validations++
+	if failed {
+		counts.errors++
+	}
+}
+
+// RecordEnrichment counts one enrichment for apiKey, flagged when it was
+// answered from cache.
+func (s *UsageStats) RecordEnrichment(at time.Time, apiKey string, cached bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := s.trafficCounts(at, apiKey)
+	counts.enrichments++
+	if cached {
+		counts.cachedEnrichments++
+	}
+}
+
+// RecordTraffic adds one request's body bytes to apiKey's totals.
+func (s *UsageStats) RecordTraffic(at time.Time, apiKey string, bytesIn, bytesOut int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := s.trafficCounts(at, apiKey)
+	counts.bytesIn += bytesIn
+	counts.bytesOut += bytesOut
+}
+
+func (s *UsageStats) trafficCounts(at time.Time, apiKey string) *trafficCounts {
+	day, apiKey := s.advance(at, apiKey)
+	key := trafficKey{day: day, apiKey: apiKey}
+	counts, exists := s.traffic[key]
+	if !exists {
+		counts = &trafficCounts{}
+		s.traffic[key] = counts
+	}
+	return counts
+}
+
+// advance normalizes the key, extends the covered window and prunes on day
+// change. It must be called with s.mu held.
+func (s *UsageStats) advance(at time.Time, apiKey string) (string, string) {
+	if apiKey == "" {
+		apiKey = anonymousAPIKey
+	}
+	day := at.UTC().Format(usageDayLayout)
+
+	if at.Before(s.since) {
+		s.since = at
+	}
+	if day != s.lastDay {
+		s.lastDay = day
+		s.prune(at)
+	}
+	return day, apiKey
+}
+
+func (s *UsageStats) prune(now time.Time) {
+	cutoff := now.UTC().AddDate(0, 0, -usageRetentionDays).Format(usageDayLayout)
+	for key := range s.counts {
+		if key.day < cutoff {
+			delete(s.counts, key)
+		}
+	}
+	for key := range s.traffic {
+		if key.day < cutoff {
+			delete(s.traffic, key)
+		}
+	}
+}
+
+// Since reports the earliest moment covered by the in-memory window.
+func (s *UsageStats) Since() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.since
+}
+
+// Rows returns the counters for days in [from, to], inclusive, sorted by
+// day, key and country. A zero bound is open.
+func (s *UsageStats) Rows(from, to time.Time) []UsageRow {
+	fromDay, toDay := "", ""
+	if !from.IsZero() {
+		fromDay = from.UTC().Format(usageDayLayout)
+	}
+	if !to.IsZero() {
+		toDay = to.UTC().Format(usageDayLayout)
+	}
+
+	s.mu.Lock()
+	rows := make([]UsageRow, 0, len(s.counts))
+	for key, counts := range s.counts {
+		if (fromDay != "" && key.day < fromDay) || (toDay != "" && key.day > toDay) {
+			continue
+		}
+		rows = append(rows, UsageRow{
+			Day:         key.day,
+			APIKey:      key.apiKey,
+			CountryCode: key.countryCode,
+			Validations: counts.validations,
+			Errors:      counts.errors,
+		})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Day != rows[j].Day {
+			return rows[i].Day < rows[j].Day
+		}
+		if rows[i].APIKey != rows[j].APIKey {
+			return rows[i].APIKey < rows[j].APIKey
+		}
+		return rows[i].CountryCode < rows[j].CountryCode
+	})
+	return rows
+}
+
+// BillingRows returns per-day, per-key billable usage for days in
+// [from, to], inclusive, sorted by day and key. A zero bound is open.
+func (s *UsageStats) BillingRows(from, to time.Time) []BillingRow {
+	fromDay, toDay := "", ""
+	if !from.IsZero() {
+		fromDay = from.UTC().Format(usageDayLayout)
+	}
+	if !to.IsZero() {
+		toDay = to.UTC().Format(usageDayLayout)
+	}
+	inRange := func(day string) bool {
+		return (fromDay == "" || day >= fromDay) && (toDay == "" || day <= toDay)
+	}
+
+	s.mu.Lock()
+	billing := map[trafficKey]*BillingRow{}
+	row := func(day, apiKey string) *BillingRow {
+		key := trafficKey{day: day, apiKey: apiKey}
+		if billing[key] == nil {
+			billing[key] = &BillingRow{Day: day, APIKey: apiKey}
+		}
+		return billing[key]
+	}
+	for key, counts := range s.counts {
+		if inRange(key.day) {
+			r := row(key.day, key.apiKey)
+			r.Items += counts.validations
+			r.Errors += counts.errors
+		}
+	}
+	for key, counts := range s.traffic {
+		if inRange(key.day) {
+			r := row(key.day, key.apiKey)
+			r.Enrichments += counts.enrichments
+			r.CachedEnrichments += counts.cachedEnrichments
+			r.BytesIn += counts.bytesIn
+			r.BytesOut += counts.bytesOut
+		}
+	}
+	s.mu.Unlock()
+
+	rows := make([]BillingRow, 0, len(billing))
+	for _, r := range billing {
+		rows = append(rows, *r)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Day != rows[j].Day {
+			return rows[i].Day < rows[j].Day
+		}
+		return rows[i].APIKey < rows[j].APIKey
+	})
+	return rows
+}
@@ -0,0 +1,220 @@
+//go:build !js
+
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyLabelKey is where authentication middleware stores the label of the
+// caller's API key; lookups without one are accounted as anonymous.
+const apiKeyLabelKey = "apiKeyLabel"
+
+func WithUsageStats(stats *UsageStats) HandlerOption {
+	return func(h *Handler) {
+		h.stats = stats
+	}
+}
+
+// recordLatency times every request on the route it matched; unmatched
+// paths are not recorded so scanners cannot grow the route table.
+func (h *Handler) recordLatency(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+	if route := c.FullPath(); route != "" {
+		h.latency.ObserveRoute(route, time.Since(start))
+	}
+}
+
+// StatsResponse is the latency report plus how often each deprecation was
+// used since startup.
+type StatsResponse struct {
+	LatencyReport
+	Deprecations map[string]int64 `json:"deprecations"`
+	// FailureReasons counts failed lookups by country, then error code; see
+	// FailureReasonOther.
+	FailureReasons map[string]map[string]int64 `json:"failureReasons"`
+	// ResultCache is only set when the result cache is enabled.
+	ResultCache *ResultCacheStats `json:"resultCache,omitempty"`
+	// NegativeCache is only set when the negative cache is enabled.
+	NegativeCache *NegativeCacheStats `json:"negativeCache,omitempty"`
+	// RedisRateLimit is only set when rate limits are counted in Redis.
+	RedisRateLimit *RedisRateLimitStats `json:"redisRateLimit,omitempty"`
+	// UnmappedErrors counts validator errors reported with the generic
+	// "invalid format" message because nothing maps them.
+	UnmappedErrors int64 `json:"unmappedErrors"`
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// recordTraffic bills the request and response body bytes of every
+// authenticated /v1 request to the caller's key.
+func (h *Handler) recordTraffic(c *gin.Context) {
+	body := &countingReader{ReadCloser: c.Request.Body}
+	if c.Request.Body != nil {
+		c.Request.Body = body
+	}
+	c.Next()
+
+	bytesOut := int64(c.Writer.Size())
+	if bytesOut < 0 {
+		bytesOut = 0
+	}
+	h.stats.RecordTraffic(time.Now(), c.GetString(apiKeyLabelKey), body.n, bytesOut)
+}
+
+func (h *Handler) Stats(c *gin.Context) {
+	c.JSON(http.StatusOK, StatsResponse{
+		LatencyReport:  h.latency.Report(),
+		Deprecations:   h.deprecations.Counts(),
+		FailureReasons: h.failureReasons.Counts(),
+		ResultCache:    h.resultCache.report(),
+		NegativeCache:  h.negativeCache.report(),
+		RedisRateLimit: h.redisLimit.report(),
+		UnmappedErrors: h.unmappedErrors.Load(),
+	})
+}
+
+func (h *Handler) recordUsage(keyLabel, countryCode string, failed bool) {
+	h.stats.Record(time.Now(), keyLabel, countryCode, failed)
+}
+
+// requestedUsageCountry is the country a rejected request is counted under:
+// the one it asked for when supported, else unknown, so arbitrary
+// countryCode values cannot add rows to the usage table.
+func requestedUsageCountry(countryCode string) string {
+	countryCode = strings.ToUpper(countryCode)
+	if _, exists := CountryPhoneLengths[countryCode]; !exists {
+		return unknownUsageCountry
+	}
+	return countryCode
+}
+
+// csvCell keeps spreadsheets from evaluating a cell as a formula by
+// prefixing the characters that start one with a quote.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (h *Handler) ExportUsageStats(c *gin.Context) {
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"format": "unsupported export format",
+			},
+		})
+		return
+	}
+
+	var from, to time.Time
+	for param, bound := range map[string]*time.Time{"from": &from, "to": &to} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(usageDayLayout, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": map[string]string{
+					param: "must be a date in YYYY-MM-DD format",
+				},
+			})
+			return
+		}
+		*bound = parsed
+	}
+
+	report := c.DefaultQuery("report", "usage")
+	if report != "usage" && report != "billing" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"report": "must be usage or billing",
+			},
+		})
+		return
+	}
+
+	c.Header("Warning", fmt.Sprintf(`299 phone-api "stats persistence is not enabled; export covers in-memory data since %s"`,
+		h.stats.Since().UTC().Format(time.RFC3339)))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+report+`.csv"`)
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	if report == "billing" {
+		writeBillingCSV(writer, h.stats.BillingRows(from, to))
+		return
+	}
+
+	rows := h.stats.Rows(from, to)
+	writer.Write([]string{"date", "apiKey", "countryCode", "validations", "errors"})
+
+	var validations, errors int64
+	for _, row := range rows {
+		writer.Write([]string{
+			row.Day,
+			csvCell(row.APIKey),
+			csvCell(row.CountryCode),
+			strconv.FormatInt(row.Validations, 10),
+			strconv.FormatInt(row.Errors, 10),
+		})
+		validations += row.Validations
+		errors += row.Errors
+	}
+	writer.Write([]string{"TOTAL", "", "", strconv.FormatInt(validations, 10), strconv.FormatInt(errors, 10)})
+	writer.Flush()
+}
+
+func writeBillingCSV(writer *csv.Writer, rows []BillingRow) {
+	writer.Write([]string{"date", "apiKey", "items", "errors", "enrichments", "cachedEnrichments", "bytesIn", "bytesOut"})
+
+	var total BillingRow
+	for _, row := range rows {
+		writer.Write([]string{
+			row.Day,
+			csvCell(row.APIKey),
+			strconv.FormatInt(row.Items, 10),
+			strconv.FormatInt(row.Errors, 10),
+			strconv.FormatInt(row.Enrichments, 10),
+			strconv.FormatInt(row.CachedEnrichments, 10),
+			strconv.FormatInt(row.BytesIn, 10),
+			strconv.FormatInt(row.BytesOut, 10),
+		})
+		total.Items += row.Items
+		total.Errors += row.Errors
+		total.Enrichments += row.Enrichments
+		total.CachedEnrichments += row.CachedEnrichments
+		total.BytesIn += row.BytesIn
+		total.BytesOut += row.BytesOut
+	}
+	writer.Write([]string{
+		"TOTAL", "",
+		strconv.FormatInt(total.Items, 10),
+		strconv.FormatInt(total.Errors, 10),
+		strconv.FormatInt(total.Enrichments, 10),
+		strconv.FormatInt(total.CachedEnrichments, 10),
+		strconv.FormatInt(total.BytesIn, 10),
+		strconv.FormatInt(total.BytesOut, 10),
+	})
+	writer.Flush()
+}
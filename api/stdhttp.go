@@ -0,0 +1,407 @@
+//go:build !js
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stdRoutes are the paths NewStdHandler serves, below the base path.
+var stdRoutes = []string{
+	"/health",
+	"/v1/jobs",
+	"/v1/jobs/:id",
+	"/v1/phone-numbers",
+	"/v1/phone-numbers/batch",
+}
+
+type stdHandler struct {
+	h     *Handler
+	paths []string
+}
+
+// NewStdHandler serves the lookup routes using only net/http: /health, the
+// single, batch (JSON and CSV) and job lookups, with the same API key, rate
+// limit, maintenance and stats handling as the gin routes. Both share
+// processLookup, so a number gets the same answer from either. validator,
+// when set, replaces the default one and any WithValidatorOptions. Mount it
+// with WithBasePath matching the mux pattern:
+//
+//	mux.Handle("/phone/", api.NewStdHandler(nil, api.WithBasePath("/phone")))
+//
+// Other gin routes (admin, interpretations, job events, CORS and OPTIONS
+// responders) are not served; they answer 404 ROUTE_NOT_FOUND.
+func NewStdHandler(validator *PhoneNumberValidator, opts ...HandlerOption) http.Handler {
+	h := NewHandler(opts...)
+	if validator != nil {
+		h.validator = validator
+	}
+
+	paths := make([]string, len(stdRoutes))
+	for i, route := range stdRoutes {
+		paths[i] = h.basePath + route
+	}
+	return &stdHandler{h: h, paths: paths}
+}
+
+func (s *stdHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestID := requestIDFrom(r.Header.Get("X-Request-ID"))
+	w.Header().Set("X-Request-ID", requestID)
+	if s.h.demo {
+		w.Header().Set("X-Demo-Mode", "true")
+	}
+
+	requested, explicit := queryValue(r.URL.Query(), "case")
+	requested = strings.ToLower(requested)
+	if !validResponseCase(requested) {
+		writeJSON(w, http.StatusBadRequest, invalidResponseCase(requested))
+		return
+	}
+	w = &stdCaseResponseWriter{ResponseWriter: w, requested: requested, explicit: explicit}
+
+	path, mounted := strings.CutPrefix(r.URL.Path, s.h.basePath)
+	if !mounted {
+		s.notFound(w, r)
+		return
+	}
+
+	if r.Method == http.MethodGet && path == "/health" {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"status":  "healthy",
+			"service": "phone-number-lookup",
+		})
+		return
+	}
+
+	var route, feature string
+	var accepted []string
+	var serve func(http.ResponseWriter, *http.Request, lookupScope)
+	switch {
+	case r.Method == http.MethodGet && path == "/v1/phone-numbers":
+		route, serve = path, s.lookup
+	case r.Method == http.MethodPost && path == "/v1/phone-numbers/batch":
+		route, feature, serve = path, FeatureBatch, s.batch
+		accepted = []string{MediaTypeJSON, MediaTypeCSV}
+	case r.Method == http.MethodPost && path == "/v1/jobs":
+		route, feature, serve = path, FeatureJobs, s.createJob
+		accepted = []string{MediaTypeJSON}
+	case r.Method == http.MethodGet && jobIDFromPath(path) != "":
+		route, feature, serve = "/v1/jobs/:id", FeatureJobs, s.getJob
+	default:
+		s.notFound(w, r)
+		return
+	}
+	if accepted != nil {
+		serve = s.requireContentType(accepted, serve)
+	}
+	if feature != "" {
+		serve = s.requireFeature(feature, serve)
+	}
+	s.v1(w, r, s.h.basePath+route, requestID, serve)
+}
+
+func (s *stdHandler) requireFeature(feature string, serve func(http.ResponseWriter, *http.Request, lookupScope)) func(http.ResponseWriter, *http.Request, lookupScope) {
+	return func(w http.ResponseWriter, r *http.Request, scope lookupScope) {
+		if errorResponse := s.h.featureUnavailable(feature); errorResponse != nil {
+			writeJSON(w, http.StatusForbidden, errorResponse)
+			return
+		}
+		serve(w, r, scope)
+	}
+}
+
+func (s *stdHandler) requireContentType(accepted []string, serve func(http.ResponseWriter, *http.Request, lookupScope)) func(http.ResponseWriter, *http.Request, lookupScope) {
+	return func(w http.ResponseWriter, r *http.Request, scope lookupScope) {
+		if errorResponse := s.h.contentTypeProblem(r.Header.Get("Content-Type"), accepted); errorResponse != nil {
+			writeJSON(w, http.StatusUnsupportedMediaType, errorResponse)
+			return
+		}
+		serve(w, r, scope)
+	}
+}
+
+// v1 is the gin /v1 group's middleware chain: latency, maintenance, API
+// key, rate limit and traffic accounting.
+func (s *stdHandler) v1(w http.ResponseWriter, r *http.Request, route, requestID string, serve func(http.ResponseWriter, *http.Request, lookupScope)) {
+	h := s.h
+	start := time.Now()
+	defer func() { h.latency.ObserveRoute(route, time.Since(start)) }()
+
+	if body, wait, unavailable := h.unavailability(); unavailable {
+		writeRetryAfter(w, http.StatusServiceUnavailable, wait, body)
+		return
+	}
+
+	scope := lookupScope{
+		ctx:       r.Context(),
+		language:  NegotiateLanguage(r.Header.Get("Accept-Language")),
+		requestID: func() string { return requestID },
+	}
+	if h.apiKeys != nil {
+		config, ok := h.apiKeys.Lookup(r.Header.Get("X-API-Key"))
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"error": map[string]string{
+					"apiKey": "valid API key required",
+				},
+			})
+			return
+		}
+		scope.key, scope.keyLabel = config, config.Label
+		if caseWriter, ok := w.(*stdCaseResponseWriter); ok {
+			caseWriter.keyCase = config.ResponseCase
+		}
+	}
+	if status, retryAfter, body := h.rateLimited(r.Context(), scope.key); status != 0 {
+		writeRetryAfter(w, status, retryAfter, body)
+		return
+	}
+
+	body := &countingReader{ReadCloser: r.Body}
+	if r.Body != nil {
+		r.Body = body
+	}
+	counted := &countingWriter{ResponseWriter: w}
+	serve(counted, r, scope)
+	h.stats.RecordTraffic(time.Now(), scope.keyLabel, body.n, counted.n)
+}
+
+func (s *stdHandler) lookup(w http.ResponseWriter, r *http.Request, scope lookupScope) {
+	h := s.h
+	query := r.URL.Query()
+	if h.exceedsInputLimit(query) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Code:  ErrorInputTooLong,
+			Error: h.mapValidationError(errInputTooLong),
+		})
+		return
+	}
+
+	if len(h.paramAliases) > 0 {
+		messages, _ := h.aliasParams(query)
+		for _, message := range messages {
+			w.Header().Add("Warning", h.deprecation(DeprecationParamAlias, message).headerValue())
+		}
+	}
+
+	var req PhoneValidationRequest
+	if errorResponse := checkLookupQuery(query, func() error { return decodeLookupQuery(query, &req) }); errorResponse != nil {
+		writeJSON(w, http.StatusBadRequest, h.echo(errorResponse))
+		return
+	}
+
+	options, errorResponse := h.requestOptions(query, r.Header, w.Header())
+	if errorResponse != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse)
+		return
+	}
+
+	outcome := h.processLookup(scope, req, options)
+	if status := h.cacheHeader(outcome); status != "" {
+		w.Header().Set("X-Cache", status)
+	}
+	if outcome.errorResponse != nil {
+		writeJSON(w, outcome.status, outcome.errorResponse)
+		return
+	}
+
+	w.Header().Set("Content-Language", scope.language)
+	for _, warning := range outcome.response.WarningDetails {
+		w.Header().Add("Warning", warning.headerValue())
+	}
+	writeJSON(w, outcome.status, outcome.response)
+}
+
+func (s *stdHandler) batch(w http.ResponseWriter, r *http.Request, scope lookupScope) {
+	h := s.h
+	if mediaType(r.Header.Get("Content-Type")) == MediaTypeCSV {
+		batch, err := readCSVBatch(r.Body, r.URL.Query())
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, csvBatchError(err))
+			return
+		}
+		options, errorResponse := h.requestOptions(r.URL.Query(), r.Header, w.Header())
+		if errorResponse != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse)
+			return
+		}
+
+		status, output := h.csvBatchLookup(scope, batch, options)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.WriteHeader(status)
+		writeCSVBatch(w, batch.passthrough, output)
+		return
+	}
+
+	var req BatchRequest
+	if errorResponse := decodeJSONBody(r.Body, &req); errorResponse != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse)
+		return
+	}
+	if problem := batchSizeProblem(len(req.Items)); problem != "" {
+		writeJSON(w, http.StatusBadRequest, fieldError("items", problem))
+		return
+	}
+	options, errorResponse := h.requestOptions(r.URL.Query(), r.Header, w.Header())
+	if errorResponse != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse)
+		return
+	}
+
+	status, response := h.batchLookup(scope, req.Items, options)
+	writeJSON(w, status, response)
+}
+
+func (s *stdHandler) createJob(w http.ResponseWriter, r *http.Request, scope lookupScope) {
+	h := s.h
+	var req BatchRequest
+	if errorResponse := decodeJSONBody(r.Body, &req); errorResponse != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse)
+		return
+	}
+	if problem := jobSizeProblem(len(req.Items)); problem != "" {
+		writeJSON(w, http.StatusBadRequest, fieldError("items", problem))
+		return
+	}
+	options, errorResponse := h.requestOptions(r.URL.Query(), r.Header, w.Header())
+	if errorResponse != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse)
+		return
+	}
+
+	idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+	if problem := idempotencyKeyProblem(idempotencyKey); problem != "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Code: ErrorMalformedRequest, Error: map[string]string{"idempotencyKey": problem}})
+		return
+	}
+
+	progress, replay, rejected := h.submitJob(scope, idempotencyKey, req.Items, options)
+	if rejected != "" {
+		writeJSON(w, ErrorRegistry[rejected].Status, jobError(rejected))
+		return
+	}
+	w.Header().Set("Location", h.basePath+"/v1/jobs/"+progress.ID)
+	if replay {
+		w.Header().Set(IdempotentReplayHeader, "true")
+		writeJSON(w, http.StatusOK, progress)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, progress)
+}
+
+func (s *stdHandler) getJob(w http.ResponseWriter, r *http.Request, scope lookupScope) {
+	response, exists, err := s.h.jobResponse(jobIDFromPath(strings.TrimPrefix(r.URL.Path, s.h.basePath)))
+	if err != nil && !errors.Is(err, errJobReleased) {
+		log.Printf("Failed to read job results: %v", err)
+		writeJSON(w, http.StatusInternalServerError, jobError(ErrorJobResultsUnavailable))
+		return
+	}
+	if !exists || err != nil {
+		writeJSON(w, http.StatusNotFound, fieldError("id", "job not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *stdHandler) notFound(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusNotFound, s.h.routeNotFound(r.URL.Path, s.paths))
+}
+
+// jobIDFromPath returns the id of a /v1/jobs/:id path, or "".
+func jobIDFromPath(path string) string {
+	id, isJob := strings.CutPrefix(path, "/v1/jobs/")
+	if !isJob || strings.Contains(id, "/") {
+		return ""
+	}
+	return id
+}
+
+// decodeLookupQuery sets every form-tagged field of req from query, the
+// way gin's ShouldBindQuery does: the first value wins and an empty bool
+// is false.
+func decodeLookupQuery(query url.Values, req *PhoneValidationRequest) error {
+	value := reflect.ValueOf(req).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name := value.Type().Field(i).Tag.Get("form")
+		values, sent := query[name]
+		if name == "" || !sent || len(values) == 0 {
+			continue
+		}
+
+		field := value.Field(i)
+		switch field.Kind() {
+		case reflect.Bool:
+			if values[0] == "" {
+				field.SetBool(false)
+				continue
+			}
+			parsed, err := strconv.ParseBool(values[0])
+			if err != nil {
+				return err
+			}
+			field.SetBool(parsed)
+		case reflect.String:
+			field.SetString(values[0])
+		}
+	}
+	return nil
+}
+
+// mediaType is gin's Context.ContentType: the header up to its first
+// parameter.
+func mediaType(header string) string {
+	for i, char := range header {
+		if char == ' ' || char == ';' {
+			return header[:i]
+		}
+	}
+	return header
+}
+
+func fieldError(field, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"error": map[string]string{
+			field: message,
+		},
+	}
+}
+
+// writeJSON encodes like gin's Context.JSON, so both adapters send
+// byte-identical bodies.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// writeRetryAfter is abortWithRetryAfter for NewStdHandler.
+func writeRetryAfter(w http.ResponseWriter, status int, wait time.Duration, body map[string]interface{}) {
+	seconds := retryAfterSeconds(wait)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	body["retryAfterSeconds"] = seconds
+	writeJSON(w, status, body)
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
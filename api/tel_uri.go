@@ -0,0 +1,51 @@
+package api
+
+import "strings"
+
+// telScheme starts an RFC 3966 tel URI, matched case-insensitively.
+const telScheme = "tel:"
+
+const ErrorMalformedTelURI ErrorCode = "MALFORMED_TEL_URI"
+
+var errMalformedTelURI = &InputFormatError{Code: ErrorMalformedTelURI, Message: "malformed tel URI"}
+
+// isTelURI reports whether phoneNumber is a tel URI rather than a number.
+func isTelURI(phoneNumber string) bool {
+	return len(phoneNumber) >= len(telScheme) && strings.EqualFold(phoneNumber[:len(telScheme)], telScheme)
+}
+
+// parseTelURI turns an RFC 3966 tel URI into the number the validator
+// reads. A global number ("tel:+1-212-569-0123") is returned as is, its
+// visual separators left to cleaning. A local number takes the digits of a
+// global phone-context as its prefix ("tel:569-0123;phone-context=+1-212");
+// with a domain context, or none, it is read as a national number of
+// countryCode. The ext parameter is returned as extension, without visual
+// separators; other parameters are ignored.
+func parseTelURI(uri string) (number, extension string, err error) {
+	parts := strings.Split(uri[len(telScheme):], ";")
+	number = parts[0]
+	if !strings.ContainsAny(number, "0123456789") {
+		return "", "", errMalformedTelURI
+	}
+
+	var prefix string
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		switch {
+		case strings.EqualFold(name, "ext"):
+			extension = formattingCharacters.Replace(value)
+			if extension == "" || strings.Trim(extension, "0123456789") != "" {
+				return "", "", errMalformedTelURI
+			}
+		case strings.EqualFold(name, "phone-context") && strings.HasPrefix(value, "+"):
+			prefix = formattingCharacters.Replace(value)
+			if len(prefix) < 2 || strings.Trim(prefix[1:], "0123456789") != "" {
+				return "", "", errMalformedTelURI
+			}
+		}
+	}
+	if strings.HasPrefix(number, "+") {
+		return number, extension, nil
+	}
+	return prefix + number, extension, nil
+}
@@ -0,0 +1,82 @@
+package api
+
+import "testing"
+
+func TestPhoneNumberValidator_TelURI(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		countryCode string
+		expected    string
+		errorMsg    string
+	}{
+		{name: "Global number", phoneNumber: "tel:+12125690123", expected: "+12125690123"},
+		{name: "Global number with separators", phoneNumber: "tel:+1-212-569-0123", expected: "+12125690123"},
+		{name: "Global number with dots and parentheses", phoneNumber: "TEL:+44-(20)-7946.0958", expected: "+442079460958"},
+		{name: "Global number ignores phone-context", phoneNumber: "tel:+1-212-569-0123;phone-context=+44", expected: "+12125690123"},
+		{name: "Unknown parameters ignored", phoneNumber: "tel:+1-212-569-0123;ext=42;isub=7;foo", expected: "+12125690123"},
+		{name: "Local number with dialing code context", phoneNumber: "tel:212-569-0123;phone-context=+1", expected: "+12125690123"},
+		{name: "Local number with area code context", phoneNumber: "tel:7946-0958;phone-context=+44-20", expected: "+442079460958"},
+		{name: "Local number with domain context", phoneNumber: "tel:212-569-0123;phone-context=example.com", countryCode: "US", expected: "+12125690123"},
+		{name: "Local number without context", phoneNumber: "tel:91-587-2200", countryCode: "ES", expected: "+34915872200"},
+		{name: "Local number without country", phoneNumber: "tel:212-569-0123", errorMsg: "countryCode is required for numbers without country code"},
+		{name: "No digits", phoneNumber: "tel:", errorMsg: "malformed tel URI"},
+		{name: "Only parameters", phoneNumber: "tel:;phone-context=+1", errorMsg: "malformed tel URI"},
+		{name: "Plus without digits", phoneNumber: "tel:+;ext=1", errorMsg: "malformed tel URI"},
+		{name: "Malformed phone-context", phoneNumber: "tel:569-0123;phone-context=+1-abc", errorMsg: "malformed tel URI"},
+		{name: "Letters in number", phoneNumber: "tel:+1-212-CALL-NOW", errorMsg: "phone number contains invalid characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+			if tt.errorMsg != "" {
+				if err == nil || err.Error() != tt.errorMsg {
+					t.Fatalf("Expected error %q, got %+v, %v", tt.errorMsg, result, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.PhoneNumber != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result.PhoneNumber)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_RFC3966(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		phoneNumber string
+		countryCode string
+		expected    string
+	}{
+		{"+12125690123", "", "tel:+1-212-569-0123"},
+		{"(212) 569-0123", "US", "tel:+1-212-569-0123"},
+		{"+442079460958", "", "tel:+44-2079-460958"},
+		{"915872200", "ES", "tel:+34-91-5872200"},
+		{"tel:+1-212-569-0123;ext=42", "", "tel:+1-212-569-0123;ext=42"},
+		{"tel:569-0123;ext=4-2;phone-context=+1-212", "", "tel:+1-212-569-0123;ext=42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.RFC3966 != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result.RFC3966)
+			}
+		})
+	}
+
+	if _, err := validator.ValidatePhoneNumber("tel:+1-212-569-0123;ext=abc", ""); err == nil || err.Error() != "malformed tel URI" {
+		t.Errorf("Expected a non-digit extension to be malformed, got %v", err)
+	}
+}
@@ -3,7 +3,9 @@ package api
 import (
 	"errors"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 var CountryPhoneLengths = map[string][2]int{
@@ -12,11 +14,110 @@ var CountryPhoneLengths = map[string][2]int{
 	"MX": {10, 10},
 	"ES": {9, 9},
 	"PT": {9, 9},
-	"GB": {10, 11},
-	"FR": {10, 10},
-	"DE": {10, 12},
-	"IT": {9, 11},
+	"GB": {9, 10},
+	"FR": {9, 9},
+	"DE": {8, 12},
+	"IT": {6, 11},
 	"BR": {10, 11},
+	"GP": {9, 9},
+	"GF": {9, 9},
+	"MQ": {9, 9},
+	"RE": {9, 9},
+	"ZA": {9, 9},
+	"NG": {8, 10},
+	"KR": {8, 10},
+}
+
+// NumberTypeLength narrows a country's length range for national numbers
+// starting with LeadingDigits. A non-zero AreaCodeLength overrides the
+// country's NDC length for them.
+type NumberTypeLength struct {
+	Type           NumberType
+	LeadingDigits  string
+	Lengths        [2]int
+	AreaCodeLength int
+}
+
+// Number types. Only landlines are geographic; see isGeographic.
+const (
+	NumberTypeLandline NumberType = "landline"
+	NumberTypeMobile   NumberType = "mobile"
+	NumberTypeTollFree NumberType = "tollfree"
+)
+
+// NumberTypeLengths lists the per-type ranges of countries where mobiles
+// and landlines differ. Numbers matching no rule use CountryPhoneLengths.
+var NumberTypeLengths = map[string][]NumberTypeLength{
+	// Italian numbers keep their leading 0 after +39, as there is no trunk
+	// prefix, and vary in length: older mobiles have 9 digits, and small
+	// districts have landlines as short as 6.
+	"IT": {
+		{Type: NumberTypeMobile, LeadingDigits: "3", Lengths: [2]int{9, 10}, AreaCodeLength: 3},
+		{Type: NumberTypeLandline, LeadingDigits: "0", Lengths: [2]int{6, 11}},
+		{Type: NumberTypeTollFree, LeadingDigits: "800", Lengths: [2]int{9, 10}},
+	},
+	"GB": {
+		{Type: NumberTypeMobile, LeadingDigits: "7", Lengths: [2]int{10, 10}},
+		{Type: NumberTypeTollFree, LeadingDigits: "800", Lengths: [2]int{10, 10}},
+	},
+	"GP": {
+		{Type: NumberTypeLandline, LeadingDigits: "590", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeMobile, LeadingDigits: "690", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeMobile, LeadingDigits: "691", Lengths: [2]int{9, 9}},
+	},
+	"GF": {
+		{Type: NumberTypeLandline, LeadingDigits: "594", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeMobile, LeadingDigits: "694", Lengths: [2]int{9, 9}},
+	},
+	"MQ": {
+		{Type: NumberTypeLandline, LeadingDigits: "596", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeMobile, LeadingDigits: "696", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeMobile, LeadingDigits: "697", Lengths: [2]int{9, 9}},
+	},
+	"RE": {
+		{Type: NumberTypeLandline, LeadingDigits: "262", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeLandline, LeadingDigits: "263", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeMobile, LeadingDigits: "692", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeMobile, LeadingDigits: "693", Lengths: [2]int{9, 9}},
+	},
+	"ZA": {
+		{Type: NumberTypeTollFree, LeadingDigits: "80", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeLandline, LeadingDigits: "1", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeLandline, LeadingDigits: "2", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeLandline, LeadingDigits: "3", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeLandline, LeadingDigits: "4", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeLandline, LeadingDigits: "5", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeMobile, LeadingDigits: "6", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeMobile, LeadingDigits: "7", Lengths: [2]int{9, 9}},
+		{Type: NumberTypeMobile, LeadingDigits: "8", Lengths: [2]int{9, 9}},
+	},
+	// Rules match in order, so the mobile prefixes have to come before the
+	// landline areas sharing their first digit.
+	"NG": {
+		{Type: NumberTypeTollFree, LeadingDigits: "800", Lengths: [2]int{10, 10}},
+		{Type: NumberTypeMobile, LeadingDigits: "70", Lengths: [2]int{10, 10}},
+		{Type: NumberTypeMobile, LeadingDigits: "80", Lengths: [2]int{10, 10}},
+		{Type: NumberTypeMobile, LeadingDigits: "81", Lengths: [2]int{10, 10}},
+		{Type: NumberTypeMobile, LeadingDigits: "90", Lengths: [2]int{10, 10}},
+		{Type: NumberTypeMobile, LeadingDigits: "91", Lengths: [2]int{10, 10}},
+		{Type: NumberTypeLandline, LeadingDigits: "", Lengths: [2]int{8, 8}},
+	},
+	// 011 and 016-019 are pre-2004 carrier prefixes that still turn up in
+	// stored data; only 010 numbers have a fixed length.
+	"KR": {
+		{Type: NumberTypeMobile, LeadingDigits: "10", Lengths: [2]int{10, 10}},
+		{Type: NumberTypeMobile, LeadingDigits: "11", Lengths: [2]int{9, 10}},
+		{Type: NumberTypeMobile, LeadingDigits: "16", Lengths: [2]int{9, 10}},
+		{Type: NumberTypeMobile, LeadingDigits: "17", Lengths: [2]int{9, 10}},
+		{Type: NumberTypeMobile, LeadingDigits: "18", Lengths: [2]int{9, 10}},
+		{Type: NumberTypeMobile, LeadingDigits: "19", Lengths: [2]int{9, 10}},
+		{Type: NumberTypeLandline, LeadingDigits: "2", Lengths: [2]int{8, 9}},
+		{Type: NumberTypeLandline, LeadingDigits: "3", Lengths: [2]int{9, 10}},
+		{Type: NumberTypeLandline, LeadingDigits: "4", Lengths: [2]int{9, 10}},
+		{Type: NumberTypeLandline, LeadingDigits: "5", Lengths: [2]int{9, 10}},
+		{Type: NumberTypeLandline, LeadingDigits: "6", Lengths: [2]int{9, 10}},
+		{Type: NumberTypeTollFree, LeadingDigits: "80", Lengths: [2]int{9, 9}},
+	},
 }
 
 var CountryDialingCodes = map[string]string{
@@ -30,6 +131,13 @@ var CountryDialingCodes = map[string]string{
 	"DE": "49",
 	"IT": "39",
 	"BR": "55",
+	"GP": "590",
+	"GF": "594",
+	"MQ": "596",
+	"RE": "262",
+	"ZA": "27",
+	"NG": "234",
+	"KR": "82",
 }
 
 var DialingCodeToCountry = map[string]string{
@@ -42,34 +150,303 @@ var DialingCodeToCountry = map[string]string{
 	"49":  "DE",
 	"39":  "IT",
 	"55":  "BR",
+	"590": "GP",
+	"594": "GF",
+	"596": "MQ",
+	"262": "RE",
+	"27":  "ZA",
+	"234": "NG",
+	"82":  "KR",
+}
+
+// CountryLeadingDigits lists the digits a national significant number may
+// start with, for countries whose numbering plan never allocates the rest.
+var CountryLeadingDigits = map[string]string{
+	"US": "23456789",
+	"CA": "23456789",
+	"ES": "23456789",
+	"FR": "123456789",
+	"IT": "0134578",
+	"GP": "56",
+	"GF": "56",
+	"MQ": "56",
+	"RE": "26",
+	"ZA": "12345678",
+	"NG": "123456789",
+	"KR": "1234568",
+}
+
+// CountryTrunkPrefixes is the prefix dialed before a national number
+// inside the country. It is dropped from national input, so
+// 0821234567 with countryCode=ZA reads as +27 82 123 4567, and added back
+// to areaCode with areaCodeStyle=national.
+var CountryTrunkPrefixes = map[string]string{
+	"GB": "0",
+	"FR": "0",
+	"DE": "0",
+	"ZA": "0",
+	"NG": "0",
+	"KR": "0",
+}
+
+// CountryAreaCodeLengths is the fixed NDC length of each country whose
+// numbers are split; countries without an entry return no NDC. NG and KR
+// vary by prefix, see splitNationalNumber.
+var CountryAreaCodeLengths = map[string]int{
+	"US": 3,
+	"CA": 3,
+	"MX": 3,
+	"ES": 2,
+	"PT": 2,
+	"FR": 1,
+	"IT": 2,
+	"BR": 2,
+	"GB": 4,
+	"DE": 2,
+	"GP": 3,
+	"GF": 3,
+	"MQ": 3,
+	"RE": 3,
+	"ZA": 2,
+	"NG": 2,
+	"KR": 2,
+}
+
+// CountryIDDPrefixes is the international (IDD) prefix dialed from each
+// country before a foreign country code. Where carriers use different
+// prefixes, as in KR, the most common one is listed; BR is absent because
+// its prefix embeds a carrier selection code.
+var CountryIDDPrefixes = map[string]string{
+	"US": "011",
+	"CA": "011",
+	"MX": "00",
+	"ES": "00",
+	"PT": "00",
+	"GB": "00",
+	"FR": "00",
+	"DE": "00",
+	"IT": "00",
+	"GP": "00",
+	"GF": "00",
+	"MQ": "00",
+	"RE": "00",
+	"ZA": "00",
+	"NG": "009",
+	"KR": "001",
 }
 
 type PhoneValidationRequest struct {
-	PhoneNumber string `form:"phoneNumber" json:"phoneNumber"`
-	CountryCode string `form:"countryCode" json:"countryCode"`
+	PhoneNumber   string `form:"phoneNumber" json:"phoneNumber"`
+	CountryCode   string `form:"countryCode" json:"countryCode"`
+	Enum          bool   `form:"enum" json:"enum,omitempty"`
+	Lenient       bool   `form:"lenient" json:"lenient,omitempty"`
+	Truncate      bool   `form:"truncate" json:"truncate,omitempty"`
+	InputFormat   string `form:"inputFormat" json:"inputFormat,omitempty"`
+	Strict        bool   `form:"strict" json:"strict,omitempty"`
+	AreaCodeStyle string `form:"areaCodeStyle" json:"areaCodeStyle,omitempty"`
+	CallWindow    bool   `form:"callWindow" json:"callWindow,omitempty"`
+	SourceType    string `form:"sourceType" json:"sourceType,omitempty"`
+	Porting       bool   `form:"porting" json:"porting,omitempty"`
+	CountryHints  string `form:"countryHints" json:"countryHints,omitempty"`
 }
 
+func (r PhoneValidationRequest) parseOptions() ParseOptions {
+	return ParseOptions{
+		Lenient:       r.Lenient,
+		Truncate:      r.Truncate,
+		StrictE164:    r.Strict || strings.EqualFold(r.InputFormat, InputFormatE164),
+		AreaCodeStyle: strings.ToLower(r.AreaCodeStyle),
+		NumericSource: strings.EqualFold(r.SourceType, SourceTypeNumeric),
+	}
+}
+
+// PhoneValidationResponse splits the national number into NDC and
+// LocalPhoneNumber. NDC, the national destination code, is always set: a
+// geographic area code, a mobile operator range or a toll-free prefix.
+// AreaCode repeats it, styled by areaCodeStyle, only when the number is
+// geographic, i.e. a landline or a number matching no type rule. Ported
+// and PortedToCarrier are only set with porting=true. CountryCodeSource is
+// CountryCodeSourceHint when the country came from countryHints and
+// CountryCodeSourcePreference when it came from WithCountryPreference.
+// NationalFormat is the number as written inside its country, see
+// CountryNationalFormats.
 type PhoneValidationResponse struct {
-	PhoneNumber      string `json:"phoneNumber"`
-	CountryCode      string `json:"countryCode"`
-	AreaCode         string `json:"areaCode"`
-	LocalPhoneNumber string `json:"localPhoneNumber"`
+	PhoneNumber       string      `json:"phoneNumber"`
+	CountryCode       string      `json:"countryCode"`
+	CountryName       string      `json:"countryName"`
+	NDC               string      `json:"ndc"`
+	AreaCode          string      `json:"areaCode"`
+	LocalPhoneNumber  string      `json:"localPhoneNumber"`
+	AreaCodeName      string      `json:"areaCodeName"`
+	RFC3966           string      `json:"rfc3966"`
+	NationalFormat    string      `json:"nationalFormat"`
+	Enum              *EnumResult `json:"enum,omitempty"`
+	CallWindow        *CallWindow `json:"callWindow,omitempty"`
+	Ported            *bool       `json:"ported,omitempty"`
+	PortedToCarrier   string      `json:"portedToCarrier,omitempty"`
+	CountryCodeSource string      `json:"countryCodeSource,omitempty"`
+	Warnings          []string    `json:"warnings,omitempty"`
+	WarningDetails    []Warning   `json:"warningDetails,omitempty"`
+	// Deprecated: the same digits are the detail of the
+	// TRAILING_DIGITS_TRUNCATED entry in WarningDetails.
+	TruncatedDigits string `json:"truncatedDigits,omitempty"`
+}
+
+// CallWindow is set on a lookup response when callWindow=true. Timezone is
+// the candidate zone with the least slack: for numbers that may be in
+// several zones it is one outside calling hours if any is, else the one
+// closest to a boundary, so WithinCallingHours holds in every zone.
+type CallWindow struct {
+	Timezone           string `json:"timezone"`
+	UTCOffsetMinutes   int    `json:"utcOffsetMinutes"`
+	LocalTime          string `json:"localTime"`
+	WithinCallingHours bool   `json:"withinCallingHours"`
+}
+
+type EnumRecord struct {
+	Service    string `json:"service"`
+	URI        string `json:"uri"`
+	Order      uint16 `json:"order"`
+	Preference uint16 `json:"preference"`
+}
+
+type EnumResult struct {
+	Domain  string       `json:"domain"`
+	Records []EnumRecord `json:"records"`
 }
 
+// ErrorResponse is the body of every failed lookup. ExpectedMin,
+// ExpectedMax, Actual and ExampleNumber are only set with code
+// LENGTH_OUT_OF_RANGE, HintFailures when no countryHints entry validated
+// a national number, Accepted, the media types a route reads, with
+// UNSUPPORTED_MEDIA_TYPE, and RegionCode, the region the ITU assigned the
+// dialing code to, with UNSUPPORTED_COUNTRY.
 type ErrorResponse struct {
-	PhoneNumber string            `json:"phoneNumber"`
-	Error       map[string]string `json:"error"`
+	PhoneNumber   string               `json:"phoneNumber"`
+	Code          ErrorCode            `json:"code,omitempty"`
+	Error         map[string]string    `json:"error"`
+	FieldPath     string               `json:"fieldPath,omitempty"`
+	Received      map[string][]string  `json:"received,omitempty"`
+	Offset        *int64               `json:"offset,omitempty"`
+	ExpectedMin   int                  `json:"expectedMin,omitempty"`
+	ExpectedMax   int                  `json:"expectedMax,omitempty"`
+	Actual        int                  `json:"actual,omitempty"`
+	ExampleNumber string               `json:"exampleNumber,omitempty"`
+	HintFailures  []CountryHintFailure `json:"hintFailures,omitempty"`
+	Accepted      []string             `json:"accepted,omitempty"`
+	RegionCode    string               `json:"regionCode,omitempty"`
+}
+
+// CountryHintFailure is why a countryHints entry did not validate the
+// number: the code the lookup would fail with under that country.
+type CountryHintFailure struct {
+	CountryCode string `json:"countryCode"`
+	Reason      string `json:"reason"`
 }
 
-type PhoneNumberValidator struct{}
+const (
+	DefaultMaxInputLength = 64
+	MaxE164Digits         = 15
+	// MinimalNationalLength is the shortest national number the minimal
+	// table accepts.
+	MinimalNationalLength = 4
+)
 
-func NewPhoneNumberValidator() *PhoneNumberValidator {
-	return &PhoneNumberValidator{}
+type PhoneNumberValidator struct {
+	maxInputLength    int
+	disabledCountries *CountryToggle
+	metadata          map[string]CountryMetadata
+	source            *MetadataSource
+	truncateTooLong   bool
+	strictE164        bool
+	// suspiciousPatterns is a SuspiciousPatterns mode; empty follows
+	// ParseOptions.Lenient.
+	suspiciousPatterns string
+}
+
+type ValidatorOption func(*PhoneNumberValidator)
+
+func WithMaxInputLength(n int) ValidatorOption {
+	return func(v *PhoneNumberValidator) {
+		if n > 0 {
+			v.maxInputLength = n
+		}
+	}
+}
+
+// WithTruncateTooLong makes every lookup behave as if it asked for
+// truncate=true.
+func WithTruncateTooLong() ValidatorOption {
+	return func(v *PhoneNumberValidator) {
+		v.truncateTooLong = true
+	}
+}
+
+func WithDisabledCountries(codes ...string) ValidatorOption {
+	return func(v *PhoneNumberValidator) {
+		v.disabledCountries.Set(codes)
+	}
+}
+
+func NewPhoneNumberValidator(opts ...ValidatorOption) *PhoneNumberValidator {
+	v := &PhoneNumberValidator{
+		maxInputLength:    DefaultMaxInputLength,
+		disabledCountries: NewCountryToggle(),
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+func (v *PhoneNumberValidator) MaxInputLength() int {
+	return v.maxInputLength
+}
+
+func (v *PhoneNumberValidator) DisabledCountries() *CountryToggle {
+	return v.disabledCountries
 }
 
 func (v *PhoneNumberValidator) ValidatePhoneNumber(phoneNumber, countryCode string) (*PhoneValidationResponse, error) {
+	return v.ValidatePhoneNumberWithOptions(phoneNumber, countryCode, ParseOptions{})
+}
+
+func (v *PhoneNumberValidator) ValidatePhoneNumberWithOptions(phoneNumber, countryCode string, opts ParseOptions) (*PhoneValidationResponse, error) {
 	if phoneNumber == "" {
-		return nil, errors.New("phoneNumber is required")
+		return nil, errPhoneNumberRequired
+	}
+
+	// Strict E.164 input bounds its own length, so it skips the digit-count
+	// check below and always fails with NOT_E164.
+	if opts.StrictE164 || v.strictE164 {
+		return v.validateStrictE164(phoneNumber, opts)
+	}
+
+	if err := v.validateInputSize(phoneNumber); err != nil {
+		return nil, err
+	}
+
+	var extension string
+	if isTelURI(phoneNumber) {
+		var err error
+		if phoneNumber, extension, err = parseTelURI(phoneNumber); err != nil {
+			return nil, err
+		}
+	}
+
+	// Numbers from integer columns get the lenient cleaning spreadsheets
+	// need as well.
+	if opts.NumericSource {
+		opts.Lenient = true
+	}
+
+	phoneNumber, warnings, err := normalizeInput(phoneNumber, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if phoneNumber, err = stripFormatting(phoneNumber); err != nil {
+		return nil, err
 	}
 
 	if err := v.validateSpacing(phoneNumber); err != nil {
@@ -81,33 +458,206 @@ func (v *PhoneNumberValidator) ValidatePhoneNumber(phoneNumber, countryCode stri
 		return nil, err
 	}
 
-	extractedCountryCode, areaCode, localNumber, err := v.parsePhoneNumber(cleanedNumber, countryCode)
+	if opts.NumericSource && countryCode != "" {
+		if cleanedNumber, err = v.restoreLeadingDigit(cleanedNumber, countryCode, &warnings); err != nil {
+			return nil, err
+		}
+	}
+
+	extractedCountryCode, nationalNumber, err := v.parsePhoneNumber(cleanedNumber, countryCode, &warnings)
 	if err != nil {
 		return nil, err
 	}
 
+	response, err := v.validateNationalNumber(extractedCountryCode, nationalNumber, opts, warnings)
+	if err == nil && extension != "" {
+		response.RFC3966 += ";ext=" + extension
+	}
+	return response, err
+}
+
+// validateNationalNumber checks a parsed number against its country's
+// rules and splits it into components.
+func (v *PhoneNumberValidator) validateNationalNumber(extractedCountryCode, nationalNumber string, opts ParseOptions, warnings warningSet) (*PhoneValidationResponse, error) {
+	var truncatedDigits string
+
 	if err := v.validateCountryCode(extractedCountryCode); err != nil {
 		return nil, err
 	}
 
-	if err := v.validatePhoneLength(areaCode+localNumber, extractedCountryCode); err != nil {
+	if v.disabledCountries.IsDisabled(extractedCountryCode) {
+		return nil, errCountryDisabled
+	}
+
+	if nationalNumber == "" {
+		return nil, errMissingSubscriberNumber
+	}
+
+	if v.MetadataDegraded() {
+		return v.validateMinimal(extractedCountryCode, nationalNumber, opts, warnings)
+	}
+
+	// Length is checked on the full national significant number; splitting
+	// is only attempted once the length is known to be valid.
+	if err := v.validatePhoneLength(nationalNumber, extractedCountryCode); err != nil {
+		if !v.truncateTooLong && !opts.Truncate {
+			return nil, err
+		}
+		truncated, truncErr := v.truncateNationalNumber(nationalNumber, extractedCountryCode, err)
+		if truncErr != nil {
+			return nil, truncErr
+		}
+		truncatedDigits = nationalNumber[len(truncated):]
+		nationalNumber = truncated
+		warnings.add(WarningTrailingDigitsTruncated, truncatedDigits)
+	}
+
+	if err := v.checkSuspiciousPattern(nationalNumber, opts, &warnings); err != nil {
+		return nil, err
+	}
+
+	if err := v.validateLeadingDigit(nationalNumber, extractedCountryCode); err != nil {
+		return nil, err
+	}
+
+	ndc, localNumber, err := v.splitNationalNumber(nationalNumber, extractedCountryCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSplit(extractedCountryCode, nationalNumber, ndc, localNumber); err != nil {
 		return nil, err
 	}
 
 	response := &PhoneValidationResponse{
-		PhoneNumber:      v.formatPhoneNumber(extractedCountryCode, areaCode, localNumber),
+		PhoneNumber:      v.formatPhoneNumber(extractedCountryCode, ndc, localNumber),
 		CountryCode:      extractedCountryCode,
-		AreaCode:         areaCode,
+		CountryName:      CountryName(extractedCountryCode, DefaultLanguage),
+		NDC:              ndc,
 		LocalPhoneNumber: localNumber,
+		AreaCodeName:     AreaCodeName(extractedCountryCode, nationalNumber),
+		RFC3966:          v.formatRFC3966(extractedCountryCode, ndc, localNumber),
+		NationalFormat:   v.formatNational(extractedCountryCode, nationalNumber, ndc, localNumber),
+		TruncatedDigits:  truncatedDigits,
+	}
+	if v.isGeographic(nationalNumber, extractedCountryCode) {
+		response.AreaCode = formatAreaCode(extractedCountryCode, ndc, opts.AreaCodeStyle)
+	}
+	response.setWarnings(warnings)
+
+	return response, nil
+}
+
+// validateMinimal checks a number against the minimal table alone: its
+// dialing code and E.164's length bounds. Nothing richer is applied, so the
+// response has no NDC, area code or area code name.
+func (v *PhoneNumberValidator) validateMinimal(countryCode, nationalNumber string, opts ParseOptions, warnings warningSet) (*PhoneValidationResponse, error) {
+	maxLength := MaxE164Digits - len(CountryDialingCodes[countryCode])
+	if actualLength := len(nationalNumber); actualLength < MinimalNationalLength || actualLength > maxLength {
+		return nil, &LengthError{CountryCode: countryCode, ExpectedMin: MinimalNationalLength, ExpectedMax: maxLength, Actual: actualLength}
+	}
+
+	if err := v.checkSuspiciousPattern(nationalNumber, opts, &warnings); err != nil {
+		return nil, err
 	}
 
+	warnings.add(WarningDegradedValidation, "")
+	response := &PhoneValidationResponse{
+		PhoneNumber:      v.formatPhoneNumber(countryCode, "", nationalNumber),
+		CountryCode:      countryCode,
+		CountryName:      CountryName(countryCode, DefaultLanguage),
+		LocalPhoneNumber: nationalNumber,
+		RFC3966:          v.formatRFC3966(countryCode, "", nationalNumber),
+		NationalFormat:   nationalNumber,
+	}
+	response.setWarnings(warnings)
 	return response, nil
 }
 
+const (
+	// ErrorInputTooLong is input over the length cap, or with more digits
+	// than E.164 allows, rejected before any parsing.
+	ErrorInputTooLong           ErrorCode = "INPUT_TOO_LONG"
+	ErrorPhoneNumberRequired    ErrorCode = "PHONE_NUMBER_REQUIRED"
+	ErrorCountryCodeRequired    ErrorCode = "COUNTRY_CODE_REQUIRED"
+	ErrorInvalidCountryCode     ErrorCode = "INVALID_COUNTRY_CODE"
+	ErrorUnsupportedCountryCode ErrorCode = "UNSUPPORTED_COUNTRY_CODE"
+	ErrorCountryDisabled        ErrorCode = "COUNTRY_DISABLED"
+	ErrorInvalidSpacing         ErrorCode = "INVALID_SPACING"
+	ErrorDialingPrefixOnly      ErrorCode = "DIALING_PREFIX_ONLY"
+	ErrorAreaCodeUndetermined   ErrorCode = "AREA_CODE_UNDETERMINED"
+)
+
+var (
+	errInputTooLong        = &InputFormatError{Code: ErrorInputTooLong, Message: "phone number input is too long"}
+	errPhoneNumberRequired = &InputFormatError{Code: ErrorPhoneNumberRequired, Message: "phoneNumber is required"}
+	// errCountryRequired is a national number validated without a country.
+	errCountryRequired    = &InputFormatError{Code: ErrorCountryCodeRequired, Message: "countryCode is required for numbers without country code"}
+	errInvalidCountryCode = &InputFormatError{Code: ErrorInvalidCountryCode, Message: "country code must be 2 characters (ISO 3166-1 alpha-2)"}
+	errUnsupportedCountry = &InputFormatError{Code: ErrorUnsupportedCountryCode, Message: "unsupported country code"}
+	errCountryDisabled    = &InputFormatError{Code: ErrorCountryDisabled, Message: "country is disabled"}
+	errInvalidSpacing     = &InputFormatError{Code: ErrorInvalidSpacing, Message: "invalid spacing pattern"}
+)
+
+// validateInputSize runs before any regex or prefix work so oversized input
+// is rejected in constant time relative to the cap.
+func (v *PhoneNumberValidator) validateInputSize(phoneNumber string) error {
+	if len(phoneNumber) > v.maxInputLength {
+		return errInputTooLong
+	}
+
+	digits := 0
+	for i := 0; i < len(phoneNumber); i++ {
+		if phoneNumber[i] >= '0' && phoneNumber[i] <= '9' {
+			digits++
+		}
+	}
+	if digits > MaxE164Digits {
+		return errInputTooLong
+	}
+
+	return nil
+}
+
+var (
+	validCharsOnce    sync.Once
+	validCharsPattern *regexp.Regexp
+)
+
+func validChars() *regexp.Regexp {
+	validCharsOnce.Do(func() {
+		validCharsPattern = regexp.MustCompile(`^[\d\s+]+$`)
+	})
+	return validCharsPattern
+}
+
+// WarmUp builds the lazily initialised lookup structures and validates the
+// example number of every enabled country, so the first real request pays
+// no setup cost and a broken country table is caught at startup.
+func (v *PhoneNumberValidator) WarmUp() error {
+	validChars()
+	ituTrie()
+
+	for countryCode := range CountryPhoneLengths {
+		if v.disabledCountries.IsDisabled(countryCode) {
+			continue
+		}
+		number, exists := ExampleNumber(countryCode)
+		if !exists {
+			return errors.New("no example number for country " + countryCode)
+		}
+		// CA shares +1 with US, so its example resolves to US and can hit
+		// the disabled check even though CA itself is enabled.
+		if _, err := v.ValidatePhoneNumber(number, ""); err != nil && err.Error() != "country is disabled" {
+			return errors.New("example number for country " + countryCode + " is invalid: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
 func (v *PhoneNumberValidator) cleanPhoneNumber(phoneNumber string) (string, error) {
-	validChars := regexp.MustCompile(`^[\d\s+]+$`)
-	if !validChars.MatchString(phoneNumber) {
-		return "", errors.New("phone number contains invalid characters")
+	if !validChars().MatchString(phoneNumber) {
+		return "", errInvalidCharacters
 	}
 
 	cleaned := strings.ReplaceAll(phoneNumber, " ", "")
@@ -115,46 +665,121 @@ func (v *PhoneNumberValidator) cleanPhoneNumber(phoneNumber string) (string, err
 	return cleaned, nil
 }
 
-func (v *PhoneNumberValidator) parsePhoneNumber(phoneNumber, providedCountryCode string) (string, string, string, error) {
+// parsePhoneNumber resolves the country and national number. With a plus
+// the dialing code decides, and a provided country with another dialing
+// code only raises COUNTRY_CODE_MISMATCH. Without a plus a provided country
+// decides: digits that start with a different country's dialing code are
+// read as its national number, and only its own dialing code can be read
+// as international. An international dialing prefix, see iddPrefix, is
+// read as a plus.
+func (v *PhoneNumberValidator) parsePhoneNumber(phoneNumber, providedCountryCode string, warnings *warningSet) (string, string, error) {
 	hasPlus := strings.HasPrefix(phoneNumber, "+")
 	if hasPlus {
 		phoneNumber = phoneNumber[1:]
+	} else if prefix := iddPrefix(phoneNumber, providedCountryCode); prefix != "" {
+		phoneNumber, hasPlus = phoneNumber[len(prefix):], true
+		if phoneNumber == "" {
+			return "", "", errBareIDDPrefix
+		}
 	}
 
 	var countryCode string
 	var nationalNumber string
 
-	if hasPlus || v.hasDialingCode(phoneNumber) {
+	if hasPlus || (v.hasDialingCode(phoneNumber) && v.dialsProvidedCountry(phoneNumber, providedCountryCode)) {
 		dialingCode, remaining, err := v.extractDialingCode(phoneNumber)
 		if err != nil {
-			return "", "", "", err
+			return "", "", err
 		}
 		
 		country, exists := DialingCodeToCountry[dialingCode]
 		if !exists {
-			return "", "", "", errors.New("unsupported country dialing code")
+			return "", "", unsupportedDialingCode(phoneNumber)
+		}
+
+		if hasPlus && providedCountryCode != "" && CountryDialingCodes[strings.ToUpper(providedCountryCode)] != dialingCode {
+			warnings.add(WarningCountryCodeMismatch, providedCountryCode)
+		}
+
+		// Without a plus, a national number can start with its own
+		// country's dialing code: overseas French numbers repeat it
+		// (+590 590 27 12 34) and Italian mobiles start with 39
+		// (+39 391 234 5678). Prefer the provided country when that
+		// reading is a valid number there.
+		if !hasPlus && providedCountryCode != "" && v.validNationalReading(phoneNumber, providedCountryCode) {
+			return providedCountryCode, phoneNumber, nil
 		}
 		
 		countryCode = country
 		nationalNumber = remaining
 	} else {
 		if providedCountryCode == "" {
-			return "", "", "", errors.New("countryCode is required for numbers without country code")
+			return "", "", errCountryRequired
 		}
 		countryCode = providedCountryCode
-		nationalNumber = phoneNumber
+		nationalNumber = v.stripCarrierSelectionCode(countryCode, phoneNumber, warnings)
+		if trunkPrefix, exists := CountryTrunkPrefixes[strings.ToUpper(countryCode)]; exists {
+			nationalNumber = strings.TrimPrefix(nationalNumber, trunkPrefix)
+		}
 	}
 
-	areaCode, localNumber := v.splitNationalNumber(nationalNumber, countryCode)
-	
-	return countryCode, areaCode, localNumber, nil
+	return countryCode, nationalNumber, nil
+}
+
+var errBareIDDPrefix = &InputFormatError{Code: ErrorDialingPrefixOnly, Message: "international dialing prefix without a number"}
+
+// iddPrefix returns the international dialing prefix digits start with, or
+// "". Without a country, 00 and 011 are recognised, as copied from
+// European and US address books. With one, only that country's own prefix
+// is, so a national number such as Leeds' 0113 496 0000 with countryCode
+// GB is not mistaken for 011.
+func iddPrefix(digits, providedCountryCode string) string {
+	if providedCountryCode != "" {
+		prefix := CountryIDDPrefixes[strings.ToUpper(providedCountryCode)]
+		if prefix != "" && strings.HasPrefix(digits, prefix) {
+			return prefix
+		}
+		return ""
+	}
+	for _, prefix := range []string{"011", "00"} {
+		if strings.HasPrefix(digits, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// dialsProvidedCountry reports whether digits without a plus may be read
+// as international: no country was provided, or the digits start with its
+// own dialing code.
+func (v *PhoneNumberValidator) dialsProvidedCountry(digits, providedCountryCode string) bool {
+	if providedCountryCode == "" {
+		return true
+	}
+	dialingCode, _, err := v.extractDialingCode(digits)
+	return err == nil && CountryDialingCodes[strings.ToUpper(providedCountryCode)] == dialingCode
+}
+
+// validNationalReading reports whether digits pass the length, leading
+// digit and split checks as a national number of countryCode.
+func (v *PhoneNumberValidator) validNationalReading(digits, countryCode string) bool {
+	if v.validatePhoneLength(digits, countryCode) != nil || v.validateLeadingDigit(digits, countryCode) != nil {
+		return false
+	}
+	ndc, localNumber, err := v.splitNationalNumber(digits, countryCode)
+	return err == nil && checkSplit(countryCode, digits, ndc, localNumber) == nil
+}
+
+func (v *PhoneNumberValidator) lengthFits(nationalNumber, countryCode string) bool {
+	lengths, exists := v.countryLengths(countryCode)
+	return exists && len(nationalNumber) >= lengths[0] && len(nationalNumber) <= lengths[1]
 }
 
 func (v *PhoneNumberValidator) validateSpacing(originalPhoneNumber string) error {
 	if strings.Contains(originalPhoneNumber, " ") {
 		parts := strings.Split(originalPhoneNumber, " ")
 		if len(parts) == 4 {
-			return errors.New("invalid spacing pattern")
+			return errInvalidSpacing
 		}
 	}
 	
@@ -192,58 +817,280 @@ func (v *PhoneNumberValidator) extractDialingCode(phoneNumber string) (string, s
 		}
 	}
 
-	return "", "", errors.New("unable to extract dialing code")
+	return "", "", unsupportedDialingCode(phoneNumber)
+}
+
+// NationalNumberSplitError reports a national number too short for its
+// country's area code rule.
+type NationalNumberSplitError struct {
+	CountryCode    string
+	NationalNumber string
+}
+
+func (e *NationalNumberSplitError) Error() string {
+	return "unable to split national number for country " + e.CountryCode
+}
+
+// isGeographic reports whether nationalNumber has an area code: it is a
+// landline, or its country has no type rule for it.
+func (v *PhoneNumberValidator) isGeographic(nationalNumber, countryCode string) bool {
+	numberType, _, typed, _ := v.lengthRange(nationalNumber, countryCode)
+	return !typed || numberType == NumberTypeLandline
 }
 
-func (v *PhoneNumberValidator) splitNationalNumber(nationalNumber, countryCode string) (string, string) {
-	if len(nationalNumber) >= 3 {
-		switch countryCode {
-		case "US", "CA", "MX":
-			return nationalNumber[:3], nationalNumber[3:]
-		case "ES", "PT", "FR", "IT", "BR":
-			return nationalNumber[:2], nationalNumber[2:]
-		case "GB":
-			if len(nationalNumber) >= 4 {
-				return nationalNumber[:4], nationalNumber[4:]
-			}
-			return nationalNumber[:3], nationalNumber[3:]
-		case "DE":
-			return nationalNumber[:3], nationalNumber[3:]
+// splitNationalNumber splits off the NDC. Toll-free numbers split after
+// their type rule's prefix; everything else follows the country's rule.
+func (v *PhoneNumberValidator) splitNationalNumber(nationalNumber, countryCode string) (string, string, error) {
+	rule, typed := numberTypeRule(nationalNumber, countryCode)
+	if typed && rule.Type == NumberTypeTollFree {
+		return nationalNumber[:len(rule.LeadingDigits)], nationalNumber[len(rule.LeadingDigits):], nil
+	}
+	areaCodeLength, exists := CountryAreaCodeLengths[countryCode]
+	if !exists {
+		return "", nationalNumber, nil
+	}
+	if typed && rule.AreaCodeLength != 0 {
+		areaCodeLength = rule.AreaCodeLength
+	}
+	switch countryCode {
+	case "NG":
+		areaCodeLength = nigerianAreaCodeLength(nationalNumber)
+	case "KR":
+		// Seoul is the only one-digit area code; mobile carrier
+		// prefixes (10, 11, 16-19) split like the other areas.
+		if strings.HasPrefix(nationalNumber, "2") {
+			areaCodeLength = 1
 		}
 	}
-	
-	return "", nationalNumber
+
+	if areaCodeLength < 0 || len(nationalNumber) <= areaCodeLength {
+		return "", "", &NationalNumberSplitError{CountryCode: countryCode, NationalNumber: nationalNumber}
+	}
+
+	return nationalNumber[:areaCodeLength], nationalNumber[areaCodeLength:], nil
+}
+
+// SplitInvariantError reports a split rule that lost, duplicated or
+// invented digits. It is a bug in the rule, never in the input.
+type SplitInvariantError struct {
+	CountryCode      string
+	NationalNumber   string
+	AreaCode         string
+	LocalPhoneNumber string
+}
+
+func (e *SplitInvariantError) Error() string {
+	return "split rule for " + e.CountryCode + " broke " + e.NationalNumber + " into " + strconv.Quote(e.AreaCode) + " and " + strconv.Quote(e.LocalPhoneNumber)
+}
+
+// checkSplit enforces that areaCode+localNumber reconstructs the national
+// significant number and that both parts are digits only.
+func checkSplit(countryCode, nationalNumber, areaCode, localNumber string) error {
+	if areaCode+localNumber == nationalNumber && localNumber != "" && strings.Trim(nationalNumber, "0123456789") == "" {
+		return nil
+	}
+	return &SplitInvariantError{CountryCode: countryCode, NationalNumber: nationalNumber, AreaCode: areaCode, LocalPhoneNumber: localNumber}
+}
+
+// nigerianAreaCodeLength splits mobiles after their three-digit network
+// prefix, Lagos (1) and Abuja (9) after one digit and other landline
+// areas after two.
+func nigerianAreaCodeLength(nationalNumber string) int {
+	if numberType, _, _ := classifyNumberType(nationalNumber, "NG"); numberType == NumberTypeMobile {
+		return 3
+	}
+	if strings.HasPrefix(nationalNumber, "1") || strings.HasPrefix(nationalNumber, "9") {
+		return 1
+	}
+	return 2
 }
 
 func (v *PhoneNumberValidator) validateCountryCode(countryCode string) error {
 	if len(countryCode) != 2 {
-		return errors.New("country code must be 2 characters (ISO 3166-1 alpha-2)")
+		return errInvalidCountryCode
 	}
 
 	if _, exists := CountryPhoneLengths[countryCode]; !exists {
-		return errors.New("unsupported country code")
+		return errUnsupportedCountry
 	}
 
 	return nil
 }
 
 func (v *PhoneNumberValidator) validatePhoneLength(nationalNumber, countryCode string) error {
-	lengths, exists := CountryPhoneLengths[countryCode]
+	numberType, lengths, typed, exists := v.lengthRange(nationalNumber, countryCode)
+	if !exists {
+		return errUnsupportedCountry
+	}
+
+	if actualLength := len(nationalNumber); actualLength < lengths[0] || actualLength > lengths[1] {
+		lengthErr := &LengthError{CountryCode: countryCode, ExpectedMin: lengths[0], ExpectedMax: lengths[1], Actual: actualLength}
+		if typed {
+			lengthErr.NumberType = numberType
+		}
+		return lengthErr
+	}
+
+	return nil
+}
+
+// LengthError reports a national number outside the length range of its
+// country, or of its number type when NumberType is set. Ambiguous marks a
+// too-long number that several shorter prefixes would fix.
+type LengthError struct {
+	CountryCode string
+	NumberType  NumberType
+	ExpectedMin int
+	ExpectedMax int
+	Actual      int
+	Ambiguous   bool
+}
+
+func (e *LengthError) Error() string {
+	message := "phone number length is invalid for country " + e.CountryCode
+	if detail := e.Detail(); detail != "" {
+		message += ": " + detail
+	}
+	return message
+}
+
+// Detail is the explanation after the country, or "" for a plain
+// country-range error.
+func (e *LengthError) Detail() string {
+	switch {
+	case e.Ambiguous:
+		return "truncation is ambiguous"
+	case e.NumberType == "":
+		return ""
+	}
+	expected := strconv.Itoa(e.ExpectedMin)
+	if e.ExpectedMax != e.ExpectedMin {
+		expected += " to " + strconv.Itoa(e.ExpectedMax)
+	}
+	return string(e.NumberType) + " numbers must have " + expected + " digits"
+}
+
+// truncateNationalNumber drops trailing digits from a too-long national
+// number. It only succeeds when exactly one prefix passes the length,
+// leading-digit and split rules; otherwise lengthErr stands, with the
+// ambiguity spelled out when several prefixes would do.
+func (v *PhoneNumberValidator) truncateNationalNumber(nationalNumber, countryCode string, lengthErr error) (string, error) {
+	if _, lengths, _, _ := v.lengthRange(nationalNumber, countryCode); len(nationalNumber) <= lengths[1] {
+		return "", lengthErr
+	}
+
+	var match string
+	matches := 0
+	for n := len(nationalNumber) - 1; n > 0; n-- {
+		prefix := nationalNumber[:n]
+		if v.validatePhoneLength(prefix, countryCode) != nil || v.validateLeadingDigit(prefix, countryCode) != nil {
+			continue
+		}
+		if _, _, err := v.splitNationalNumber(prefix, countryCode); err != nil {
+			continue
+		}
+		match = prefix
+		matches++
+	}
+
+	switch matches {
+	case 0:
+		return "", lengthErr
+	case 1:
+		return match, nil
+	}
+	ambiguous := &LengthError{CountryCode: countryCode, Actual: len(nationalNumber), Ambiguous: true}
+	var rangeErr *LengthError
+	if errors.As(lengthErr, &rangeErr) {
+		ambiguous.NumberType, ambiguous.ExpectedMin, ambiguous.ExpectedMax = rangeErr.NumberType, rangeErr.ExpectedMin, rangeErr.ExpectedMax
+	}
+	return "", ambiguous
+}
+
+// lengthRange is the length range that applies to nationalNumber: its
+// number type's range when it has one, else the country's.
+func (v *PhoneNumberValidator) lengthRange(nationalNumber, countryCode string) (NumberType, [2]int, bool, bool) {
+	lengths, exists := v.countryLengths(countryCode)
 	if !exists {
-		return errors.New("unsupported country code")
+		return "", lengths, false, false
+	}
+	if _, overridden := v.metadataEntry(countryCode); overridden {
+		return "", lengths, false, true
+	}
+	if numberType, typeLengths, typed := classifyNumberType(nationalNumber, countryCode); typed {
+		return numberType, typeLengths, true, true
+	}
+	return "", lengths, false, true
+}
+
+func classifyNumberType(nationalNumber, countryCode string) (NumberType, [2]int, bool) {
+	rule, typed := numberTypeRule(nationalNumber, countryCode)
+	return rule.Type, rule.Lengths, typed
+}
+
+func numberTypeRule(nationalNumber, countryCode string) (NumberTypeLength, bool) {
+	for _, rule := range NumberTypeLengths[countryCode] {
+		if strings.HasPrefix(nationalNumber, rule.LeadingDigits) {
+			return rule, true
+		}
 	}
+	return NumberTypeLength{}, false
+}
+
+// LeadingDigitError reports a national number starting with a digit its
+// country never allocates.
+type LeadingDigitError struct {
+	CountryCode string
+	Digit       string
+}
 
-	minLength, maxLength := lengths[0], lengths[1]
-	actualLength := len(nationalNumber)
+func (e *LeadingDigitError) Error() string {
+	return "national number cannot start with digit " + e.Digit + " for country " + e.CountryCode
+}
 
-	if actualLength < minLength || actualLength > maxLength {
-		return errors.New("phone number length is invalid for country " + countryCode)
+func (v *PhoneNumberValidator) validateLeadingDigit(nationalNumber, countryCode string) error {
+	allowed, exists := v.countryLeadingDigits(countryCode)
+	if !exists || nationalNumber == "" {
+		return nil
+	}
+
+	digit := nationalNumber[:1]
+	if !strings.Contains(allowed, digit) {
+		return &LeadingDigitError{CountryCode: countryCode, Digit: digit}
 	}
 
 	return nil
 }
 
+// formatAreaCode renders an area code in the requested style: bare as it
+// appears in E.164, or national with the country's trunk prefix as dialed
+// inside the country. Countries without a trunk prefix look the same in
+// both styles.
+func formatAreaCode(countryCode, areaCode, style string) string {
+	if style != AreaCodeStyleNational || areaCode == "" {
+		return areaCode
+	}
+	return CountryTrunkPrefixes[countryCode] + areaCode
+}
+
 func (v *PhoneNumberValidator) formatPhoneNumber(countryCode, areaCode, localNumber string) string {
 	dialingCode := CountryDialingCodes[countryCode]
 	return "+" + dialingCode + areaCode + localNumber
 }
+
+// formatRFC3966 is the number as an RFC 3966 global tel URI with hyphens
+// between the dialing code, area code and local number, such as
+// tel:+34-91-5872200. Seven-digit NANP local numbers are also split after
+// the exchange, as written in tel:+1-212-569-0123. An empty areaCode is
+// left out.
+func (v *PhoneNumberValidator) formatRFC3966(countryCode, areaCode, localNumber string) string {
+	dialingCode := CountryDialingCodes[countryCode]
+	if dialingCode == "1" && len(localNumber) == 7 {
+		localNumber = localNumber[:3] + "-" + localNumber[3:]
+	}
+	uri := "tel:+" + dialingCode
+	if areaCode != "" {
+		uri += "-" + areaCode
+	}
+	return uri + "-" + localNumber
+}
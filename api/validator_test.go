@@ -1,6 +1,9 @@
 package api
 
 import (
+	"errors"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -26,8 +29,8 @@ func TestPhoneNumberValidator_CleanPhoneNumber(t *testing.T) {
 			shouldError: false,
 		},
 		{
-			name:        "Invalid characters - hyphen",
-			phoneNumber: "212-569-0123",
+			name:        "Invalid characters - unbalanced parenthesis",
+			phoneNumber: "+1 (212 569 0123",
 			shouldError: true,
 		},
 		{
@@ -139,11 +142,16 @@ func TestPhoneNumberValidator_ValidatePhoneNumber(t *testing.T) {
 			errorMsg:    "phone number contains invalid characters",
 		},
 		{
-			name:        "Invalid characters - hyphen",
+			name:        "Hyphens are formatting",
 			phoneNumber: "212-569-0123",
 			countryCode: "US",
-			shouldError: true,
-			errorMsg:    "phone number contains invalid characters",
+			expected: &PhoneValidationResponse{
+				PhoneNumber:      "+12125690123",
+				CountryCode:      "US",
+				AreaCode:         "212",
+				LocalPhoneNumber: "5690123",
+			},
+			shouldError: false,
 		},
 		{
 			name:        "Invalid spacing pattern",
@@ -349,3 +357,777 @@ func TestPhoneNumberValidator_DialingCodeExtraction(t *testing.T) {
 		})
 	}
 }
+
+func TestPhoneNumberValidator_InputSizeGuard(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		shouldError bool
+	}{
+		{name: "15 digits", phoneNumber: "+" + strings.Repeat("1", 15), shouldError: false},
+		{name: "16 digits", phoneNumber: "+" + strings.Repeat("1", 16), shouldError: true},
+		{name: "15 digits with separators", phoneNumber: "+1 212 5690 12345 67", shouldError: false},
+		{name: "At length cap", phoneNumber: "+1" + strings.Repeat(" ", 52) + "2125690123", shouldError: false},
+		{name: "Over length cap", phoneNumber: "+1" + strings.Repeat(" ", 53) + "2125690123", shouldError: true},
+		{name: "Oversized input", phoneNumber: strings.Repeat("1", 1<<20), shouldError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.validateInputSize(tt.phoneNumber)
+			if tt.shouldError && err == nil {
+				t.Errorf("Expected error but got none")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+
+	t.Run("Rejected before parsing", func(t *testing.T) {
+		_, err := validator.ValidatePhoneNumber(strings.Repeat("a", 100), "US")
+		if err == nil || err.Error() != "phone number input is too long" {
+			t.Errorf("Expected input size error, got %v", err)
+		}
+	})
+
+	t.Run("Configurable cap", func(t *testing.T) {
+		validator := NewPhoneNumberValidator(WithMaxInputLength(12))
+		if _, err := validator.ValidatePhoneNumber("+12125690123", ""); err != nil {
+			t.Errorf("Unexpected error at cap: %v", err)
+		}
+		if _, err := validator.ValidatePhoneNumber("+1 2125690123", ""); err == nil {
+			t.Errorf("Expected error over cap")
+		}
+	})
+}
+
+func BenchmarkValidatePhoneNumber_OversizedInput(b *testing.B) {
+	validator := NewPhoneNumberValidator()
+	input := "+" + strings.Repeat("1", 1<<20)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		validator.ValidatePhoneNumber(input, "")
+	}
+}
+
+func TestCountryExampleNumbers(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	for countryCode := range CountryPhoneLengths {
+		t.Run(countryCode, func(t *testing.T) {
+			number, exists := ExampleNumber(countryCode)
+			if !exists {
+				t.Fatalf("No example number for %s", countryCode)
+			}
+			if _, err := validator.ValidatePhoneNumber(number, ""); err != nil {
+				t.Errorf("Example number %s does not validate: %v", number, err)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_LengthBoundaries(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	for countryCode, lengths := range CountryPhoneLengths {
+		minLength, maxLength := lengths[0], lengths[1]
+		tests := []struct {
+			name    string
+			length  int
+			wantErr bool
+		}{
+			{"min-1", minLength - 1, true},
+			{"min", minLength, false},
+			{"max", maxLength, false},
+			{"max+1", maxLength + 1, true},
+		}
+
+		for _, tt := range tests {
+			t.Run(countryCode+" "+tt.name, func(t *testing.T) {
+				// The number is all 2s, or starts with 6 where the country
+				// does not allocate 2; no supported dialing code is a prefix
+				// of it, so the input is always treated as a national number.
+				digit := "2"
+				if allowed, restricted := CountryLeadingDigits[countryCode]; restricted && !strings.Contains(allowed, "2") {
+					digit = allowed[len(allowed)-1:]
+				}
+				number := digit + strings.Repeat("2", tt.length-1)
+				if _, typeLengths, typed := classifyNumberType(number, countryCode); typed && typeLengths != lengths {
+					t.Skip("a number type narrows the country range; see NumberTypeLengths")
+				}
+				result, err := validator.ValidatePhoneNumber(number, countryCode)
+
+				if tt.wantErr {
+					want := "phone number length is invalid for country " + countryCode
+					if err == nil || !strings.HasPrefix(err.Error(), want) {
+						t.Errorf("Expected %q, got %v", want, err)
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if result.NDC == "" {
+					t.Errorf("Expected NDC for %s", number)
+				}
+				if result.NDC+result.LocalPhoneNumber != number {
+					t.Errorf("Expected split of %s, got %s/%s", number, result.NDC, result.LocalPhoneNumber)
+				}
+			})
+		}
+	}
+}
+
+func TestPhoneNumberValidator_NumberTypeLengths(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		countryCode string
+		wantErr     string
+	}{
+		{"IT mobile", "3123456789", "IT", ""},
+		{"IT 9-digit mobile", "312345678", "IT", ""},
+		{"IT mobile too long", "31234567890", "IT", "phone number length is invalid for country IT: mobile numbers must have 9 to 10 digits"},
+		{"IT mobile too short", "31234567", "IT", "phone number length is invalid for country IT: mobile numbers must have 9 to 10 digits"},
+		{"IT landline shortest", "061234", "IT", ""},
+		{"IT short Rome landline", "0612345", "IT", ""},
+		{"IT landline longest", "06123456789", "IT", ""},
+		{"IT landline too short", "06123", "IT", "phone number length is invalid for country IT: landline numbers must have 6 to 11 digits"},
+		{"IT landline too long", "061234567890", "IT", "phone number length is invalid for country IT: landline numbers must have 6 to 11 digits"},
+		{"IT unallocated leading digit", "61234567", "IT", "national number cannot start with digit 6 for country IT"},
+		{"GB mobile", "7400123456", "GB", ""},
+		{"GB mobile too long", "74001234567", "GB", "phone number length is invalid for country GB: mobile numbers must have 10 digits"},
+		{"GB geographic uses country range", "169773456", "GB", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_CandidateMetadata(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	candidate, err := validator.WithCandidateMetadata(CountryMetadata{CountryCode: "PT", MinLength: 9, MaxLength: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := candidate.ValidatePhoneNumber("+3512109420001", ""); err != nil {
+		t.Errorf("Expected candidate to accept 10 digits, got %v", err)
+	}
+	if _, err := validator.ValidatePhoneNumber("+3512109420001", ""); err == nil {
+		t.Errorf("Expected live validator to keep rejecting 10 digits")
+	}
+
+	invalid := []CountryMetadata{
+		{CountryCode: "XX", MinLength: 9, MaxLength: 9},
+		{CountryCode: "PT", MinLength: 10, MaxLength: 9},
+		{CountryCode: "PT", MinLength: 9, MaxLength: 9, LeadingDigits: "2a"},
+	}
+	for _, entry := range invalid {
+		if _, err := validator.WithCandidateMetadata(entry); err == nil {
+			t.Errorf("Expected %+v to be rejected", entry)
+		}
+	}
+}
+
+func TestPhoneNumberValidator_FrenchOverseas(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		countryCode string
+		wantCountry string
+		wantArea    string
+		wantE164    string
+	}{
+		{"GP landline", "+590590271234", "", "GP", "590", "+590590271234"},
+		{"GP mobile", "+590690123456", "", "GP", "690", "+590690123456"},
+		{"GF landline", "+594594301234", "", "GF", "594", "+594594301234"},
+		{"GF mobile", "+594694123456", "", "GF", "694", "+594694123456"},
+		{"MQ landline", "+596596301234", "", "MQ", "596", "+596596301234"},
+		{"MQ mobile", "+596696123456", "", "MQ", "696", "+596696123456"},
+		{"RE landline", "+262262301234", "", "RE", "262", "+262262301234"},
+		{"RE mobile", "+262692123456", "", "RE", "692", "+262692123456"},
+		{"GP national landline", "590271234", "GP", "GP", "590", "+590590271234"},
+		{"RE national landline", "262301234", "RE", "RE", "262", "+262262301234"},
+		{"GP without plus", "590590271234", "", "GP", "590", "+590590271234"},
+		{"FR metropolitan", "+33142685300", "", "FR", "1", "+33142685300"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.CountryCode != tt.wantCountry {
+				t.Errorf("Expected country %s, got %s", tt.wantCountry, result.CountryCode)
+			}
+			if result.NDC != tt.wantArea {
+				t.Errorf("Expected NDC %s, got %s", tt.wantArea, result.NDC)
+			}
+			if result.PhoneNumber != tt.wantE164 {
+				t.Errorf("Expected %s, got %s", tt.wantE164, result.PhoneNumber)
+			}
+		})
+	}
+
+	rejected := []struct {
+		phoneNumber string
+		wantErr     string
+	}{
+		{"+59069012345", "phone number length is invalid for country GP: mobile numbers must have 9 digits"},
+		{"+594194301234", "national number cannot start with digit 1 for country GF"},
+		{"+597123456789", "unsupported country dialing code for SR"},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			_, err := validator.ValidatePhoneNumber(tt.phoneNumber, "")
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_Truncate(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+	truncate := ParseOptions{Truncate: true}
+
+	result, err := validator.ValidatePhoneNumberWithOptions("+44740012345699", "", truncate)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.PhoneNumber != "+447400123456" {
+		t.Errorf("Expected +447400123456, got %s", result.PhoneNumber)
+	}
+	if result.TruncatedDigits != "99" {
+		t.Errorf("Expected truncated digits 99, got %q", result.TruncatedDigits)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0] != WarningTrailingDigitsTruncated {
+		t.Errorf("Expected %s warning, got %v", WarningTrailingDigitsTruncated, result.Warnings)
+	}
+
+	rejected := []struct {
+		name        string
+		phoneNumber string
+		opts        ParseOptions
+		wantErr     string
+	}{
+		{"off by default", "+44740012345699", ParseOptions{}, "phone number length is invalid for country GB: mobile numbers must have 10 digits"},
+		{"ambiguous range", "+493012345678901", truncate, "phone number length is invalid for country DE: truncation is ambiguous"},
+		{"too short", "+44740012345", truncate, "phone number length is invalid for country GB: mobile numbers must have 10 digits"},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := validator.ValidatePhoneNumberWithOptions(tt.phoneNumber, "", tt.opts)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	always := NewPhoneNumberValidator(WithTruncateTooLong())
+	if _, err := always.ValidatePhoneNumber("+44740012345699", ""); err != nil {
+		t.Errorf("Expected WithTruncateTooLong to truncate, got %v", err)
+	}
+}
+
+func TestPhoneNumberValidator_SouthAfricaNigeria(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		countryCode string
+		wantCountry string
+		wantArea    string
+		wantLocal   string
+		wantType    NumberType
+	}{
+		{"ZA mobile", "+27821234567", "", "ZA", "82", "1234567", NumberTypeMobile},
+		{"ZA national with trunk zero", "0821234567", "ZA", "ZA", "82", "1234567", NumberTypeMobile},
+		{"ZA Johannesburg", "+27111234567", "", "ZA", "11", "1234567", NumberTypeLandline},
+		{"ZA Cape Town national", "0211234567", "ZA", "ZA", "21", "1234567", NumberTypeLandline},
+		{"NG mobile", "+2348012345678", "", "NG", "801", "2345678", NumberTypeMobile},
+		{"NG national mobile", "08031234567", "NG", "NG", "803", "1234567", NumberTypeMobile},
+		{"NG Lagos", "+23412345678", "", "NG", "1", "2345678", NumberTypeLandline},
+		{"NG Kano national", "064123456", "NG", "NG", "64", "123456", NumberTypeLandline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.CountryCode != tt.wantCountry {
+				t.Errorf("Expected country %s, got %s", tt.wantCountry, result.CountryCode)
+			}
+			if result.NDC != tt.wantArea || result.LocalPhoneNumber != tt.wantLocal {
+				t.Errorf("Expected split %s/%s, got %s/%s", tt.wantArea, tt.wantLocal, result.NDC, result.LocalPhoneNumber)
+			}
+			if numberType, _, _ := classifyNumberType(tt.wantArea+tt.wantLocal, tt.wantCountry); numberType != tt.wantType {
+				t.Errorf("Expected %s number, got %q", tt.wantType, numberType)
+			}
+		})
+	}
+
+	rejected := []struct {
+		phoneNumber string
+		countryCode string
+		wantErr     string
+	}{
+		{"+2348012345", "", "phone number length is invalid for country NG: mobile numbers must have 10 digits"},
+		{"+234123456789", "", "phone number length is invalid for country NG: landline numbers must have 8 digits"},
+		{"+2782123456", "", "phone number length is invalid for country ZA: mobile numbers must have 9 digits"},
+		{"+27021234567", "", "national number cannot start with digit 0 for country ZA"},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			_, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_SouthKorea(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		countryCode string
+		wantArea    string
+		wantE164    string
+		wantName    string
+	}{
+		{"Seoul landline", "+82212345678", "", "2", "+82212345678", "Seoul"},
+		{"Seoul national", "02 1234 5678", "KR", "2", "+82212345678", "Seoul"},
+		{"Busan national", "051 123 4567", "KR", "51", "+82511234567", "Busan"},
+		{"010 mobile", "+821012345678", "", "10", "+821012345678", ""},
+		{"010 mobile national", "010 1234 5678", "KR", "10", "+821012345678", ""},
+		{"010 mobile national without separators", "01012345678", "KR", "10", "+821012345678", ""},
+		{"legacy 011 mobile", "011 123 4567", "KR", "11", "+82111234567", ""},
+		{"legacy 016 mobile", "+821612345678", "", "16", "+821612345678", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.CountryCode != "KR" {
+				t.Errorf("Expected country KR, got %s", result.CountryCode)
+			}
+			if result.NDC != tt.wantArea {
+				t.Errorf("Expected NDC %s, got %s", tt.wantArea, result.NDC)
+			}
+			if result.PhoneNumber != tt.wantE164 {
+				t.Errorf("Expected %s, got %s", tt.wantE164, result.PhoneNumber)
+			}
+			if result.AreaCodeName != tt.wantName {
+				t.Errorf("Expected area name %q, got %q", tt.wantName, result.AreaCodeName)
+			}
+		})
+	}
+
+	rejected := []struct {
+		phoneNumber string
+		wantErr     string
+	}{
+		{"+82101234567", "phone number length is invalid for country KR: mobile numbers must have 10 digits"},
+		{"+822123456789", "phone number length is invalid for country KR: landline numbers must have 8 to 9 digits"},
+		{"+82712345678", "national number cannot start with digit 7 for country KR"},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			_, err := validator.ValidatePhoneNumber(tt.phoneNumber, "")
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_AreaCodeStyle(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		phoneNumber  string
+		countryCode  string
+		wantBare     string
+		wantNational string
+	}{
+		{"+27211234567", "", "21", "021"},
+		{"0211234567", "ZA", "21", "021"},
+		{"+442079460958", "", "2079", "02079"},
+		{"02079460958", "GB", "2079", "02079"},
+		{"+33142685300", "", "1", "01"},
+		{"0142685300", "FR", "1", "01"},
+		{"+493012345678", "", "30", "030"},
+		{"030123456", "DE", "30", "030"},
+		{"+12125690123", "", "212", "212"},
+		{"+526313118150", "", "631", "631"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			bare, err := validator.ValidatePhoneNumberWithOptions(tt.phoneNumber, tt.countryCode, ParseOptions{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			national, err := validator.ValidatePhoneNumberWithOptions(tt.phoneNumber, tt.countryCode, ParseOptions{AreaCodeStyle: AreaCodeStyleNational})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if bare.AreaCode != tt.wantBare {
+				t.Errorf("Expected bare area code %s, got %s", tt.wantBare, bare.AreaCode)
+			}
+			if national.AreaCode != tt.wantNational {
+				t.Errorf("Expected national area code %s, got %s", tt.wantNational, national.AreaCode)
+			}
+			if bare.PhoneNumber != national.PhoneNumber || bare.LocalPhoneNumber != national.LocalPhoneNumber {
+				t.Errorf("Expected the style to change only areaCode, got %+v and %+v", bare, national)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_SplitNationalNumber(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	if _, _, err := validator.splitNationalNumber("212", "US"); err == nil {
+		t.Errorf("Expected split error for national number no longer than the area code")
+	} else if splitErr, ok := err.(*NationalNumberSplitError); !ok || splitErr.CountryCode != "US" {
+		t.Errorf("Expected NationalNumberSplitError for US, got %v", err)
+	}
+
+	areaCode, localNumber, err := validator.splitNationalNumber("2125690123", "US")
+	if err != nil || areaCode != "212" || localNumber != "5690123" {
+		t.Errorf("Unexpected split: %q %q %v", areaCode, localNumber, err)
+	}
+}
+
+func TestPhoneNumberValidator_LeadingDigits(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		countryCode string
+		reject      string
+		digit       string
+		accept      string
+	}{
+		{"US", "+10125690123", "0", "+12125690123"},
+		{"CA", "0165550123", "0", "4165550123"},
+		{"ES", "+34112345678", "1", "912345678"},
+		{"FR", "+33012345678", "0", "0123456789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.countryCode, func(t *testing.T) {
+			if _, ok := CountryLeadingDigits[tt.countryCode]; !ok {
+				t.Fatalf("No leading digit rule for %s", tt.countryCode)
+			}
+
+			_, err := validator.ValidatePhoneNumber(tt.reject, tt.countryCode)
+			leadingErr, ok := err.(*LeadingDigitError)
+			if !ok {
+				t.Fatalf("Expected LeadingDigitError for %s, got %v", tt.reject, err)
+			}
+			if leadingErr.Digit != tt.digit || leadingErr.CountryCode != tt.countryCode {
+				t.Errorf("Expected digit %s for %s, got %s for %s", tt.digit, tt.countryCode, leadingErr.Digit, leadingErr.CountryCode)
+			}
+
+			if _, err := validator.ValidatePhoneNumber(tt.accept, tt.countryCode); err != nil {
+				t.Errorf("Unexpected error for %s: %v", tt.accept, err)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_AreaCodeName(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		phoneNumber string
+		countryCode string
+		expected    string
+	}{
+		{"+12125690123", "", "New York"},
+		{"4165550123", "CA", "Toronto"},
+		{"+442079460958", "", "London"},
+		{"+493012345678", "", "Berlin"},
+		{"+34915872200", "", "Madrid"},
+		{"+12995690123", "", ""},
+		{"+526313118150", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.AreaCodeName != tt.expected {
+				t.Errorf("Expected area code name %q, got %q", tt.expected, result.AreaCodeName)
+			}
+		})
+	}
+}
+
+// TestSplitNationalNumber_Reconstructs checks, for every country, length
+// and leading digit, that a split never loses or duplicates digits.
+func TestSplitNationalNumber_Reconstructs(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+	const digits = "1234567890123456789"
+
+	for countryCode, lengths := range CountryPhoneLengths {
+		for length := 1; length <= lengths[1]+2; length++ {
+			for lead := 0; lead <= 9; lead++ {
+				nationalNumber := strconv.Itoa(lead) + digits[:length-1]
+
+				areaCode, localNumber, err := validator.splitNationalNumber(nationalNumber, countryCode)
+				if err != nil {
+					var splitErr *NationalNumberSplitError
+					if !errors.As(err, &splitErr) {
+						t.Errorf("%s %s: unexpected error %v", countryCode, nationalNumber, err)
+					}
+					continue
+				}
+				if err := checkSplit(countryCode, nationalNumber, areaCode, localNumber); err != nil {
+					t.Errorf("%v", err)
+				}
+
+				response, err := validator.ValidatePhoneNumber("+"+CountryDialingCodes[countryCode]+nationalNumber, "")
+				if err != nil || response.CountryCode != countryCode {
+					continue
+				}
+				if response.NDC+response.LocalPhoneNumber != nationalNumber {
+					t.Errorf("%s %s: response split into %q and %q", countryCode, nationalNumber, response.NDC, response.LocalPhoneNumber)
+				}
+			}
+		}
+	}
+}
+
+func TestCheckSplit(t *testing.T) {
+	tests := []struct {
+		name        string
+		areaCode    string
+		localNumber string
+		valid       bool
+	}{
+		{"Exact", "212", "5690123", true},
+		{"No Area Code", "", "2125690123", true},
+		{"Lost Digit", "212", "569012", false},
+		{"Duplicated Digit", "2122", "25690123", false},
+		{"Empty Local Number", "2125690123", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSplit("US", "2125690123", tt.areaCode, tt.localNumber)
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid=%v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+// TestPhoneNumberValidator_NDC covers a geographic, a mobile and a
+// toll-free number in each country with toll-free type rules. The French
+// overseas departments have no toll-free range of their own.
+func TestPhoneNumberValidator_NDC(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		phoneNumber  string
+		wantNDC      string
+		wantAreaCode string
+		wantLocal    string
+	}{
+		{"+390612345678", "06", "06", "12345678"},
+		{"+393123456789", "312", "", "3456789"},
+		{"+39800123456", "800", "", "123456"},
+		{"+442079460958", "2079", "2079", "460958"},
+		{"+447911123456", "7911", "", "123456"},
+		{"+448001234567", "800", "", "1234567"},
+		{"+27211234567", "21", "21", "1234567"},
+		{"+27821234567", "82", "", "1234567"},
+		{"+27800123456", "80", "", "0123456"},
+		{"+23412345678", "1", "1", "2345678"},
+		{"+2348012345678", "801", "", "2345678"},
+		{"+2348001234567", "800", "", "1234567"},
+		{"+82212345678", "2", "2", "12345678"},
+		{"+821012345678", "10", "", "12345678"},
+		{"+82801234567", "80", "", "1234567"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, "")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.NDC != tt.wantNDC || result.AreaCode != tt.wantAreaCode || result.LocalPhoneNumber != tt.wantLocal {
+				t.Errorf("Expected %s/%q/%s, got %s/%q/%s", tt.wantNDC, tt.wantAreaCode, tt.wantLocal, result.NDC, result.AreaCode, result.LocalPhoneNumber)
+			}
+		})
+	}
+}
+
+func TestPhoneNumberValidator_ProvidedCountryDecisionTable(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name         string
+		phoneNumber  string
+		countryCode  string
+		wantCountry  string
+		wantNumber   string
+		wantMismatch bool
+		wantErr      string
+	}{
+		{"no plus, no country, dialing code", "34915872200", "", "ES", "+34915872200", false, ""},
+		{"no plus, no country, no dialing code", "915872200", "", "", "", false, "countryCode is required for numbers without country code"},
+		{"no plus, agreeing country, international digits", "34915872200", "ES", "ES", "+34915872200", false, ""},
+		{"no plus, agreeing country, national digits", "915872200", "ES", "ES", "+34915872200", false, ""},
+		{"no plus, agreeing country, valid national reading wins", "3912345678", "IT", "IT", "+393912345678", false, ""},
+		{"no plus, disagreeing country wins", "4930123456", "US", "US", "+14930123456", false, ""},
+		{"no plus, disagreeing country fails its own length", "34915872200", "PT", "", "", false, "phone number length is invalid for country PT"},
+		{"plus, no country", "+34915872200", "", "ES", "+34915872200", false, ""},
+		{"plus, agreeing country", "+34915872200", "ES", "ES", "+34915872200", false, ""},
+		{"plus, agreeing country in lower case", "+34915872200", "es", "ES", "+34915872200", false, ""},
+		{"plus, country sharing the dialing code", "+12125690123", "CA", "US", "+12125690123", false, ""},
+		{"plus, disagreeing country is ignored with a warning", "+34915872200", "PT", "ES", "+34915872200", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.CountryCode != tt.wantCountry || result.PhoneNumber != tt.wantNumber {
+				t.Errorf("Expected %s %s, got %s %s", tt.wantCountry, tt.wantNumber, result.CountryCode, result.PhoneNumber)
+			}
+			mismatch := false
+			for _, warning := range result.WarningDetails {
+				if warning.Code == WarningCountryCodeMismatch {
+					mismatch = warning.Detail == tt.countryCode
+				}
+			}
+			if mismatch != tt.wantMismatch {
+				t.Errorf("Expected mismatch warning %v, got %+v", tt.wantMismatch, result.WarningDetails)
+			}
+		})
+	}
+}
+
+// San Marino's +378 must not be read as a shorter dialing code followed by
+// national digits, nor as Italy, whose +39 shares its first digit.
+func TestPhoneNumberValidator_SanMarino(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	for _, phoneNumber := range []string{"+3780549123456", "+37866123456"} {
+		_, err := validator.ValidatePhoneNumber(phoneNumber, "")
+		if err == nil || err.Error() != "unsupported country dialing code for SM" {
+			t.Errorf("%s: expected an unsupported dialing code error, got %v", phoneNumber, err)
+		}
+	}
+
+	response, err := validator.ValidatePhoneNumber("+390549123456", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if response.CountryCode != "IT" {
+		t.Errorf("Expected +39 0549 to stay Italian, got %s", response.CountryCode)
+	}
+}
+
+func TestPhoneNumberValidator_IDDPrefix(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	tests := []struct {
+		name        string
+		phoneNumber string
+		countryCode string
+		expected    string
+		areaCode    string
+		errorMsg    string
+	}{
+		{name: "00 without country", phoneNumber: "0034915872200", expected: "+34915872200", areaCode: "91"},
+		{name: "011 with spaces", phoneNumber: "011 34 915 872 200", expected: "+34915872200", areaCode: "91"},
+		{name: "Provided country's own prefix", phoneNumber: "0034915872200", countryCode: "ES", expected: "+34915872200", areaCode: "91"},
+		{name: "US prefix", phoneNumber: "01134915872200", countryCode: "US", expected: "+34915872200", areaCode: "91"},
+		{name: "Prefix dialing another country", phoneNumber: "0044 2079 460958", countryCode: "FR", expected: "+442079460958", areaCode: "2079"},
+		{name: "Another country's prefix is national", phoneNumber: "0111234567", countryCode: "ZA", expected: "+27111234567", areaCode: "11"},
+		{name: "Trunk prefix is not a dialing prefix", phoneNumber: "0211234567", countryCode: "ZA", expected: "+27211234567", areaCode: "21"},
+		{name: "Bare 00", phoneNumber: "00", errorMsg: "international dialing prefix without a number"},
+		{name: "Bare 011", phoneNumber: "011", errorMsg: "international dialing prefix without a number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validator.ValidatePhoneNumber(tt.phoneNumber, tt.countryCode)
+			if tt.errorMsg != "" {
+				if err == nil || err.Error() != tt.errorMsg {
+					t.Fatalf("Expected error %q, got %+v, %v", tt.errorMsg, result, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.PhoneNumber != tt.expected || result.AreaCode != tt.areaCode {
+				t.Errorf("Expected %s/%s, got %s/%s", tt.expected, tt.areaCode, result.PhoneNumber, result.AreaCode)
+			}
+		})
+	}
+}
+
+// Italian mobiles in the 39x range start with Italy's own dialing code, so
+// without a plus they must not lose it to the international reading.
+func TestPhoneNumberValidator_ItalianMobilesStartingWith39(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+
+	for _, phoneNumber := range []string{"3912345678", "391234567"} {
+		response, err := validator.ValidatePhoneNumber(phoneNumber, "IT")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", phoneNumber, err)
+		}
+		if want := "+39" + phoneNumber; response.PhoneNumber != want {
+			t.Errorf("%s: expected %s, got %s", phoneNumber, want, response.PhoneNumber)
+		}
+	}
+}
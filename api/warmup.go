@@ -0,0 +1,41 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WarmUp prepares the validator, fills the result cache from the seed
+// file and marks the handler ready. It must run before the listener opens;
+// until it succeeds /readyz answers 503.
+func (h *Handler) WarmUp() (time.Duration, error) {
+	start := time.Now()
+	if err := h.validator.WarmUp(); err != nil {
+		return time.Since(start), err
+	}
+	if h.cacheSeed != nil {
+		if h.resultCache == nil {
+			h.resultCache = newResultCache(DefaultResultCacheSize)
+		}
+		if err := h.resultCache.seed(h.validator, h.cacheSeed); err != nil {
+			return time.Since(start), err
+		}
+	}
+	h.warmedUp.Store(true)
+	return time.Since(start), nil
+}
+
+func (h *Handler) warmingUp(c *gin.Context) bool {
+	if h.warmedUp.Load() {
+		return false
+	}
+	abortWithRetryAfter(c, http.StatusServiceUnavailable, time.Second, gin.H{
+		"status": "not ready",
+		"reason": "warming up",
+	})
+	return true
+}
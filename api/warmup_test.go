@@ -0,0 +1,54 @@
+//go:build !js
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandler_WarmUp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewHandler()
+	handler.SetupRoutes(router)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/readyz"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 before warm-up, got %d", w.Code)
+	}
+
+	if _, err := handler.WarmUp(); err != nil {
+		t.Fatalf("Warm-up failed: %v", err)
+	}
+	if w := get("/readyz"); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 after warm-up, got %d", w.Code)
+	}
+
+	// The first request must find the lazily built lookup structures ready.
+	if validCharsPattern == nil {
+		t.Errorf("Warm-up did not build the valid characters pattern")
+	}
+	if dialingCodeTrie == nil {
+		t.Errorf("Warm-up did not build the dialing code trie")
+	}
+	if w := get("/v1/phone-numbers?phoneNumber=%2B12125690123"); w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", w.Code)
+	}
+}
+
+func TestPhoneNumberValidator_WarmUpSkipsDisabledCountries(t *testing.T) {
+	validator := NewPhoneNumberValidator(WithDisabledCountries("US", "FR"))
+	if err := validator.WarmUp(); err != nil {
+		t.Errorf("Unexpected warm-up error: %v", err)
+	}
+}
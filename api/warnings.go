@@ -0,0 +1,93 @@
+package api
+
+const (
+	WarningDuplicatePlusCollapsed      = "DUPLICATE_PLUS_COLLAPSED"
+	WarningTrailingPunctuationRemoved  = "TRAILING_PUNCTUATION_REMOVED"
+	WarningTrunkPrefixDropped          = "TRUNK_PREFIX_DROPPED"
+	WarningExcelFormatRecovered        = "EXCEL_FORMAT_RECOVERED"
+	WarningTrailingDigitsTruncated     = "TRAILING_DIGITS_TRUNCATED"
+	WarningPlusSignRecovered           = "PLUS_SIGN_RECOVERED"
+	WarningEnumNotEnabled              = "ENUM_NOT_ENABLED"
+	WarningEnumLookupFailed            = "ENUM_LOOKUP_FAILED"
+	WarningSuspiciousPattern           = "SUSPICIOUS_PATTERN"
+	WarningCountryCodeMismatch         = "COUNTRY_CODE_MISMATCH"
+	WarningLeadingZeroRestored         = "LEADING_ZERO_RESTORED"
+	WarningDegradedValidation          = "DEGRADED_VALIDATION"
+	WarningCarrierSelectionCodeRemoved = "CARRIER_SELECTION_CODE_REMOVED"
+	WarningPortingLookupFailed         = "PORTING_LOOKUP_FAILED"
+	WarningCountryFromPreference       = "COUNTRY_FROM_PREFERENCE"
+)
+
+// WarningMessages is the registry of warning codes. A code must be listed
+// here before anything can raise it.
+var WarningMessages = map[string]string{
+	WarningDuplicatePlusCollapsed:      "duplicate leading plus signs were collapsed",
+	WarningTrailingPunctuationRemoved:  "a trailing punctuation mark was removed",
+	WarningTrunkPrefixDropped:          "a parenthesized trunk prefix (0) was dropped",
+	WarningExcelFormatRecovered:        "the number was reconstructed from a spreadsheet numeric format",
+	WarningTrailingDigitsTruncated:     "trailing digits beyond the country's maximum length were removed",
+	WarningPlusSignRecovered:           "a leading space was read as an unencoded plus sign",
+	WarningEnumNotEnabled:              "enum lookup is not enabled",
+	WarningEnumLookupFailed:            "enum lookup failed",
+	WarningSuspiciousPattern:           "the digits are all identical or a trivial sequence",
+	WarningCountryCodeMismatch:         "the dialing code names a different country than countryCode, which was ignored",
+	WarningLeadingZeroRestored:         "a leading digit lost to integer storage was restored",
+	WarningDegradedValidation:          "metadata failed to load; only the dialing code and E.164 length were checked",
+	WarningCarrierSelectionCodeRemoved: "a trunk prefix and carrier selection code were removed",
+	WarningPortingLookupFailed:         "porting lookup failed; the number is reported as not ported",
+	WarningCountryFromPreference:       "no country was given; the first country of the preference order that validates the number was used",
+}
+
+// Warning reports something non-obvious done to the input or the lookup.
+// Detail carries the specifics when there are any, such as the digits a
+// truncation removed.
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// headerValue is the Warning header form: 299 phone-api "CODE: message (detail)".
+func (w Warning) headerValue() string {
+	text := w.Code + ": " + w.Message
+	if w.Detail != "" {
+		text += " (" + w.Detail + ")"
+	}
+	return `299 phone-api "` + text + `"`
+}
+
+// warningSet accumulates warnings through the parse pipeline in the order
+// they are raised, which is the order clients see.
+type warningSet []Warning
+
+// add panics on unregistered codes so a typo cannot ship a warning
+// without a message.
+func (s *warningSet) add(code, detail string) {
+	message, registered := WarningMessages[code]
+	if !registered {
+		panic("api: unregistered warning code " + code)
+	}
+	*s = append(*s, Warning{Code: code, Message: message, Detail: detail})
+}
+
+// setWarnings stores warnings in both response forms; empty sets leave
+// both fields out of the JSON.
+func (r *PhoneValidationResponse) setWarnings(warnings warningSet) {
+	if len(warnings) == 0 {
+		r.Warnings, r.WarningDetails = nil, nil
+		return
+	}
+	r.Warnings = make([]string, len(warnings))
+	for i, warning := range warnings {
+		r.Warnings[i] = warning.Code
+	}
+	r.WarningDetails = warnings
+}
+
+// addWarning appends one warning raised after validation, such as by
+// enrichment.
+func (r *PhoneValidationResponse) addWarning(code, detail string) {
+	warnings := warningSet(r.WarningDetails)
+	warnings.add(code, detail)
+	r.setWarnings(warnings)
+}
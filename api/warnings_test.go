@@ -0,0 +1,54 @@
+package api
+
+import "testing"
+
+func TestPhoneNumberValidator_WarningOrder(t *testing.T) {
+	validator := NewPhoneNumberValidator()
+	want := []string{WarningTrailingPunctuationRemoved, WarningDuplicatePlusCollapsed, WarningTrunkPrefixDropped}
+
+	for run := 0; run < 20; run++ {
+		result, err := validator.ValidatePhoneNumberWithOptions("++44 (0) 2079460958.", "", ParseOptions{Lenient: true})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(result.WarningDetails) != len(want) {
+			t.Fatalf("Expected %d warnings, got %v", len(want), result.WarningDetails)
+		}
+		for i, code := range want {
+			if result.Warnings[i] != code || result.WarningDetails[i].Code != code {
+				t.Fatalf("Expected %s at position %d, got %v", code, i, result.WarningDetails)
+			}
+			if result.WarningDetails[i].Message != WarningMessages[code] {
+				t.Errorf("Expected registry message for %s, got %q", code, result.WarningDetails[i].Message)
+			}
+		}
+	}
+}
+
+func TestWarningSet(t *testing.T) {
+	var warnings warningSet
+	warnings.add(WarningTrailingDigitsTruncated, "99")
+	warnings.add(WarningEnumLookupFailed, "")
+
+	if got := warnings[0].headerValue(); got != `299 phone-api "TRAILING_DIGITS_TRUNCATED: trailing digits beyond the country's maximum length were removed (99)"` {
+		t.Errorf("Unexpected header value %s", got)
+	}
+
+	response := &PhoneValidationResponse{}
+	response.setWarnings(nil)
+	if response.Warnings != nil || response.WarningDetails != nil {
+		t.Errorf("Expected empty warnings to be omitted")
+	}
+	response.setWarnings(warnings)
+	response.addWarning(WarningEnumNotEnabled, "")
+	if len(response.Warnings) != 3 || response.Warnings[2] != WarningEnumNotEnabled {
+		t.Errorf("Expected appended warning last, got %v", response.Warnings)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected an unregistered code to panic")
+		}
+	}()
+	warnings.add("NOT_A_WARNING", "")
+}
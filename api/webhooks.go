@@ -0,0 +1,210 @@
+//go:build !js
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// WebhookSignatureHeader carries "sha256=" and the hex HMAC-SHA256 of
+	// the request body under the webhook secret.
+	WebhookSignatureHeader = "X-Phone-Api-Signature"
+
+	WebhookEventTest = "webhook.test"
+
+	// WebhookTestsPerMinute caps test-fires per caller: an API key label,
+	// or the client IP without keys.
+	WebhookTestsPerMinute = 5
+)
+
+// WebhookPayload is the body of every delivery. Test is only set by the
+// test-fire endpoint, whose Data is a sample lookup result.
+type WebhookPayload struct {
+	Event  string      `json:"event"`
+	Test   bool        `json:"test,omitempty"`
+	SentAt time.Time   `json:"sentAt"`
+	Data   interface{} `json:"data"`
+}
+
+// WebhookDelivery is the outcome of one delivery attempt. StatusCode is
+// zero when no response arrived; Error then names the connection, TLS or
+// policy failure, and Code is ErrorOutboundBlocked for policy refusals.
+type WebhookDelivery struct {
+	CallbackURL    string    `json:"callbackUrl"`
+	Delivered      bool      `json:"delivered"`
+	Signed         bool      `json:"signed"`
+	StatusCode     int       `json:"statusCode,omitempty"`
+	ResponseTimeMs int64     `json:"responseTimeMs"`
+	Code           ErrorCode `json:"code,omitempty"`
+	Error          string    `json:"error,omitempty"`
+}
+
+type WebhookTestRequest struct {
+	CallbackURL string `json:"callbackUrl"`
+}
+
+type webhooks struct {
+	client *OutboundClient
+	secret string
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+	swept   time.Time
+}
+
+func newWebhooks() *webhooks {
+	return &webhooks{client: NewOutboundClient(OutboundPolicy{}), windows: map[string]*rateWindow{}}
+}
+
+// WithWebhooks sets the outbound client and signing secret for webhook
+// deliveries. Without it deliveries use the default outbound policy and
+// are unsigned.
+func WithWebhooks(client *OutboundClient, secret string) HandlerOption {
+	return func(h *Handler) {
+		if client != nil {
+			h.webhooks.client = client
+		}
+		h.webhooks.secret = secret
+	}
+}
+
+// SignWebhookPayload returns the WebhookSignatureHeader value for body, so
+// receivers can verify deliveries.
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliver POSTs payload to callbackURL through the outbound client and
+// reports what the receiver answered. It never returns an error: every
+// failure is described in the result.
+func (w *webhooks) deliver(ctx context.Context, callbackURL string, payload WebhookPayload) WebhookDelivery {
+	result := WebhookDelivery{CallbackURL: callbackURL, Signed: w.secret != ""}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if result.Signed {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(w.secret, body))
+	}
+
+	start := time.Now()
+	resp, err := w.client.Do(req)
+	result.ResponseTimeMs = time.Since(start).Milliseconds()
+	if err != nil {
+		var blocked *OutboundBlockedError
+		if errors.As(err, &blocked) {
+			result.Code = ErrorOutboundBlocked
+		}
+		result.Error = err.Error()
+		return result
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.Delivered = resp.StatusCode/100 == 2
+	return result
+}
+
+// allowTest counts a test-fire against caller's fixed one-minute window.
+// Expired windows are dropped at most once a minute, so callers that never
+// return do not accumulate.
+func (w *webhooks) allowTest(caller string, now time.Time) (bool, time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if now.Sub(w.swept) >= time.Minute {
+		for key, window := range w.windows {
+			if now.Sub(window.start) >= time.Minute {
+				delete(w.windows, key)
+			}
+		}
+		w.swept = now
+	}
+
+	window, exists := w.windows[caller]
+	if !exists || now.Sub(window.start) >= time.Minute {
+		window = &rateWindow{start: now}
+		w.windows[caller] = window
+	}
+	if window.count >= WebhookTestsPerMinute {
+		return false, window.start.Add(time.Minute).Sub(now)
+	}
+	window.count++
+	return true, 0
+}
+
+// TestWebhook sends a signed sample payload marked "test": true to
+// callbackUrl and returns the receiver's answer. The receiver's status is
+// reported in the body; the endpoint itself answers 200 once an attempt
+// was made.
+func (h *Handler) TestWebhook(c *gin.Context) {
+	caller := c.GetString(apiKeyLabelKey)
+	if caller == "" {
+		caller = c.ClientIP()
+	}
+	if allowed, retryAfter := h.webhooks.allowTest(caller, h.now()); !allowed {
+		abortWithRetryAfter(c, http.StatusTooManyRequests, retryAfter, gin.H{
+			"error": map[string]string{
+				"callbackUrl": "webhook test limit exceeded",
+			},
+		})
+		return
+	}
+
+	var req WebhookTestRequest
+	if errorResponse := bindJSONBody(c, &req); errorResponse != nil {
+		c.JSON(http.StatusBadRequest, errorResponse)
+		return
+	}
+	if target, err := url.Parse(req.CallbackURL); err != nil || !target.IsAbs() || target.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": map[string]string{
+				"callbackUrl": "must be an absolute URL",
+			},
+		})
+		return
+	}
+
+	example, _ := ExampleNumber("US")
+	sample, err := h.validator.ValidatePhoneNumber(example, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": map[string]string{
+				"callbackUrl": "sample payload could not be built",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.webhooks.deliver(c.Request.Context(), req.CallbackURL, WebhookPayload{
+		Event:  WebhookEventTest,
+		Test:   true,
+		SentAt: h.now().UTC(),
+		Data:   sample,
+	}))
+}
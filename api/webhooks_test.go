@@ -0,0 +1,101 @@
+//go:build !js
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	var received []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/slow":
+			time.Sleep(300 * time.Millisecond)
+		default:
+			received, _ = io.ReadAll(r.Body)
+			signature = r.Header.Get(WebhookSignatureHeader)
+		}
+	}))
+	defer server.Close()
+
+	loopback := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	webhooks := newWebhooks()
+	webhooks.client = NewOutboundClient(OutboundPolicy{AllowedSchemes: []string{"http"}, AllowedNetworks: loopback, Timeout: 100 * time.Millisecond})
+	webhooks.secret = "secret"
+	payload := WebhookPayload{Event: WebhookEventTest, Test: true, Data: map[string]string{"phoneNumber": "+12125690123"}}
+
+	t.Run("Delivered And Signed", func(t *testing.T) {
+		result := webhooks.deliver(context.Background(), server.URL+"/ok", payload)
+		if !result.Delivered || !result.Signed || result.StatusCode != http.StatusOK || result.Error != "" {
+			t.Fatalf("Expected a signed delivery, got %+v", result)
+		}
+		if signature != SignWebhookPayload("secret", received) {
+			t.Errorf("Signature %q does not match the body", signature)
+		}
+		var decoded WebhookPayload
+		if err := json.Unmarshal(received, &decoded); err != nil || !decoded.Test || decoded.Event != WebhookEventTest {
+			t.Errorf("Expected a test payload, got %s", received)
+		}
+	})
+
+	t.Run("Receiver Error", func(t *testing.T) {
+		result := webhooks.deliver(context.Background(), server.URL+"/fail", payload)
+		if result.Delivered || result.StatusCode != http.StatusInternalServerError || result.Error != "" {
+			t.Errorf("Expected an undelivered 500, got %+v", result)
+		}
+	})
+
+	t.Run("Slow Receiver", func(t *testing.T) {
+		result := webhooks.deliver(context.Background(), server.URL+"/slow", payload)
+		if result.Delivered || result.StatusCode != 0 || result.Error == "" || result.Code != "" {
+			t.Errorf("Expected a timeout error, got %+v", result)
+		}
+		if result.ResponseTimeMs < 100 {
+			t.Errorf("Expected the response time to cover the timeout, got %dms", result.ResponseTimeMs)
+		}
+	})
+
+	t.Run("Private Address Blocked", func(t *testing.T) {
+		result := newWebhooks().deliver(context.Background(), server.URL, payload)
+		if result.Delivered || result.Code != ErrorOutboundBlocked || result.Signed {
+			t.Errorf("Expected an unsigned blocked attempt, got %+v", result)
+		}
+	})
+}
+
+func TestWebhookTestLimit(t *testing.T) {
+	webhooks := newWebhooks()
+	now := time.Unix(1700000000, 0)
+
+	for i := 0; i < WebhookTestsPerMinute; i++ {
+		if allowed, _ := webhooks.allowTest("alpha", now); !allowed {
+			t.Fatalf("Expected test %d to be allowed", i+1)
+		}
+	}
+	if allowed, retryAfter := webhooks.allowTest("alpha", now.Add(15*time.Second)); allowed || retryAfter != 45*time.Second {
+		t.Errorf("Expected the limit to apply with 45s left, got %v %s", allowed, retryAfter)
+	}
+	if allowed, _ := webhooks.allowTest("beta", now); !allowed {
+		t.Error("Expected callers to be limited separately")
+	}
+	if allowed, _ := webhooks.allowTest("alpha", now.Add(time.Minute)); !allowed {
+		t.Error("Expected a new window after a minute")
+	}
+	if _, exists := webhooks.windows["beta"]; exists {
+		t.Error("Expected the expired window of beta to be dropped")
+	}
+	if len(webhooks.windows) != 1 {
+		t.Errorf("Expected only the window of alpha to be kept, got %d", len(webhooks.windows))
+	}
+}
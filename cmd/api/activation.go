@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// systemd passes activated sockets starting at fd 3 (SD_LISTEN_FDS_START).
+const listenFDsStart = 3
+
+// activatedListeners returns the sockets handed over by systemd socket
+// activation, or nil when the process was not socket-activated.
+func activatedListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	// Unset so child processes don't try to claim the same descriptors.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	files := make([]*os.File, count)
+	for i := range files {
+		name := "LISTEN_FD_" + strconv.Itoa(listenFDsStart+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		files[i] = os.NewFile(uintptr(listenFDsStart+i), name)
+	}
+
+	return listenersFromFiles(files)
+}
+
+// listenersFromFiles wraps inherited socket files. net.FileListener dups the
+// descriptor, so the inherited file is closed here and shutdown only ever
+// closes our copy; Unix sockets are never unlinked since systemd owns the
+// path and needs it for the next activation.
+func listenersFromFiles(files []*os.File) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(files))
+	for _, file := range files {
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("inherited socket %s: %w", file.Name(), err)
+		}
+		if unixListener, ok := listener.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(false)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"phone-api/api"
+)
+
+// serveListeners runs a default server on listeners until ctx is done.
+func serveListeners(ctx context.Context, listeners []net.Listener) error {
+	gin.SetMode(gin.TestMode)
+	server, err := api.NewServer(api.Config{Listeners: listeners})
+	if err != nil {
+		return err
+	}
+	return server.Run(ctx)
+}
+
+func TestActivatedListeners_NotActivated(t *testing.T) {
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	t.Setenv("LISTEN_FDS", "1")
+
+	listeners, err := activatedListeners()
+	assert.NoError(t, err)
+	assert.Nil(t, listeners)
+
+	t.Setenv("LISTEN_PID", "")
+	listeners, err = activatedListeners()
+	assert.NoError(t, err)
+	assert.Nil(t, listeners)
+}
+
+func TestServe_InheritedTCPListener(t *testing.T) {
+	original, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	file, err := original.(*net.TCPListener).File()
+	require.NoError(t, err)
+	addr := original.Addr().String()
+	original.Close()
+
+	listeners, err := listenersFromFiles([]*os.File{file})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serveListeners(ctx, listeners) }()
+
+	resp, err := http.Get("http://" + addr + "/health")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "healthy")
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not shut down")
+	}
+}
+
+func TestServe_InheritedUnixListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "phone-api.sock")
+	original, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	original.(*net.UnixListener).SetUnlinkOnClose(false)
+	file, err := original.(*net.UnixListener).File()
+	require.NoError(t, err)
+	original.Close()
+
+	listeners, err := listenersFromFiles([]*os.File{file})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serveListeners(ctx, listeners) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	<-done
+
+	_, err = os.Stat(path)
+	assert.NoError(t, err, "socket path must survive shutdown for the next activation")
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"phone-api/api"
+)
+
+type config struct {
+	Port                    string
+	ListenAddrs             []string
+	AdminToken              string
+	MaxInputLength          int
+	DisabledCountries       []string
+	ParamAliases            map[string]string
+	FailureSampleRate       float64
+	FailureSamplesPerMinute int
+	EnumEnabled             bool
+	EnumSuffix              string
+	EnumDNSServer           string
+	APIKeysFile             string
+	RecentLookups           int
+	BasePath                string
+	SuspiciousPatterns      string
+	WebhookSecret           string
+	ErrorMessagesFile       string
+	ResultCacheSize         int
+	CacheSeedFile           string
+	CacheSeedBudget         time.Duration
+	NegativeCacheSize       int
+	NegativeCacheTTL        time.Duration
+	DemoMode                bool
+	StrictContentType       bool
+	RedisURL                string
+	RedisTimeout            time.Duration
+	RateLimitFailClosed     bool
+	CountryPreferenceOrder  []string
+	CallWindowStart         string
+	CallWindowEnd           string
+	CorpusFile              string
+	MetadataFile            string
+	MetadataFallback        string
+	EchoMaxLength           int
+	PortedRangesFile        string
+	JobSpillDir             string
+	JobMemoryResults        int
+	JobMaxRunning           int
+	JobMaxDiskBytes         int64
+	JobIdempotencyWindow    time.Duration
+	ShadowMetadataFile      string
+	ShadowSampleRate        float64
+	SelfUpgrade             bool
+	PIDFile                 string
+}
+
+func loadConfig() config {
+	cfg := config{
+		Port:               os.Getenv("PORT"),
+		AdminToken:         os.Getenv("ADMIN_TOKEN"),
+		DisabledCountries:  api.ParseCountryList(os.Getenv("DISABLED_COUNTRIES")),
+		ParamAliases:       api.ParseParamAliases(os.Getenv("PARAM_ALIASES")),
+		EnumSuffix:         os.Getenv("ENUM_SUFFIX"),
+		EnumDNSServer:      os.Getenv("ENUM_DNS_SERVER"),
+		APIKeysFile:        os.Getenv("API_KEYS_FILE"),
+		BasePath:           api.NormalizeBasePath(os.Getenv("BASE_PATH")),
+		SuspiciousPatterns: strings.ToLower(os.Getenv("SUSPICIOUS_PATTERNS")),
+		WebhookSecret:      os.Getenv("WEBHOOK_SECRET"),
+		ErrorMessagesFile:  os.Getenv("ERROR_MESSAGES_FILE"),
+		CacheSeedFile:      os.Getenv("CACHE_SEED_FILE"),
+		CallWindowStart:    os.Getenv("CALL_WINDOW_START"),
+		CallWindowEnd:      os.Getenv("CALL_WINDOW_END"),
+		CorpusFile:         os.Getenv("CORPUS_FILE"),
+		MetadataFile:       os.Getenv("METADATA_FILE"),
+		MetadataFallback:   strings.ToLower(os.Getenv("METADATA_FALLBACK")),
+		PortedRangesFile:   os.Getenv("PORTED_RANGES_FILE"),
+		JobSpillDir:        os.Getenv("JOB_SPILL_DIR"),
+		ShadowMetadataFile: os.Getenv("SHADOW_METADATA_FILE"),
+		RedisURL:           os.Getenv("REDIS_URL"),
+		PIDFile:            os.Getenv("PID_FILE"),
+	}
+	if cfg.Port == "" {
+		cfg.Port = "8000"
+	}
+	for _, addr := range strings.Split(os.Getenv("LISTEN_ADDRS"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			cfg.ListenAddrs = append(cfg.ListenAddrs, addr)
+		}
+	}
+
+	cfg.MaxInputLength, _ = strconv.Atoi(os.Getenv("MAX_INPUT_LENGTH"))
+	cfg.FailureSampleRate, _ = strconv.ParseFloat(os.Getenv("FAILURE_SAMPLE_RATE"), 64)
+	cfg.FailureSamplesPerMinute, _ = strconv.Atoi(os.Getenv("FAILURE_SAMPLE_MAX_PER_MINUTE"))
+	cfg.RecentLookups, _ = strconv.Atoi(os.Getenv("RECENT_LOOKUPS"))
+	cfg.EnumEnabled, _ = strconv.ParseBool(os.Getenv("ENUM_ENABLED"))
+	cfg.DemoMode, _ = strconv.ParseBool(os.Getenv("DEMO_MODE"))
+	cfg.StrictContentType, _ = strconv.ParseBool(os.Getenv("STRICT_CONTENT_TYPE"))
+	cfg.ResultCacheSize, _ = strconv.Atoi(os.Getenv("RESULT_CACHE_SIZE"))
+	cfg.EchoMaxLength, _ = strconv.Atoi(os.Getenv("ECHO_MAX_LENGTH"))
+	cfg.ShadowSampleRate, _ = strconv.ParseFloat(os.Getenv("SHADOW_SAMPLE_RATE"), 64)
+	cfg.JobMemoryResults, _ = strconv.Atoi(os.Getenv("JOB_MEMORY_RESULTS"))
+	cfg.JobMaxRunning, _ = strconv.Atoi(os.Getenv("JOB_MAX_RUNNING"))
+	cfg.JobMaxDiskBytes, _ = strconv.ParseInt(os.Getenv("JOB_MAX_DISK_BYTES"), 10, 64)
+	cfg.JobIdempotencyWindow, _ = time.ParseDuration(os.Getenv("JOB_IDEMPOTENCY_WINDOW"))
+	cfg.CacheSeedBudget, _ = time.ParseDuration(os.Getenv("CACHE_SEED_BUDGET"))
+	cfg.NegativeCacheSize, _ = strconv.Atoi(os.Getenv("NEGATIVE_CACHE_SIZE"))
+	cfg.NegativeCacheTTL, _ = time.ParseDuration(os.Getenv("NEGATIVE_CACHE_TTL"))
+	cfg.RedisTimeout, _ = time.ParseDuration(os.Getenv("REDIS_TIMEOUT"))
+	cfg.RateLimitFailClosed, _ = strconv.ParseBool(os.Getenv("RATE_LIMIT_FAIL_CLOSED"))
+	cfg.SelfUpgrade, _ = strconv.ParseBool(os.Getenv("ENABLE_SELF_UPGRADE"))
+	cfg.CountryPreferenceOrder = api.ParseCountryList(os.Getenv("COUNTRY_PREFERENCE_ORDER"))
+	if cfg.EnumDNSServer == "" {
+		cfg.EnumDNSServer = systemNameserver()
+	}
+
+	return cfg
+}
+
+// probeURL is the address the healthcheck subcommand targets; it always
+// derives from the same config the server listens on, base path included.
+// With LISTEN_ADDRS that is the first TCP address, probed on loopback when
+// it binds every interface.
+func (cfg config) probeURL() string {
+	hostPort := net.JoinHostPort("127.0.0.1", cfg.Port)
+	for _, addr := range cfg.ListenAddrs {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || strings.HasPrefix(addr, "unix:") {
+			continue
+		}
+		if ip := net.ParseIP(host); host == "" || ip.IsUnspecified() {
+			host = "127.0.0.1"
+			if ip != nil && ip.To4() == nil {
+				host = "::1"
+			}
+		}
+		hostPort = net.JoinHostPort(host, port)
+		break
+	}
+	return "http://" + hostPort + cfg.BasePath + "/readyz"
+}
+
+// systemNameserver returns the first nameserver from /etc/resolv.conf.
+func systemNameserver() string {
+	file, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return "127.0.0.1:53"
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53")
+		}
+	}
+	return "127.0.0.1:53"
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const healthcheckTimeout = 2 * time.Second
+
+func runHealthcheck(url string, stderr io.Writer) int {
+	client := &http.Client{Timeout: healthcheckTimeout}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		fmt.Fprintf(stderr, "healthcheck failed: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		fmt.Fprintf(stderr, "healthcheck failed: %s returned %d: %s\n", url, resp.StatusCode, body)
+		return 1
+	}
+
+	return 0
+}
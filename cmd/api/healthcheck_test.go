@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"phone-api/api"
+)
+
+func TestRunHealthcheck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	apiServer, err := api.NewServer(api.Config{HandlerOptions: []api.HandlerOption{api.WithAdminToken("secret")}})
+	assert.NoError(t, err)
+	server := httptest.NewServer(apiServer.Handler())
+	defer server.Close()
+
+	setMaintenance := func(enabled bool) {
+		body := `{"enabled":false}`
+		if enabled {
+			body = `{"enabled":true}`
+		}
+		req, _ := http.NewRequest("POST", server.URL+"/admin/maintenance", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	t.Run("Healthy", func(t *testing.T) {
+		var stderr bytes.Buffer
+		assert.Equal(t, 0, runHealthcheck(server.URL+"/readyz", &stderr))
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("Not Ready", func(t *testing.T) {
+		setMaintenance(true)
+		defer setMaintenance(false)
+
+		var stderr bytes.Buffer
+		assert.Equal(t, 1, runHealthcheck(server.URL+"/readyz", &stderr))
+		assert.Contains(t, stderr.String(), "503")
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		unreachable := httptest.NewServer(apiServer.Handler())
+		url := unreachable.URL + "/readyz"
+		unreachable.Close()
+
+		var stderr bytes.Buffer
+		assert.Equal(t, 1, runHealthcheck(url, &stderr))
+		assert.Contains(t, stderr.String(), "healthcheck failed")
+	})
+}
+
+func TestConfigProbeURL(t *testing.T) {
+	t.Setenv("PORT", "9123")
+	assert.Equal(t, "http://127.0.0.1:9123/readyz", loadConfig().probeURL())
+
+	t.Setenv("PORT", "")
+	assert.Equal(t, "http://127.0.0.1:8000/readyz", loadConfig().probeURL())
+
+	t.Setenv("BASE_PATH", "api/phone/")
+	assert.Equal(t, "http://127.0.0.1:8000/api/phone/readyz", loadConfig().probeURL())
+
+	t.Setenv("BASE_PATH", "")
+	t.Setenv("LISTEN_ADDRS", "unix:/run/phone-api.sock, [::]:9000,0.0.0.0:9001")
+	assert.Equal(t, "http://[::1]:9000/readyz", loadConfig().probeURL())
+
+	t.Setenv("LISTEN_ADDRS", "10.0.0.5:9002")
+	assert.Equal(t, "http://10.0.0.5:9002/readyz", loadConfig().probeURL())
+}
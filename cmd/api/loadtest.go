@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phone-api/api"
+)
+
+var loadtestInvalidInputs = []string{
+	"212abc0123",
+	"212-569-0123",
+	"2125690123",
+	"+999123456789",
+	"351 21 094 2000",
+	"+1212569",
+}
+
+type loadtestInput struct {
+	phoneNumber string
+	valid       bool
+}
+
+type latencyPercentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+type loadtestReport struct {
+	Target               string             `json:"target"`
+	Mix                  string             `json:"mix"`
+	DurationSeconds      float64            `json:"durationSeconds"`
+	Requests             int                `json:"requests"`
+	InvalidInputs        int                `json:"invalidInputs"`
+	Errors               int                `json:"errors"`
+	ErrorRate            float64            `json:"errorRate"`
+	LatencyMs            latencyPercentiles `json:"latencyMs"`
+	P99ThresholdMs       float64            `json:"p99ThresholdMs,omitempty"`
+	P99ThresholdExceeded bool               `json:"p99ThresholdExceeded"`
+}
+
+func runLoadtest(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	target := flags.String("target", "http://localhost:8000", "base URL of the server under test")
+	rps := flags.Int("rps", 100, "requests per second")
+	duration := flags.Duration("duration", 10*time.Second, "how long to generate load")
+	mix := flags.String("mix", "mixed", "input mix: valid, invalid or mixed")
+	invalidPercent := flags.Int("invalid-percent", 20, "percentage of invalid inputs in the mixed profile")
+	format := flags.String("format", "text", "report format: text or json")
+	p99Threshold := flags.Duration("p99-threshold", 0, "exit non-zero when p99 latency exceeds this")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "random seed for input selection")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	switch *mix {
+	case "valid":
+		*invalidPercent = 0
+	case "invalid":
+		*invalidPercent = 100
+	case "mixed":
+	default:
+		fmt.Fprintf(stderr, "loadtest: unknown mix %q\n", *mix)
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "loadtest: unknown format %q\n", *format)
+		return 2
+	}
+	if *rps <= 0 || *duration <= 0 {
+		fmt.Fprintln(stderr, "loadtest: rps and duration must be positive")
+		return 2
+	}
+
+	report := generateLoad(*target, *rps, *duration, *invalidPercent, rand.New(rand.NewSource(*seed)))
+	report.Mix = *mix
+	if *p99Threshold > 0 {
+		report.P99ThresholdMs = float64(*p99Threshold) / float64(time.Millisecond)
+		report.P99ThresholdExceeded = report.LatencyMs.P99 > report.P99ThresholdMs
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		writeLoadtestText(stdout, report)
+	}
+
+	if report.P99ThresholdExceeded {
+		return 1
+	}
+	return 0
+}
+
+func generateLoad(target string, rps int, duration time.Duration, invalidPercent int, rng *rand.Rand) loadtestReport {
+	countries := make([]string, 0, len(api.CountryExampleNumbers))
+	for code := range api.CountryExampleNumbers {
+		countries = append(countries, code)
+	}
+	sort.Strings(countries)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	endpoint := strings.TrimRight(target, "/") + "/v1/phone-numbers?phoneNumber="
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+		report    = loadtestReport{Target: target}
+	)
+
+	ticker := time.NewTicker(time.Second / time.Duration(rps))
+	defer ticker.Stop()
+	start := time.Now()
+	deadline := time.After(duration)
+
+loop:
+	for {
+		select {
+		case <-deadline:
+			break loop
+		case <-ticker.C:
+			input := loadtestInput{valid: rng.Intn(100) >= invalidPercent}
+			if input.valid {
+				input.phoneNumber, _ = api.ExampleNumber(countries[rng.Intn(len(countries))])
+			} else {
+				input.phoneNumber = loadtestInvalidInputs[rng.Intn(len(loadtestInvalidInputs))]
+			}
+
+			wg.Add(1)
+			go func(input loadtestInput) {
+				defer wg.Done()
+				requestStart := time.Now()
+				resp, err := client.Get(endpoint + url.QueryEscape(input.phoneNumber))
+				latency := time.Since(requestStart)
+
+				failed := err != nil
+				if err == nil {
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+					expected := http.StatusOK
+					if !input.valid {
+						expected = http.StatusBadRequest
+					}
+					failed = resp.StatusCode != expected
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				latencies = append(latencies, latency)
+				report.Requests++
+				if !input.valid {
+					report.InvalidInputs++
+				}
+				if failed {
+					report.Errors++
+				}
+			}(input)
+		}
+	}
+	wg.Wait()
+
+	report.DurationSeconds = time.Since(start).Seconds()
+	if report.Requests > 0 {
+		report.ErrorRate = float64(report.Errors) / float64(report.Requests)
+	}
+	report.LatencyMs = computePercentiles(latencies)
+	return report
+}
+
+func computePercentiles(latencies []time.Duration) latencyPercentiles {
+	if len(latencies) == 0 {
+		return latencyPercentiles{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	at := func(p float64) float64 {
+		index := int(math.Ceil(p*float64(len(latencies)))) - 1
+		if index < 0 {
+			index = 0
+		}
+		return float64(latencies[index]) / float64(time.Millisecond)
+	}
+
+	return latencyPercentiles{
+		P50: at(0.50),
+		P90: at(0.90),
+		P99: at(0.99),
+		Max: at(1),
+	}
+}
+
+func writeLoadtestText(w io.Writer, report loadtestReport) {
+	fmt.Fprintf(w, "target:    %s (%s mix)\n", report.Target, report.Mix)
+	fmt.Fprintf(w, "duration:  %.1fs\n", report.DurationSeconds)
+	fmt.Fprintf(w, "requests:  %d (%d invalid inputs)\n", report.Requests, report.InvalidInputs)
+	fmt.Fprintf(w, "errors:    %d (%.2f%%)\n", report.Errors, report.ErrorRate*100)
+	fmt.Fprintf(w, "latency:   p50=%.2fms p90=%.2fms p99=%.2fms max=%.2fms\n",
+		report.LatencyMs.P50, report.LatencyMs.P90, report.LatencyMs.P99, report.LatencyMs.Max)
+	if report.P99ThresholdMs > 0 {
+		status := "ok"
+		if report.P99ThresholdExceeded {
+			status = "EXCEEDED"
+		}
+		fmt.Fprintf(w, "threshold: p99 <= %.2fms %s\n", report.P99ThresholdMs, status)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"phone-api/api"
+)
+
+func TestRunLoadtest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	apiServer, err := api.NewServer(api.Config{})
+	assert.NoError(t, err)
+	server := httptest.NewServer(apiServer.Handler())
+	defer server.Close()
+
+	t.Run("JSON Report", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := runLoadtest([]string{
+			"--target", server.URL, "--rps", "50", "--duration", "1s",
+			"--mix", "mixed", "--format", "json", "--seed", "1",
+		}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stderr.String())
+
+		var report loadtestReport
+		err := json.Unmarshal(stdout.Bytes(), &report)
+		assert.NoError(t, err)
+		assert.Equal(t, server.URL, report.Target)
+		assert.Equal(t, "mixed", report.Mix)
+		assert.Greater(t, report.Requests, 10)
+		assert.Greater(t, report.InvalidInputs, 0)
+		assert.Equal(t, 0, report.Errors)
+		assert.LessOrEqual(t, report.LatencyMs.P50, report.LatencyMs.P99)
+		assert.LessOrEqual(t, report.LatencyMs.P99, report.LatencyMs.Max)
+	})
+
+	t.Run("P99 Threshold Exceeded", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := runLoadtest([]string{
+			"--target", server.URL, "--rps", "20", "--duration", "1s",
+			"--mix", "valid", "--p99-threshold", "1ns",
+		}, &stdout, &stderr)
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stdout.String(), "EXCEEDED")
+	})
+
+	t.Run("Unknown Mix", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, runLoadtest([]string{"--mix", "bogus"}, &stdout, &stderr))
+	})
+
+	t.Run("Unknown Format", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, runLoadtest([]string{"--format", "yaml"}, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), `unknown format "yaml"`)
+		assert.Empty(t, stdout.String())
+	})
+}
+
+func TestComputePercentiles(t *testing.T) {
+	latencies := make([]time.Duration, 0, 100)
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	p := computePercentiles(latencies)
+	assert.Equal(t, 50.0, p.P50)
+	assert.Equal(t, 90.0, p.P90)
+	assert.Equal(t, 99.0, p.P99)
+	assert.Equal(t, 100.0, p.Max)
+}
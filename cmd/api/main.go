@@ -1,38 +1,230 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"phone-api/api"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "loadtest" {
+		os.Exit(runLoadtest(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "smoke" {
+		os.Exit(runSmoke(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	healthcheck := flag.Bool("healthcheck", false, "probe the running server's /readyz and exit 0 if ready")
+	flag.Parse()
+
+	cfg := loadConfig()
+
+	if *healthcheck {
+		os.Exit(runHealthcheck(cfg.probeURL(), os.Stderr))
+	}
+
 	if os.Getenv("GIN_MODE") == "" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := gin.Default()
+	var enumLookup *api.EnumLookup
+	if cfg.EnumEnabled {
+		enumLookup = api.NewEnumLookup(&api.DNSResolver{Server: cfg.EnumDNSServer}, cfg.EnumSuffix)
+	}
+
+	var apiKeys *api.APIKeyStore
+	if cfg.APIKeysFile != "" {
+		store, err := api.LoadAPIKeyStore(cfg.APIKeysFile)
+		if err != nil {
+			log.Fatal("Failed to load API keys:", err)
+		}
+		reloadOnHangup("API keys", store.Reload)
+		apiKeys = store
+	}
+
+	var errorMessages *api.ErrorMessageStore
+	if cfg.ErrorMessagesFile != "" {
+		store, err := api.LoadErrorMessages(cfg.ErrorMessagesFile)
+		if err != nil {
+			log.Fatal("Failed to load error messages:", err)
+		}
+		reloadOnHangup("Error messages", store.Reload)
+		errorMessages = store
+	}
+
+	var portability api.PortabilityResolver
+	if cfg.PortedRangesFile != "" {
+		ranges, err := api.LoadPortedRanges(cfg.PortedRangesFile)
+		if err != nil {
+			log.Fatal("Failed to load ported ranges:", err)
+		}
+		reloadOnHangup("Ported ranges", ranges.Reload)
+		portability = ranges
+	}
+
+	var corpus *api.CorpusRecorder
+	if cfg.CorpusFile != "" {
+		recorder, err := api.NewCorpusRecorder(cfg.CorpusFile)
+		if err != nil {
+			log.Fatal("Failed to open corpus file:", err)
+		}
+		corpus = recorder
+	}
+
+	var metadata *api.MetadataSource
+	if cfg.MetadataFile != "" {
+		file, err := api.LoadMetadataFile(cfg.MetadataFile, cfg.MetadataFallback)
+		if file == nil {
+			log.Fatal("Failed to load metadata:", err)
+		}
+		if err != nil {
+			log.Printf("Failed to load metadata, validating on the minimal table: %v", err)
+		}
+		reloadOnHangup("Metadata", file.Reload)
+		metadata = file.Source()
+	}
+
+	// The shadow validator is the primary one on candidate metadata, so
+	// a regenerated metadata file can be compared on live traffic first.
+	var shadow api.Validator
+	if cfg.ShadowMetadataFile != "" {
+		file, err := api.LoadMetadataFile(cfg.ShadowMetadataFile, "")
+		if err != nil {
+			log.Fatal("Failed to load shadow metadata:", err)
+		}
+		reloadOnHangup("Shadow metadata", file.Reload)
+		shadow = api.NewPhoneNumberValidator(
+			api.WithMaxInputLength(cfg.MaxInputLength),
+			api.WithDisabledCountries(cfg.DisabledCountries...),
+			api.WithSuspiciousPatterns(cfg.SuspiciousPatterns),
+			api.WithMetadataSource(file.Source()),
+		)
+	}
 
-	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{"*"}
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	router.Use(cors.New(config))
+	callingHours, err := api.ParseCallingHours(cfg.CallWindowStart, cfg.CallWindowEnd)
+	if err != nil {
+		log.Fatal("Invalid calling hours:", err)
+	}
 
-	handler := api.NewHandler()
-	handler.SetupRoutes(router)
+	listeners, err := activatedListeners()
+	if err != nil {
+		log.Fatal("Failed to use activated sockets:", err)
+	}
+	addrs := cfg.ListenAddrs
+	inherited, err := inheritedListeners(cfg.ListenAddrs)
+	if err != nil {
+		log.Fatal("Failed to use sockets handed over by the previous process:", err)
+	}
+	if inherited != nil {
+		listeners, addrs = inherited, nil
+	}
+	if len(listeners) == 0 && len(addrs) == 0 {
+		log.Printf("Starting server on port %s", cfg.Port)
+	}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8000"
+	handlerOptions := []api.HandlerOption{
+		api.WithValidatorOptions(
+			api.WithMaxInputLength(cfg.MaxInputLength),
+			api.WithDisabledCountries(cfg.DisabledCountries...),
+			api.WithSuspiciousPatterns(cfg.SuspiciousPatterns),
+			api.WithMetadataSource(metadata),
+		),
+		api.WithAdminToken(cfg.AdminToken),
+		api.WithFailureSampling(cfg.FailureSampleRate, cfg.FailureSamplesPerMinute, log.Default()),
+		api.WithParamAliases(cfg.ParamAliases),
+		api.WithEnumLookup(enumLookup),
+		api.WithAPIKeys(apiKeys),
+		api.WithRecentLookups(cfg.RecentLookups),
+		api.WithBasePath(cfg.BasePath),
+		api.WithWebhooks(nil, cfg.WebhookSecret),
+		api.WithErrorMessages(errorMessages),
+		api.WithResultCache(cfg.ResultCacheSize),
+		api.WithCacheSeed(cfg.CacheSeedFile, cfg.CacheSeedBudget, log.Default()),
+		api.WithNegativeCache(cfg.NegativeCacheSize, cfg.NegativeCacheTTL),
+		api.WithCallingHours(callingHours),
+		api.WithCorpusRecorder(corpus),
+		api.WithEchoLimit(cfg.EchoMaxLength),
+		api.WithPortability(portability),
+		api.WithJobSpill(cfg.JobSpillDir, cfg.JobMemoryResults),
+		api.WithJobLimits(cfg.JobMaxRunning, cfg.JobMaxDiskBytes),
+		api.WithShadowValidator(shadow, cfg.ShadowSampleRate),
+		api.WithCountryPreference(cfg.CountryPreferenceOrder...),
+	}
+	if cfg.RedisURL != "" {
+		redisOptions, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			log.Fatal("Invalid REDIS_URL:", err)
+		}
+		handlerOptions = append(handlerOptions, api.WithRedisRateLimit(redis.NewClient(redisOptions), cfg.RedisTimeout, cfg.RateLimitFailClosed))
+	}
+	if cfg.DemoMode {
+		log.Printf("Demo mode enabled")
+		handlerOptions = append(handlerOptions, api.WithDemoMode())
+	}
+	if cfg.StrictContentType {
+		handlerOptions = append(handlerOptions, api.WithStrictContentType())
+	}
+	if cfg.JobIdempotencyWindow > 0 {
+		handlerOptions = append(handlerOptions, api.WithJobIdempotency(cfg.JobIdempotencyWindow))
+	}
+
+	server, err := api.NewServer(api.Config{
+		Addr:           ":" + cfg.Port,
+		Addrs:          addrs,
+		Listeners:      listeners,
+		Logger:         log.Default(),
+		HandlerOptions: handlerOptions,
+	})
+	if err != nil {
+		log.Fatal("Failed to build server:", err)
 	}
 
-	log.Printf("Starting server on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	if err := server.Listen(); err != nil {
+		log.Fatal("Server error:", err)
 	}
-}
\ No newline at end of file
+	if cfg.SelfUpgrade {
+		handleUpgrades(server, log.Default())
+	}
+	announceReady(cfg.PIDFile)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = server.Run(ctx)
+	removePIDFile(cfg.PIDFile)
+	if err != nil {
+		log.Fatal("Server error:", err)
+	}
+	if corpus != nil {
+		if err := corpus.Close(); err != nil {
+			log.Printf("Failed to write corpus file: %v", err)
+		}
+	}
+}
+
+// reloadOnHangup calls reload on SIGHUP; a bad file is logged and the
+// previous contents stay in effect.
+func reloadOnHangup(name string, reload func() error) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			if err := reload(); err != nil {
+				log.Printf("%s reload failed: %v", name, err)
+				continue
+			}
+			log.Printf("%s reloaded", name)
+		}
+	}()
+}
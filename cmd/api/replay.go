@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	"phone-api/api"
+)
+
+type replayReport struct {
+	Corpus   string `json:"corpus"`
+	Metadata string `json:"metadata"`
+	api.ReplayReport
+	Threshold         int  `json:"threshold"`
+	ThresholdExceeded bool `json:"thresholdExceeded"`
+}
+
+// runReplay re-validates a recorded corpus against candidate metadata and
+// exits 1 when more outcomes changed than the threshold allows.
+func runReplay(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	corpus := flags.String("corpus", "", "corpus file written by CORPUS_FILE")
+	metadata := flags.String("metadata", "", "JSON array of candidate country metadata")
+	threshold := flags.Int("threshold", 0, "number of changed outcomes tolerated")
+	format := flags.String("format", "text", "report format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *corpus == "" || *metadata == "" {
+		fmt.Fprintln(stderr, "replay: --corpus and --metadata are required")
+		return 2
+	}
+
+	entries, err := api.LoadCorpus(*corpus)
+	if err != nil {
+		fmt.Fprintln(stderr, "replay:", err)
+		return 2
+	}
+	candidate, err := api.LoadCountryMetadata(*metadata)
+	if err != nil {
+		fmt.Fprintln(stderr, "replay:", err)
+		return 2
+	}
+
+	report := replayReport{
+		Corpus:       *corpus,
+		Metadata:     *metadata,
+		ReplayReport: api.ReplayCorpus(entries, api.NewPhoneNumberValidator(api.WithCountryMetadata(candidate...))),
+		Threshold:    *threshold,
+	}
+	report.ThresholdExceeded = report.Changed > report.Threshold
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		writeReplayText(stdout, report)
+	}
+
+	if report.ThresholdExceeded {
+		return 1
+	}
+	return 0
+}
+
+func writeReplayText(w io.Writer, report replayReport) {
+	fmt.Fprintf(w, "corpus:    %s (%d entries)\n", report.Corpus, report.Replayed)
+	fmt.Fprintf(w, "metadata:  %s\n", report.Metadata)
+	fmt.Fprintf(w, "changed:   %d\n", report.Changed)
+
+	countries := make([]string, 0, len(report.Diffs))
+	for country := range report.Diffs {
+		countries = append(countries, country)
+	}
+	sort.Strings(countries)
+	for _, country := range countries {
+		transitions := make([]string, 0, len(report.Diffs[country]))
+		for transition := range report.Diffs[country] {
+			transitions = append(transitions, transition)
+		}
+		sort.Strings(transitions)
+		for _, transition := range transitions {
+			fmt.Fprintf(w, "  %-6s %s: %d\n", country, transition, report.Diffs[country][transition])
+		}
+	}
+
+	status := "ok"
+	if report.ThresholdExceeded {
+		status = "EXCEEDED"
+	}
+	fmt.Fprintf(w, "threshold: changed <= %d %s\n", report.Threshold, status)
+}
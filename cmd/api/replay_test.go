@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"phone-api/api"
+)
+
+// recordCorpus sends lookups through a server recording to a corpus file.
+func recordCorpus(t *testing.T, opts ...api.HandlerOption) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "corpus.jsonl")
+	recorder, err := api.NewCorpusRecorder(path)
+	assert.NoError(t, err)
+
+	apiServer, err := api.NewServer(api.Config{HandlerOptions: append(opts, api.WithCorpusRecorder(recorder))})
+	assert.NoError(t, err)
+	server := httptest.NewServer(apiServer.Handler())
+	defer server.Close()
+
+	for _, query := range []url.Values{
+		{"phoneNumber": {"+12125690123"}},
+		{"phoneNumber": {"212 569 0124"}, "countryCode": {"US"}},
+		{"phoneNumber": {"+12125690"}},
+		{"phoneNumber": {"+442079460958"}},
+		{"phoneNumber": {"+493012345678"}},
+	} {
+		resp, err := http.Get(server.URL + "/v1/phone-numbers?" + query.Encode())
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+	resp, err := http.Post(server.URL+"/v1/phone-numbers/batch", "application/json",
+		strings.NewReader(`{"items": [{"phoneNumber": "+14165550123"}, {"phoneNumber": "+1416555"}]}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.NoError(t, recorder.Close())
+	return path
+}
+
+func writeMetadata(t *testing.T, entries ...api.CountryMetadata) string {
+	t.Helper()
+	data, err := json.Marshal(entries)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestRunReplay(t *testing.T) {
+	corpus := recordCorpus(t)
+	entries, err := api.LoadCorpus(corpus)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 7)
+	assert.Equal(t, "+12125690124", entries[1].Request.PhoneNumber, "clean successes are stored as E.164")
+	assert.Equal(t, string(api.ErrorLengthOutOfRange), entries[2].Outcome)
+
+	validToLength := api.OutcomeValid + "->" + string(api.ErrorLengthOutOfRange)
+	lengthToValid := string(api.ErrorLengthOutOfRange) + "->" + api.OutcomeValid
+
+	t.Run("Unchanged Metadata", func(t *testing.T) {
+		metadata := writeMetadata(t, api.CountryMetadata{CountryCode: "GB", MinLength: 10, MaxLength: 11})
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, runReplay([]string{"--corpus", corpus, "--metadata", metadata}, &stdout, &stderr), stderr.String())
+		assert.Contains(t, stdout.String(), "changed:   0")
+	})
+
+	t.Run("Altered Metadata", func(t *testing.T) {
+		// US numbers become 7 digits long and GB ones can no longer start
+		// with 2.
+		metadata := writeMetadata(t,
+			api.CountryMetadata{CountryCode: "US", MinLength: 7, MaxLength: 7},
+			api.CountryMetadata{CountryCode: "GB", MinLength: 10, MaxLength: 11, LeadingDigits: "13"},
+		)
+		var stdout, stderr bytes.Buffer
+		code := runReplay([]string{"--corpus", corpus, "--metadata", metadata, "--format", "json", "--threshold", "2"}, &stdout, &stderr)
+		assert.Equal(t, 1, code, stderr.String())
+
+		var report replayReport
+		assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+		assert.Equal(t, 7, report.Replayed)
+		assert.Equal(t, 5, report.Changed)
+		assert.True(t, report.ThresholdExceeded)
+		assert.Equal(t, map[string]map[string]int{
+			"US": {validToLength: 3, lengthToValid: 1},
+			"GB": {api.OutcomeValid + "->" + string(api.ErrorInvalidLeadingDigit): 1},
+		}, report.Diffs)
+
+		stdout.Reset()
+		assert.Equal(t, 0, runReplay([]string{"--corpus", corpus, "--metadata", metadata, "--threshold", "5"}, &stdout, &stderr))
+		assert.Contains(t, stdout.String(), "US     "+validToLength+": 3")
+	})
+
+	t.Run("Privacy Mode", func(t *testing.T) {
+		masked := recordCorpus(t, api.WithPrivacyMode())
+		data, err := os.ReadFile(masked)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "2125690123")
+
+		entries, err := api.LoadCorpus(masked)
+		assert.NoError(t, err)
+		assert.True(t, entries[0].Masked)
+		assert.Len(t, entries[0].Hash, 64)
+		assert.Equal(t, "+1212", entries[0].Request.PhoneNumber[:5])
+		assert.Len(t, entries[0].Request.PhoneNumber, len("+12125690123"))
+
+		metadata := writeMetadata(t, api.CountryMetadata{CountryCode: "US", MinLength: 7, MaxLength: 7})
+		var stdout, stderr bytes.Buffer
+		runReplay([]string{"--corpus", masked, "--metadata", metadata, "--format", "json"}, &stdout, &stderr)
+		var report replayReport
+		assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+		assert.Equal(t, map[string]int{validToLength: 3, lengthToValid: 1}, report.Diffs["US"])
+	})
+
+	t.Run("Missing Flags", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, runReplay([]string{"--corpus", corpus}, &stdout, &stderr))
+	})
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"phone-api/api"
+)
+
+// smokeInvalidNumber is too short for the NANP, so every deployment must
+// reject it with the status and code ErrorRegistry declares.
+const (
+	smokeInvalidNumber = "+1212569"
+	smokeInvalidCode   = api.ErrorLengthOutOfRange
+)
+
+type smokeCheck struct {
+	Name       string  `json:"name"`
+	Passed     bool    `json:"passed"`
+	DurationMs float64 `json:"durationMs"`
+	Detail     string  `json:"detail,omitempty"`
+}
+
+type smokeReport struct {
+	Target          string       `json:"target"`
+	MetadataVersion string       `json:"metadataVersion"`
+	Checks          []smokeCheck `json:"checks"`
+	Failed          int          `json:"failed"`
+}
+
+type smokeRunner struct {
+	client *http.Client
+	target string
+	apiKey string
+	report smokeReport
+}
+
+// runSmoke runs a curated suite against a live deployment and exits 1 when
+// any check fails. The countries and expected error statuses come from the
+// deployment's capabilities and the contract in ErrorRegistry.
+func runSmoke(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("smoke", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	target := flags.String("target", "", "base URL of the deployment, including any base path")
+	apiKey := flags.String("api-key", "", "API key sent as X-API-Key")
+	timeout := flags.Duration("timeout", 5*time.Second, "timeout of each request")
+	checkAuth := flags.Bool("check-auth", false, "check that requests without an API key are refused")
+	rateLimitBurst := flags.Int("rate-limit-burst", 0, "send up to this many lookups and check one is answered 429")
+	format := flags.String("format", "text", "report format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *target == "" {
+		fmt.Fprintln(stderr, "smoke: --target is required")
+		return 2
+	}
+	if *checkAuth && *apiKey == "" {
+		fmt.Fprintln(stderr, "smoke: --check-auth needs --api-key")
+		return 2
+	}
+
+	runner := &smokeRunner{
+		client: &http.Client{Timeout: *timeout},
+		target: strings.TrimRight(*target, "/"),
+		apiKey: *apiKey,
+		report: smokeReport{Target: *target},
+	}
+	runner.run(*checkAuth, *rateLimitBurst)
+
+	if *format == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(runner.report)
+	} else {
+		writeSmokeText(stdout, runner.report)
+	}
+
+	if runner.report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (r *smokeRunner) run(checkAuth bool, rateLimitBurst int) {
+	r.check("health", func() error {
+		_, err := r.get("/health", true, http.StatusOK)
+		return err
+	})
+
+	var capabilities api.Capabilities
+	if !r.check("capabilities", func() error {
+		body, err := r.get("/v1/capabilities", true, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(body, &capabilities); err != nil {
+			return fmt.Errorf("decoding capabilities: %v", err)
+		}
+		if capabilities.MetadataVersion == "" {
+			return fmt.Errorf("no metadataVersion reported")
+		}
+		if len(capabilities.Countries) == 0 {
+			return fmt.Errorf("no countries reported")
+		}
+		r.report.MetadataVersion = capabilities.MetadataVersion
+		return nil
+	}) {
+		// Everything below is derived from the capabilities.
+		return
+	}
+
+	disabled := map[string]bool{}
+	for _, country := range capabilities.DisabledCountries {
+		disabled[country] = true
+	}
+	var batch []api.BatchItem
+	for _, country := range capabilities.Countries {
+		example, exists := api.ExampleNumber(country)
+		if !exists || disabled[country] {
+			continue
+		}
+		batch = append(batch, api.BatchItem{ID: country, PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: example}})
+		r.check("lookup "+country, func() error {
+			return r.lookupCountry(example, country)
+		})
+	}
+
+	r.check("lookup invalid", func() error {
+		expected := api.ErrorRegistry[smokeInvalidCode].Status
+		body, err := r.get("/v1/phone-numbers?phoneNumber="+url.QueryEscape(smokeInvalidNumber), true, expected)
+		if err != nil {
+			return err
+		}
+		var response api.ErrorResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return fmt.Errorf("decoding error response: %v", err)
+		}
+		if response.Code != smokeInvalidCode {
+			return fmt.Errorf("code %q, expected %q", response.Code, smokeInvalidCode)
+		}
+		return nil
+	})
+
+	if capabilities.Features["batch"] && len(batch) > 0 {
+		if len(batch) > 3 {
+			batch = batch[:3]
+		}
+		r.check("batch", func() error {
+			return r.batch(batch)
+		})
+	}
+
+	if checkAuth {
+		r.check("auth", func() error {
+			_, err := r.get("/v1/capabilities", false, http.StatusUnauthorized)
+			return err
+		})
+	}
+
+	if rateLimitBurst > 0 {
+		example := smokeInvalidNumber
+		if len(batch) > 0 {
+			example = batch[0].PhoneNumber
+		}
+		r.check("rate limit", func() error {
+			return r.rateLimit(example, rateLimitBurst)
+		})
+	}
+}
+
+// check runs fn as the check name and records its outcome.
+func (r *smokeRunner) check(name string, fn func() error) bool {
+	start := time.Now()
+	err := fn()
+	check := smokeCheck{
+		Name:       name,
+		Passed:     err == nil,
+		DurationMs: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+	if err != nil {
+		check.Detail = err.Error()
+		r.report.Failed++
+	}
+	r.report.Checks = append(r.report.Checks, check)
+	return err == nil
+}
+
+func (r *smokeRunner) lookupCountry(example, country string) error {
+	body, err := r.get("/v1/phone-numbers?phoneNumber="+url.QueryEscape(example), true, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	var response api.PhoneValidationResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("decoding lookup response: %v", err)
+	}
+	// CA shares +1 with US, so its example resolves to US; any country
+	// with the same dialing code passes.
+	if api.CountryDialingCodes[response.CountryCode] != api.CountryDialingCodes[country] {
+		return fmt.Errorf("countryCode %q, expected %q", response.CountryCode, country)
+	}
+	return nil
+}
+
+func (r *smokeRunner) batch(items []api.BatchItem) error {
+	payload, _ := json.Marshal(api.BatchRequest{Items: items})
+	req, _ := http.NewRequest(http.MethodPost, r.target+"/v1/phone-numbers/batch", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", api.MediaTypeJSON)
+	body, err := r.do(req, true, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	var response api.BatchResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("decoding batch response: %v", err)
+	}
+	if response.Summary.Total != len(items) || response.Summary.ValidCount != len(items) {
+		return fmt.Errorf("%d of %d items valid, expected all", response.Summary.ValidCount, len(items))
+	}
+	return nil
+}
+
+// rateLimit sends up to burst lookups and passes on the first 429, which
+// must carry a Retry-After header.
+func (r *smokeRunner) rateLimit(example string, burst int) error {
+	for i := 0; i < burst; i++ {
+		req, _ := http.NewRequest(http.MethodGet, r.target+"/v1/phone-numbers?phoneNumber="+url.QueryEscape(example), nil)
+		r.authorize(req, true)
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if resp.Header.Get("Retry-After") == "" {
+				return fmt.Errorf("429 without Retry-After")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no 429 after %d requests", burst)
+}
+
+func (r *smokeRunner) get(path string, withKey bool, expected int) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, r.target+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return r.do(req, withKey, expected)
+}
+
+func (r *smokeRunner) do(req *http.Request, withKey bool, expected int) ([]byte, error) {
+	r.authorize(req, withKey)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != expected {
+		if len(body) > 200 {
+			body = body[:200]
+		}
+		return nil, fmt.Errorf("%s %s returned %d, expected %d: %s", req.Method, req.URL.Path, resp.StatusCode, expected, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+func (r *smokeRunner) authorize(req *http.Request, withKey bool) {
+	if withKey && r.apiKey != "" {
+		req.Header.Set("X-API-Key", r.apiKey)
+	}
+}
+
+func writeSmokeText(w io.Writer, report smokeReport) {
+	fmt.Fprintf(w, "target:   %s\n", report.Target)
+	if report.MetadataVersion != "" {
+		fmt.Fprintf(w, "metadata: %s\n", report.MetadataVersion)
+	}
+	for _, check := range report.Checks {
+		status := "PASS"
+		if !check.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "  %s %-16s %8.1fms", status, check.Name, check.DurationMs)
+		if check.Detail != "" {
+			fmt.Fprintf(w, "  %s", check.Detail)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "checks:   %d, failed %d\n", len(report.Checks), report.Failed)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"phone-api/api"
+)
+
+func newSmokeServer(t *testing.T, opts ...api.HandlerOption) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	apiServer, err := api.NewServer(api.Config{HandlerOptions: opts})
+	assert.NoError(t, err)
+	server := httptest.NewServer(apiServer.Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunSmoke(t *testing.T) {
+	t.Run("Healthy Deployment", func(t *testing.T) {
+		server := newSmokeServer(t)
+		var stdout, stderr bytes.Buffer
+		code := runSmoke([]string{"--target", server.URL, "--format", "json"}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stdout.String())
+
+		var report smokeReport
+		assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+		assert.Equal(t, api.MetadataVersion(), report.MetadataVersion)
+		assert.Equal(t, 0, report.Failed)
+
+		names := map[string]bool{}
+		for _, check := range report.Checks {
+			names[check.Name] = true
+		}
+		for country := range api.CountryExampleNumbers {
+			assert.True(t, names["lookup "+country], country)
+		}
+		assert.True(t, names["health"])
+		assert.True(t, names["lookup invalid"])
+		assert.True(t, names["batch"])
+	})
+
+	t.Run("Auth And Rate Limit", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys.json")
+		keys, _ := json.Marshal(map[string]api.APIKeyConfig{
+			api.HashAPIKey("smoke-key"): {Label: "smoke", RateLimitPerMinute: 40},
+		})
+		assert.NoError(t, os.WriteFile(path, keys, 0o600))
+		store, err := api.LoadAPIKeyStore(path)
+		assert.NoError(t, err)
+		server := newSmokeServer(t, api.WithAPIKeys(store))
+
+		var stdout, stderr bytes.Buffer
+		code := runSmoke([]string{
+			"--target", server.URL, "--api-key", "smoke-key", "--check-auth", "--rate-limit-burst", "50",
+		}, &stdout, &stderr)
+		assert.Equal(t, 0, code, stdout.String())
+		assert.Contains(t, stdout.String(), "PASS auth")
+		assert.Contains(t, stdout.String(), "PASS rate limit")
+	})
+
+	t.Run("Disabled Countries Skipped", func(t *testing.T) {
+		server := newSmokeServer(t, api.WithValidatorOptions(api.WithDisabledCountries("GB")))
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, runSmoke([]string{"--target", server.URL}, &stdout, &stderr), stdout.String())
+		assert.NotContains(t, stdout.String(), "lookup GB")
+	})
+
+	t.Run("Broken Deployment", func(t *testing.T) {
+		healthy := newSmokeServer(t)
+		// Proxies to a healthy server but answers every lookup with 500.
+		broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v1/phone-numbers" {
+				http.Error(w, "lookup backend down", http.StatusInternalServerError)
+				return
+			}
+			resp, err := http.Get(healthy.URL + r.URL.RequestURI())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			defer resp.Body.Close()
+			w.WriteHeader(resp.StatusCode)
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			w.Write(buf.Bytes())
+		}))
+		defer broken.Close()
+
+		var stdout, stderr bytes.Buffer
+		code := runSmoke([]string{"--target", broken.URL}, &stdout, &stderr)
+		assert.Equal(t, 1, code)
+		assert.Contains(t, stdout.String(), "FAIL lookup US")
+		assert.Contains(t, stdout.String(), "FAIL lookup invalid")
+		assert.Contains(t, stdout.String(), "PASS health")
+	})
+
+	t.Run("Unreachable Target", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 1, runSmoke([]string{"--target", "http://127.0.0.1:1"}, &stdout, &stderr))
+		assert.Contains(t, stdout.String(), "FAIL health")
+	})
+
+	t.Run("Missing Target", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, runSmoke(nil, &stdout, &stderr))
+	})
+}
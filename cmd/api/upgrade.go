@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// upgradeFDsEnv is how many listening sockets a process handing over to an
+// upgraded binary passes it, starting at fd 3 like socket activation. The
+// readiness pipe follows them. systemd's LISTEN_PID cannot be used because
+// the new process's pid is unknown until it has started.
+const upgradeFDsEnv = "PHONE_API_UPGRADE_FDS"
+
+// upgradeTimeout is how long the new binary has to warm up and report
+// ready before the upgrade is abandoned.
+const upgradeTimeout = time.Minute
+
+// upgradeReady is the pipe to the process being replaced, written once
+// this one serves.
+var upgradeReady *os.File
+
+// inheritedListeners returns the sockets the previous process handed over
+// in a self-upgrade, or nil when the process was started normally. Unix
+// sockets bound from addrs, rather than by systemd, are unlinked on
+// shutdown again, as they would have been by the process that bound them.
+func inheritedListeners(addrs []string) ([]net.Listener, error) {
+	value, inherited := os.LookupEnv(upgradeFDsEnv)
+	if !inherited {
+		return nil, nil
+	}
+	os.Unsetenv(upgradeFDsEnv)
+	count, err := strconv.Atoi(value)
+	if err != nil || count <= 0 {
+		return nil, fmt.Errorf("invalid %s %q", upgradeFDsEnv, value)
+	}
+
+	files := make([]*os.File, count)
+	for i := range files {
+		files[i] = os.NewFile(uintptr(listenFDsStart+i), "UPGRADE_FD_"+strconv.Itoa(listenFDsStart+i))
+	}
+	upgradeReady = os.NewFile(uintptr(listenFDsStart+count), "upgrade-ready")
+
+	listeners, err := listenersFromFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	for _, listener := range listeners {
+		unixListener, ok := listener.(*net.UnixListener)
+		if !ok {
+			continue
+		}
+		for _, addr := range addrs {
+			if addr == "unix:"+listener.Addr().String() {
+				unixListener.SetUnlinkOnClose(true)
+			}
+		}
+	}
+	return listeners, nil
+}
+
+// announceReady reports that the process serves: it writes pidFile, tells
+// systemd its new main pid when run under Type=notify, and lets the
+// process it replaces, if any, hand off.
+func announceReady(pidFile string) {
+	if pidFile != "" {
+		if err := os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+			log.Printf("Failed to write PID file: %v", err)
+		}
+	}
+	if err := notifySystemd("READY=1\nMAINPID=" + strconv.Itoa(os.Getpid())); err != nil {
+		log.Printf("Failed to notify systemd: %v", err)
+	}
+	if upgradeReady != nil {
+		upgradeReady.Write([]byte{1})
+		upgradeReady.Close()
+		upgradeReady = nil
+	}
+}
+
+// removePIDFile removes pidFile unless a process that took over has
+// rewritten it.
+func removePIDFile(pidFile string) {
+	if pidFile == "" {
+		return
+	}
+	data, err := os.ReadFile(pidFile)
+	if err == nil && strings.TrimSpace(string(data)) == strconv.Itoa(os.Getpid()) {
+		os.Remove(pidFile)
+	}
+}
+
+// notifySystemd sends state to systemd's notification socket, if the
+// process was given one.
+func notifySystemd(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	conn, err := net.Dial("unixgram", socket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
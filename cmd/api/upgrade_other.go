@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import (
+	"log"
+
+	"phone-api/api"
+)
+
+// handleUpgrades only logs: there is no SIGUSR2 to trigger an upgrade on
+// this platform.
+func handleUpgrades(server *api.Server, logger *log.Logger) {
+	logger.Printf("Self-upgrade is not supported on this platform")
+}
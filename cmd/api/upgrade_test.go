@@ -0,0 +1,173 @@
+//go:build unix
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"phone-api/api"
+)
+
+const upgradeHelperEnv = "PHONE_API_UPGRADE_HELPER"
+
+// TestUpgradeHelperProcess is not a test: TestSelfUpgrade_NoFailedRequests
+// runs the test binary with it as a server process, which upgrades by
+// starting the test binary again the same way. Each one prints its pid
+// and address once it serves.
+func TestUpgradeHelperProcess(t *testing.T) {
+	if os.Getenv(upgradeHelperEnv) != "1" {
+		return
+	}
+	gin.SetMode(gin.TestMode)
+	logger := log.New(os.Stderr, fmt.Sprintf("[%d] ", os.Getpid()), 0)
+
+	listeners, err := inheritedListeners(nil)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if listeners == nil {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			logger.Fatal(err)
+		}
+		listeners = []net.Listener{listener}
+	}
+	server, err := api.NewServer(api.Config{Listeners: listeners})
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err := server.Listen(); err != nil {
+		logger.Fatal(err)
+	}
+	handleUpgrades(server, logger)
+	fmt.Printf("serving %d %s\n", os.Getpid(), server.Addrs()[0])
+	announceReady("")
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+	if err := server.Run(ctx); err != nil {
+		logger.Fatal(err)
+	}
+	os.Exit(0)
+}
+
+func TestSelfUpgrade_NoFailedRequests(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts server processes")
+	}
+
+	// A file rather than a buffer: the new process inherits it and outlives
+	// the one started here.
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	require.NoError(t, err)
+	defer func() {
+		if t.Failed() {
+			output, _ := os.ReadFile(stderr.Name())
+			t.Logf("server output:\n%s", output)
+		}
+	}()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestUpgradeHelperProcess$")
+	cmd.Env = append(os.Environ(), upgradeHelperEnv+"=1")
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+	defer cmd.Process.Kill()
+
+	lines := bufio.NewScanner(stdout)
+	var oldPID, newPID int
+	var addr, newAddr string
+	require.True(t, lines.Scan(), "old process did not start")
+	_, err = fmt.Sscanf(lines.Text(), "serving %d %s", &oldPID, &addr)
+	require.NoError(t, err)
+
+	url := "http://" + addr + "/v1/phone-numbers?phoneNumber=%2B12125690123"
+	var sent, failed atomic.Int64
+	var failures sync.Map
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := &http.Client{Timeout: 5 * time.Second}
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				sent.Add(1)
+				resp, err := client.Get(url)
+				if err != nil {
+					failed.Add(1)
+					failures.Store(err.Error(), true)
+					continue
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					failed.Add(1)
+					failures.Store(resp.Status, true)
+				}
+			}
+		}()
+	}
+	stopStream := sync.OnceFunc(func() {
+		close(stop)
+		wg.Wait()
+	})
+	defer stopStream()
+
+	time.Sleep(200 * time.Millisecond)
+	require.NoError(t, cmd.Process.Signal(syscall.SIGUSR2))
+
+	require.True(t, lines.Scan(), "new process did not start")
+	_, err = fmt.Sscanf(lines.Text(), "serving %d %s", &newPID, &newAddr)
+	require.NoError(t, err)
+	defer syscall.Kill(newPID, syscall.SIGTERM)
+	assert.NotEqual(t, oldPID, newPID)
+	assert.Equal(t, addr, newAddr)
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	select {
+	case err := <-exited:
+		assert.NoError(t, err, "old process must exit cleanly after handing off")
+	case <-time.After(10 * time.Second):
+		t.Fatal("old process did not exit after handing off")
+	}
+
+	// Only the new process is left to answer these.
+	beforeExit := sent.Load()
+	time.Sleep(200 * time.Millisecond)
+	assert.Greater(t, sent.Load(), beforeExit, "no requests were served after the handoff")
+
+	stopStream()
+	var messages []string
+	failures.Range(func(key, _ any) bool {
+		messages = append(messages, key.(string))
+		return true
+	})
+	assert.Zero(t, failed.Load(), "%d of %d requests failed: %s", failed.Load(), sent.Load(), strings.Join(messages, "; "))
+}
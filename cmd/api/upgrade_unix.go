@@ -0,0 +1,110 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"phone-api/api"
+)
+
+// handleUpgrades starts the binary at the executable's path, usually a
+// newer one installed over it, on SIGUSR2 and hands it server's sockets.
+// Once the new process reports ready, server stops accepting and Run
+// returns after in-flight requests finish; a failed upgrade is logged and
+// server keeps serving.
+func handleUpgrades(server *api.Server, logger *log.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR2)
+	go func() {
+		for range signals {
+			pid, err := upgrade(server.Listeners())
+			if err != nil {
+				logger.Printf("Upgrade failed, still serving: %v", err)
+				continue
+			}
+			logger.Printf("Upgrade: process %d is ready", pid)
+			signal.Stop(signals)
+			server.Handoff()
+			return
+		}
+	}()
+}
+
+// upgrade starts the executable with this process's arguments and
+// environment plus listeners, and waits for it to report ready. It
+// returns the new process's pid.
+func upgrade(listeners []net.Listener) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	files := make([]*os.File, 0, len(listeners)+1)
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	for _, listener := range listeners {
+		filer, ok := listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			return 0, fmt.Errorf("listener on %s cannot be handed over", listener.Addr())
+		}
+		file, err := filer.File()
+		if err != nil {
+			return 0, fmt.Errorf("listener on %s: %w", listener.Addr(), err)
+		}
+		files = append(files, file)
+	}
+	ready, readyWriter, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer ready.Close()
+	files = append(files, readyWriter)
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), upgradeFDsEnv+"="+strconv.Itoa(len(listeners)))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+	// Only the new process holds the write end now, so a read sees EOF if
+	// it exits before reporting ready.
+	readyWriter.Close()
+
+	reported := make(chan error, 1)
+	go func() {
+		_, err := ready.Read(make([]byte, 1))
+		reported <- err
+	}()
+	select {
+	case err = <-reported:
+	case <-time.After(upgradeTimeout):
+		err = fmt.Errorf("not ready within %s", upgradeTimeout)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return 0, fmt.Errorf("new process %d: %w", cmd.Process.Pid, err)
+	}
+
+	// The new process serves the same Unix socket paths; closing ours must
+	// not remove them.
+	for _, listener := range listeners {
+		if unixListener, ok := listener.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(false)
+		}
+	}
+	return cmd.Process.Pid, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+
+	"phone-api/api"
+)
+
+type bridgeResult struct {
+	Valid  bool                         `json:"valid"`
+	Result *api.PhoneValidationResponse `json:"result,omitempty"`
+	Error  string                       `json:"error,omitempty"`
+}
+
+var validator = api.NewPhoneNumberValidator()
+
+// validateToJSON is the logic behind the JavaScript validatePhoneNumber
+// export, kept free of syscall/js so it can be tested on any platform.
+func validateToJSON(phoneNumber, countryCode string) string {
+	var result bridgeResult
+
+	response, err := validator.ValidatePhoneNumber(phoneNumber, countryCode)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Valid = true
+		result.Result = response
+	}
+
+	encoded, _ := json.Marshal(result)
+	return string(encoded)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateToJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		phoneNumber string
+		countryCode string
+		valid       bool
+		e164        string
+		errorMsg    string
+	}{
+		{name: "International", phoneNumber: "+12125690123", valid: true, e164: "+12125690123"},
+		{name: "National with country", phoneNumber: "2125690123", countryCode: "US", valid: true, e164: "+12125690123"},
+		{name: "Missing country", phoneNumber: "2125690123", valid: false, errorMsg: "countryCode is required for numbers without country code"},
+		{name: "Invalid characters", phoneNumber: "212abc0123", countryCode: "US", valid: false, errorMsg: "phone number contains invalid characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result bridgeResult
+			if err := json.Unmarshal([]byte(validateToJSON(tt.phoneNumber, tt.countryCode)), &result); err != nil {
+				t.Fatalf("Bridge returned invalid JSON: %v", err)
+			}
+
+			if result.Valid != tt.valid {
+				t.Fatalf("Expected valid=%v, got %v (%s)", tt.valid, result.Valid, result.Error)
+			}
+			if tt.valid && result.Result.PhoneNumber != tt.e164 {
+				t.Errorf("Expected PhoneNumber '%s', got '%s'", tt.e164, result.Result.PhoneNumber)
+			}
+			if !tt.valid && result.Error != tt.errorMsg {
+				t.Errorf("Expected error message '%s', got '%s'", tt.errorMsg, result.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+)
+
+func main() {
+	js.Global().Set("validatePhoneNumber", js.FuncOf(func(this js.Value, args []js.Value) any {
+		var phoneNumber, countryCode string
+		if len(args) > 0 {
+			phoneNumber = args[0].String()
+		}
+		if len(args) > 1 && args[1].Type() == js.TypeString {
+			countryCode = args[1].String()
+		}
+		return validateToJSON(phoneNumber, countryCode)
+	}))
+
+	select {}
+}
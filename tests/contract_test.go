@@ -0,0 +1,282 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"phone-api/api"
+)
+
+// Run `go test ./tests -run Contract -update` to rewrite the golden files
+// after an intentional, additive change to the v1 wire format.
+var updateGolden = flag.Bool("update", false, "rewrite contract golden files")
+
+const contractDir = "testdata/contract"
+
+// contractTypes are the v1 wire types whose JSON field names are frozen:
+// existing fields may never be renamed or removed, only added.
+var contractTypes = []interface{}{
+	api.PhoneValidationResponse{},
+	api.Warning{},
+	api.ErrorResponse{},
+	api.EnumResult{},
+	api.EnumRecord{},
+	api.BatchResponse{},
+	api.BatchItemResult{},
+	api.BatchSummary{},
+	api.CountriesResponse{},
+	api.CountryInfo{},
+	api.RouteCapabilities{},
+	api.RouteParameter{},
+	api.RouteNotFoundResponse{},
+	api.InterpretationsResponse{},
+	api.Interpretation{},
+	api.DialingInstructionsResponse{},
+	api.NumberRange{},
+}
+
+func compareGolden(t *testing.T, name string, actual []byte) {
+	t.Helper()
+	path := filepath.Join(contractDir, name)
+
+	if *updateGolden {
+		assert.NoError(t, os.MkdirAll(contractDir, 0o755))
+		assert.NoError(t, os.WriteFile(path, actual, 0o644))
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if !assert.NoError(t, err, "missing golden file; run with -update") {
+		return
+	}
+	assert.Equal(t, string(expected), string(actual), "wire format of %s changed", name)
+}
+
+func TestContractResponses(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	router := setupTestRouter(t,
+		api.WithAdminToken("secret"),
+		api.WithClock(func() time.Time { return now }),
+		api.WithValidatorOptions(api.WithDisabledCountries("FR")),
+	)
+
+	tests := []struct {
+		golden string
+		method string
+		url    string
+		body   string
+		header map[string]string
+		status int
+	}{
+		{golden: "lookup.json", method: "GET", url: "/v1/phone-numbers?phoneNumber=%2B12125690123", status: http.StatusOK},
+		{golden: "lookup_national.json", method: "GET", url: "/v1/phone-numbers?phoneNumber=915872200&countryCode=ES", status: http.StatusOK},
+		{golden: "lookup_warnings.json", method: "GET", url: "/v1/phone-numbers?phoneNumber=%2B%2B12125690123.&lenient=true", status: http.StatusOK},
+		{golden: "error_required.json", method: "GET", url: "/v1/phone-numbers", status: http.StatusBadRequest},
+		{golden: "error_invalid_characters.json", method: "GET", url: "/v1/phone-numbers?phoneNumber=212abc0123&countryCode=US", status: http.StatusBadRequest},
+		{golden: "error_length.json", method: "GET", url: "/v1/phone-numbers?phoneNumber=%2B1212", status: http.StatusBadRequest},
+		{golden: "error_country_disabled.json", method: "GET", url: "/v1/phone-numbers?phoneNumber=%2B33142685300", status: http.StatusForbidden},
+		{golden: "error_malformed.json", method: "GET", url: "/v1/phone-numbers?phoneNumber=%2B12125690123&phoneNumber=%2B12125690124", status: http.StatusBadRequest},
+		{golden: "error_malformed_multiple.json", method: "GET", url: "/v1/phone-numbers?phoneNumber=%2B12125690123&phoneNumber=%2B12125690124&lenient=maybe&enum=perhaps&countryCode[]=US", status: http.StatusBadRequest},
+		{golden: "error_leading_digit.json", method: "GET", url: "/v1/phone-numbers?phoneNumber=%2B10125690123", status: http.StatusBadRequest},
+		{
+			golden: "batch.json", method: "POST", url: "/v1/phone-numbers/batch",
+			body:   `{"items":[{"phoneNumber":"+12125690123"},{"phoneNumber":"+1212"}]}`,
+			status: http.StatusMultiStatus,
+		},
+		{golden: "interpretations.json", method: "GET", url: "/v1/phone-numbers/interpretations?phoneNumber=2125690123", status: http.StatusOK},
+		{golden: "dialing_instructions.json", method: "GET", url: "/v1/phone-numbers/dialing-instructions?phoneNumber=%2B442079460958&fromCountry=US", status: http.StatusOK},
+		{golden: "range.json", method: "GET", url: "/v1/phone-numbers/range?start=%2B349158722XX", status: http.StatusOK},
+		{golden: "countries.json", method: "GET", url: "/v1/countries", status: http.StatusOK},
+		{golden: "options.json", method: "OPTIONS", url: "/v1/phone-numbers", header: map[string]string{"Accept": "application/json"}, status: http.StatusOK},
+		{golden: "not_found.json", method: "GET", url: "/v1/phone-number", status: http.StatusNotFound},
+		{golden: "lookup_warnings_snake.json", method: "GET", url: "/v1/phone-numbers?phoneNumber=%2B%2B12125690123.&lenient=true&case=snake", status: http.StatusOK},
+		{
+			golden: "batch_snake.json", method: "POST", url: "/v1/phone-numbers/batch?case=snake",
+			body:   `{"items":[{"phoneNumber":"+12125690123"},{"phoneNumber":"+1212"}]}`,
+			status: http.StatusMultiStatus,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+
+			var indented bytes.Buffer
+			assert.NoError(t, json.Indent(&indented, w.Body.Bytes(), "", "  "))
+			indented.WriteByte('\n')
+			compareGolden(t, tt.golden, indented.Bytes())
+		})
+	}
+}
+
+// TestContractSnakeCase requests the same responses in both cases: the
+// snake_case body must be the camelCase one with every field name, at any
+// depth, re-keyed and nothing else changed.
+func TestContractSnakeCase(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	router := setupTestRouter(t, api.WithClock(func() time.Time { return now }))
+
+	tests := []struct {
+		method string
+		url    string
+		body   string
+	}{
+		{method: "GET", url: "/v1/phone-numbers?phoneNumber=%2B%2B12125690123.&lenient=true&callWindow=true"},
+		{method: "GET", url: "/v1/phone-numbers?phoneNumber=%2B1212"},
+		{method: "GET", url: "/v1/phone-numbers?phoneNumber=%2B12125690123&phoneNumber=%2B12125690124"},
+		{method: "POST", url: "/v1/phone-numbers/batch", body: `{"items":[{"id":"a","phoneNumber":"+12125690123","callWindow":true},{"id":"a","phoneNumber":"+1212"}]}`},
+		{method: "GET", url: "/v1/countries?dialingCode=1"},
+		{method: "GET", url: "/v1/phone-number"},
+	}
+
+	render := func(method, url, body string) (int, interface{}) {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var decoded interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded), w.Body.String())
+		return w.Code, decoded
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			separator := "?"
+			if strings.Contains(tt.url, "?") {
+				separator = "&"
+			}
+			camelStatus, camel := render(tt.method, tt.url, tt.body)
+			snakeStatus, snake := render(tt.method, tt.url+separator+"case=snake", tt.body)
+			_, explicitCamel := render(tt.method, tt.url+separator+"case=camel", tt.body)
+
+			assert.Equal(t, camelStatus, snakeStatus)
+			assert.Equal(t, snakeCaseKeys(camel), snake)
+			assert.Equal(t, camel, explicitCamel)
+		})
+	}
+}
+
+// snakeCaseKeys is the reference re-keying: field names, which start with
+// a lower case letter, become snake_case; data keys are left alone.
+func snakeCaseKeys(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		rekeyed := make(map[string]interface{}, len(value))
+		for key, nested := range value {
+			if key != "" && key[0] >= 'a' && key[0] <= 'z' {
+				var snake strings.Builder
+				for i, r := range key {
+					if r >= 'A' && r <= 'Z' {
+						if i > 0 {
+							snake.WriteByte('_')
+						}
+						r += 'a' - 'A'
+					}
+					snake.WriteRune(r)
+				}
+				key = snake.String()
+			}
+			rekeyed[key] = snakeCaseKeys(nested)
+		}
+		return rekeyed
+	case []interface{}:
+		rekeyed := make([]interface{}, len(value))
+		for i, nested := range value {
+			rekeyed[i] = snakeCaseKeys(nested)
+		}
+		return rekeyed
+	}
+	return value
+}
+
+// TestContractErrorKeyOrder renders a multi-field error repeatedly: the
+// error map must serialize with its keys sorted, byte for byte the same on
+// every run, so clients can compare snapshots.
+func TestContractErrorKeyOrder(t *testing.T) {
+	router := setupTestRouter(t)
+	url := "/v1/phone-numbers?phoneNumber=%2B12125690123&phoneNumber=%2B12125690124&lenient=maybe&enum=perhaps&countryCode[]=US"
+
+	var first string
+	for i := 0; i < 50; i++ {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		body := w.Body.String()
+		if i == 0 {
+			first = body
+			continue
+		}
+		if !assert.Equal(t, first, body, "error body changed between runs") {
+			return
+		}
+	}
+
+	keys := []string{`"countryCode"`, `"enum"`, `"lenient"`, `"phoneNumber"`}
+	errorObject := first[strings.Index(first, `"error":`):]
+	last := -1
+	for _, key := range keys {
+		position := strings.Index(errorObject, key)
+		assert.Greater(t, position, last, "error keys are not sorted at %s", key)
+		last = position
+	}
+}
+
+// TestContractFieldTags fails when a frozen type gains a field without the
+// golden list being updated, and when any field in the golden list is
+// renamed or removed, which v1 never allows.
+func TestContractFieldTags(t *testing.T) {
+	var current []string
+	for _, value := range contractTypes {
+		typ := reflect.TypeOf(value)
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			current = append(current, fmt.Sprintf("%s.%s %s", typ.Name(), field.Name, field.Tag.Get("json")))
+		}
+	}
+	sort.Strings(current)
+	actual := strings.Join(current, "\n") + "\n"
+
+	if *updateGolden {
+		compareGolden(t, "fields.txt", []byte(actual))
+		return
+	}
+
+	expected, err := os.ReadFile(filepath.Join(contractDir, "fields.txt"))
+	if !assert.NoError(t, err, "missing golden file; run with -update") {
+		return
+	}
+
+	currentSet := map[string]bool{}
+	for _, line := range current {
+		currentSet[line] = true
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(expected)), "\n") {
+		assert.True(t, currentSet[line], "v1 field %q was renamed or removed", line)
+	}
+	assert.Equal(t, string(expected), actual, "v1 fields changed; additive changes must update %s/fields.txt with -update", contractDir)
+}
@@ -1,28 +1,76 @@
 package tests
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net"
 	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+	"unicode/utf8"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 
 	"phone-api/api"
+	"phone-api/api/apitest"
 )
 
-func setupTestRouter() *gin.Engine {
-	gin.SetMode(gin.TestMode)
-	router := gin.New()
-	handler := api.NewHandler()
-	handler.SetupRoutes(router)
-	return router
+func setupTestRouter(t *testing.T, opts ...api.HandlerOption) http.Handler {
+	return apitest.NewRouter(t, apitest.WithHandlerOptions(opts...))
+}
+
+// get serves a GET of url on router. header holds name, value pairs;
+// pairs with an empty value are left out.
+func get(router http.Handler, url string, header ...string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", url, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		if header[i+1] != "" {
+			req.Header.Set(header[i], header[i+1])
+		}
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// lookup serves GET /v1/phone-numbers with query on router.
+func lookup(router http.Handler, query string, header ...string) *httptest.ResponseRecorder {
+	return get(router, "/v1/phone-numbers?"+query, header...)
+}
+
+// decodeJSON decodes a response body, failing the test if it is not JSON.
+func decodeJSON[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var response T
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), w.Body.String())
+	return response
 }
 
 // TestAPIEndpoints focuses purely on API response testing
 func TestHealthEndpoint(t *testing.T) {
-	router := setupTestRouter()
+	router := setupTestRouter(t)
 
 	req, _ := http.NewRequest("GET", "/health", nil)
 	w := httptest.NewRecorder()
@@ -44,7 +92,7 @@ func TestHealthEndpoint(t *testing.T) {
 
 // Additional comprehensive API endpoint tests
 func TestAPIEndpoints_ComprehensiveResponseTesting(t *testing.T) {
-	router := setupTestRouter()
+	router := setupTestRouter(t)
 
 	t.Run("Success Responses", func(t *testing.T) {
 		testCases := []struct {
@@ -62,6 +110,56 @@ func TestAPIEndpoints_ComprehensiveResponseTesting(t *testing.T) {
 					"localPhoneNumber": "5690123",
 				},
 			},
+			{
+				name: "US Number with Punctuation",
+				url:  "/v1/phone-numbers?phoneNumber=%2B1%20(212)%20569-0123",
+				expected: map[string]string{
+					"phoneNumber":      "+12125690123",
+					"countryCode":      "US",
+					"areaCode":         "212",
+					"localPhoneNumber": "5690123",
+				},
+			},
+			{
+				name: "National Number with Dots",
+				url:  "/v1/phone-numbers?phoneNumber=212.569.0123&countryCode=US",
+				expected: map[string]string{
+					"phoneNumber":      "+12125690123",
+					"countryCode":      "US",
+					"areaCode":         "212",
+					"localPhoneNumber": "5690123",
+				},
+			},
+			{
+				name: "Tel URI",
+				url:  "/v1/phone-numbers?phoneNumber=tel:%2B1-212-569-0123",
+				expected: map[string]string{
+					"phoneNumber":      "+12125690123",
+					"countryCode":      "US",
+					"areaCode":         "212",
+					"localPhoneNumber": "5690123",
+				},
+			},
+			{
+				name: "Local Tel URI with Phone Context",
+				url:  "/v1/phone-numbers?phoneNumber=tel:569-0123%3Bphone-context%3D%2B1-212",
+				expected: map[string]string{
+					"phoneNumber":      "+12125690123",
+					"countryCode":      "US",
+					"areaCode":         "212",
+					"localPhoneNumber": "5690123",
+				},
+			},
+			{
+				name: "Spain Number with IDD Prefix",
+				url:  "/v1/phone-numbers?phoneNumber=0034915872200",
+				expected: map[string]string{
+					"phoneNumber":      "+34915872200",
+					"countryCode":      "ES",
+					"areaCode":         "91",
+					"localPhoneNumber": "5872200",
+				},
+			},
 			{
 				name: "Mexico Number with Spaces",
 				url:  "/v1/phone-numbers?phoneNumber=%2B52%20631%203118150",
@@ -155,11 +253,18 @@ func TestAPIEndpoints_ComprehensiveResponseTesting(t *testing.T) {
 				expectedPhoneNum:   "212abc0123",
 			},
 			{
-				name:               "Invalid Characters - Hyphen",
-				url:                "/v1/phone-numbers?phoneNumber=212-569-0123&countryCode=US",
+				name:               "Invalid Characters - Unbalanced Parenthesis",
+				url:                "/v1/phone-numbers?phoneNumber=(212%20569-0123&countryCode=US",
+				expectedStatus:     http.StatusBadRequest,
+				expectedErrorField: "phoneNumber",
+				expectedPhoneNum:   "(212 569-0123",
+			},
+			{
+				name:               "Malformed Tel URI",
+				url:                "/v1/phone-numbers?phoneNumber=tel:",
 				expectedStatus:     http.StatusBadRequest,
 				expectedErrorField: "phoneNumber",
-				expectedPhoneNum:   "212-569-0123",
+				expectedPhoneNum:   "tel:",
 			},
 			{
 				name:               "Invalid Spacing Pattern",
@@ -272,3 +377,4413 @@ func TestAPIEndpoints_ComprehensiveResponseTesting(t *testing.T) {
 		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
 	})
 }
+
+func TestParamAliases(t *testing.T) {
+	aliases := api.ParseParamAliases("msisdn:phoneNumber, country:countryCode,bogus,number:unknownParam")
+	assert.Equal(t, map[string]string{"msisdn": "phoneNumber", "country": "countryCode"}, aliases)
+
+	router := setupTestRouter(t, api.WithParamAliases(aliases))
+
+	t.Run("Alias Only", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?msisdn=2125690123&country=US", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, w.Header().Values("Warning"), 2)
+
+		var response api.PhoneValidationResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "+12125690123", response.PhoneNumber)
+		assert.Equal(t, "US", response.CountryCode)
+	})
+
+	t.Run("Canonical Wins When Both Present", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber=%2B34915872200&msisdn=%2B12125690123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Warning"), "msisdn")
+
+		var response api.PhoneValidationResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "+34915872200", response.PhoneNumber)
+	})
+
+	t.Run("Unknown Alias Ignored", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?number=%2B12125690123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, w.Header().Get("Warning"))
+
+		var response api.ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Contains(t, response.Error, "phoneNumber")
+	})
+
+	t.Run("No Aliases Configured", func(t *testing.T) {
+		router := setupTestRouter(t)
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?msisdn=%2B12125690123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, w.Header().Get("Warning"))
+	})
+}
+
+func TestInputSizeGuard(t *testing.T) {
+	router := setupTestRouter(t)
+
+	t.Run("At Cap", func(t *testing.T) {
+		phoneNumber := "%2B1" + strings.Repeat("%20", 52) + "2125690123"
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber="+phoneNumber, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Over Cap Rejected Without Echo", func(t *testing.T) {
+		phoneNumber := strings.Repeat("1", 65)
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber="+phoneNumber, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response api.ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Empty(t, response.PhoneNumber)
+		assert.Equal(t, api.ErrorInputTooLong, response.Code)
+		assert.Equal(t, "input exceeds maximum length", response.Error["phoneNumber"])
+	})
+
+	t.Run("Too Many Digits", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber=%2B1212569012345678", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response api.ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ErrorInputTooLong, response.Code)
+		assert.Equal(t, "input exceeds maximum length", response.Error["phoneNumber"])
+	})
+
+	t.Run("Batch Item Over Cap", func(t *testing.T) {
+		body, _ := json.Marshal(api.BatchRequest{Items: []api.BatchItem{
+			{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "+12125690123"}},
+			{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: strings.Repeat("1", 65)}},
+		}})
+		req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		var batch api.BatchResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+		if assert.Len(t, batch.Results, 2) && assert.NotNil(t, batch.Results[1].Error) {
+			assert.Equal(t, http.StatusUnprocessableEntity, batch.Results[1].Status)
+			assert.Equal(t, api.ErrorInputTooLong, batch.Results[1].Error.Code)
+			assert.Equal(t, "input exceeds maximum length", batch.Results[1].Error.Error["phoneNumber"])
+		}
+	})
+
+	t.Run("Other Parameters Not Capped", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber=%2B12125690123&callbackUrl="+strings.Repeat("a", 100), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Alias Over Cap Rejected", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithParamAliases(map[string]string{"msisdn": "phoneNumber"}))
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?msisdn="+strings.Repeat("1", 65), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "input exceeds maximum length")
+		assert.Contains(t, w.Body.String(), string(api.ErrorInputTooLong))
+	})
+}
+
+func TestLeadingDigitRejection(t *testing.T) {
+	router := setupTestRouter(t)
+
+	req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber=%2B10125690123", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response api.ErrorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, api.ErrorInvalidLeadingDigit, response.Code)
+	assert.Equal(t, "cannot start with digit 0", response.Error["phoneNumber"])
+}
+
+func TestMalformedRequests(t *testing.T) {
+	router := setupTestRouter(t)
+
+	send := func(method, url, body string) (int, api.ErrorResponse) {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response api.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+
+	t.Run("Repeated Parameter", func(t *testing.T) {
+		status, response := send("GET", "/v1/phone-numbers?phoneNumber=%2B12125690123&phoneNumber=%2B14165550123", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, api.ErrorMalformedRequest, response.Code)
+		assert.Equal(t, "repeated parameter; a single value is expected", response.Error["phoneNumber"])
+		assert.Equal(t, []string{"+12125690123", "+14165550123"}, response.Received["phoneNumber"])
+	})
+
+	t.Run("Array Parameter", func(t *testing.T) {
+		status, response := send("GET", "/v1/phone-numbers?phoneNumber[]=%2B12125690123", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, api.ErrorMalformedRequest, response.Code)
+		assert.Equal(t, "array given where a single value is expected", response.Error["phoneNumber"])
+		assert.Equal(t, []string{"+12125690123"}, response.Received["phoneNumber"])
+	})
+
+	t.Run("Type Mismatch", func(t *testing.T) {
+		status, response := send("GET", "/v1/phone-numbers?phoneNumber=%2B12125690123&lenient=maybe", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, api.ErrorMalformedRequest, response.Code)
+		assert.Equal(t, "must be true or false", response.Error["lenient"])
+		assert.Equal(t, []string{"maybe"}, response.Received["lenient"])
+	})
+
+	t.Run("Validation Is Not Malformed", func(t *testing.T) {
+		status, response := send("GET", "/v1/phone-numbers?phoneNumber=%2B1212", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, api.ErrorLengthOutOfRange, response.Code)
+	})
+
+	t.Run("Truncated JSON", func(t *testing.T) {
+		body := `{"items":[{"phoneNumber":"+12125690123"}`
+		status, response := send("POST", "/v1/phone-numbers/batch", body)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, api.ErrorMalformedRequest, response.Code)
+		if assert.NotNil(t, response.Offset) {
+			assert.Equal(t, int64(len(body)), *response.Offset)
+		}
+		assert.Contains(t, response.Error["body"], "offset 40")
+	})
+
+	t.Run("JSON Array Where Scalar Expected", func(t *testing.T) {
+		status, response := send("POST", "/v1/phone-numbers/batch", `{"items":[{"phoneNumber":["+12125690123"]}]}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, api.ErrorMalformedRequest, response.Code)
+		// The field path includes the item index on newer Go releases.
+		for field, message := range response.Error {
+			assert.True(t, strings.HasSuffix(field, "phoneNumber"), field)
+			assert.Equal(t, "expected string, got array", message)
+		}
+		assert.Len(t, response.Error, 1)
+		assert.NotNil(t, response.Offset)
+	})
+}
+
+func TestTruncateTooLong(t *testing.T) {
+	router := setupTestRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber=%2B44740012345699&truncate=true", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `299 phone-api "TRAILING_DIGITS_TRUNCATED: `+api.WarningMessages[api.WarningTrailingDigitsTruncated]+` (99)"`, w.Header().Get("Warning"))
+
+	var response api.PhoneValidationResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "+447400123456", response.PhoneNumber)
+	assert.Equal(t, "99", response.TruncatedDigits)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/v1/phone-numbers?phoneNumber=%2B44740012345699", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPlusSignRecovery(t *testing.T) {
+	// An unencoded + in "+44 2079 460958" decodes to a leading space.
+	lostPlus := "phoneNumber=+44+2079+460958"
+
+	t.Run("Recovered", func(t *testing.T) {
+		w := lookup(setupTestRouter(t), lostPlus+"&fixPlus=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response api.PhoneValidationResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "+442079460958", response.PhoneNumber)
+		assert.Equal(t, []string{api.WarningPlusSignRecovered}, response.Warnings)
+		assert.Contains(t, w.Header().Get("Warning"), api.WarningPlusSignRecovered)
+	})
+
+	t.Run("Handler Option", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithPlusRecovery())
+		assert.Equal(t, http.StatusOK, lookup(router, lostPlus).Code)
+		assert.Equal(t, http.StatusBadRequest, lookup(router, lostPlus+"&fixPlus=false").Code)
+	})
+
+	t.Run("National Number Not Recovered", func(t *testing.T) {
+		w := lookup(setupTestRouter(t), "phoneNumber=+2125690123&countryCode=US&fixPlus=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response api.PhoneValidationResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "+12125690123", response.PhoneNumber)
+		assert.Empty(t, response.Warnings)
+	})
+
+	t.Run("Off By Default", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, lookup(setupTestRouter(t), lostPlus).Code)
+	})
+
+	t.Run("Strict Mode", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, lookup(setupTestRouter(t), lostPlus+"&options=fixplus,strict").Code)
+	})
+}
+
+func TestInterpretations(t *testing.T) {
+	router := setupTestRouter(t)
+
+	t.Run("Valid In Several Countries", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers/interpretations?phoneNumber=2125690123")
+		response := decodeJSON[api.InterpretationsResponse](t, w)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		byCountry := map[string]string{}
+		for _, interpretation := range response.Interpretations {
+			byCountry[interpretation.CountryCode] = interpretation.PhoneNumber
+		}
+		assert.Equal(t, "US", response.Interpretations[0].CountryCode)
+		assert.Equal(t, "+12125690123", byCountry["US"])
+		assert.Equal(t, "+522125690123", byCountry["MX"])
+		assert.Contains(t, byCountry, "CA")
+	})
+
+	t.Run("Valid Nowhere", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers/interpretations?phoneNumber=12345")
+		response := decodeJSON[api.InterpretationsResponse](t, w)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotNil(t, response.Interpretations)
+		assert.Empty(t, response.Interpretations)
+	})
+
+	t.Run("International Input", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers/interpretations?phoneNumber=%2B12125690123")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "must be a national number")
+	})
+}
+
+func TestDialingInstructions(t *testing.T) {
+	router := setupTestRouter(t)
+
+	tests := []struct {
+		query    string
+		wantDial string
+		wantIDD  string
+	}{
+		{"phoneNumber=%2B12125690123&fromCountry=US", "2125690123", ""},
+		{"phoneNumber=%2B12125690123&fromCountry=de", "0012125690123", "00"},
+		{"phoneNumber=%2B442079460958&fromCountry=US", "011442079460958", "011"},
+		{"phoneNumber=02079460958&countryCode=GB&fromCountry=GB", "02079460958", ""},
+		{"phoneNumber=%2B442079460958&fromCountry=AU", "0011442079460958", "0011"},
+	}
+	for _, tt := range tests {
+		w := get(router, "/v1/phone-numbers/dialing-instructions?"+tt.query)
+		response := decodeJSON[api.DialingInstructionsResponse](t, w)
+		assert.Equal(t, http.StatusOK, w.Code, tt.query)
+		assert.Equal(t, tt.wantDial, response.Dial, tt.query)
+		assert.Equal(t, tt.wantIDD, response.IDDPrefix, tt.query)
+	}
+
+	t.Run("Origin Country Errors", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers/dialing-instructions?phoneNumber=%2B12125690123")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"fromCountry":"required value is missing"`)
+
+		w = get(router, "/v1/phone-numbers/dialing-instructions?phoneNumber=%2B12125690123&fromCountry=XX")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"fromCountry":"unsupported origin country"`)
+	})
+
+	t.Run("Invalid Number", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers/dialing-instructions?phoneNumber=%2B1212&fromCountry=US")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "length is invalid")
+	})
+}
+
+func TestNumberRange(t *testing.T) {
+	router := setupTestRouter(t)
+
+	t.Run("Clean Block", func(t *testing.T) {
+		for _, query := range []string{
+			"start=%2B34915872200&end=%2B34915872299",
+			"start=%2B349158722XX",
+			"start=+34915872200&end=+34915872299",
+		} {
+			w := get(router, "/v1/phone-numbers/range?"+query)
+			response := decodeJSON[api.NumberRange](t, w)
+			assert.Equal(t, http.StatusOK, w.Code, query)
+			assert.Equal(t, "+34915872200", response.Start, query)
+			assert.Equal(t, "+34915872299", response.End, query)
+			assert.Equal(t, int64(100), response.Size, query)
+			assert.Equal(t, "+349158722", response.SharedPrefix, query)
+			assert.Equal(t, "ES", response.CountryCode, query)
+			assert.Equal(t, "91", response.NDC, query)
+			assert.True(t, response.AllValid, query)
+			assert.Equal(t, int64(0), response.InvalidCount, query)
+		}
+	})
+
+	t.Run("Straddles Two Area Codes", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers/range?start=%2B34919999900&end=%2B34920000099")
+		errorResponse := decodeJSON[api.ErrorResponse](t, w)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, api.ErrorRangeCrossesBoundary, errorResponse.Code)
+		assert.Equal(t, "starts in NDC 91 and ends in NDC 92", errorResponse.Error["range"])
+	})
+
+	t.Run("Oversized Range", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers/range?start=%2B349XXXXXXXX")
+		errorResponse := decodeJSON[api.ErrorResponse](t, w)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, api.ErrorRangeTooLarge, errorResponse.Code)
+
+		w = get(router, "/v1/phone-numbers/range?start=%2B1212XXXXXXX")
+		response := decodeJSON[api.NumberRange](t, w)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, int64(api.MaxRangeSize), response.Size)
+	})
+
+	t.Run("Invalid Ranges", func(t *testing.T) {
+		tests := []struct {
+			query     string
+			wantField string
+		}{
+			{"start=%2B34915872200", "end"},
+			{"start=%2B349158722XX&end=%2B34915872299", "start"},
+			{"start=%2B34915872299&end=%2B34915872200", "range"},
+			{"start=%2B34915872200&end=%2B349158722999", "range"},
+		}
+		for _, tt := range tests {
+			w := get(router, "/v1/phone-numbers/range?"+tt.query)
+			errorResponse := decodeJSON[api.ErrorResponse](t, w)
+			assert.Equal(t, http.StatusBadRequest, w.Code, tt.query)
+			assert.Equal(t, api.ErrorRangeInvalid, errorResponse.Code, tt.query)
+			assert.Contains(t, errorResponse.Error, tt.wantField, tt.query)
+		}
+
+		w := get(router, "/v1/phone-numbers/range?")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"start":"required value is missing"`)
+	})
+
+	t.Run("Invalid Endpoint", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers/range?start=%2B10125690100&end=%2B10125690199")
+		errorResponse := decodeJSON[api.ErrorResponse](t, w)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, api.ErrorInvalidLeadingDigit, errorResponse.Code)
+		assert.Equal(t, "start", errorResponse.FieldPath)
+
+		w = get(router, "/v1/phone-numbers/range?start=2125690100&end=2125690199")
+		errorResponse = decodeJSON[api.ErrorResponse](t, w)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, api.ErrorNotE164, errorResponse.Code)
+	})
+
+	t.Run("Suspicious Members", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithValidatorOptions(api.WithSuspiciousPatterns(api.SuspiciousPatternsReject)))
+		w := get(router, "/v1/phone-numbers/range?start=%2B122222222XX")
+		response := decodeJSON[api.NumberRange](t, w)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, response.AllValid)
+		assert.Equal(t, int64(1), response.InvalidCount)
+	})
+}
+
+func TestStrictE164InputFormat(t *testing.T) {
+	router := setupTestRouter(t)
+
+	w := lookup(router, "phoneNumber=%2B442079460958&inputFormat=e164")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"areaCode":"2079"`)
+
+	for _, query := range []string{
+		"phoneNumber=2125690123&countryCode=US&inputFormat=e164",
+		"phoneNumber=%2B1+212+569+0123&inputFormat=e164",
+		"phoneNumber=%2B12125690123.&inputFormat=e164&lenient=true",
+	} {
+		w := lookup(router, query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+
+		var response api.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, api.ErrorNotE164, response.Code, query)
+	}
+
+	w = lookup(router, "phoneNumber=%2B12125690123&inputFormat=national")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), api.ErrorMalformedRequest)
+}
+
+func TestStrictParameter(t *testing.T) {
+	adapters := map[string]http.Handler{
+		"gin":    setupTestRouter(t),
+		"stdlib": api.NewStdHandler(nil),
+	}
+	for name, router := range adapters {
+		t.Run(name, func(t *testing.T) {
+			w := lookup(router, "phoneNumber=%2B12125690123&strict=true")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), `"phoneNumber":"+12125690123"`)
+
+			for _, query := range []string{
+				"phoneNumber=%2B34+91+587+22+00&strict=true",
+				"phoneNumber=12125690123&strict=true",
+				"phoneNumber=2125690123&countryCode=US&strict=true",
+				"phoneNumber=+12125690123&strict=true&fixPlus=true",
+			} {
+				w := lookup(router, query)
+				assert.Equal(t, http.StatusBadRequest, w.Code, query)
+
+				var response api.ErrorResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, api.ErrorNotE164, response.Code, query)
+			}
+
+			// Without the parameter the lenient path is unchanged.
+			assert.Equal(t, http.StatusOK, lookup(router, "phoneNumber=%2B34+91+587+22+00").Code)
+			assert.Equal(t, http.StatusOK, lookup(router, "phoneNumber=%2B34+91+587+22+00&strict=false").Code)
+
+			w = lookup(router, "phoneNumber=%2B12125690123&strict=maybe")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), api.ErrorMalformedRequest)
+		})
+	}
+}
+
+func TestAreaCodeStyle(t *testing.T) {
+	router := setupTestRouter(t)
+
+	response := decodeJSON[api.PhoneValidationResponse](t, lookup(router, "phoneNumber=%2B27211234567"))
+	assert.Equal(t, "21", response.AreaCode)
+
+	response = decodeJSON[api.PhoneValidationResponse](t, lookup(router, "phoneNumber=%2B27211234567&areaCodeStyle=national"))
+	assert.Equal(t, "021", response.AreaCode)
+	assert.Equal(t, "+27211234567", response.PhoneNumber)
+
+	response = decodeJSON[api.PhoneValidationResponse](t, lookup(router, "phoneNumber=%2B12125690123&areaCodeStyle=national"))
+	assert.Equal(t, "212", response.AreaCode)
+
+	w := lookup(router, "phoneNumber=%2B442079460958&areaCodeStyle=trunk")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), api.ErrorMalformedRequest)
+}
+
+func TestRequestOptions(t *testing.T) {
+	router := setupTestRouter(t)
+
+	messy := "/v1/phone-numbers?phoneNumber=%2B12125690123."
+
+	t.Run("Header", func(t *testing.T) {
+		w := get(router, messy, api.RequestOptionsHeader, "lenient")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, w.Header().Values("Warning"), 1)
+	})
+
+	t.Run("Query Parameter", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(router, messy+"&options=lenient").Code)
+	})
+
+	t.Run("Query Parameter Replaces Header", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get(router, messy+"&options=enum", api.RequestOptionsHeader, "lenient").Code)
+	})
+
+	t.Run("Explicit Parameter Overrides List", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get(router, messy+"&lenient=false", api.RequestOptionsHeader, "lenient").Code)
+		assert.Equal(t, http.StatusOK, get(router, messy+"&lenient=true&options=").Code)
+	})
+
+	t.Run("Unknown Token Ignored", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", api.RequestOptionsHeader, "lenint")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{`299 phone-api "unknown option lenint ignored"`}, w.Header().Values("Warning"))
+	})
+
+	t.Run("Unknown Token Rejected When Strict", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", api.RequestOptionsHeader, "strict, lenint")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response api.ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ErrorMalformedRequest, response.Code)
+		assert.Equal(t, "unknown option lenint", response.Error["options"])
+	})
+}
+
+func TestNumberTypeLengthRejection(t *testing.T) {
+	router := setupTestRouter(t)
+
+	req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber=%2B3931234567890", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response api.ErrorResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "length is invalid for country: mobile numbers must have 9 to 10 digits", response.Error["phoneNumber"])
+}
+
+func TestStrayCharacterHandling(t *testing.T) {
+	router := setupTestRouter(t)
+
+	t.Run("Lenient Warnings", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B%2B12125690123.&lenient=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response api.PhoneValidationResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "+12125690123", response.PhoneNumber)
+		assert.Equal(t, []string{api.WarningTrailingPunctuationRemoved, api.WarningDuplicatePlusCollapsed}, response.Warnings)
+		assert.Len(t, w.Header().Values("Warning"), 2)
+	})
+
+	t.Run("Structured Warnings From Several Features", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B%2B12125690123.&lenient=true&enum=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response api.PhoneValidationResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, []api.Warning{
+			{Code: api.WarningTrailingPunctuationRemoved, Message: api.WarningMessages[api.WarningTrailingPunctuationRemoved]},
+			{Code: api.WarningDuplicatePlusCollapsed, Message: api.WarningMessages[api.WarningDuplicatePlusCollapsed]},
+			{Code: api.WarningEnumNotEnabled, Message: api.WarningMessages[api.WarningEnumNotEnabled]},
+		}, response.WarningDetails)
+		assert.Equal(t, []string{api.WarningTrailingPunctuationRemoved, api.WarningDuplicatePlusCollapsed, api.WarningEnumNotEnabled}, response.Warnings)
+
+		headers := w.Header().Values("Warning")
+		assert.Len(t, headers, 3)
+		for i, warning := range response.WarningDetails {
+			assert.Contains(t, headers[i], warning.Code)
+		}
+	})
+
+	t.Run("Misplaced Plus Code", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B%2B12125690123")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response api.ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ErrorMisplacedPlus, response.Code)
+	})
+
+	t.Run("Trunk Prefix", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B1%20(0)%20212%205690123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Warning"), api.WarningTrunkPrefixDropped)
+	})
+
+	t.Run("Spreadsheet Formats", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=2.125690123E9&countryCode=US&lenient=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Warning"), api.WarningExcelFormatRecovered)
+
+		w = get(router, "/v1/phone-numbers?phoneNumber=2.12569E%2B9&countryCode=US&lenient=true")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response api.ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ErrorLossyNumericFormat, response.Code)
+	})
+
+	t.Run("No Warnings Field When Clean", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "warnings")
+		assert.NotContains(t, w.Body.String(), "warningDetails")
+		assert.Empty(t, w.Header().Get("Warning"))
+	})
+}
+
+func TestOptionsResponder(t *testing.T) {
+	router := setupTestRouter(t)
+
+	t.Run("Allow Header", func(t *testing.T) {
+		for path, allow := range map[string]string{
+			"/health":           "GET, OPTIONS",
+			"/v1/phone-numbers": "GET, OPTIONS",
+		} {
+			req, _ := http.NewRequest("OPTIONS", path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, allow, w.Header().Get("Allow"))
+			assert.Empty(t, w.Body.String())
+		}
+	})
+
+	t.Run("JSON Capability Document", func(t *testing.T) {
+		req, _ := http.NewRequest("OPTIONS", "/v1/phone-numbers", nil)
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "GET, OPTIONS", w.Header().Get("Allow"))
+
+		var response api.RouteCapabilities
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"GET", "OPTIONS"}, response.Methods)
+		assert.Len(t, response.Parameters, 16)
+		assert.Equal(t, "phoneNumber", response.Parameters[0].Name)
+		assert.True(t, response.Parameters[0].Required)
+	})
+
+	t.Run("Tracks Route Registration", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/v1/phone-numbers", func(c *gin.Context) {})
+		api.NewHandler().SetupRoutes(router)
+
+		req, _ := http.NewRequest("OPTIONS", "/v1/phone-numbers", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "GET, OPTIONS, POST", w.Header().Get("Allow"))
+	})
+}
+
+func TestDisabledCountries(t *testing.T) {
+	router := setupTestRouter(t, api.WithAdminToken("secret"))
+
+	setDisabled := func(body, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/admin/disabled-countries", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	countryEnabled := func(code string) bool {
+		w := get(router, "/v1/countries")
+		var response api.CountriesResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		for _, country := range response.Countries {
+			if country.CountryCode == code {
+				return country.Enabled
+			}
+		}
+		t.Fatalf("country %s not listed", code)
+		return false
+	}
+
+	assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123").Code)
+	assert.True(t, countryEnabled("US"))
+
+	t.Run("Requires Admin Token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, setDisabled(`{"countries":["US"]}`, "").Code)
+		assert.Equal(t, http.StatusUnauthorized, setDisabled(`{"countries":["US"]}`, "wrong").Code)
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123").Code)
+	})
+
+	t.Run("Rejects Unsupported Country", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, setDisabled(`{"countries":["XX"]}`, "secret").Code)
+	})
+
+	t.Run("Disable At Runtime", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, setDisabled(`{"countries":["us"]}`, "secret").Code)
+
+		for _, url := range []string{
+			"/v1/phone-numbers?phoneNumber=%2B12125690123",
+			"/v1/phone-numbers?phoneNumber=2125690123&countryCode=US",
+		} {
+			w := get(router, url)
+			assert.Equal(t, http.StatusForbidden, w.Code)
+
+			var response api.ErrorResponse
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, api.ErrorCountryDisabled, response.Code)
+			assert.Contains(t, response.Error, "countryCode")
+		}
+
+		assert.False(t, countryEnabled("US"))
+		assert.True(t, countryEnabled("ES"))
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B34915872200").Code)
+	})
+
+	t.Run("Re-enable At Runtime", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, setDisabled(`{"countries":[]}`, "secret").Code)
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123").Code)
+		assert.True(t, countryEnabled("US"))
+	})
+
+	t.Run("Admin Routes Absent Without Token", func(t *testing.T) {
+		router := setupTestRouter(t)
+		req, _ := http.NewRequest("PUT", "/admin/disabled-countries", strings.NewReader(`{"countries":["US"]}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Configured At Startup", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithValidatorOptions(api.WithDisabledCountries(api.ParseCountryList("fr, de")...)))
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber=%2B33123456789", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+// divergentValidator disagrees with the default validator on purpose:
+// GB numbers fail and US numbers get another area code.
+type divergentValidator struct {
+	primary *api.PhoneNumberValidator
+}
+
+func (v divergentValidator) ValidatePhoneNumberWithOptions(phoneNumber, countryCode string, opts api.ParseOptions) (*api.PhoneValidationResponse, error) {
+	response, err := v.primary.ValidatePhoneNumberWithOptions(phoneNumber, countryCode, opts)
+	if err != nil {
+		return nil, err
+	}
+	switch response.CountryCode {
+	case "GB":
+		return nil, errors.New("unsupported country code")
+	case "US":
+		response.NDC, response.AreaCode = "999", "999"
+	}
+	return response, nil
+}
+
+func TestShadowValidator(t *testing.T) {
+	plain := setupTestRouter(t)
+	shadowed := setupTestRouter(t, api.WithAdminToken("secret"),
+		api.WithShadowValidator(divergentValidator{primary: api.NewPhoneNumberValidator()}, 1))
+
+	for _, number := range []string{"+12125690123", "+442079460958", "+34915872200", "+1212"} {
+		path := "/v1/phone-numbers?phoneNumber=" + url.QueryEscape(number)
+		expected := httptest.NewRecorder()
+		plain.ServeHTTP(expected, httptest.NewRequest("GET", path, nil))
+		w := httptest.NewRecorder()
+		shadowed.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, expected.Code, w.Code, number)
+		assert.Equal(t, expected.Body.String(), w.Body.String(), number)
+	}
+
+	var report api.ShadowReport
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		req, _ := http.NewRequest("GET", "/admin/shadow-report", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		shadowed.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		if report.Compared == 4 {
+			break
+		}
+	}
+
+	assert.True(t, report.Enabled)
+	assert.Equal(t, int64(4), report.Compared)
+	assert.Equal(t, int64(2), report.Mismatched)
+	assert.Equal(t, map[string]int64{"outcome": 1, "ndc": 1, "areaCode": 1}, report.Fields)
+	assert.Equal(t, map[string]int64{api.OutcomeValid + "->" + api.OutcomeInvalidCountry: 1}, report.Transitions)
+	if assert.Len(t, report.Examples, 2) {
+		for _, example := range report.Examples {
+			assert.NotContains(t, example.PhoneNumber, "569012", "examples are masked")
+			assert.NotContains(t, example.PhoneNumber, "794609", "examples are masked")
+		}
+		assert.ElementsMatch(t, []string{"ndc", "areaCode"}, report.Examples[0].Fields)
+		assert.Equal(t, []string{"outcome"}, report.Examples[1].Fields)
+		assert.Equal(t, api.OutcomeInvalidCountry, report.Examples[1].Secondary)
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithAdminToken("secret"))
+		req, _ := http.NewRequest("GET", "/admin/shadow-report", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"enabled":false,"sampleRate":0,"compared":0,"mismatched":0,"dropped":0,"fields":{},"transitions":{},"examples":[]}`, w.Body.String())
+	})
+}
+
+func TestMetadataCoverage(t *testing.T) {
+	router := setupTestRouter(t, api.WithAdminToken("secret"))
+
+	req, _ := http.NewRequest("GET", "/admin/metadata/coverage", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req, _ = http.NewRequest("GET", "/admin/metadata/coverage", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response api.MetadataCoverageResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Countries, len(api.CountryPhoneLengths))
+	tiers := map[string]string{}
+	for _, country := range response.Countries {
+		tiers[country.CountryCode] = country.Tier
+	}
+	assert.Equal(t, api.CoverageTierFull, tiers["ZA"])
+	assert.Equal(t, api.CoverageTierPartial, tiers["MX"])
+
+	req, _ = http.NewRequest("GET", "/v1/countries", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var countries api.CountriesResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &countries))
+	for _, country := range countries.Countries {
+		assert.Equal(t, tiers[country.CountryCode], country.Tier, country.CountryCode)
+	}
+}
+
+func TestMetadataDryRun(t *testing.T) {
+	router := setupTestRouter(t, api.WithAdminToken("secret"), api.WithRecentLookups(10))
+
+	dryRun := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/admin/metadata/dry-run", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Length Change Flips Samples", func(t *testing.T) {
+		w := dryRun(`{
+			"candidate": {"countryCode": "pt", "minLength": 9, "maxLength": 10},
+			"samples": [
+				{"phoneNumber": "+351210942000"},
+				{"phoneNumber": "+3512109420001"},
+				{"phoneNumber": "2109420001", "countryCode": "PT"},
+				{"phoneNumber": "+35121094200012"},
+				{"phoneNumber": "+12125690123"}
+			]
+		}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response api.MetadataDryRunResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "PT", response.Candidate.CountryCode)
+		assert.Equal(t, 5, response.Sampled)
+		assert.Equal(t, 2, response.Changed)
+		assert.Len(t, response.Transitions, 1)
+
+		flipped := response.Transitions[string(api.ErrorLengthOutOfRange)+"->"+api.OutcomeValid]
+		if assert.Len(t, flipped, 2) {
+			assert.Equal(t, "+3512109420001", flipped[0].PhoneNumber)
+			assert.Equal(t, "2109420001", flipped[1].PhoneNumber)
+		}
+	})
+
+	t.Run("Live Metadata Unchanged", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get(router, "/v1/phone-numbers?phoneNumber=%2B3512109420001").Code)
+	})
+
+	t.Run("Recent Lookups", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B351210942000").Code)
+
+		w := dryRun(`{"candidate": {"countryCode": "PT", "minLength": 9, "maxLength": 10}, "useRecent": true}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response api.MetadataDryRunResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, response.Sampled)
+		flipped := response.Transitions[string(api.ErrorLengthOutOfRange)+"->"+api.OutcomeValid]
+		if assert.Len(t, flipped, 1) {
+			assert.Equal(t, "+35*********01", flipped[0].PhoneNumber)
+		}
+	})
+
+	t.Run("Invalid Candidate", func(t *testing.T) {
+		w := dryRun(`{"candidate": {"countryCode": "PT", "minLength": 10, "maxLength": 9}, "samples": [{"phoneNumber": "+351210942000"}]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("No Samples", func(t *testing.T) {
+		w := dryRun(`{"candidate": {"countryCode": "PT", "minLength": 9, "maxLength": 10}}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestMetadataDryRunWithoutRecentLookups(t *testing.T) {
+	router := setupTestRouter(t, api.WithAdminToken("secret"))
+
+	req, _ := http.NewRequest("POST", "/admin/metadata/dry-run", strings.NewReader(`{"candidate": {"countryCode": "PT", "minLength": 9, "maxLength": 10}, "useRecent": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	router := setupTestRouter(t, api.WithAdminToken("secret"))
+
+	setMaintenance := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/admin/maintenance", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Before Maintenance", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123").Code)
+		assert.Equal(t, http.StatusOK, get(router, "/readyz").Code)
+		assert.Equal(t, http.StatusOK, get(router, "/livez").Code)
+	})
+
+	t.Run("Enter Maintenance", func(t *testing.T) {
+		w := setMaintenance(`{"enabled":true,"message":"migrating data","retryAfterSeconds":120}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var state api.MaintenanceState
+		err := json.Unmarshal(w.Body.Bytes(), &state)
+		assert.NoError(t, err)
+		assert.True(t, state.Enabled)
+		assert.Equal(t, "migrating data", state.Message)
+	})
+
+	t.Run("Serving During Maintenance", func(t *testing.T) {
+		for _, url := range []string{"/v1/phone-numbers?phoneNumber=%2B12125690123", "/v1/countries"} {
+			w := get(router, url)
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+			assert.Equal(t, "120", w.Header().Get("Retry-After"))
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "migrating data", response["message"])
+		}
+
+		assert.Equal(t, http.StatusOK, get(router, "/health").Code)
+		assert.Equal(t, http.StatusOK, get(router, "/livez").Code)
+		assert.Equal(t, http.StatusServiceUnavailable, get(router, "/readyz").Code)
+	})
+
+	t.Run("Exit Maintenance", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, setMaintenance(`{"enabled":false}`).Code)
+
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Retry-After"))
+		assert.Equal(t, http.StatusOK, get(router, "/readyz").Code)
+	})
+
+	t.Run("Concurrent Toggles", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				setMaintenance(fmt.Sprintf(`{"enabled":%t}`, i%2 == 0))
+			}(i)
+			go func() {
+				defer wg.Done()
+				get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123")
+			}()
+		}
+		wg.Wait()
+		setMaintenance(`{"enabled":false}`)
+	})
+}
+
+func TestFailureSampling(t *testing.T) {
+	failingLookup := func(router http.Handler, requestID string) *httptest.ResponseRecorder {
+		w := lookup(router, "phoneNumber=2125690123", "X-Request-ID", requestID)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		return w
+	}
+
+	t.Run("Rate 1.0 Logs Masked Failure", func(t *testing.T) {
+		var buf bytes.Buffer
+		router := setupTestRouter(t, api.WithFailureSampling(1.0, 10, log.New(&buf, "", 0)))
+		w := failingLookup(router, "req-1")
+		assert.Equal(t, "req-1", w.Header().Get("X-Request-ID"))
+
+		output := buf.String()
+		assert.Contains(t, output, "validation failure")
+		assert.NotContains(t, output, "[DEBUG]")
+		assert.Contains(t, output, `requestId="req-1"`)
+		assert.Contains(t, output, `phoneNumber="212*****23"`)
+		assert.NotContains(t, output, "2125690123")
+
+		buf.Reset()
+		lookup(router, "phoneNumber=%2B1212", "X-Request-ID", "req-2")
+		assert.Contains(t, buf.String(), `code="`+string(api.ErrorLengthOutOfRange)+`"`)
+		assert.NotContains(t, buf.String(), "error=")
+	})
+
+	t.Run("Request ID Is Quoted", func(t *testing.T) {
+		var buf bytes.Buffer
+		router := setupTestRouter(t, api.WithFailureSampling(1.0, 10, log.New(&buf, "", 0)))
+		failingLookup(router, "req-1 forged=entry")
+		assert.Contains(t, buf.String(), `requestId="req-1 forged=entry"`)
+	})
+
+	t.Run("Rate 0.0 Logs Nothing", func(t *testing.T) {
+		var buf bytes.Buffer
+		router := setupTestRouter(t, api.WithFailureSampling(0.0, 10, log.New(&buf, "", 0)))
+		failingLookup(router, "req-1")
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("Successful Lookups Are Not Logged", func(t *testing.T) {
+		var buf bytes.Buffer
+		router := setupTestRouter(t, api.WithFailureSampling(1.0, 10, log.New(&buf, "", 0)))
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber=%2B12125690123", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("Per Minute Cap", func(t *testing.T) {
+		var buf bytes.Buffer
+		router := setupTestRouter(t, api.WithFailureSampling(1.0, 2, log.New(&buf, "", 0)))
+		for i := 0; i < 5; i++ {
+			w := failingLookup(router, "")
+			assert.NotEmpty(t, w.Header().Get("X-Request-ID"))
+		}
+		assert.Equal(t, 2, strings.Count(buf.String(), "validation failure"))
+	})
+
+	t.Run("Deterministic Per Request ID", func(t *testing.T) {
+		var buf bytes.Buffer
+		router := setupTestRouter(t, api.WithFailureSampling(0.5, 100, log.New(&buf, "", 0)))
+		for i := 0; i < 10; i++ {
+			failingLookup(router, fmt.Sprintf("req-%d", i))
+		}
+		first := buf.String()
+		buf.Reset()
+		for i := 0; i < 10; i++ {
+			failingLookup(router, fmt.Sprintf("req-%d", i))
+		}
+		assert.Equal(t, first, buf.String())
+	})
+}
+
+type recordingHook struct {
+	name   string
+	calls  *[]string
+	before func(req *api.PhoneValidationRequest) error
+	after  func(result *api.PhoneValidationResponse, err error)
+}
+
+func (h *recordingHook) BeforeValidate(ctx context.Context, req *api.PhoneValidationRequest) error {
+	*h.calls = append(*h.calls, h.name+".before")
+	if h.before != nil {
+		return h.before(req)
+	}
+	return nil
+}
+
+func (h *recordingHook) AfterValidate(ctx context.Context, req api.PhoneValidationRequest, result *api.PhoneValidationResponse, err error) {
+	*h.calls = append(*h.calls, h.name+".after")
+	if h.after != nil {
+		h.after(result, err)
+	}
+}
+
+func TestValidationHooks(t *testing.T) {
+	t.Run("Ordering", func(t *testing.T) {
+		var calls []string
+		router := setupTestRouter(t, api.WithHooks(
+			&recordingHook{name: "first", calls: &calls},
+			&recordingHook{name: "second", calls: &calls},
+		))
+
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123").Code)
+		assert.Equal(t, []string{"first.before", "second.before", "first.after", "second.after"}, calls)
+	})
+
+	t.Run("Mutation", func(t *testing.T) {
+		var calls []string
+		router := setupTestRouter(t, api.WithHooks(&recordingHook{name: "default-country", calls: &calls,
+			before: func(req *api.PhoneValidationRequest) error {
+				if req.CountryCode == "" {
+					req.CountryCode = "US"
+				}
+				return nil
+			},
+		}))
+
+		w := get(router, "/v1/phone-numbers?phoneNumber=2125690123")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response api.PhoneValidationResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "+12125690123", response.PhoneNumber)
+	})
+
+	t.Run("Rejection", func(t *testing.T) {
+		var calls []string
+		var afterErr error
+		router := setupTestRouter(t, api.WithHooks(
+			api.NewPrefixDenyListHook("+1900", "+44"),
+			&recordingHook{name: "observer", calls: &calls,
+				after: func(result *api.PhoneValidationResponse, err error) { afterErr = err },
+			},
+		))
+
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B44%202079460958")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var response api.ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ErrorRejectedByHook, response.Code)
+		assert.Equal(t, "number prefix is not allowed", response.Error["phoneNumber"])
+		assert.Equal(t, []string{"observer.before", "observer.after"}, calls)
+		assert.Error(t, afterErr)
+
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123").Code)
+	})
+
+	t.Run("Plain Error Rejection", func(t *testing.T) {
+		var calls []string
+		router := setupTestRouter(t, api.WithHooks(&recordingHook{name: "gate", calls: &calls,
+			before: func(req *api.PhoneValidationRequest) error { return errors.New("closed for maintenance") },
+		}))
+
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		response := decodeJSON[api.ErrorResponse](t, w)
+		assert.Equal(t, api.ErrorRejectedByHook, response.Code)
+		assert.Equal(t, "closed for maintenance", response.Error["phoneNumber"])
+	})
+
+	t.Run("Rejection Matches The Normalized Number", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithHooks(api.NewPrefixDenyListHook("+1900")))
+		assert.Equal(t, http.StatusOK, lookup(router, "phoneNumber=%2B12125690123").Code)
+
+		for _, query := range []string{
+			"phoneNumber=%2B19005550123",
+			"phoneNumber=%2B1-900-555-0123",
+			"phoneNumber=%2B1%20(900)%20555-0123",
+			"phoneNumber=tel:%2B1-900-555-0123",
+			"phoneNumber=0019005550123&countryCode=GB",
+			"phoneNumber=01119005550123&countryCode=US",
+			"phoneNumber=9005550123&countryCode=US",
+		} {
+			w := lookup(router, query)
+			assert.Equal(t, http.StatusForbidden, w.Code, query)
+			assert.Equal(t, "number prefix is not allowed", decodeJSON[api.ErrorResponse](t, w).Error["phoneNumber"], query)
+		}
+	})
+
+	t.Run("Panic Containment", func(t *testing.T) {
+		var calls []string
+		router := setupTestRouter(t, api.WithHooks(
+			&recordingHook{name: "broken", calls: &calls,
+				before: func(req *api.PhoneValidationRequest) error {
+					req.PhoneNumber = "garbage"
+					panic("boom")
+				},
+				after: func(*api.PhoneValidationResponse, error) { panic("boom again") },
+			},
+			&recordingHook{name: "healthy", calls: &calls},
+		))
+
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"broken.before", "healthy.before", "broken.after", "healthy.after"}, calls)
+	})
+
+	t.Run("Timing Hook", func(t *testing.T) {
+		var recorded []string
+		router := setupTestRouter(t, api.WithHooks(&api.TimingHook{
+			Record: func(countryCode string, duration time.Duration, err error) {
+				assert.GreaterOrEqual(t, duration, time.Duration(0))
+				recorded = append(recorded, fmt.Sprintf("%s:%v", countryCode, err != nil))
+			},
+		}))
+
+		get(router, "/v1/phone-numbers?phoneNumber=%2B34915872200")
+		get(router, "/v1/phone-numbers?phoneNumber=2125690123")
+		assert.Equal(t, []string{"ES:false", ":true"}, recorded)
+	})
+}
+
+type fakeNAPTRResolver struct {
+	records []api.NAPTRRecord
+	err     error
+}
+
+func (r *fakeNAPTRResolver) LookupNAPTR(ctx context.Context, name string) ([]api.NAPTRRecord, error) {
+	return r.records, r.err
+}
+
+func TestEnumLookup(t *testing.T) {
+	t.Run("Records Returned", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithEnumLookup(api.NewEnumLookup(&fakeNAPTRResolver{
+			records: []api.NAPTRRecord{{Order: 10, Preference: 1, Flags: "U", Service: "E2U+sip", Regexp: "!^.*$!sip:desk@example.com!"}},
+		}, "")))
+
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123&enum=true")
+		response := decodeJSON[map[string]interface{}](t, w)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Warning"))
+
+		enum := response["enum"].(map[string]interface{})
+		assert.Equal(t, "3.2.1.0.9.6.5.2.1.2.1.e164.arpa", enum["domain"])
+		records := enum["records"].([]interface{})
+		assert.Len(t, records, 1)
+		assert.Equal(t, "sip:desk@example.com", records[0].(map[string]interface{})["uri"])
+	})
+
+	t.Run("Not Requested", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithEnumLookup(api.NewEnumLookup(&fakeNAPTRResolver{}, "")))
+
+		response := decodeJSON[map[string]interface{}](t, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123"))
+		assert.NotContains(t, response, "enum")
+	})
+
+	t.Run("DNS Failure Degrades", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithEnumLookup(api.NewEnumLookup(&fakeNAPTRResolver{
+			err: context.DeadlineExceeded,
+		}, "")))
+
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123&enum=true")
+		response := decodeJSON[map[string]interface{}](t, w)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Warning"), "enum lookup failed")
+		assert.Empty(t, response["enum"].(map[string]interface{})["records"])
+	})
+
+	t.Run("Not Enabled", func(t *testing.T) {
+		router := setupTestRouter(t)
+
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123&enum=true")
+		response := decodeJSON[map[string]interface{}](t, w)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Warning"), "not enabled")
+		assert.NotContains(t, response, "enum")
+	})
+}
+
+func TestUsageStatsExport(t *testing.T) {
+	stats := api.NewUsageStats()
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	for i := 0; i < 3; i++ {
+		stats.Record(day(1), "acme", "US", false)
+	}
+	stats.Record(day(1), "acme", "US", true)
+	stats.Record(day(1), "", "ES", false)
+	stats.Record(day(2), "acme", "GB", true)
+	stats.Record(day(5), "globex", "FR", false)
+
+	router := setupTestRouter(t, api.WithAdminToken("secret"), api.WithUsageStats(stats))
+
+	export := func(query string) (*httptest.ResponseRecorder, [][]string) {
+		req, _ := http.NewRequest("GET", "/admin/stats/export"+query, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		records, err := csv.NewReader(bytes.NewReader(w.Body.Bytes())).ReadAll()
+		if w.Code == http.StatusOK {
+			assert.NoError(t, err)
+		}
+		return w, records
+	}
+
+	t.Run("Range Export", func(t *testing.T) {
+		w, records := export("?from=2024-03-01&to=2024-03-02&format=csv")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+		assert.Contains(t, w.Header().Get("Warning"), "persistence is not enabled")
+
+		assert.Equal(t, [][]string{
+			{"date", "apiKey", "countryCode", "validations", "errors"},
+			{"2024-03-01", "acme", "US", "4", "1"},
+			{"2024-03-01", "anonymous", "ES", "1", "0"},
+			{"2024-03-02", "acme", "GB", "1", "1"},
+			{"TOTAL", "", "", "6", "2"},
+		}, records)
+	})
+
+	t.Run("Live Lookups Are Counted", func(t *testing.T) {
+		for _, url := range []string{
+			"/v1/phone-numbers?phoneNumber=%2B34915872200",
+			"/v1/phone-numbers?phoneNumber=2125690123&countryCode=US",
+			"/v1/phone-numbers?phoneNumber=212abc0123&countryCode=US",
+		} {
+			req, _ := http.NewRequest("GET", url, nil)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		today := time.Now().UTC().Format("2006-01-02")
+		_, records := export("?from=" + today)
+
+		totals := map[string][]string{}
+		for _, record := range records[1:] {
+			totals[record[2]] = record
+		}
+		assert.Equal(t, []string{today, "anonymous", "ES", "1", "0"}, totals["ES"])
+		assert.Equal(t, []string{today, "anonymous", "US", "2", "1"}, totals["US"])
+		assert.Equal(t, []string{"TOTAL", "", "", "3", "1"}, records[len(records)-1])
+	})
+
+	t.Run("Unsupported Countries Count As Unknown", func(t *testing.T) {
+		stats := api.NewUsageStats()
+		router := setupTestRouter(t, api.WithUsageStats(stats))
+		for _, query := range []string{
+			"phoneNumber=2125690123&countryCode=ZZ",
+			"phoneNumber=2125690123&countryCode=%3DCMD()",
+			"phoneNumber=212abc0123&countryCode=us",
+		} {
+			lookup(router, query)
+		}
+
+		var countries []string
+		for _, row := range stats.Rows(time.Time{}, time.Time{}) {
+			countries = append(countries, row.CountryCode)
+		}
+		assert.ElementsMatch(t, []string{"US", "unknown"}, countries)
+	})
+
+	t.Run("Formula Cells Are Escaped", func(t *testing.T) {
+		stats := api.NewUsageStats()
+		stats.Record(day(1), "=HYPERLINK(\"x\")", "US", false)
+		stats.Record(day(1), "@sum", "US", false)
+		router := setupTestRouter(t, api.WithAdminToken("secret"), api.WithUsageStats(stats))
+
+		for _, report := range []string{"usage", "billing"} {
+			w := get(router, "/admin/stats/export?report="+report, "Authorization", "Bearer secret")
+			records, err := csv.NewReader(bytes.NewReader(w.Body.Bytes())).ReadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, "'=HYPERLINK(\"x\")", records[1][1], report)
+			assert.Equal(t, "'@sum", records[2][1], report)
+		}
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		w, _ := export("?from=March")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, _ = export("?format=xlsx")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestNoRouteHandler(t *testing.T) {
+	router := setupTestRouter(t)
+
+	notFound := func(path string) api.RouteNotFoundResponse {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		assert.NotContains(t, w.Body.String(), "<script>")
+
+		var response api.RouteNotFoundResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ErrorRouteNotFound, response.Code)
+		return response
+	}
+
+	t.Run("Close Miss", func(t *testing.T) {
+		response := notFound("/v1/phone-number")
+		assert.Equal(t, "/v1/phone-number", response.Path)
+		assert.Equal(t, "/v1/phone-numbers", response.DidYouMean)
+
+		assert.Equal(t, "/v1/countries", notFound("/v1/country").DidYouMean)
+	})
+
+	t.Run("Far Miss", func(t *testing.T) {
+		response := notFound("/api/internal/users")
+		assert.Equal(t, "/api/internal/users", response.Path)
+		assert.Empty(t, response.DidYouMean)
+	})
+
+	t.Run("Path Is Escaped And Truncated", func(t *testing.T) {
+		response := notFound("/<script>alert(1)</script>")
+		assert.Equal(t, "/<script>alert(1)</script>", response.Path)
+
+		response = notFound("/" + strings.Repeat("a", 1000))
+		assert.Len(t, response.Path, 256)
+	})
+
+	t.Run("Real Routes Unaffected", func(t *testing.T) {
+		for _, path := range []string{"/health", "/v1/phone-numbers?phoneNumber=%2B12125690123", "/v1/countries"} {
+			req, _ := http.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.NotContains(t, w.Body.String(), string(api.ErrorRouteNotFound))
+		}
+	})
+}
+
+func TestBillingAccounting(t *testing.T) {
+	stats := api.NewUsageStats()
+	router := apitest.NewRouter(t,
+		apitest.WithAPIKey("key-alpha", api.APIKeyConfig{Label: "alpha", Enrichment: true}),
+		apitest.WithAPIKey("key-beta", api.APIKeyConfig{Label: "beta"}),
+		apitest.WithHandlerOptions(
+			api.WithUsageStats(stats),
+			api.WithAdminToken("secret"),
+			api.WithEnumLookup(api.NewEnumLookup(&fakeNAPTRResolver{}, "")),
+		),
+	)
+
+	send := func(method, url, key, contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	batch := `{"items":[{"phoneNumber":"+12125690123"},{"phoneNumber":"+1212"},{"phoneNumber":"+34915872200"}]}`
+	assert.Equal(t, http.StatusOK, send("GET", "/v1/phone-numbers?phoneNumber=%2B12125690123&enum=true", "key-alpha", "", "").Code)
+	assert.Equal(t, http.StatusOK, send("GET", "/v1/phone-numbers?phoneNumber=%2B12125690123&enum=true", "key-alpha", "", "").Code)
+	assert.Equal(t, http.StatusMultiStatus, send("POST", "/v1/phone-numbers/batch", "key-alpha", "application/json", batch).Code)
+	assert.Equal(t, http.StatusOK, send("POST", "/v1/phone-numbers/batch", "key-beta", "text/csv", "phoneNumber\n+12125690123\n+34915872200\n").Code)
+	assert.Equal(t, http.StatusBadRequest, send("GET", "/v1/phone-numbers?phoneNumber=%2B1212", "key-beta", "", "").Code)
+
+	rows := map[string]api.BillingRow{}
+	for _, row := range stats.BillingRows(time.Time{}, time.Time{}) {
+		rows[row.APIKey] = row
+	}
+
+	alpha := rows["alpha"]
+	assert.Equal(t, int64(5), alpha.Items)
+	assert.Equal(t, int64(1), alpha.Errors)
+	assert.Equal(t, int64(2), alpha.Enrichments)
+	assert.Equal(t, int64(1), alpha.CachedEnrichments)
+	assert.Equal(t, int64(len(batch)), alpha.BytesIn)
+	assert.Greater(t, alpha.BytesOut, int64(0))
+
+	beta := rows["beta"]
+	assert.Equal(t, int64(3), beta.Items)
+	assert.Equal(t, int64(1), beta.Errors)
+	assert.Equal(t, int64(0), beta.Enrichments)
+
+	req, _ := http.NewRequest("GET", "/admin/stats/export?report=billing", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "billing.csv")
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 4) {
+		assert.Equal(t, []string{"date", "apiKey", "items", "errors", "enrichments", "cachedEnrichments", "bytesIn", "bytesOut"}, records[0])
+		assert.Equal(t, "alpha", records[1][1])
+		assert.Equal(t, []string{"5", "1", "2", "1"}, records[1][2:6])
+		assert.Equal(t, "beta", records[2][1])
+		assert.Equal(t, []string{"TOTAL", "", "8", "2", "2", "1"}, records[3][:6])
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	writeKeys := func(keys map[string]api.APIKeyConfig) {
+		hashed := map[string]api.APIKeyConfig{}
+		for key, config := range keys {
+			hashed[api.HashAPIKey(key)] = config
+		}
+		data, err := json.Marshal(hashed)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(path, data, 0o600))
+	}
+	writeKeys(map[string]api.APIKeyConfig{
+		"key-us":      {Label: "us-only", AllowedCountries: []string{"US"}},
+		"key-es":      {Label: "es-only", AllowedCountries: []string{"ES"}},
+		"key-limited": {Label: "limited", RateLimitPerMinute: 1, Enrichment: true},
+	})
+
+	store, err := api.LoadAPIKeyStore(path)
+	assert.NoError(t, err)
+	stats := api.NewUsageStats()
+	router := setupTestRouter(t, api.WithAPIKeys(store), api.WithUsageStats(stats))
+
+	t.Run("Requires Key", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123").Code)
+		assert.Equal(t, http.StatusUnauthorized, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", "X-API-Key", "unknown").Code)
+	})
+
+	t.Run("Country Restrictions Per Key", func(t *testing.T) {
+		url := "/v1/phone-numbers?phoneNumber=%2B12125690123"
+		assert.Equal(t, http.StatusOK, get(router, url, "X-API-Key", "key-us").Code)
+
+		w := get(router, url, "X-API-Key", "key-es")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var response api.ErrorResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, api.ErrorCountryNotAllowed, response.Code)
+
+		labels := map[string]api.UsageRow{}
+		for _, row := range stats.Rows(time.Time{}, time.Time{}) {
+			labels[row.APIKey] = row
+		}
+		assert.Equal(t, int64(0), labels["us-only"].Errors)
+		assert.Equal(t, int64(1), labels["es-only"].Errors)
+	})
+
+	t.Run("Enrichment Gate", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123&enum=true", "X-API-Key", "key-us")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), string(api.ErrorEnrichmentNotAllowed))
+	})
+
+	t.Run("Rate Limit Per Key", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", "X-API-Key", "key-limited").Code)
+
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", "X-API-Key", "key-limited")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", "X-API-Key", "key-us").Code)
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		writeKeys(map[string]api.APIKeyConfig{
+			"key-es": {Label: "es-only", AllowedCountries: []string{"ES", "US"}},
+		})
+		assert.NoError(t, store.Reload())
+
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", "X-API-Key", "key-es").Code)
+		assert.Equal(t, http.StatusUnauthorized, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", "X-API-Key", "key-us").Code)
+
+		assert.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+		assert.Error(t, store.Reload())
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", "X-API-Key", "key-es").Code)
+	})
+
+	t.Run("Duplicate Labels Are Rejected", func(t *testing.T) {
+		writeKeys(map[string]api.APIKeyConfig{
+			"key-es":    {Label: "es-only", AllowedCountries: []string{"ES"}},
+			"key-other": {Label: "es-only", RateLimitPerMinute: 1},
+		})
+		err := store.Reload()
+		assert.ErrorContains(t, err, `share the label "es-only"`)
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", "X-API-Key", "key-es").Code)
+		assert.Equal(t, http.StatusUnauthorized, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", "X-API-Key", "key-other").Code)
+	})
+}
+
+func TestRetryAfterGuidance(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	assertRetryAfter := func(t *testing.T, w *httptest.ResponseRecorder, status int, seconds int) {
+		t.Helper()
+		assert.Equal(t, status, w.Code)
+		assert.Equal(t, fmt.Sprint(seconds), w.Header().Get("Retry-After"))
+
+		var body map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &body)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(seconds), body["retryAfterSeconds"])
+	}
+
+	t.Run("Rate Limit Window", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys.json")
+		data, _ := json.Marshal(map[string]api.APIKeyConfig{
+			api.HashAPIKey("key"): {Label: "limited", RateLimitPerMinute: 1},
+		})
+		assert.NoError(t, os.WriteFile(path, data, 0o600))
+		store, err := api.LoadAPIKeyStore(path)
+		assert.NoError(t, err)
+		router := setupTestRouter(t, api.WithAPIKeys(store), api.WithClock(clock))
+		url := "/v1/phone-numbers?phoneNumber=%2B12125690123"
+
+		assert.Equal(t, http.StatusOK, get(router, url, "X-API-Key", "key").Code)
+		assertRetryAfter(t, get(router, url, "X-API-Key", "key"), http.StatusTooManyRequests, 60)
+
+		now = now.Add(45 * time.Second)
+		assertRetryAfter(t, get(router, url, "X-API-Key", "key"), http.StatusTooManyRequests, 15)
+
+		now = now.Add(14500 * time.Millisecond)
+		assertRetryAfter(t, get(router, url, "X-API-Key", "key"), http.StatusTooManyRequests, 1)
+
+		now = now.Add(500 * time.Millisecond)
+		assert.Equal(t, http.StatusOK, get(router, url, "X-API-Key", "key").Code)
+	})
+
+	t.Run("Maintenance Deadline", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithAdminToken("secret"), api.WithClock(clock))
+		req, _ := http.NewRequest("POST", "/admin/maintenance", strings.NewReader(`{"enabled":true,"retryAfterSeconds":120}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assertRetryAfter(t, get(router, "/v1/countries"), http.StatusServiceUnavailable, 120)
+		assertRetryAfter(t, get(router, "/readyz"), http.StatusServiceUnavailable, 120)
+
+		now = now.Add(100 * time.Second)
+		assertRetryAfter(t, get(router, "/v1/countries"), http.StatusServiceUnavailable, 20)
+
+		now = now.Add(30 * time.Second)
+		assertRetryAfter(t, get(router, "/v1/countries"), http.StatusServiceUnavailable, 1)
+	})
+
+	t.Run("Shutdown Drain", func(t *testing.T) {
+		handler := api.NewHandler(api.WithClock(clock))
+		router := gin.New()
+		handler.SetupRoutes(router)
+		handler.WarmUp()
+
+		assert.Equal(t, http.StatusOK, get(router, "/readyz").Code)
+		handler.Drain(now.Add(10 * time.Second))
+
+		assertRetryAfter(t, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123"), http.StatusServiceUnavailable, 10)
+		assertRetryAfter(t, get(router, "/readyz"), http.StatusServiceUnavailable, 10)
+
+		now = now.Add(9 * time.Second)
+		assertRetryAfter(t, get(router, "/readyz"), http.StatusServiceUnavailable, 1)
+
+		now = now.Add(5 * time.Second)
+		assertRetryAfter(t, get(router, "/readyz"), http.StatusServiceUnavailable, 1)
+		assert.Equal(t, http.StatusOK, get(router, "/livez").Code)
+	})
+}
+
+func TestAreaCodeNames(t *testing.T) {
+	router := setupTestRouter(t)
+
+	t.Run("Lookup", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B442079460958")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response api.PhoneValidationResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "London", response.AreaCodeName)
+	})
+
+	t.Run("Empty When Unknown", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B526313118150")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"areaCodeName":""`)
+	})
+
+	t.Run("Countries Listing", func(t *testing.T) {
+		var response api.CountriesResponse
+		err := json.Unmarshal(get(router, "/v1/countries").Body.Bytes(), &response)
+		assert.NoError(t, err)
+
+		supported := map[string]bool{}
+		for _, country := range response.Countries {
+			supported[country.CountryCode] = country.AreaCodeNames
+		}
+		for _, code := range []string{"US", "CA", "GB", "DE", "ES"} {
+			assert.True(t, supported[code], code)
+		}
+		assert.False(t, supported["MX"])
+	})
+}
+
+func TestBatchLookup(t *testing.T) {
+	router := setupTestRouter(t, api.WithValidatorOptions(api.WithDisabledCountries("FR")))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) api.BatchResponse {
+		var response api.BatchResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		return response
+	}
+
+	t.Run("All Valid", func(t *testing.T) {
+		w := post(`{"items":[{"phoneNumber":"+12125690123"},{"phoneNumber":"915872200","countryCode":"ES"}]}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		response := decode(t, w)
+		assert.Equal(t, api.BatchSummary{Total: 2, ValidCount: 2, FailedCount: 0}, response.Summary)
+		for _, item := range response.Results {
+			assert.Equal(t, http.StatusOK, item.Status)
+			assert.NotNil(t, item.Result)
+			assert.Nil(t, item.Error)
+		}
+		assert.Equal(t, "ES", response.Results[1].Result.CountryCode)
+	})
+
+	t.Run("Mixed", func(t *testing.T) {
+		w := post(`{"items":[{"phoneNumber":"+12125690123"},{"phoneNumber":"+1212"},{"phoneNumber":"+33142685300"}]}`)
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+
+		response := decode(t, w)
+		assert.Equal(t, api.BatchSummary{
+			Total: 3, ValidCount: 1, FailedCount: 2,
+			FailureReasons: map[string]map[string]int64{"US": {string(api.ErrorLengthOutOfRange): 1}, "FR": {string(api.ErrorCountryDisabled): 1}},
+		}, response.Summary)
+		assert.Equal(t, http.StatusOK, response.Results[0].Status)
+		assert.Equal(t, http.StatusUnprocessableEntity, response.Results[1].Status)
+		assert.Equal(t, "length is invalid for country", response.Results[1].Error.Error["phoneNumber"])
+		assert.Equal(t, http.StatusForbidden, response.Results[2].Status)
+		assert.Equal(t, api.ErrorCountryDisabled, response.Results[2].Error.Code)
+	})
+
+	t.Run("All Invalid", func(t *testing.T) {
+		w := post(`{"items":[{"phoneNumber":"abc"},{"phoneNumber":""}]}`)
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+
+		response := decode(t, w)
+		assert.Equal(t, 2, response.Summary.FailedCount)
+		assert.Equal(t, 1, response.Results[1].Index)
+		assert.Equal(t, "required value is missing", response.Results[1].Error.Error["phoneNumber"])
+	})
+
+	t.Run("Field Paths", func(t *testing.T) {
+		w := post(`{"items":[{"phoneNumber":"+12125690123"},{"phoneNumber":"915872200","countryCode":"ESP"},{"phoneNumber":"+1212"},{"phoneNumber":"+33123456789"}]}`)
+		response := decode(t, w)
+		assert.Nil(t, response.Results[0].Error)
+		assert.Equal(t, "items[1].countryCode", response.Results[1].Error.FieldPath)
+		assert.Equal(t, "items[2].phoneNumber", response.Results[2].Error.FieldPath)
+		assert.Equal(t, "items[3].countryCode", response.Results[3].Error.FieldPath)
+
+		single := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber=%2B1212", nil)
+		router.ServeHTTP(single, req)
+		assert.Equal(t, http.StatusBadRequest, single.Code)
+		assert.NotContains(t, single.Body.String(), "fieldPath", "single lookups keep plain field names")
+	})
+
+	t.Run("Malformed Envelope", func(t *testing.T) {
+		for _, body := range []string{`not json`, `{}`, `{"items":[]}`, `{"items":"+12125690123"}`} {
+			assert.Equal(t, http.StatusBadRequest, post(body).Code, body)
+		}
+
+		items := strings.Repeat(`{"phoneNumber":"+12125690123"},`, api.MaxBatchSize)
+		assert.Equal(t, http.StatusBadRequest, post(`{"items":[`+items+`{"phoneNumber":"+12125690123"}]}`).Code)
+	})
+}
+
+func TestCountryNameLocalization(t *testing.T) {
+	router := setupTestRouter(t)
+
+	countryName := func(t *testing.T, language string) string {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", "Accept-Language", language)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response api.PhoneValidationResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		return response.CountryName
+	}
+
+	t.Run("Negotiated", func(t *testing.T) {
+		assert.Equal(t, "Estados Unidos", countryName(t, "es-ES,es;q=0.9"))
+		assert.Equal(t, "États-Unis", countryName(t, "fr"))
+		assert.Equal(t, "fr", get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123", "Accept-Language", "fr").Header().Get("Content-Language"))
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		assert.Equal(t, "United States", countryName(t, ""))
+		assert.Equal(t, "United States", countryName(t, "ja"))
+	})
+
+	t.Run("Partial Coverage", func(t *testing.T) {
+		german := api.CountryNames["de"]
+		api.CountryNames["de"] = map[string]string{"ES": "Spanien"}
+		defer func() { api.CountryNames["de"] = german }()
+
+		assert.Equal(t, "United States", countryName(t, "de"))
+
+		var response api.CountriesResponse
+		err := json.Unmarshal(get(router, "/v1/countries", "Accept-Language", "de").Body.Bytes(), &response)
+		assert.NoError(t, err)
+		names := map[string]string{}
+		for _, country := range response.Countries {
+			names[country.CountryCode] = country.CountryName
+		}
+		assert.Equal(t, "Spanien", names["ES"])
+		assert.Equal(t, "France", names["FR"])
+	})
+
+	t.Run("Countries Listing", func(t *testing.T) {
+		var response api.CountriesResponse
+		err := json.Unmarshal(get(router, "/v1/countries", "Accept-Language", "es").Body.Bytes(), &response)
+		assert.NoError(t, err)
+		for _, country := range response.Countries {
+			if country.CountryCode == "ES" {
+				assert.Equal(t, "España", country.CountryName)
+			}
+		}
+	})
+}
+
+func TestDeprecationWarnings(t *testing.T) {
+	router := setupTestRouter(t, api.WithParamAliases(map[string]string{"msisdn": "phoneNumber"}))
+
+	deprecations := func() map[string]int64 {
+		var report api.StatsResponse
+		err := json.Unmarshal(get(router, "/v1/stats").Body.Bytes(), &report)
+		assert.NoError(t, err)
+		return report.Deprecations
+	}
+
+	t.Run("Canonical Parameters", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Values("Warning"))
+		assert.Empty(t, deprecations())
+	})
+
+	t.Run("Alias Parameter", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?msisdn=%2B12125690123")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		warnings := w.Header().Values("Warning")
+		if assert.Len(t, warnings, 1) {
+			assert.True(t, strings.HasPrefix(warnings[0], `299 phone-api "parameter msisdn is an alias, use phoneNumber; see https://`), warnings[0])
+		}
+		assert.Equal(t, map[string]int64{api.DeprecationParamAlias: 1}, deprecations())
+	})
+}
+
+func TestLatencyStats(t *testing.T) {
+	router := setupTestRouter(t)
+
+	for _, url := range []string{
+		"/v1/phone-numbers?phoneNumber=%2B12125690123",
+		"/v1/phone-numbers?phoneNumber=%2B442079460958",
+		"/v1/phone-numbers?phoneNumber=%2B442079460958",
+		"/v1/countries",
+		"/v1/unknown",
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req, _ := http.NewRequest("GET", "/v1/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report api.LatencyReport
+	err := json.Unmarshal(w.Body.Bytes(), &report)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), report.Routes["/v1/phone-numbers"].Count)
+	assert.Equal(t, uint64(1), report.Routes["/v1/countries"].Count)
+	assert.NotContains(t, report.Routes, "/v1/unknown")
+	assert.Equal(t, uint64(1), report.Countries["US"].Count)
+	assert.Equal(t, uint64(2), report.Countries["GB"].Count)
+	assert.GreaterOrEqual(t, report.Countries["GB"].P99Ms, report.Countries["GB"].P50Ms)
+}
+
+func TestFailureReasons(t *testing.T) {
+	router := setupTestRouter(t)
+
+	items := []api.BatchItem{
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "+12125690123"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "+1212"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "2125690123", CountryCode: "US"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "12", CountryCode: "US"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "+49301"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "+4930123456789012"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "+12125690123", InputFormat: "bogus", CountryCode: "GB"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "2125690123", CountryCode: "XX"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "+12125690123", CountryCode: "ZZ", InputFormat: "bogus"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "+1 212 569 0123", InputFormat: "e164"}},
+	}
+	// XX is not a known country, so it is counted under other; the
+	// over-long and NOT_E164 numbers are counted under their dialing
+	// code's country.
+	want := map[string]map[string]int64{
+		"US":                   {string(api.ErrorLengthOutOfRange): 2, string(api.ErrorNotE164): 1},
+		"DE":                   {string(api.ErrorLengthOutOfRange): 1, string(api.ErrorInputTooLong): 1},
+		"GB":                   {string(api.ErrorMalformedRequest): 1},
+		api.FailureReasonOther: {string(api.ErrorUnsupportedCountryCode): 1, string(api.ErrorMalformedRequest): 1},
+	}
+
+	body, _ := json.Marshal(api.BatchRequest{Items: items})
+	req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+
+	var batch api.BatchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+	assert.Equal(t, 8, batch.Summary.FailedCount)
+	assert.Equal(t, want, batch.Summary.FailureReasons)
+
+	req, _ = http.NewRequest("GET", "/v1/phone-numbers?phoneNumber=%2B1212", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	want["US"][string(api.ErrorLengthOutOfRange)]++
+
+	req, _ = http.NewRequest("GET", "/v1/stats", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var stats api.StatsResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, want, stats.FailureReasons)
+
+	body, _ = json.Marshal(api.BatchRequest{Items: items[:2]})
+	req, _ = http.NewRequest("POST", "/v1/phone-numbers/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var second api.BatchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &second))
+	assert.Equal(t, map[string]map[string]int64{"US": {string(api.ErrorLengthOutOfRange): 1}}, second.Summary.FailureReasons, "scoped to the batch")
+}
+
+func TestFailureReasonsInputErrors(t *testing.T) {
+	router := setupTestRouter(t)
+
+	items := []api.BatchItem{
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "212abc0123", CountryCode: "US"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "2125690123"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "212 569 01 23", CountryCode: "US"}},
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: strings.Repeat("2", 65), CountryCode: "US"}},
+	}
+	body, _ := json.Marshal(api.BatchRequest{Items: items})
+	req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var batch api.BatchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+	assert.Equal(t, len(items), batch.Summary.FailedCount)
+	codes := map[string]int64{}
+	for _, byCode := range batch.Summary.FailureReasons {
+		for code, count := range byCode {
+			codes[code] += count
+		}
+	}
+	assert.Equal(t, map[string]int64{
+		string(api.ErrorInvalidCharacters):   1,
+		string(api.ErrorCountryCodeRequired): 1,
+		string(api.ErrorInvalidSpacing):      1,
+		string(api.ErrorInputTooLong):        1,
+	}, codes)
+}
+
+func TestDialingCodeOutcomes(t *testing.T) {
+	router := setupTestRouter(t)
+
+	t.Run("Unknown", func(t *testing.T) {
+		w := lookup(router, "phoneNumber="+url.QueryEscape("+999123456789"))
+		response := decodeJSON[api.ErrorResponse](t, w)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, api.ErrorUnknownDialingCode, response.Code)
+		assert.Equal(t, "dialing code is not assigned to any country", response.Error["phoneNumber"])
+		assert.Empty(t, response.RegionCode)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		w := lookup(router, "phoneNumber="+url.QueryEscape("+81312345678"))
+		response := decodeJSON[api.ErrorResponse](t, w)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, api.ErrorUnsupportedCountry, response.Code)
+		assert.Equal(t, "dialing code +81 belongs to JP, which is not supported", response.Error["phoneNumber"])
+		assert.Equal(t, "JP", response.RegionCode)
+	})
+
+	t.Run("Supported", func(t *testing.T) {
+		w := lookup(router, "phoneNumber="+url.QueryEscape("+12125690123"))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/stats", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var stats api.StatsResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		assert.Equal(t, map[string]map[string]int64{
+			"JP":                   {string(api.ErrorUnsupportedCountry): 1},
+			api.FailureReasonOther: {string(api.ErrorUnknownDialingCode): 1},
+		}, stats.FailureReasons)
+		assert.Zero(t, stats.UnmappedErrors)
+	})
+}
+
+func TestTrunkPrefix(t *testing.T) {
+	router := setupTestRouter(t)
+
+	tests := []struct {
+		country  string
+		national string
+		e164     string
+		areaCode string
+	}{
+		{"GB", "2079460958", "+442079460958", "2079"},
+		{"FR", "142685300", "+33142685300", "1"},
+		{"DE", "30123456", "+4930123456", "30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.country, func(t *testing.T) {
+			withZero := lookup(router, "phoneNumber=0"+tt.national+"&countryCode="+tt.country)
+			withoutZero := lookup(router, "phoneNumber="+tt.national+"&countryCode="+tt.country)
+			assert.Equal(t, http.StatusOK, withZero.Code, withZero.Body.String())
+			assert.Equal(t, http.StatusOK, withoutZero.Code, withoutZero.Body.String())
+
+			response := decodeJSON[api.PhoneValidationResponse](t, withZero)
+			assert.Equal(t, decodeJSON[api.PhoneValidationResponse](t, withoutZero), response)
+			assert.Equal(t, tt.e164, response.PhoneNumber)
+			assert.Equal(t, tt.areaCode, response.AreaCode)
+		})
+	}
+
+	t.Run("Italian Zero Is Significant", func(t *testing.T) {
+		w := lookup(router, "phoneNumber=0612345678&countryCode=IT")
+		assert.Equal(t, http.StatusOK, w.Code)
+		response := decodeJSON[api.PhoneValidationResponse](t, w)
+		assert.Equal(t, "+390612345678", response.PhoneNumber)
+		assert.Equal(t, "06", response.AreaCode)
+	})
+}
+
+func TestCapabilities(t *testing.T) {
+	capabilities := func(t *testing.T, router http.Handler) api.Capabilities {
+		req, _ := http.NewRequest("GET", "/v1/capabilities", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var document api.Capabilities
+		err := json.Unmarshal(w.Body.Bytes(), &document)
+		assert.NoError(t, err)
+		return document
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		document := capabilities(t, setupTestRouter(t))
+
+		assert.Contains(t, document.Endpoints, api.Endpoint{Method: "POST", Path: "/v1/phone-numbers/batch"})
+		assert.NotContains(t, document.Endpoints, api.Endpoint{Method: "PUT", Path: "/admin/disabled-countries"})
+		assert.False(t, document.Features["enum"])
+		assert.False(t, document.Features["admin"])
+		assert.Equal(t, api.MaxBatchSize, document.Limits.MaxBatchSize)
+		assert.Equal(t, api.DefaultMaxInputLength, document.Limits.MaxInputLength)
+		assert.Equal(t, []string{"de", "en", "es", "fr", "pt"}, document.Languages)
+		assert.Equal(t, api.MetadataVersion(), document.MetadataVersion)
+		assert.Empty(t, document.DisabledCountries)
+	})
+
+	t.Run("Tracks Configuration", func(t *testing.T) {
+		router := setupTestRouter(t,
+			api.WithAdminToken("secret"),
+			api.WithEnumLookup(api.NewEnumLookup(&fakeNAPTRResolver{}, "")),
+			api.WithValidatorOptions(api.WithMaxInputLength(32), api.WithDisabledCountries("FR")),
+		)
+		document := capabilities(t, router)
+
+		assert.True(t, document.Features["enum"])
+		assert.True(t, document.Features["admin"])
+		assert.Equal(t, 32, document.Limits.MaxInputLength)
+		assert.Equal(t, []string{"FR"}, document.DisabledCountries)
+	})
+}
+
+func TestBatchLookupCSV(t *testing.T) {
+	router := setupTestRouter(t)
+
+	post := func(t *testing.T, body string) (*httptest.ResponseRecorder, [][]string) {
+		req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code >= http.StatusBadRequest {
+			return w, nil
+		}
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"row", "status", "phoneNumber", "countryCode", "areaCode", "localPhoneNumber", "extension", "code", "error", "id", "ndc", "fieldPath"}, records[0])
+		return w, records[1:]
+	}
+	fixture := func(t *testing.T, name string) string {
+		data, err := os.ReadFile(filepath.Join("testdata", "batch", name))
+		assert.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("Extension Present", func(t *testing.T) {
+		w, rows := post(t, fixture(t, "with_extension.csv"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, rows, 3)
+		assert.Equal(t, []string{"1", "200", "+12125690123", "US", "212", "5690123", "123", "", "", "", "212", ""}, rows[0])
+		assert.Equal(t, "", rows[1][6])
+		assert.Equal(t, "+34915872200", rows[1][2])
+		assert.Equal(t, "4567", rows[2][6])
+	})
+
+	t.Run("Extension Column Absent", func(t *testing.T) {
+		w, rows := post(t, fixture(t, "without_extension.csv"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, rows, 2)
+		for _, row := range rows {
+			assert.Equal(t, "+12125690123", row[2])
+			assert.Equal(t, "", row[6])
+		}
+	})
+
+	t.Run("Invalid Extension", func(t *testing.T) {
+		w, rows := post(t, fixture(t, "invalid_extension.csv"))
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		for _, row := range rows[:2] {
+			assert.Equal(t, "422", row[1])
+			assert.Equal(t, string(api.ErrorInvalidExtension), row[7])
+		}
+		assert.Equal(t, "rows[2].Extension", rows[1][11])
+		assert.Equal(t, "200", rows[2][1])
+		assert.Equal(t, "99", rows[2][6])
+	})
+
+	t.Run("Row Validation Errors", func(t *testing.T) {
+		w, rows := post(t, "phoneNumber\n+1212\n2.125690123E9\n")
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		assert.Equal(t, "phoneNumber: length is invalid for country", rows[0][8])
+		assert.Equal(t, "rows[1].phoneNumber", rows[0][11])
+		assert.Equal(t, "422", rows[1][1])
+	})
+
+	t.Run("Item IDs", func(t *testing.T) {
+		w, rows := post(t, "id,phoneNumber\ncust-1,+12125690123\ncust-2,+1212\n,+442079460958\n")
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		assert.Equal(t, "cust-1", rows[0][9])
+		assert.Equal(t, "cust-2", rows[1][9])
+		assert.Equal(t, "422", rows[1][1])
+		assert.Equal(t, "", rows[2][9])
+	})
+
+	t.Run("Lenient Applies To Rows", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch?lenient=true", strings.NewReader("phoneNumber,countryCode\n2.125690123E9,US\n"))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "+12125690123")
+	})
+
+	mapped := func(t *testing.T, query, body string) (*httptest.ResponseRecorder, [][]string) {
+		req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch?"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code >= http.StatusBadRequest {
+			return w, nil
+		}
+		records, err := csv.NewReader(w.Body).ReadAll()
+		assert.NoError(t, err)
+		return w, records
+	}
+
+	t.Run("Mapped Columns", func(t *testing.T) {
+		w, records := mapped(t, "numberColumn=Telefono&countryColumn=pais&idColumn=CustomerID", fixture(t, "foreign_headers.csv"))
+		assert.Equal(t, http.StatusMultiStatus, w.Code)
+		assert.Len(t, records, 4)
+		assert.Equal(t, []string{"Notas", "Segmento"}, records[0][12:])
+		assert.Equal(t, []string{"1", "200", "+12125690123", "US", "212", "5690123", "", "", "", "c-1", "212", "", "llamar, tarde", "retail"}, records[1])
+		assert.Equal(t, "+34915872200", records[2][2])
+		assert.Equal(t, "c-2", records[2][9])
+		assert.Equal(t, []string{"", "wholesale"}, records[2][12:], "passthrough cells are not trimmed")
+		assert.Equal(t, "422", records[3][1])
+		assert.Equal(t, []string{"corto", "retail"}, records[3][12:])
+		assert.Equal(t, "rows[3].Telefono", records[3][11], "paths name the input header")
+	})
+
+	t.Run("Missing Mapped Column", func(t *testing.T) {
+		w, _ := mapped(t, "numberColumn=MSISDN", fixture(t, "foreign_headers.csv"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var body struct {
+			Error            map[string]string `json:"error"`
+			AvailableColumns []string          `json:"availableColumns"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Contains(t, body.Error["numberColumn"], `"MSISDN"`)
+		assert.Equal(t, []string{"Telefono", "Pais", "CustomerID", "Notas", "Segmento"}, body.AvailableColumns)
+	})
+
+	t.Run("Unmapped Headers Need Auto Detect", func(t *testing.T) {
+		w, _ := mapped(t, "", fixture(t, "auto_detect.csv"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Auto Detect", func(t *testing.T) {
+		w, records := mapped(t, "autoDetect=true&idColumn=Kundennummer", fixture(t, "auto_detect.csv"))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"Notiz"}, records[0][12:])
+		assert.Equal(t, "+447911123456", records[1][2])
+		assert.Equal(t, "k-1", records[1][9])
+		assert.Equal(t, "DE", records[2][3])
+		assert.Equal(t, "zweite", records[2][12])
+	})
+
+	t.Run("Extra Columns Pass Through", func(t *testing.T) {
+		w, records := mapped(t, "", "phoneNumber,note\n+12125690123,keep me\n")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"note"}, records[0][12:])
+		assert.Equal(t, "keep me", records[1][12])
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		for _, body := range []string{"", "countryCode\nUS\n", "phoneNumber\n", "phoneNumber\n\"unterminated\n"} {
+			w, _ := post(t, body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		}
+	})
+}
+
+type sseEvent struct {
+	id   string
+	name string
+	data string
+}
+
+// readSSE reads events until the server closes the stream, recording
+// comment lines as events named by their text.
+func readSSE(t *testing.T, resp *http.Response) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if current != (sseEvent{}) {
+				events = append(events, current)
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, ":"):
+			events = append(events, sseEvent{name: strings.TrimSpace(line[1:])})
+		case strings.HasPrefix(line, "id: "):
+			current.id = line[4:]
+		case strings.HasPrefix(line, "event: "):
+			current.name = line[7:]
+		case strings.HasPrefix(line, "data: "):
+			current.data = line[6:]
+		}
+	}
+	return events
+}
+
+func TestJobEvents(t *testing.T) {
+	release := make(chan struct{})
+	var calls []string
+	gate := &recordingHook{name: "gate", calls: &calls, before: func(req *api.PhoneValidationRequest) error {
+		<-release
+		return nil
+	}}
+	router := setupTestRouter(t, api.WithHooks(gate), api.WithJobProgress(1, time.Hour, 20*time.Millisecond))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/v1/jobs", "application/json",
+		strings.NewReader(`{"items":[{"phoneNumber":"+12125690123"},{"phoneNumber":"+1212"},{"phoneNumber":"+442079460958"}]}`))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	var created api.JobProgress
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, api.JobStatusRunning, created.Status)
+	assert.Equal(t, 3, created.Total)
+	assert.Equal(t, "/v1/jobs/"+created.ID, resp.Header.Get("Location"))
+
+	stream, err := http.Get(server.URL + "/v1/jobs/" + created.ID + "/events")
+	assert.NoError(t, err)
+	defer stream.Body.Close()
+	assert.Equal(t, "text/event-stream", stream.Header.Get("Content-Type"))
+
+	// Hold the job until the stream has had time to send a heartbeat.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	var names []string
+	var progress []api.JobProgress
+	for _, event := range readSSE(t, stream) {
+		if event.name == "heartbeat" {
+			if len(names) == 0 || names[len(names)-1] != "heartbeat" {
+				names = append(names, "heartbeat")
+			}
+			continue
+		}
+		names = append(names, event.name)
+		var p api.JobProgress
+		assert.NoError(t, json.Unmarshal([]byte(event.data), &p))
+		progress = append(progress, p)
+	}
+
+	assert.Equal(t, []string{"heartbeat", "progress", "progress", "progress", "complete"}, names)
+	if assert.Len(t, progress, 4) {
+		assert.Equal(t, 1, progress[0].Processed)
+		assert.Equal(t, 0, progress[0].InvalidCount)
+		assert.Equal(t, 2, progress[1].Processed)
+		assert.Equal(t, 1, progress[1].InvalidCount)
+		assert.Equal(t, api.JobStatusComplete, progress[3].Status)
+		assert.Equal(t, &api.BatchSummary{
+			Total: 3, ValidCount: 2, FailedCount: 1,
+			FailureReasons: map[string]map[string]int64{"US": {string(api.ErrorLengthOutOfRange): 1}},
+		}, progress[3].Summary)
+	}
+
+	t.Run("Resume From Last-Event-ID", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", server.URL+"/v1/jobs/"+created.ID+"/events", nil)
+		req.Header.Set("Last-Event-ID", "2")
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		events := readSSE(t, resp)
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, "3", events[0].id)
+			assert.Equal(t, "complete", events[0].name)
+		}
+	})
+
+	t.Run("Job Results", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/v1/jobs/" + created.ID)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		var job api.JobResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+		assert.Equal(t, api.JobStatusComplete, job.Status)
+		if assert.Len(t, job.Results, 3) {
+			assert.Equal(t, http.StatusUnprocessableEntity, job.Results[1].Status)
+		}
+	})
+
+	t.Run("Unknown Job", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/v1/jobs/unknown/events")
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+// createJob posts items as a job and returns the response recorder.
+func createJob(router http.Handler, items ...string) *httptest.ResponseRecorder {
+	body := `{"items":[`
+	for i, item := range items {
+		if i > 0 {
+			body += ","
+		}
+		body += `{"phoneNumber":"` + item + `"}`
+	}
+	req, _ := http.NewRequest("POST", "/v1/jobs", strings.NewReader(body+"]}"))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+// waitForJob polls a job until it is complete and returns it.
+func waitForJob(t *testing.T, router http.Handler, id string) api.JobResponse {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		req, _ := http.NewRequest("GET", "/v1/jobs/"+id, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var job api.JobResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		if job.Status == api.JobStatusComplete || time.Now().After(deadline) {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJobSpill(t *testing.T) {
+	numbers := []string{"+12125690123", "+1212", "+442079460958", "+34915872200", "+12125690124"}
+	dir := t.TempDir()
+	orphan := filepath.Join(dir, "job-orphan.ndjson")
+	assert.NoError(t, os.WriteFile(orphan, []byte("{}\n"), 0o600))
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	router := setupTestRouter(t, api.WithJobSpill(dir, 2), api.WithClock(func() time.Time { return now }))
+
+	_, err := os.Stat(orphan)
+	assert.True(t, os.IsNotExist(err), "orphaned results file is removed on startup")
+
+	w := createJob(router, numbers...)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var created api.JobProgress
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	path := filepath.Join(dir, "job-"+created.ID+".ndjson")
+
+	job := waitForJob(t, router, created.ID)
+	assert.Equal(t, api.JobStatusComplete, job.Status)
+	if assert.Len(t, job.Results, 5) {
+		for i, result := range job.Results {
+			assert.Equal(t, i, result.Index)
+		}
+		assert.Equal(t, http.StatusUnprocessableEntity, job.Results[1].Status)
+		assert.Equal(t, "+34915872200", job.Results[3].Result.PhoneNumber)
+	}
+
+	t.Run("Results Spill To Disk", func(t *testing.T) {
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if assert.Len(t, lines, 5) {
+			var result api.BatchItemResult
+			assert.NoError(t, json.Unmarshal([]byte(lines[2]), &result))
+			assert.Equal(t, 2, result.Index)
+			assert.Equal(t, "+442079460958", result.Result.PhoneNumber)
+		}
+	})
+
+	t.Run("Paginated Results", func(t *testing.T) {
+		tests := []struct {
+			query   string
+			indexes []int
+			next    *int
+		}{
+			{"", []int{0, 1, 2, 3, 4}, nil},
+			{"?limit=2", []int{0, 1}, intPtr(2)},
+			{"?offset=2&limit=2", []int{2, 3}, intPtr(4)},
+			{"?offset=3", []int{3, 4}, nil},
+			{"?offset=9", []int{}, nil},
+		}
+		for _, tt := range tests {
+			req, _ := http.NewRequest("GET", "/v1/jobs/"+created.ID+"/results"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code, tt.query)
+
+			var page api.JobResultsPage
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+			assert.Equal(t, 5, page.Processed)
+			indexes := []int{}
+			for _, result := range page.Results {
+				indexes = append(indexes, result.Index)
+			}
+			assert.Equal(t, tt.indexes, indexes, tt.query)
+			assert.Equal(t, tt.next, page.NextOffset, tt.query)
+		}
+	})
+
+	t.Run("Invalid Page", func(t *testing.T) {
+		for _, query := range []string{"?offset=-1", "?limit=abc", "?limit=1001"} {
+			req, _ := http.NewRequest("GET", "/v1/jobs/"+created.ID+"/results"+query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+			assert.Contains(t, w.Body.String(), api.ErrorMalformedRequest)
+		}
+	})
+
+	t.Run("Delete Removes File", func(t *testing.T) {
+		w := createJob(router, numbers[0])
+		var deleted api.JobProgress
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &deleted))
+		waitForJob(t, router, deleted.ID)
+		deletedPath := filepath.Join(dir, "job-"+deleted.ID+".ndjson")
+		assert.FileExists(t, deletedPath)
+
+		req, _ := http.NewRequest("DELETE", "/v1/jobs/"+deleted.ID, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.NoFileExists(t, deletedPath)
+
+		for _, path := range []string{"/v1/jobs/" + deleted.ID, "/v1/jobs/" + deleted.ID + "/results"} {
+			req, _ = http.NewRequest("GET", path, nil)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusNotFound, w.Code, path)
+		}
+	})
+
+	t.Run("Expiry Removes File", func(t *testing.T) {
+		assert.FileExists(t, path)
+		now = now.Add(api.DefaultJobRetention + time.Minute)
+		w := createJob(router, numbers[0])
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.NoFileExists(t, path)
+	})
+}
+
+func TestJobLimits(t *testing.T) {
+	t.Run("Concurrent Jobs", func(t *testing.T) {
+		release := make(chan struct{})
+		var calls []string
+		gate := &recordingHook{name: "gate", calls: &calls, before: func(req *api.PhoneValidationRequest) error {
+			<-release
+			return nil
+		}}
+		router := setupTestRouter(t, api.WithHooks(gate), api.WithJobLimits(1, 0))
+
+		first := createJob(router, "+12125690123")
+		assert.Equal(t, http.StatusAccepted, first.Code)
+
+		w := createJob(router, "+12125690123")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		var errorResponse api.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errorResponse))
+		assert.Equal(t, api.ErrorTooManyJobs, errorResponse.Code)
+
+		close(release)
+		var created api.JobProgress
+		assert.NoError(t, json.Unmarshal(first.Body.Bytes(), &created))
+		waitForJob(t, router, created.ID)
+		assert.Equal(t, http.StatusAccepted, createJob(router, "+12125690123").Code)
+	})
+
+	t.Run("Disk Cap", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithJobSpill(t.TempDir(), 0), api.WithJobLimits(0, 100))
+
+		w := createJob(router, "+12125690123")
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		var created api.JobProgress
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		waitForJob(t, router, created.ID)
+
+		w = createJob(router, "+12125690123")
+		assert.Equal(t, http.StatusInsufficientStorage, w.Code)
+		var errorResponse api.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errorResponse))
+		assert.Equal(t, api.ErrorJobStorageFull, errorResponse.Code)
+
+		// Deleting the job frees its share of the cap.
+		req, _ := http.NewRequest("DELETE", "/v1/jobs/"+created.ID, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, http.StatusAccepted, createJob(router, "+12125690123").Code)
+	})
+}
+
+func TestJobIdempotency(t *testing.T) {
+	// Jobs from earlier subtests still read the clock as it is advanced.
+	var clockMu sync.Mutex
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		now = now.Add(d)
+	}
+	submit := func(router http.Handler, apiKey, idempotencyKey, number string) (*httptest.ResponseRecorder, api.JobProgress) {
+		req, _ := http.NewRequest("POST", "/v1/jobs", strings.NewReader(`{"items":[{"phoneNumber":"`+number+`"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		if idempotencyKey != "" {
+			req.Header.Set(api.IdempotencyKeyHeader, idempotencyKey)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w, decodeJSON[api.JobProgress](t, w)
+	}
+
+	for adapter, router := range map[string]http.Handler{
+		"gin":    setupTestRouter(t, api.WithJobIdempotency(time.Hour), api.WithClock(clock)),
+		"stdlib": api.NewStdHandler(nil, api.WithJobIdempotency(time.Hour), api.WithClock(clock)),
+	} {
+		t.Run(adapter, func(t *testing.T) {
+			first, created := submit(router, "", "retry-1", "+12125690123")
+			assert.Equal(t, http.StatusAccepted, first.Code)
+			assert.Empty(t, first.Header().Get(api.IdempotentReplayHeader))
+			waitForJob(t, router, created.ID)
+
+			replay, replayed := submit(router, "", "retry-1", "+12125690123")
+			assert.Equal(t, http.StatusOK, replay.Code)
+			assert.Equal(t, "true", replay.Header().Get(api.IdempotentReplayHeader))
+			assert.Equal(t, "/v1/jobs/"+created.ID, replay.Header().Get("Location"))
+			assert.Equal(t, created.ID, replayed.ID)
+			assert.Equal(t, api.JobStatusComplete, replayed.Status, "a replay reports the job's current status")
+
+			conflict, _ := submit(router, "", "retry-1", "+442079460958")
+			assert.Equal(t, http.StatusConflict, conflict.Code)
+			var errorResponse api.ErrorResponse
+			assert.NoError(t, json.Unmarshal(conflict.Body.Bytes(), &errorResponse))
+			assert.Equal(t, api.ErrorIdempotencyKeyReused, errorResponse.Code)
+
+			other, otherJob := submit(router, "", "retry-2", "+12125690123")
+			assert.Equal(t, http.StatusAccepted, other.Code)
+			assert.NotEqual(t, created.ID, otherJob.ID)
+
+			unkeyed, unkeyedJob := submit(router, "", "", "+12125690123")
+			assert.Equal(t, http.StatusAccepted, unkeyed.Code)
+			assert.NotEqual(t, created.ID, unkeyedJob.ID)
+
+			tooLong, _ := submit(router, "", strings.Repeat("k", api.MaxIdempotencyKeyLength+1), "+12125690123")
+			assert.Equal(t, http.StatusBadRequest, tooLong.Code)
+			assert.Contains(t, tooLong.Body.String(), "idempotencyKey")
+		})
+	}
+
+	t.Run("Expiry", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithJobIdempotency(10*time.Minute), api.WithClock(clock))
+		_, created := submit(router, "", "retry-1", "+12125690123")
+		waitForJob(t, router, created.ID)
+
+		advance(10 * time.Minute)
+		w, recreated := submit(router, "", "retry-1", "+442079460958")
+		assert.Equal(t, http.StatusAccepted, w.Code, "an expired key may be used for another payload")
+		assert.NotEqual(t, created.ID, recreated.ID)
+	})
+
+	t.Run("Expired Job", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithJobIdempotency(24*time.Hour), api.WithClock(clock))
+		_, created := submit(router, "", "retry-1", "+12125690123")
+		waitForJob(t, router, created.ID)
+
+		advance(api.DefaultJobRetention + time.Minute)
+		w, recreated := submit(router, "", "retry-1", "+442079460958")
+		assert.Equal(t, http.StatusAccepted, w.Code, "the key expires with its job, not with the window")
+		assert.NotEqual(t, created.ID, recreated.ID)
+	})
+
+	t.Run("Deleted Job", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithJobIdempotency(time.Hour), api.WithClock(clock))
+		_, created := submit(router, "", "retry-1", "+12125690123")
+		waitForJob(t, router, created.ID)
+		req, _ := http.NewRequest("DELETE", "/v1/jobs/"+created.ID, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		w, recreated := submit(router, "", "retry-1", "+12125690123")
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.NotEqual(t, created.ID, recreated.ID)
+	})
+
+	t.Run("Disabled By Default", func(t *testing.T) {
+		router := setupTestRouter(t)
+		_, created := submit(router, "", "retry-1", "+12125690123")
+		w, second := submit(router, "", "retry-1", "+12125690123")
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.NotEqual(t, created.ID, second.ID)
+	})
+
+	t.Run("Per API Key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys.json")
+		keys, _ := json.Marshal(map[string]api.APIKeyConfig{
+			api.HashAPIKey("key-a"): {Label: "a"},
+			api.HashAPIKey("key-b"): {Label: "b"},
+		})
+		assert.NoError(t, os.WriteFile(path, keys, 0o600))
+		store, err := api.LoadAPIKeyStore(path)
+		assert.NoError(t, err)
+		router := setupTestRouter(t, api.WithAPIKeys(store), api.WithJobIdempotency(time.Hour), api.WithClock(clock))
+
+		_, jobA := submit(router, "key-a", "shared", "+12125690123")
+		w, jobB := submit(router, "key-b", "shared", "+442079460958")
+		assert.Equal(t, http.StatusAccepted, w.Code, "another API key's idempotency keys do not conflict")
+		assert.NotEqual(t, jobA.ID, jobB.ID)
+
+		w, replayed := submit(router, "key-a", "shared", "+12125690123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, jobA.ID, replayed.ID)
+	})
+
+	t.Run("Redis Across Replicas", func(t *testing.T) {
+		redisStore := miniredis.RunT(t)
+		replica := func() http.Handler {
+			client := redis.NewClient(&redis.Options{Addr: redisStore.Addr(), MaxRetries: -1})
+			t.Cleanup(func() { client.Close() })
+			return setupTestRouter(t, api.WithRedisRateLimit(client, time.Second, false), api.WithJobIdempotency(time.Hour), api.WithClock(clock))
+		}
+		first, second := replica(), replica()
+
+		_, created := submit(first, "", "retry-1", "+12125690123")
+		w, replayed := submit(second, "", "retry-1", "+12125690123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "true", w.Header().Get(api.IdempotentReplayHeader))
+		assert.Equal(t, created.ID, replayed.ID)
+		assert.Equal(t, 1, replayed.Total)
+
+		conflict, _ := submit(second, "", "retry-1", "+442079460958")
+		assert.Equal(t, http.StatusConflict, conflict.Code)
+
+		redisStore.FastForward(time.Hour)
+		w, recreated := submit(second, "", "retry-1", "+442079460958")
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.NotEqual(t, created.ID, recreated.ID)
+	})
+
+	t.Run("Redis Follows The Job", func(t *testing.T) {
+		redisStore := miniredis.RunT(t)
+		replica := func() http.Handler {
+			client := redis.NewClient(&redis.Options{Addr: redisStore.Addr(), MaxRetries: -1})
+			t.Cleanup(func() { client.Close() })
+			return setupTestRouter(t, api.WithRedisRateLimit(client, time.Second, false), api.WithJobIdempotency(24*time.Hour), api.WithClock(clock))
+		}
+		first, second := replica(), replica()
+
+		_, created := submit(first, "", "retry-1", "+12125690123")
+		waitForJob(t, first, created.ID)
+		assert.Eventually(t, func() bool {
+			_, replayed := submit(second, "", "retry-1", "+12125690123")
+			return replayed.Status == api.JobStatusComplete
+		}, time.Second, 10*time.Millisecond, "another replica reports the status the job's replica recorded")
+
+		redisStore.FastForward(api.DefaultJobRetention)
+		w, recreated := submit(second, "", "retry-1", "+442079460958")
+		assert.Equal(t, http.StatusAccepted, w.Code, "the key expires with the completed job, not with the window")
+		assert.NotEqual(t, created.ID, recreated.ID)
+
+		req, _ := http.NewRequest("DELETE", "/v1/jobs/"+recreated.ID, nil)
+		second.ServeHTTP(httptest.NewRecorder(), req)
+		w, _ = submit(first, "", "retry-1", "+12125690123")
+		assert.Equal(t, http.StatusAccepted, w.Code, "deleting the job releases its key on every replica")
+	})
+}
+
+func TestAPITestServer(t *testing.T) {
+	server, client := apitest.NewServer(t,
+		apitest.WithAPIKey("key-es", api.APIKeyConfig{Label: "es-only", AllowedCountries: []string{"ES"}}),
+		apitest.WithDisabledCountries("FR"),
+	)
+	assert.NotEmpty(t, server.URL)
+
+	response, err := client.Lookup(context.Background(), apitest.ValidNumber("ES"), "")
+	assert.NoError(t, err)
+	assert.Equal(t, "ES", response.CountryCode)
+	assert.Equal(t, "+34915872200", response.PhoneNumber)
+
+	_, err = client.Lookup(context.Background(), apitest.ValidNumber("US"), "")
+	var lookupErr *apitest.LookupError
+	assert.ErrorAs(t, err, &lookupErr)
+	assert.Equal(t, http.StatusForbidden, lookupErr.Status)
+	assert.Equal(t, apitest.ErrorCountryNotAllowed, lookupErr.Response.Code)
+
+	_, err = client.Lookup(context.Background(), apitest.ValidNumber("FR"), "")
+	assert.ErrorAs(t, err, &lookupErr)
+	assert.Equal(t, apitest.ErrorCountryDisabled, lookupErr.Response.Code)
+
+	client.APIKey = ""
+	_, err = client.Lookup(context.Background(), apitest.ValidNumber("ES"), "")
+	assert.ErrorAs(t, err, &lookupErr)
+	assert.Equal(t, http.StatusUnauthorized, lookupErr.Status)
+
+	assert.Panics(t, func() { apitest.ValidNumber("XX") })
+}
+
+func TestBasePath(t *testing.T) {
+	t.Run("Mounted Under Prefix", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithBasePath("/api/phone/"), api.WithAdminToken("secret"))
+
+		assert.Equal(t, http.StatusOK, get(router, "/api/phone/health").Code)
+		assert.Equal(t, http.StatusOK, get(router, "/api/phone/v1/phone-numbers?phoneNumber=%2B12125690123").Code)
+
+		req, _ := http.NewRequest("POST", "/api/phone/admin/maintenance", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		req, _ = http.NewRequest("OPTIONS", "/api/phone/v1/phone-numbers", nil)
+		req.Header.Set("Accept", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var options api.RouteCapabilities
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &options))
+		assert.NotEmpty(t, options.Parameters)
+
+		w = get(router, "/api/phone/v1/capabilities")
+		var capabilities api.Capabilities
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &capabilities))
+		for _, endpoint := range capabilities.Endpoints {
+			assert.True(t, strings.HasPrefix(endpoint.Path, "/api/phone/"), endpoint.Path)
+		}
+
+		req, _ = http.NewRequest("POST", "/api/phone/v1/jobs", strings.NewReader(`{"items":[{"phoneNumber":"+12125690123"}]}`))
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("Location"), "/api/phone/v1/jobs/"))
+	})
+
+	t.Run("Unprefixed Path Is Not Found", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithBasePath("/api/phone"))
+
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		var response api.RouteNotFoundResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, api.ErrorRouteNotFound, response.Code)
+		assert.Equal(t, "/api/phone/v1/phone-numbers", response.DidYouMean)
+	})
+
+	t.Run("Root", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithBasePath("/"))
+
+		assert.Equal(t, http.StatusOK, get(router, "/health").Code)
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123").Code)
+		assert.Equal(t, http.StatusNotFound, get(router, "/api/phone/health").Code)
+	})
+}
+
+// lookupResult is the part of a lookup outcome every transport can
+// express, so results can be compared across them.
+type lookupResult struct {
+	Status           int
+	PhoneNumber      string
+	CountryCode      string
+	AreaCode         string
+	LocalPhoneNumber string
+	Code             api.ErrorCode
+	Error            string
+}
+
+func resultFromJSON(status int, response *api.PhoneValidationResponse, errorResponse *api.ErrorResponse) lookupResult {
+	if status == http.StatusBadRequest {
+		status = http.StatusUnprocessableEntity
+	}
+	result := lookupResult{Status: status}
+	if response != nil {
+		result.PhoneNumber = response.PhoneNumber
+		result.CountryCode = response.CountryCode
+		result.AreaCode = response.AreaCode
+		result.LocalPhoneNumber = response.LocalPhoneNumber
+	}
+	if errorResponse != nil {
+		var parts []string
+		for field, message := range errorResponse.Error {
+			parts = append(parts, field+": "+message)
+		}
+		sort.Strings(parts)
+		result.Code = errorResponse.Code
+		result.Error = strings.Join(parts, "; ")
+	}
+	return result
+}
+
+func TestCrossTransportConformance(t *testing.T) {
+	// Every transport of both adapters must answer like a gin GET.
+	adapters := map[string]http.Handler{
+		"gin":      setupTestRouter(t),
+		"stdlib":   api.NewStdHandler(nil),
+	}
+	router := adapters["gin"]
+	const options = "lenient,truncate"
+
+	items := []api.PhoneValidationRequest{
+		{PhoneNumber: "+12125690123"},
+		{PhoneNumber: "2125690123", CountryCode: "us"},
+		{PhoneNumber: "+1212"},
+		{PhoneNumber: "2125690123"},
+		{PhoneNumber: "2125690123", CountryCode: "XX"},
+		{PhoneNumber: "+12125690123."},
+		{PhoneNumber: "++442079460958"},
+		{PhoneNumber: "+1212A690123"},
+		{PhoneNumber: "+12125690123", InputFormat: "e164"},
+		{PhoneNumber: "+1 212 569 0123", InputFormat: "e164"},
+		{PhoneNumber: "+12125690123", InputFormat: "bogus"},
+		{PhoneNumber: "+442079460958", AreaCodeStyle: "national"},
+		{PhoneNumber: "+4930123456789012"},
+	}
+
+	batchItems := make([]api.BatchItem, len(items))
+	for i, item := range items {
+		batchItems[i] = api.BatchItem{PhoneValidationRequest: item}
+	}
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set(api.RequestOptionsHeader, options)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	transports := map[string]func() []lookupResult{
+		"GET": func() []lookupResult {
+			var results []lookupResult
+			for _, item := range items {
+				query := url.Values{"phoneNumber": {item.PhoneNumber}}
+				for name, value := range map[string]string{"countryCode": item.CountryCode, "inputFormat": item.InputFormat, "areaCodeStyle": item.AreaCodeStyle} {
+					if value != "" {
+						query.Set(name, value)
+					}
+				}
+				req, _ := http.NewRequest("GET", "/v1/phone-numbers?"+query.Encode(), nil)
+				w := serve(req)
+
+				var response api.PhoneValidationResponse
+				var errorResponse api.ErrorResponse
+				if w.Code == http.StatusOK {
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					results = append(results, resultFromJSON(w.Code, &response, nil))
+				} else {
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errorResponse))
+					results = append(results, resultFromJSON(w.Code, nil, &errorResponse))
+				}
+			}
+			return results
+		},
+		"Batch JSON": func() []lookupResult {
+			body, _ := json.Marshal(api.BatchRequest{Items: batchItems})
+			req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := serve(req)
+
+			var response api.BatchResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			var results []lookupResult
+			for _, item := range response.Results {
+				results = append(results, resultFromJSON(item.Status, item.Result, item.Error))
+			}
+			return results
+		},
+		"Batch CSV": func() []lookupResult {
+			// CSV has no inputFormat or areaCodeStyle columns; those rows
+			// are compared through the JSON transports only.
+			var body strings.Builder
+			body.WriteString("phoneNumber,countryCode\n")
+			for _, item := range items {
+				body.WriteString(item.PhoneNumber + "," + item.CountryCode + "\n")
+			}
+			req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", strings.NewReader(body.String()))
+			req.Header.Set("Content-Type", "text/csv")
+			w := serve(req)
+
+			records, err := csv.NewReader(w.Body).ReadAll()
+			assert.NoError(t, err)
+			var results []lookupResult
+			for _, record := range records[1:] {
+				status, _ := strconv.Atoi(record[1])
+				result := lookupResult{Status: status, Code: api.ErrorCode(record[7]), Error: record[8]}
+				if status == http.StatusOK {
+					result.PhoneNumber, result.CountryCode, result.AreaCode, result.LocalPhoneNumber = record[2], record[3], record[4], record[5]
+				}
+				results = append(results, result)
+			}
+			return results
+		},
+		"Job": func() []lookupResult {
+			body, _ := json.Marshal(api.BatchRequest{Items: batchItems})
+			req, _ := http.NewRequest("POST", "/v1/jobs", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := serve(req)
+			assert.Equal(t, http.StatusAccepted, w.Code)
+
+			var job api.JobResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+			for deadline := time.Now().Add(5 * time.Second); job.Status != api.JobStatusComplete && time.Now().Before(deadline); {
+				time.Sleep(5 * time.Millisecond)
+				req, _ := http.NewRequest("GET", "/v1/jobs/"+job.ID, nil)
+				w := serve(req)
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+			}
+			assert.Equal(t, api.JobStatusComplete, job.Status)
+
+			var results []lookupResult
+			for _, item := range job.Results {
+				results = append(results, resultFromJSON(item.Status, item.Result, item.Error))
+			}
+			return results
+		},
+	}
+
+	expected := transports["GET"]()
+	assert.Len(t, expected, len(items))
+	assert.Equal(t, http.StatusOK, expected[5].Status, "lenient option applies")
+	assert.Equal(t, api.ErrorMalformedRequest, expected[10].Code)
+
+	for _, adapter := range []string{"gin", "stdlib"} {
+		router = adapters[adapter]
+		for name, run := range transports {
+			t.Run(adapter+" "+name, func(t *testing.T) {
+				results := run()
+				if !assert.Len(t, results, len(items)) {
+					return
+				}
+				for i, item := range items {
+					if name == "Batch CSV" && (item.InputFormat != "" || item.AreaCodeStyle != "") {
+						continue
+					}
+					assert.Equal(t, expected[i], results[i], "item %d %+v", i, item)
+				}
+			})
+		}
+	}
+}
+
+func TestStdHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/phone/", api.NewStdHandler(nil, api.WithBasePath("/phone")))
+	router := setupTestRouter(t, api.WithBasePath("/phone"))
+
+	serve := func(handler http.Handler, method, path, contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("X-Request-ID", "std-handler-test")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Same Responses As Gin", func(t *testing.T) {
+		requests := []struct{ method, path, contentType, body string }{
+			{"GET", "/phone/health", "", ""},
+			{"GET", "/phone/v1/phone-numbers?phoneNumber=%2B12125690123", "", ""},
+			{"GET", "/phone/v1/phone-numbers?phoneNumber=%2B1212&options=lenient,bogus", "", ""},
+			{"GET", "/phone/v1/phone-numbers?phoneNumber=%2B12125690123&lenient=maybe", "", ""},
+			{"POST", "/phone/v1/phone-numbers/batch", "application/json", `{"items": [{"phoneNumber": "+12125690123"}, {"phoneNumber": "+1212"}]}`},
+			{"POST", "/phone/v1/phone-numbers/batch", "application/json", `{"items": []}`},
+			{"POST", "/phone/v1/phone-numbers/batch", "application/json", `{"items": [`},
+			{"POST", "/phone/v1/phone-numbers/batch", "text/csv; charset=utf-8", "phoneNumber,id\n+12125690123,a\n+1212,b\n"},
+			{"GET", "/phone/v1/jobs/missing", "", ""},
+		}
+		for _, r := range requests {
+			want := serve(router, r.method, r.path, r.contentType, r.body)
+			got := serve(mux, r.method, r.path, r.contentType, r.body)
+			assert.Equal(t, want.Code, got.Code, "%s %s", r.method, r.path)
+			assert.Equal(t, want.Header().Get("Content-Type"), got.Header().Get("Content-Type"), "%s %s", r.method, r.path)
+			assert.Equal(t, want.Header().Values("Warning"), got.Header().Values("Warning"), "%s %s", r.method, r.path)
+			assert.Equal(t, want.Body.String(), got.Body.String(), "%s %s", r.method, r.path)
+		}
+	})
+
+	t.Run("Jobs", func(t *testing.T) {
+		w := serve(mux, "POST", "/phone/v1/jobs", "application/json", `{"items": [{"phoneNumber": "+12125690123"}]}`)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		var job api.JobResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		assert.Equal(t, "/phone/v1/jobs/"+job.ID, w.Header().Get("Location"))
+
+		for deadline := time.Now().Add(5 * time.Second); job.Status != api.JobStatusComplete && time.Now().Before(deadline); {
+			time.Sleep(5 * time.Millisecond)
+			w = serve(mux, "GET", w.Header().Get("Location"), "", "")
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		}
+		assert.Equal(t, api.JobStatusComplete, job.Status)
+		assert.Len(t, job.Results, 1)
+	})
+
+	t.Run("Unknown Route", func(t *testing.T) {
+		w := serve(mux, "GET", "/phone/v1/phone-number", "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		var response api.RouteNotFoundResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, api.ErrorRouteNotFound, response.Code)
+		assert.Equal(t, "/phone/v1/phone-numbers", response.DidYouMean)
+		assert.Equal(t, "std-handler-test", w.Header().Get("X-Request-ID"))
+
+		assert.Equal(t, http.StatusNotFound, serve(mux, "GET", "/phone/admin/maintenance", "", "").Code)
+	})
+
+	t.Run("API Keys", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "keys.json")
+		keys := fmt.Sprintf(`{%q: {"label": "std", "rateLimitPerMinute": 1}}`, api.HashAPIKey("std-key"))
+		assert.NoError(t, os.WriteFile(path, []byte(keys), 0o600))
+		store, err := api.LoadAPIKeyStore(path)
+		assert.NoError(t, err)
+		handler := api.NewStdHandler(nil, api.WithAPIKeys(store))
+
+		assert.Equal(t, http.StatusUnauthorized, lookup(handler, "phoneNumber=%2B12125690123", "X-API-Key", "wrong").Code)
+		assert.Equal(t, http.StatusOK, lookup(handler, "phoneNumber=%2B12125690123", "X-API-Key", "std-key").Code)
+		limited := lookup(handler, "phoneNumber=%2B12125690123", "X-API-Key", "std-key")
+		assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+		assert.NotEmpty(t, limited.Header().Get("Retry-After"))
+	})
+}
+
+func TestReadinessDependencies(t *testing.T) {
+	healthy := api.HealthCheckerFunc(func(ctx context.Context) error { return nil })
+	failing := api.HealthCheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") })
+	// slow ignores its context, so only the handler's timeout ends it.
+	slow := api.HealthCheckerFunc(func(ctx context.Context) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	})
+
+	readyz := func(router http.Handler) (*httptest.ResponseRecorder, api.ReadinessResponse) {
+		req, _ := http.NewRequest("GET", "/readyz", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response api.ReadinessResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+	byName := func(response api.ReadinessResponse) map[string]api.DependencyStatus {
+		statuses := map[string]api.DependencyStatus{}
+		for _, dependency := range response.Dependencies {
+			statuses[dependency.Name] = dependency
+		}
+		return statuses
+	}
+
+	t.Run("Healthy", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithHealthChecks(api.HealthCheck{Name: "cache", Checker: healthy}))
+		w, response := readyz(router)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ready", response.Status)
+
+		statuses := byName(response)
+		assert.Equal(t, "healthy", statuses["cache"].Status)
+		assert.Equal(t, "healthy", statuses["jobs"].Status)
+		assert.True(t, statuses["jobs"].Hard)
+		assert.Equal(t, "jobs", response.Dependencies[0].Name)
+	})
+
+	t.Run("Failing Soft Dependency", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithHealthChecks(api.HealthCheck{Name: "cache", Checker: failing}))
+		w, response := readyz(router)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "unhealthy", byName(response)["cache"].Status)
+		assert.Equal(t, "connection refused", byName(response)["cache"].Error)
+	})
+
+	t.Run("Failing Hard Dependency", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithHealthChecks(
+			api.HealthCheck{Name: "cache", Checker: healthy},
+			api.HealthCheck{Name: "store", Checker: failing, Hard: true},
+		))
+		w, response := readyz(router)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "not ready", response.Status)
+		assert.Equal(t, "dependency unavailable", response.Reason)
+		assert.Equal(t, "healthy", byName(response)["cache"].Status)
+		assert.Equal(t, "unhealthy", byName(response)["store"].Status)
+	})
+
+	t.Run("Slow Hard Dependency Times Out", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithHealthChecks(
+			api.HealthCheck{Name: "store", Checker: slow, Hard: true, Timeout: 20 * time.Millisecond},
+		))
+		start := time.Now()
+		w, response := readyz(router)
+		assert.Less(t, time.Since(start), 150*time.Millisecond)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		store := byName(response)["store"]
+		assert.Equal(t, "unhealthy", store.Status)
+		assert.Contains(t, store.Error, "timed out")
+		assert.GreaterOrEqual(t, store.LatencyMs, 20.0)
+	})
+
+	t.Run("Results Are Cached", func(t *testing.T) {
+		var calls atomic.Int32
+		counting := api.HealthCheckerFunc(func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		})
+
+		router := setupTestRouter(t, api.WithHealthChecks(api.HealthCheck{Name: "cache", Checker: counting}))
+		for i := 0; i < 5; i++ {
+			readyz(router)
+		}
+		assert.Equal(t, int32(1), calls.Load())
+
+		router = setupTestRouter(t, api.WithHealthCacheTTL(0), api.WithHealthChecks(api.HealthCheck{Name: "cache", Checker: counting}))
+		readyz(router)
+		readyz(router)
+		assert.Equal(t, int32(3), calls.Load())
+	})
+}
+
+func TestLengthOutOfRange(t *testing.T) {
+	router := setupTestRouter(t)
+
+	tests := []struct {
+		phoneNumber   string
+		expectedMin   int
+		expectedMax   int
+		actual        int
+		exampleNumber string
+		message       string
+	}{
+		{"+1212569", 10, 10, 6, "+12125690123", "length is invalid for country"},
+		{"+121256901234", 10, 10, 11, "+12125690123", "length is invalid for country"},
+		{"+3931234567", 9, 10, 8, "+390612345678", "length is invalid for country: mobile numbers must have 9 to 10 digits"},
+		{"+39061234567890", 6, 11, 12, "+390612345678", "length is invalid for country: landline numbers must have 6 to 11 digits"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber="+url.QueryEscape(tt.phoneNumber), nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response api.ErrorResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, api.ErrorLengthOutOfRange, response.Code)
+			assert.Equal(t, tt.expectedMin, response.ExpectedMin)
+			assert.Equal(t, tt.expectedMax, response.ExpectedMax)
+			assert.Equal(t, tt.actual, response.Actual)
+			assert.Equal(t, tt.exampleNumber, response.ExampleNumber)
+			assert.Equal(t, tt.message, response.Error["phoneNumber"])
+		})
+	}
+}
+
+func TestBatchItemIDs(t *testing.T) {
+	router := setupTestRouter(t)
+
+	post := func(body string) api.BatchResponse {
+		req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response api.BatchResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("With IDs", func(t *testing.T) {
+		response := post(`{"items":[{"id":"  Row 7 ","phoneNumber":"+12125690123"},{"id":"row-8","phoneNumber":"+1212"}]}`)
+		if assert.Len(t, response.Results, 2) {
+			assert.Equal(t, "  Row 7 ", response.Results[0].ID)
+			assert.Equal(t, 0, response.Results[0].Index)
+			assert.Equal(t, "row-8", response.Results[1].ID)
+			assert.Equal(t, http.StatusUnprocessableEntity, response.Results[1].Status)
+		}
+		assert.Empty(t, response.Summary.DuplicateIDs)
+	})
+
+	t.Run("Without IDs", func(t *testing.T) {
+		response := post(`{"items":[{"phoneNumber":"+12125690123"},{"phoneNumber":"+442079460958"}]}`)
+		if assert.Len(t, response.Results, 2) {
+			assert.Empty(t, response.Results[1].ID)
+			assert.Equal(t, 1, response.Results[1].Index)
+		}
+		assert.Empty(t, response.Summary.DuplicateIDs)
+	})
+
+	t.Run("Duplicate IDs", func(t *testing.T) {
+		response := post(`{"items":[{"id":"b","phoneNumber":"+12125690123"},{"id":"a","phoneNumber":"+12125690123"},{"id":"b","phoneNumber":"+1212"},{"id":"a","phoneNumber":"+12125690123"},{"id":"b","phoneNumber":"+12125690123"},{"phoneNumber":"+12125690123"},{"phoneNumber":"+12125690123"}]}`)
+		assert.Equal(t, []string{"a", "b"}, response.Summary.DuplicateIDs)
+		if assert.Len(t, response.Results, 7) {
+			assert.Equal(t, "b", response.Results[2].ID)
+			assert.Equal(t, 2, response.Results[2].Index)
+		}
+	})
+}
+
+func TestNationalDestinationCode(t *testing.T) {
+	router := setupTestRouter(t)
+
+	for _, tt := range []struct {
+		phoneNumber string
+		ndc         string
+		areaCode    string
+	}{
+		{"+442079460958", "2079", "2079"},
+		{"+447911123456", "7911", ""},
+		{"+448001234567", "800", ""},
+		{"+12125690123", "212", "212"},
+	} {
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?phoneNumber="+url.QueryEscape(tt.phoneNumber)+"&areaCodeStyle=national", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response api.PhoneValidationResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, tt.ndc, response.NDC, tt.phoneNumber)
+		if tt.areaCode != "" {
+			assert.Contains(t, response.AreaCode, tt.areaCode, tt.phoneNumber)
+		} else {
+			assert.Empty(t, response.AreaCode, tt.phoneNumber)
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var stages []string
+	router := apitest.NewRouter(t,
+		apitest.WithAPIKey("key-alpha", api.APIKeyConfig{Label: "alpha", RateLimitPerMinute: 1}),
+		apitest.WithMiddlewareObserver(func(c *gin.Context, stage string) {
+			stages = append(stages, stage)
+		}),
+	)
+
+	observe := func(header ...string) *httptest.ResponseRecorder {
+		stages = nil
+		return lookup(router, "phoneNumber=%2B12125690123", header...)
+	}
+
+	t.Run("Full Stack", func(t *testing.T) {
+		w := observe("X-API-Key", "key-alpha")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{
+			api.StageRecovery, api.StageRequestID, api.StageLogging, api.StageCORS, api.StageAuth, api.StageLimits,
+		}, stages)
+		assert.NotEmpty(t, w.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("Limits After Auth", func(t *testing.T) {
+		w := observe("X-API-Key", "key-alpha")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, api.StageLimits, stages[len(stages)-1])
+	})
+
+	t.Run("Rejected By Auth", func(t *testing.T) {
+		w := observe()
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, api.StageAuth, stages[len(stages)-1])
+	})
+
+	t.Run("Preflight Stops At CORS", func(t *testing.T) {
+		w := observe("Origin", "https://example.com")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+
+		stages = nil
+		req, _ := http.NewRequest("OPTIONS", "/v1/phone-numbers", nil)
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, api.StageCORS, stages[len(stages)-1])
+	})
+}
+
+func TestProvidedCountryMismatch(t *testing.T) {
+	router := setupTestRouter(t)
+
+	w := lookup(router, "phoneNumber=%2B34915872200&countryCode=PT")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response api.PhoneValidationResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "ES", response.CountryCode)
+	assert.Equal(t, []string{api.WarningCountryCodeMismatch}, response.Warnings)
+	assert.Contains(t, w.Header().Get("Warning"), api.WarningCountryCodeMismatch)
+
+	w = lookup(router, "phoneNumber=34915872200&countryCode=PT")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errorResponse api.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errorResponse))
+	assert.Equal(t, api.ErrorLengthOutOfRange, errorResponse.Code)
+	assert.Equal(t, 9, errorResponse.ExpectedMax)
+
+	w = lookup(router, "phoneNumber=34915872200&countryCode=ES")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Warning"))
+}
+
+func TestGarbageNumbers(t *testing.T) {
+	t.Run("Missing Subscriber Number", func(t *testing.T) {
+		router := setupTestRouter(t)
+		for _, phoneNumber := range []string{"%2B1", "%2B34", "%2B234"} {
+			w := lookup(router, "phoneNumber="+phoneNumber)
+			response := decodeJSON[api.ErrorResponse](t, w)
+			assert.Equal(t, http.StatusBadRequest, w.Code, phoneNumber)
+			assert.Equal(t, api.ErrorMissingSubscriberNumber, response.Code, phoneNumber)
+			assert.Equal(t, "contains only a dialing code", response.Error["phoneNumber"])
+		}
+	})
+
+	t.Run("Suspicious Pattern Rejected", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithValidatorOptions(api.WithSuspiciousPatterns(api.SuspiciousPatternsReject)))
+		for _, query := range []string{"phoneNumber=0000000000&countryCode=US", "phoneNumber=%2B12222222222", "phoneNumber=%2B12345678901"} {
+			w := lookup(router, query)
+			response := decodeJSON[api.ErrorResponse](t, w)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+			assert.Equal(t, api.ErrorSuspiciousPattern, response.Code, query)
+		}
+	})
+
+	t.Run("Suspicious Pattern Warned On Lenient Lookups", func(t *testing.T) {
+		router := setupTestRouter(t)
+		w := lookup(router, "phoneNumber=%2B12222222222&lenient=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), api.WarningSuspiciousPattern)
+
+		assert.Equal(t, http.StatusOK, lookup(router, "phoneNumber=%2B12222222222").Code)
+	})
+}
+
+func TestWebhookTestFire(t *testing.T) {
+	var payload api.WebhookPayload
+	var signature string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		signature = r.Header.Get(api.WebhookSignatureHeader)
+		if signature != api.SignWebhookPayload("secret", body) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer receiver.Close()
+
+	fire := func(router http.Handler, body string) (*httptest.ResponseRecorder, api.WebhookDelivery) {
+		req, _ := http.NewRequest("POST", "/v1/webhooks/test", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var result api.WebhookDelivery
+		if w.Code == http.StatusOK {
+			result = decodeJSON[api.WebhookDelivery](t, w)
+		}
+		return w, result
+	}
+
+	loopback := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	client := api.NewOutboundClient(api.OutboundPolicy{AllowedSchemes: []string{"http"}, AllowedNetworks: loopback})
+
+	t.Run("Signed Sample Delivered", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithWebhooks(client, "secret"))
+		w, result := fire(router, `{"callbackUrl":"`+receiver.URL+`"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, result.Delivered)
+		assert.True(t, result.Signed)
+		assert.Equal(t, http.StatusOK, result.StatusCode)
+		assert.True(t, payload.Test)
+		assert.Equal(t, api.WebhookEventTest, payload.Event)
+		assert.True(t, strings.HasPrefix(signature, "sha256="))
+	})
+
+	t.Run("Private Receiver Blocked By Default", func(t *testing.T) {
+		router := setupTestRouter(t)
+		w, result := fire(router, `{"callbackUrl":"`+receiver.URL+`"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, result.Delivered)
+		assert.Equal(t, api.ErrorOutboundBlocked, result.Code)
+	})
+
+	t.Run("Invalid Callback URL", func(t *testing.T) {
+		router := setupTestRouter(t)
+		w, _ := fire(router, `{"callbackUrl":"not a url"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "callbackUrl")
+	})
+
+	t.Run("Rate Limited", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithWebhooks(client, "secret"))
+		for i := 0; i < api.WebhookTestsPerMinute; i++ {
+			w, _ := fire(router, `{"callbackUrl":"`+receiver.URL+`"}`)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+		w, _ := fire(router, `{"callbackUrl":"`+receiver.URL+`"}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+}
+
+func TestCountryFilters(t *testing.T) {
+	router := setupTestRouter(t)
+
+	list := func(query, language string) (int, api.CountriesResponse) {
+		w := get(router, "/v1/countries?"+query, "Accept-Language", language)
+		return w.Code, decodeJSON[api.CountriesResponse](t, w)
+	}
+	codes := func(response api.CountriesResponse) []string {
+		var codes []string
+		for _, country := range response.Countries {
+			codes = append(codes, country.CountryCode)
+		}
+		return codes
+	}
+
+	t.Run("Unfiltered", func(t *testing.T) {
+		_, response := list("", "")
+		assert.Equal(t, len(api.CountryPhoneLengths), response.Total)
+		assert.Equal(t, response.Total, response.Count)
+	})
+
+	t.Run("Dialing Code", func(t *testing.T) {
+		_, response := list("dialingCode=1", "")
+		assert.Equal(t, []string{"CA", "US"}, codes(response))
+		assert.Equal(t, 2, response.Count)
+		assert.Equal(t, len(api.CountryPhoneLengths), response.Total)
+
+		_, response = list("dialingCode=%2B44", "")
+		assert.Equal(t, []string{"GB"}, codes(response))
+	})
+
+	t.Run("Name", func(t *testing.T) {
+		_, response := list("q=uni", "")
+		assert.Equal(t, []string{"GB", "RE", "US"}, codes(response))
+
+		_, response = list("q=FRENCH", "")
+		assert.Equal(t, []string{"GF"}, codes(response))
+	})
+
+	t.Run("Localized Name", func(t *testing.T) {
+		_, response := list("q=verein", "de")
+		assert.Equal(t, []string{"GB", "US"}, codes(response))
+		assert.Equal(t, "Vereinigte Staaten", response.Countries[1].CountryName)
+
+		_, response = list("q=etats", "fr")
+		assert.Equal(t, []string{"US"}, codes(response))
+
+		_, response = list("q=verein", "")
+		assert.Empty(t, response.Countries)
+	})
+
+	t.Run("Capability", func(t *testing.T) {
+		_, response := list("capability=typeClassification", "")
+		assert.Equal(t, []string{"GB", "GF", "GP", "IT", "KR", "MQ", "NG", "RE", "ZA"}, codes(response))
+
+		status, _ := list("capability=teleportation", "")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("Combined", func(t *testing.T) {
+		_, response := list("q=sud&capability=geocoding", "fr")
+		assert.Equal(t, []string{"KR", "ZA"}, codes(response))
+
+		_, response = list("dialingCode=1&capability=typeClassification", "")
+		assert.Empty(t, response.Countries)
+		assert.Equal(t, 0, response.Count)
+	})
+
+	t.Run("No Matches", func(t *testing.T) {
+		status, response := list("q=atlantis", "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.NotNil(t, response.Countries)
+		assert.Empty(t, response.Countries)
+		assert.Equal(t, len(api.CountryPhoneLengths), response.Total)
+	})
+}
+
+func TestErrorMessageOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.json")
+	data, err := json.Marshal(map[api.ErrorCode]map[string]string{
+		api.ErrorLengthOutOfRange: {
+			"en": "Acme: {{.Country}} numbers have {{.ExpectedMin}}-{{.ExpectedMax}} digits, not {{.Actual}}. Try {{.ExampleNumber}} or see https://help.acme.example/phones",
+			"de": "Acme: {{.Country}}-Nummern haben {{.ExpectedMin}} Ziffern",
+		},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+	store, err := api.LoadErrorMessages(path)
+	assert.NoError(t, err)
+	router := setupTestRouter(t, api.WithErrorMessages(store))
+
+	response := decodeJSON[api.ErrorResponse](t, lookup(router, "phoneNumber=%2B1212"))
+	assert.Equal(t, api.ErrorLengthOutOfRange, response.Code)
+	assert.Equal(t, "Acme: US numbers have 10-10 digits, not 3. Try +12125690123 or see https://help.acme.example/phones", response.Error["phoneNumber"])
+
+	response = decodeJSON[api.ErrorResponse](t, lookup(router, "phoneNumber=%2B1212", "Accept-Language", "de-DE"))
+	assert.Equal(t, "Acme: US-Nummern haben 10 Ziffern", response.Error["phoneNumber"])
+
+	response = decodeJSON[api.ErrorResponse](t, lookup(router, "phoneNumber=%2B1212", "Accept-Language", "fr"))
+	assert.True(t, strings.HasPrefix(response.Error["phoneNumber"], "Acme: US numbers"))
+
+	response = decodeJSON[api.ErrorResponse](t, lookup(router, "phoneNumber=%2B10123456789"))
+	assert.Equal(t, api.ErrorInvalidLeadingDigit, response.Code)
+	assert.Equal(t, "cannot start with digit 0", response.Error["phoneNumber"])
+
+	response = decodeJSON[api.ErrorResponse](t, lookup(router, "phoneNumber=%2B1&lenient=true"))
+	assert.Equal(t, api.ErrorMissingSubscriberNumber, response.Code)
+	assert.Equal(t, "contains only a dialing code", response.Error["phoneNumber"])
+}
+
+func TestCacheSeed(t *testing.T) {
+	seed := filepath.Join(t.TempDir(), "seed.txt")
+	assert.NoError(t, os.WriteFile(seed, []byte("# warm numbers\n+12125690123\n\n2079460958,GB\nnot a number\n+1212\n"), 0o600))
+
+	router := setupTestRouter(t, api.WithCacheSeed(seed, time.Second, nil))
+
+	w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+
+	w = get(router, "/v1/phone-numbers?phoneNumber=2079460958&countryCode=gb")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	var response api.PhoneValidationResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "United Kingdom", response.CountryName)
+
+	w = get(router, "/v1/phone-numbers?phoneNumber=%2B493012345678")
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	w = get(router, "/v1/phone-numbers?phoneNumber=%2B493012345678")
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+
+	var report api.StatsResponse
+	assert.NoError(t, json.Unmarshal(get(router, "/v1/stats").Body.Bytes(), &report))
+	if assert.NotNil(t, report.ResultCache) {
+		assert.Equal(t, api.DefaultResultCacheSize, report.ResultCache.Capacity)
+		assert.Equal(t, int64(2), report.ResultCache.Seeded)
+		assert.Equal(t, int64(2), report.ResultCache.SeedSkipped)
+		assert.Equal(t, 3, report.ResultCache.Size)
+		assert.Equal(t, int64(3), report.ResultCache.Hits)
+	}
+
+	_, err := api.NewServer(api.Config{HandlerOptions: []api.HandlerOption{
+		api.WithCacheSeed(filepath.Join(t.TempDir(), "missing.txt"), time.Second, nil),
+	}})
+	assert.Error(t, err)
+}
+
+func TestNegativeCache(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	router := setupTestRouter(t, api.WithNegativeCache(10, 30*time.Second), api.WithClock(clock))
+
+	w := get(router, "/v1/phone-numbers?phoneNumber=%2B1212")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	first := w.Body.String()
+
+	w = get(router, "/v1/phone-numbers?phoneNumber=%2B1212")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "HIT-NEGATIVE", w.Header().Get("X-Cache"))
+	assert.JSONEq(t, first, w.Body.String(), "a hit rebuilds the same error response")
+
+	t.Run("Expiry", func(t *testing.T) {
+		now = now.Add(31 * time.Second)
+		assert.Equal(t, "MISS", get(router, "/v1/phone-numbers?phoneNumber=%2B1212").Header().Get("X-Cache"))
+		assert.Equal(t, "HIT-NEGATIVE", get(router, "/v1/phone-numbers?phoneNumber=%2B1212").Header().Get("X-Cache"))
+	})
+
+	t.Run("Missing Parameters Are Not Cached", func(t *testing.T) {
+		for _, url := range []string{"/v1/phone-numbers?phoneNumber=", "/v1/phone-numbers?phoneNumber=2125690123", "/v1/phone-numbers?phoneNumber=%2B12125690123&lenient=maybe"} {
+			for i := 0; i < 2; i++ {
+				w := get(router, url)
+				assert.Equal(t, http.StatusBadRequest, w.Code, url)
+				assert.NotEqual(t, "HIT-NEGATIVE", w.Header().Get("X-Cache"), url)
+			}
+		}
+	})
+
+	t.Run("Valid Numbers Are Not Cached", func(t *testing.T) {
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-Cache"), "the result cache is off")
+	})
+
+	var report api.StatsResponse
+	assert.NoError(t, json.Unmarshal(get(router, "/v1/stats").Body.Bytes(), &report))
+	if assert.NotNil(t, report.NegativeCache) {
+		assert.Equal(t, 10, report.NegativeCache.Capacity)
+		assert.Equal(t, 1, report.NegativeCache.Size)
+		assert.Equal(t, int64(2), report.NegativeCache.Hits)
+		assert.Nil(t, report.ResultCache)
+	}
+}
+
+func TestRedisRateLimit(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	replica := func(addr string, failClosed bool) http.Handler {
+		client := redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1})
+		t.Cleanup(func() { client.Close() })
+		return setupTestRouter(t, api.WithGlobalRateLimit(4), api.WithRedisRateLimit(client, time.Second, failClosed), api.WithClock(clock))
+	}
+
+	t.Run("Shared Across Replicas", func(t *testing.T) {
+		store := miniredis.RunT(t)
+		replicas := []http.Handler{replica(store.Addr(), false), replica(store.Addr(), false)}
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, http.StatusOK, get(replicas[i%2], "/v1/phone-numbers?phoneNumber=%2B12125690123").Code, "request %d", i)
+		}
+		for _, router := range replicas {
+			w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123")
+			assert.Equal(t, http.StatusTooManyRequests, w.Code, "each replica alone is under the limit")
+			assert.Equal(t, "15", w.Header().Get("Retry-After"), "a token refills every 15 seconds")
+		}
+
+		now = now.Add(15 * time.Second)
+		assert.Equal(t, http.StatusOK, get(replicas[1], "/v1/phone-numbers?phoneNumber=%2B12125690123").Code)
+		assert.Equal(t, http.StatusTooManyRequests, get(replicas[0], "/v1/phone-numbers?phoneNumber=%2B12125690123").Code)
+	})
+
+	t.Run("Fail Open", func(t *testing.T) {
+		store := miniredis.RunT(t)
+		router := replica(store.Addr(), false)
+		store.Close()
+		now = now.Add(time.Minute)
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123").Code, "request %d", i)
+		}
+		var report api.StatsResponse
+		assert.NoError(t, json.Unmarshal(get(router, "/v1/stats").Body.Bytes(), &report))
+		if assert.NotNil(t, report.RedisRateLimit) {
+			assert.False(t, report.RedisRateLimit.FailClosed)
+			assert.Equal(t, int64(4), report.RedisRateLimit.Errors)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123").Code, "the local limiter still applies")
+	})
+
+	t.Run("Fail Closed", func(t *testing.T) {
+		store := miniredis.RunT(t)
+		router := replica(store.Addr(), true)
+		store.Close()
+		w := get(router, "/v1/phone-numbers?phoneNumber=%2B12125690123")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "rate limiter is unavailable")
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	})
+}
+
+func TestContentType(t *testing.T) {
+	const batch = `{"items": [{"phoneNumber": "+12125690123"}]}`
+	for name, strict := range map[string]bool{"Default": false, "Strict": true} {
+		var opts []api.HandlerOption
+		if strict {
+			opts = append(opts, api.WithStrictContentType())
+		}
+		adapters := map[string]http.Handler{
+			"gin":    setupTestRouter(t, opts...),
+			"stdlib": api.NewStdHandler(nil, opts...),
+		}
+		for adapter, router := range adapters {
+			post := func(path, contentType, body string) *httptest.ResponseRecorder {
+				req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+				if contentType != "" {
+					req.Header.Set("Content-Type", contentType)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				return w
+			}
+
+			t.Run(name+"/"+adapter, func(t *testing.T) {
+				assert.Equal(t, http.StatusOK, post("/v1/phone-numbers/batch", "application/json; charset=utf-8", batch).Code)
+				assert.Equal(t, http.StatusOK, post("/v1/phone-numbers/batch", "application/json; charset=UTF-8", batch).Code)
+
+				w := post("/v1/phone-numbers/batch", "application/json; charset=utf-16", batch)
+				assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+				var errorResponse api.ErrorResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errorResponse))
+				assert.Equal(t, api.ErrorUnsupportedMediaType, errorResponse.Code)
+				assert.Equal(t, "charset must be utf-8", errorResponse.Error["contentType"])
+				assert.Equal(t, []string{"application/json", "text/csv"}, errorResponse.Accepted)
+
+				w = post("/v1/phone-numbers/batch", "text/plain", batch)
+				if strict {
+					assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errorResponse))
+					assert.Equal(t, "must be one of application/json, text/csv", errorResponse.Error["contentType"])
+					assert.Equal(t, http.StatusUnsupportedMediaType, post("/v1/phone-numbers/batch", "", batch).Code)
+					assert.Equal(t, http.StatusUnsupportedMediaType, post("/v1/jobs", "text/csv", batch).Code, "jobs read JSON only")
+				} else {
+					assert.Equal(t, http.StatusOK, w.Code, "text/plain is read as JSON")
+					assert.Equal(t, http.StatusOK, post("/v1/phone-numbers/batch", "", batch).Code)
+				}
+
+				w = post("/v1/phone-numbers/batch", "text/csv; charset=utf-8", "\xEF\xBB\xBFphoneNumber\n+12125690123\n")
+				assert.Equal(t, http.StatusOK, w.Code, "a UTF-8 byte order mark is skipped")
+				assert.Contains(t, w.Body.String(), "1,200,+12125690123,US")
+				assert.Equal(t, http.StatusUnsupportedMediaType, post("/v1/phone-numbers/batch", "text/csv; charset=utf-16", "phoneNumber\n").Code)
+			})
+		}
+	}
+}
+
+func TestServerListenAddrs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	server, err := api.NewServer(api.Config{
+		Addrs:  []string{"127.0.0.1:0", "127.0.0.1:0"},
+		Logger: log.New(&logs, "", 0),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, server.Listen())
+	addrs := server.Addrs()
+	if !assert.Len(t, addrs, 2) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Run(ctx) }()
+
+	for _, addr := range addrs {
+		resp, err := http.Get("http://" + addr.String() + "/health")
+		if assert.NoError(t, err, addr.String()) {
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode, addr.String())
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+	// Run has returned, so nothing writes to logs any more.
+	for _, addr := range addrs {
+		assert.Contains(t, logs.String(), "Serving on tcp "+addr.String())
+		_, err := net.DialTimeout("tcp", addr.String(), time.Second)
+		assert.Error(t, err, "%s still accepts connections", addr)
+	}
+
+	t.Run("Bind Failure", func(t *testing.T) {
+		taken, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer taken.Close()
+
+		server, err := api.NewServer(api.Config{Addrs: []string{"127.0.0.1:0", taken.Addr().String()}})
+		assert.NoError(t, err)
+		err = server.Run(context.Background())
+		assert.ErrorContains(t, err, taken.Addr().String())
+		assert.Empty(t, server.Addrs())
+	})
+}
+
+func TestDemoMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	server, err := api.NewServer(api.Config{
+		Logger: log.New(&logs, "", 0),
+		HandlerOptions: []api.HandlerOption{
+			api.WithAdminToken("secret"),
+			api.WithRecentLookups(10),
+			api.WithDemoMode(),
+		},
+	})
+	assert.NoError(t, err)
+	router := server.Handler()
+	send := func(method, url, contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("GET", "/v1/phone-numbers?phoneNumber=%2B12125690123", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get("X-Demo-Mode"))
+	assert.Contains(t, logs.String(), "/v1/phone-numbers")
+	assert.NotContains(t, logs.String(), "12125690123")
+
+	for _, unavailable := range []struct {
+		name, method, url, contentType, body string
+	}{
+		{"batch", "POST", "/v1/phone-numbers/batch", "application/json", `{"items": [{"phoneNumber": "+12125690123"}]}`},
+		{"csv batch", "POST", "/v1/phone-numbers/batch", "text/csv", "phoneNumber\n+12125690123\n"},
+		{"job", "POST", "/v1/jobs", "application/json", `{"items": [{"phoneNumber": "+12125690123"}]}`},
+		{"job status", "GET", "/v1/jobs/abc", "", ""},
+		{"webhook test", "POST", "/v1/webhooks/test", "application/json", `{"callbackUrl": "https://example.com"}`},
+	} {
+		w := send(unavailable.method, unavailable.url, unavailable.contentType, unavailable.body)
+		assert.Equal(t, http.StatusForbidden, w.Code, unavailable.name)
+		assert.Equal(t, "true", w.Header().Get("X-Demo-Mode"), unavailable.name)
+		var response api.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), unavailable.name)
+		assert.Equal(t, api.ErrorNotAvailableInDemo, response.Code, unavailable.name)
+	}
+
+	w = send("GET", "/admin/recent-lookups", "", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = send("GET", "/v1/capabilities", "", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var capabilities api.Capabilities
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &capabilities))
+	assert.Equal(t, api.DemoBanner, capabilities.Banner)
+	assert.False(t, capabilities.Features["batch"])
+	assert.False(t, capabilities.Features["jobs"])
+	assert.False(t, capabilities.Features["admin"])
+
+	limited := 0
+	for i := 0; i < api.DemoRateLimitPerMinute; i++ {
+		if send("GET", "/v1/countries", "", "").Code == http.StatusTooManyRequests {
+			limited++
+		}
+	}
+	// Seven /v1 requests were already counted against the shared window.
+	assert.Equal(t, 7, limited)
+	w = send("GET", "/v1/phone-numbers?phoneNumber=%2B12125690123", "", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, "true", w.Header().Get("X-Demo-Mode"))
+
+	stdRouter := api.NewStdHandler(nil, api.WithDemoMode())
+	req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", strings.NewReader(`{"items": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	stdRouter.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "true", w.Header().Get("X-Demo-Mode"))
+	assert.Contains(t, w.Body.String(), api.ErrorNotAvailableInDemo)
+}
+
+func TestCallWindow(t *testing.T) {
+	now := time.Date(2024, 3, 10, 14, 0, 0, 0, time.UTC)
+	hours, err := api.ParseCallingHours("11:00", "")
+	assert.NoError(t, err)
+	router := setupTestRouter(t, api.WithClock(func() time.Time { return now }), api.WithCallingHours(hours))
+
+	w := lookup(router, "phoneNumber=%2B12125690123")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, decodeJSON[api.PhoneValidationResponse](t, w).CallWindow)
+
+	response := decodeJSON[api.PhoneValidationResponse](t, lookup(router, "phoneNumber=%2B12125690123&callWindow=true"))
+	assert.Equal(t, &api.CallWindow{
+		Timezone:           "America/New_York",
+		UTCOffsetMinutes:   -240,
+		LocalTime:          "2024-03-10T10:00:00-04:00",
+		WithinCallingHours: false,
+	}, response.CallWindow)
+
+	now = now.Add(time.Hour)
+	response = decodeJSON[api.PhoneValidationResponse](t, lookup(router, "phoneNumber=%2B12125690123&callWindow=true"))
+	assert.True(t, response.CallWindow.WithinCallingHours)
+
+	body, _ := json.Marshal(api.BatchRequest{Items: []api.BatchItem{
+		{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: "+442079460958", CallWindow: true}},
+	}})
+	req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var batch api.BatchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+	if assert.NotNil(t, batch.Results[0].Result) {
+		assert.Equal(t, "Europe/London", batch.Results[0].Result.CallWindow.Timezone)
+		assert.Equal(t, 0, batch.Results[0].Result.CallWindow.UTCOffsetMinutes)
+	}
+}
+
+func TestNumericSourceType(t *testing.T) {
+	router := setupTestRouter(t)
+
+	w := lookup(router, "phoneNumber=142685300&countryCode=FR&sourceType=numeric")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response api.PhoneValidationResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "+33142685300", response.PhoneNumber)
+	assert.Equal(t, []api.Warning{{Code: api.WarningLeadingZeroRestored, Message: api.WarningMessages[api.WarningLeadingZeroRestored], Detail: "0"}}, response.WarningDetails)
+
+	w = lookup(router, "phoneNumber=125690123&countryCode=US&sourceType=numeric")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var errorResponse api.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errorResponse))
+	assert.Equal(t, api.ErrorPossibleIntegerTruncation, errorResponse.Code)
+	assert.Contains(t, errorResponse.Error["phoneNumber"], "several leading digits")
+
+	w = lookup(router, "phoneNumber=125690123&countryCode=US")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), string(api.ErrorLengthOutOfRange))
+
+	w = lookup(router, "phoneNumber=7911123456&countryCode=GB&sourceType=text")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), api.ErrorMalformedRequest)
+}
+
+func TestResponseCase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	keys := fmt.Sprintf(`{%q: {"label": "python", "responseCase": "snake"}, %q: {"label": "go"}}`, api.HashAPIKey("snake-key"), api.HashAPIKey("camel-key"))
+	assert.NoError(t, os.WriteFile(path, []byte(keys), 0o600))
+	store, err := api.LoadAPIKeyStore(path)
+	assert.NoError(t, err)
+
+	adapters := map[string]http.Handler{
+		"gin":    setupTestRouter(t, api.WithAPIKeys(store)),
+		"stdlib": api.NewStdHandler(nil, api.WithAPIKeys(store)),
+	}
+	for name, handler := range adapters {
+		t.Run(name, func(t *testing.T) {
+			w := lookup(handler, "phoneNumber=%2B12125690123", "X-API-Key", "snake-key")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), `"phone_number":"+12125690123"`)
+
+			w = lookup(handler, "phoneNumber=%2B1212", "X-API-Key", "snake-key")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), `"expected_min":10`)
+
+			w = lookup(handler, "phoneNumber=%2B12125690123&case=camel", "X-API-Key", "snake-key")
+			assert.Contains(t, w.Body.String(), `"phoneNumber":"+12125690123"`)
+
+			w = lookup(handler, "phoneNumber=%2B12125690123", "X-API-Key", "camel-key")
+			assert.Contains(t, w.Body.String(), `"phoneNumber":"+12125690123"`)
+
+			w = lookup(handler, "phoneNumber=%2B12125690123&case=snake", "X-API-Key", "camel-key")
+			assert.Contains(t, w.Body.String(), `"country_code":"US"`)
+
+			w = lookup(handler, "phoneNumber=%2B12125690123&case=kebab", "X-API-Key", "camel-key")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), api.ErrorMalformedRequest)
+		})
+	}
+
+	invalid := fmt.Sprintf(`{%q: {"label": "bad", "responseCase": "kebab"}}`, api.HashAPIKey("bad-key"))
+	assert.NoError(t, os.WriteFile(path, []byte(invalid), 0o600))
+	assert.Error(t, store.Reload())
+}
+
+func TestMetadataFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"countryCode": "US", "minLength": 10,`), 0o600))
+
+	_, err := api.LoadMetadataFile(path, "")
+	assert.Error(t, err)
+
+	file, err := api.LoadMetadataFile(path, api.MetadataFallbackMinimal)
+	assert.Error(t, err)
+	if !assert.NotNil(t, file) {
+		return
+	}
+	router := setupTestRouter(t, api.WithValidatorOptions(api.WithMetadataSource(file.Source())))
+
+	lookupNumber := func() api.PhoneValidationResponse {
+		w := lookup(router, "phoneNumber=%2B12125690123")
+		assert.Equal(t, http.StatusOK, w.Code)
+		return decodeJSON[api.PhoneValidationResponse](t, w)
+	}
+	readiness := func() api.ReadinessResponse {
+		w := get(router, "/readyz")
+		assert.Equal(t, http.StatusOK, w.Code)
+		return decodeJSON[api.ReadinessResponse](t, w)
+	}
+	capabilities := func() api.Capabilities {
+		return decodeJSON[api.Capabilities](t, get(router, "/v1/capabilities"))
+	}
+
+	t.Run("Degraded", func(t *testing.T) {
+		response := lookupNumber()
+		assert.Equal(t, "+12125690123", response.PhoneNumber)
+		assert.Equal(t, "US", response.CountryCode)
+		assert.Empty(t, response.NDC)
+		assert.Empty(t, response.AreaCodeName)
+		assert.Equal(t, []string{api.WarningDegradedValidation}, response.Warnings)
+
+		// A leading digit US never allocates passes on the minimal table.
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers?phoneNumber=%2B10125690123").Code)
+		assert.Equal(t, http.StatusBadRequest, get(router, "/v1/phone-numbers?phoneNumber=%2B1212").Code)
+
+		assert.True(t, readiness().Degraded)
+		assert.True(t, capabilities().Degraded)
+		assert.False(t, capabilities().Features["interpretations"])
+
+		w := get(router, "/v1/phone-numbers/interpretations?phoneNumber=2125690123")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), api.ErrorMetadataDegraded)
+	})
+
+	t.Run("Reload Recovers", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte(`[{"countryCode": "US", "minLength": 10, "maxLength": 10}]`), 0o600))
+		assert.NoError(t, file.Reload())
+
+		response := lookupNumber()
+		assert.Equal(t, "212", response.NDC)
+		assert.Equal(t, "New York", response.AreaCodeName)
+		assert.Empty(t, response.Warnings)
+
+		assert.Equal(t, http.StatusBadRequest, get(router, "/v1/phone-numbers?phoneNumber=%2B10125690123").Code)
+		assert.False(t, readiness().Degraded)
+		assert.False(t, capabilities().Degraded)
+		assert.Equal(t, http.StatusOK, get(router, "/v1/phone-numbers/interpretations?phoneNumber=2125690123").Code)
+	})
+
+	t.Run("Bad Reload Keeps Recovered Metadata", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte(`not json`), 0o600))
+		assert.Error(t, file.Reload())
+		assert.Empty(t, lookupNumber().Warnings)
+	})
+}
+
+func TestErrorEcho(t *testing.T) {
+	batch := func(t *testing.T, router http.Handler, phoneNumber string) api.BatchItemResult {
+		body, _ := json.Marshal(api.BatchRequest{Items: []api.BatchItem{{PhoneValidationRequest: api.PhoneValidationRequest{PhoneNumber: phoneNumber, CountryCode: "US"}}}})
+		req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response api.BatchResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Results, 1)
+		assert.NotNil(t, response.Results[0].Error)
+		return response.Results[0]
+	}
+
+	t.Run("Control Characters", func(t *testing.T) {
+		router := setupTestRouter(t)
+		req, _ := http.NewRequest("GET", "/v1/phone-numbers?countryCode=US&phoneNumber="+url.QueryEscape("212\x00\x1b[31m569​0123\r\n"), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var response api.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "212[31m5690123", response.PhoneNumber)
+	})
+
+	t.Run("Emoji", func(t *testing.T) {
+		router := setupTestRouter(t)
+		item := batch(t, router, "📞 call me 📞 on +1 212 569 0123 📞 ☎ anytime")
+		assert.Equal(t, "📞 call me 📞 on +1 212 569 0123 📞…", item.Error.PhoneNumber)
+		assert.Equal(t, api.DefaultEchoLength+1, utf8.RuneCountInString(item.Error.PhoneNumber))
+	})
+
+	t.Run("10 KB Input", func(t *testing.T) {
+		router := setupTestRouter(t)
+		item := batch(t, router, strings.Repeat("9", 10*1024))
+		assert.Equal(t, http.StatusUnprocessableEntity, item.Status)
+		assert.Equal(t, strings.Repeat("9", api.DefaultEchoLength)+"…", item.Error.PhoneNumber)
+
+		req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", strings.NewReader("phoneNumber\n"+strings.Repeat("x", 10*1024)+"\n"))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		records, err := csv.NewReader(w.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("x", api.DefaultEchoLength)+"…", records[1][2])
+	})
+
+	t.Run("Configured Limit", func(t *testing.T) {
+		item := batch(t, setupTestRouter(t, api.WithEchoLimit(4)), "+1 212 569 012")
+		assert.Equal(t, "+1 2…", item.Error.PhoneNumber)
+
+		item = batch(t, setupTestRouter(t, api.WithEchoLimit(-1)), strings.Repeat("9", 100))
+		assert.Equal(t, strings.Repeat("9", 100), item.Error.PhoneNumber)
+	})
+
+	t.Run("Hashed In Privacy Mode", func(t *testing.T) {
+		item := batch(t, setupTestRouter(t, api.WithPrivacyMode()), "+1212")
+		sum := sha256.Sum256([]byte("+1212"))
+		assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), item.Error.PhoneNumber)
+	})
+}
+
+type failingPortability struct{}
+
+func (failingPortability) Resolve(context.Context, string) (api.PortingInfo, error) {
+	return api.PortingInfo{}, errors.New("porting database unavailable")
+}
+
+func TestPorting(t *testing.T) {
+	ranges, err := api.LoadPortedRanges(filepath.Join("testdata", "porting", "ported_ranges.csv"))
+	assert.NoError(t, err)
+
+	validate := func(t *testing.T, router http.Handler, query string) api.PhoneValidationResponse {
+		w := lookup(router, query)
+		assert.Equal(t, http.StatusOK, w.Code)
+		return decodeJSON[api.PhoneValidationResponse](t, w)
+	}
+
+	t.Run("Ported Range", func(t *testing.T) {
+		router := setupTestRouter(t, api.WithPortability(ranges))
+		response := validate(t, router, "phoneNumber=%2B12125690123&porting=true")
+		if assert.NotNil(t, response.Ported) {
+			assert.True(t, *response.Ported)
+		}
+		assert.Equal(t, "Acme Wireless", response.PortedToCarrier)
+
+		response = validate(t, router, "phoneNumber=%2B447911123456&porting=true")
+		assert.Equal(t, "Blue Mobile", response.PortedToCarrier)
+	})
+
+	t.Run("Not Ported", func(t *testing.T) {
+		response := validate(t, setupTestRouter(t, api.WithPortability(ranges)), "phoneNumber=%2B12125690200&porting=true")
+		if assert.NotNil(t, response.Ported) {
+			assert.False(t, *response.Ported)
+		}
+		assert.Empty(t, response.PortedToCarrier)
+	})
+
+	t.Run("Only On Request", func(t *testing.T) {
+		response := validate(t, setupTestRouter(t, api.WithPortability(ranges)), "phoneNumber=%2B12125690123")
+		assert.Nil(t, response.Ported)
+		assert.Empty(t, response.PortedToCarrier)
+	})
+
+	t.Run("No-op Default", func(t *testing.T) {
+		response := validate(t, setupTestRouter(t), "phoneNumber=%2B12125690123&porting=true")
+		if assert.NotNil(t, response.Ported) {
+			assert.False(t, *response.Ported)
+		}
+		assert.Empty(t, response.Warnings)
+	})
+
+	t.Run("Resolver Failure Degrades", func(t *testing.T) {
+		response := validate(t, setupTestRouter(t, api.WithPortability(failingPortability{})), "phoneNumber=%2B12125690123&porting=true")
+		assert.Equal(t, "+12125690123", response.PhoneNumber)
+		if assert.NotNil(t, response.Ported) {
+			assert.False(t, *response.Ported)
+		}
+		assert.Equal(t, []string{api.WarningPortingLookupFailed}, response.Warnings)
+	})
+}
+
+func TestCountryPreference(t *testing.T) {
+	router := setupTestRouter(t, api.WithCountryPreference("MX", "US"))
+
+	t.Run("First Country Validates", func(t *testing.T) {
+		w := lookup(router, "phoneNumber=6313118150&lenient=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response api.PhoneValidationResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "+526313118150", response.PhoneNumber)
+		assert.Equal(t, api.CountryCodeSourcePreference, response.CountryCodeSource)
+		if assert.Len(t, response.WarningDetails, 1) {
+			assert.Equal(t, api.WarningCountryFromPreference, response.WarningDetails[0].Code)
+			assert.Equal(t, "MX", response.WarningDetails[0].Detail)
+		}
+	})
+
+	t.Run("Order Decides", func(t *testing.T) {
+		w := lookup(setupTestRouter(t, api.WithCountryPreference("us", "mx")), "phoneNumber=6313118150&lenient=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"phoneNumber":"+16313118150"`)
+	})
+
+	t.Run("Later Country Validates", func(t *testing.T) {
+		w := lookup(setupTestRouter(t, api.WithCountryPreference("ES", "US")), "phoneNumber=2125690123&lenient=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"phoneNumber":"+12125690123"`)
+	})
+
+	t.Run("No Country Validates", func(t *testing.T) {
+		w := lookup(router, "phoneNumber=915872200&lenient=true")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, lookup(setupTestRouter(t), "phoneNumber=915872200&lenient=true").Body.String(), w.Body.String())
+	})
+
+	t.Run("Strict Requests Unchanged", func(t *testing.T) {
+		w := lookup(router, "phoneNumber=6313118150")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "required value is missing")
+	})
+
+	t.Run("Unset Keeps The Error", func(t *testing.T) {
+		w := lookup(setupTestRouter(t), "phoneNumber=6313118150&lenient=true")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "required value is missing")
+	})
+
+	t.Run("Explicit Country Wins", func(t *testing.T) {
+		w := lookup(router, "phoneNumber=6313118150&countryCode=US&lenient=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response api.PhoneValidationResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "US", response.CountryCode)
+		assert.Empty(t, response.CountryCodeSource)
+	})
+
+	t.Run("Hints Win", func(t *testing.T) {
+		w := lookup(router, "phoneNumber=6313118150&countryHints=US&lenient=true")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"countryCodeSource":"hint"`)
+
+		w = lookup(router, "phoneNumber=6313118150&countryHints=ES&lenient=true")
+		assert.Equal(t, http.StatusBadRequest, w.Code, "failed hints do not fall through to the preference order")
+		assert.Contains(t, w.Body.String(), "hintFailures")
+	})
+}
+
+func TestCountryHints(t *testing.T) {
+	router := setupTestRouter(t)
+
+	t.Run("Second Hint Validates", func(t *testing.T) {
+		w := lookup(router, "phoneNumber=915872200&countryHints=US,ES,PT")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response api.PhoneValidationResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "+34915872200", response.PhoneNumber)
+		assert.Equal(t, "ES", response.CountryCode)
+		assert.Equal(t, api.CountryCodeSourceHint, response.CountryCodeSource)
+	})
+
+	t.Run("No Hint Validates", func(t *testing.T) {
+		w := lookup(router, "phoneNumber=0123456789&countryHints=pt,US")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		var errorResponse api.ErrorResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &errorResponse))
+		assert.Equal(t, map[string]string{"countryCode": "required value is missing"}, errorResponse.Error)
+		assert.Equal(t, []api.CountryHintFailure{
+			{CountryCode: "PT", Reason: string(api.ErrorLengthOutOfRange)},
+			{CountryCode: "US", Reason: string(api.ErrorInvalidLeadingDigit)},
+		}, errorResponse.HintFailures)
+	})
+
+	t.Run("Explicit Country Wins", func(t *testing.T) {
+		w := lookup(router, "phoneNumber=915872200&countryCode=PT&countryHints=ES")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response api.PhoneValidationResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "PT", response.CountryCode)
+		assert.Empty(t, response.CountryCodeSource)
+	})
+
+	t.Run("International Numbers Ignore Hints", func(t *testing.T) {
+		w := lookup(router, "phoneNumber=%2B34915872200&countryHints=PT")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "countryCodeSource")
+	})
+
+	t.Run("Invalid Hint Lists", func(t *testing.T) {
+		for _, hints := range []string{"ES,PRT", "ES,PT,FR,DE,IT,GB,US,CA,MX,BR,AR"} {
+			w := lookup(router, "phoneNumber=915872200&countryHints="+hints)
+			assert.Equal(t, http.StatusBadRequest, w.Code, hints)
+			assert.Contains(t, w.Body.String(), api.ErrorMalformedRequest, hints)
+		}
+	})
+
+	t.Run("Batch Items", func(t *testing.T) {
+		body := `{"items":[{"phoneNumber":"915872200","countryHints":"US,ES"},{"phoneNumber":"915872200"}]}`
+		req, _ := http.NewRequest("POST", "/v1/phone-numbers/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response api.BatchResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		if assert.Len(t, response.Results, 2) {
+			assert.Equal(t, "ES", response.Results[0].Result.CountryCode)
+			assert.Equal(t, api.CountryCodeSourceHint, response.Results[0].Result.CountryCodeSource)
+			assert.Equal(t, http.StatusUnprocessableEntity, response.Results[1].Status)
+		}
+	})
+}